@@ -24,6 +24,7 @@ import (
 // NOTE: json tags are required. Any new fields you add must have json tags for the fields to be serialized.
 
 // BGPAdvertisementSpec defines the desired state of BGPAdvertisement.
+// +kubebuilder:validation:XValidation:rule="!has(self.aggregation) || ((!has(self.aggregationLength) || self.aggregationLength == 32) && (!has(self.aggregationLengthV6) || self.aggregationLengthV6 == 128))",message="aggregation and aggregationLength are mutually exclusive, can only be used together with default aggregationLength (32) and aggregationLengthV6 (128)"
 // +kubebuilder:validation:XValidation:rule="!has(self.serviceSelectors) || self.serviceSelectors.size() == 0 || ((!has(self.aggregationLength) || self.aggregationLength == 32) && (!has(self.aggregationLengthV6) || self.aggregationLengthV6 == 128))",message="serviceSelectors and aggregationLength are mutually exclusive, can only be used together with default aggregationLength (32) and aggregationLengthV6 (128)"
 type BGPAdvertisementSpec struct {
 	// The aggregation-length advertisement option lets you “roll up” the /32s into a larger prefix. Defaults to 32. Works for IPv4 addresses.
@@ -37,17 +38,70 @@ type BGPAdvertisementSpec struct {
 	// +optional
 	AggregationLengthV6 *int32 `json:"aggregationLengthV6,omitempty"`
 
+	// Aggregation collapses the IPs announced by the node through this advertisement into
+	// the minimal set of prefixes covering exactly them, merging the adjacent IPs announced
+	// with the same attributes. Requires the default aggregation lengths.
+	// +optional
+	Aggregation *Aggregation `json:"aggregation,omitempty"`
+
 	// The BGP LOCAL_PREF attribute which is used by BGP best path algorithm,
 	// Path with higher localpref is preferred over one with lower localpref.
 	// +optional
 	LocalPref uint32 `json:"localPref,omitempty"`
 
 	// The BGP communities to be associated with the announcement. Each item can be a standard community of the
-	// form 1234:1234, a large community of the form large:1234:1234:1234 or the name of an alias defined in the
-	// Community CRD.
+	// form 1234:1234, a large community of the form large:1234:1234:1234, an extended community of the form
+	// target:1234:1234 or bandwidth:1234 or the name of an alias defined in the Community CRD.
 	// +optional
 	Communities []string `json:"communities,omitempty"`
 
+	// MED is the BGP MULTI_EXIT_DISC attribute, used by the peers to choose between
+	// multiple paths to the same prefix coming from the same AS. Paths with a lower MED are preferred.
+	// +optional
+	MED *MED `json:"med,omitempty"`
+
+	// LinkBandwidthPerEndpoint adds a link bandwidth extended community to the announcement,
+	// set to this value (in Mbps) multiplied by the number of ready endpoints of the service
+	// running on the node, so that the routers can spread the traffic across the nodes with
+	// weighted ECMP. The nodes with no ready endpoints announce no link bandwidth.
+	// The bandwidth is capped to 25600 Mbps. Requires the default aggregation lengths.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=25600
+	// +optional
+	LinkBandwidthPerEndpoint *uint32 `json:"linkBandwidthPerEndpoint,omitempty"`
+
+	// ASPathPrepend prepends the local ASN to the AS_PATH of the announcement, making
+	// the path less preferred by the peers.
+	// +optional
+	ASPathPrepend *ASPathPrepend `json:"asPathPrepend,omitempty"`
+
+	// Condition makes the advertisement depend on the routes received from the peers:
+	// the IPs are advertised only while the tracked routes exist, or do not exist.
+	// Only one condition can apply to the announcements towards a given peer.
+	// Not supported in native mode.
+	// +optional
+	Condition *AdvertisementCondition `json:"condition,omitempty"`
+
+	// ImportVRFs leaks the announced IPs, from the VRF of the peers they are announced to,
+	// into the given VRFs, so that they are installed in their routing tables. Use "default"
+	// for the default VRF. The leaked IPs are announced only to the peers of those VRFs
+	// selected by the advertisement. Supported in FRR mode only.
+	// +optional
+	ImportVRFs []string `json:"importVRFs,omitempty"`
+
+	// EVPN announces the IPs as EVPN Type-5 routes of the given L3VNI, instead of as unicast
+	// routes: the IPs are installed in the VRF bound to the L3VNI and exported over the l2vpn
+	// evpn address family to the selected peers, which must be in the default VRF.
+	// Not supported in native mode.
+	// +optional
+	EVPN *EVPN `json:"evpn,omitempty"`
+
+	// LabeledUnicast announces the IPs over the labeled-unicast address family (RFC 8277),
+	// instead of the unicast one, to the selected peers, which must be in the default VRF.
+	// Supported in FRR mode only.
+	// +optional
+	LabeledUnicast *LabeledUnicast `json:"labeledUnicast,omitempty"`
+
 	// The list of IPAddressPools to advertise via this advertisement, selected by name.
 	// +optional
 	IPAddressPools []string `json:"ipAddressPools,omitempty"`
@@ -74,6 +128,156 @@ type BGPAdvertisementSpec struct {
 	ServiceSelectors []metav1.LabelSelector `json:"serviceSelectors,omitempty"`
 }
 
+// Aggregation configures the automatic aggregation of the announced IPs.
+type Aggregation struct {
+	// WithinPools prevents merging the IPs of different IPAddressPools, or of different
+	// CIDRs and ranges of the same IPAddressPool, into the same prefix.
+	// +optional
+	WithinPools bool `json:"withinPools,omitempty"`
+
+	// SummaryOnly announces only the aggregated prefixes. When false, the single IPs are
+	// announced too, along with the prefixes covering them.
+	// +kubebuilder:default:=true
+	// +optional
+	SummaryOnly *bool `json:"summaryOnly,omitempty"`
+}
+
+// EVPN configures the L3VNI the IPs are announced in as EVPN Type-5 routes.
+type EVPN struct {
+	// VRF is the name of the VRF bound to the L3VNI. The VRF and the VXLAN interface
+	// of the VNI must exist on the nodes.
+	// +kubebuilder:validation:MinLength=1
+	VRF string `json:"vrf"`
+
+	// VNI is the L3VNI the routes are announced with.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=16777215
+	VNI uint32 `json:"vni"`
+
+	// RouteDistinguisher of the routes, of the form ASN:NN or IP:NN. When not set,
+	// it is derived from the router ID.
+	// +optional
+	RouteDistinguisher string `json:"routeDistinguisher,omitempty"`
+
+	// ImportRouteTargets are the route targets of the routes imported into the VRF,
+	// of the form ASN:NN or IP:NN. When empty, it is derived from the ASN and the VNI.
+	// +optional
+	ImportRouteTargets []string `json:"importRouteTargets,omitempty"`
+
+	// ExportRouteTargets are the route targets attached to the announced routes,
+	// of the form ASN:NN or IP:NN. When empty, it is derived from the ASN and the VNI.
+	// +optional
+	ExportRouteTargets []string `json:"exportRouteTargets,omitempty"`
+}
+
+// LabeledUnicast configures the labels of the IPs announced over the labeled-unicast
+// address family.
+type LabeledUnicast struct {
+	// LabelIndexes allocate a block of label indexes to each of the given IPAddressPools:
+	// each IP of a pool gets the base index plus its offset in the pool, announced as a
+	// BGP Prefix-SID so that the routers derive the label from their SRGB. The IPs of the
+	// other pools are announced with the label allocated by FRR. Requires the default
+	// aggregation lengths.
+	// +optional
+	LabelIndexes []PoolLabelIndex `json:"labelIndexes,omitempty"`
+}
+
+// PoolLabelIndex is the first label index of the IPs of an IPAddressPool.
+type PoolLabelIndex struct {
+	// IPAddressPool is the name of the pool.
+	IPAddressPool string `json:"ipAddressPool"`
+
+	// Base is the label index of the first IP of the pool.
+	// +kubebuilder:validation:Maximum=1048560
+	Base uint32 `json:"base"`
+}
+
+// ASPathPrepend configures how many times the local ASN is prepended to the AS_PATH.
+// When multiple BGPAdvertisements apply to the same prefix and peer, the highest count is used.
+type ASPathPrepend struct {
+	// Count is the number of times the local ASN is prepended to the AS_PATH.
+	// +kubebuilder:validation:Maximum=10
+	// +optional
+	Count uint32 `json:"count,omitempty"`
+
+	// PerPeer overrides the count for the given BGPPeers.
+	// +optional
+	PerPeer []PeerASPathPrepend `json:"perPeer,omitempty"`
+}
+
+// PeerASPathPrepend configures how many times the local ASN is prepended to the AS_PATH
+// of the announcements to a given BGPPeer.
+type PeerASPathPrepend struct {
+	// Peer is the name of the BGPPeer.
+	Peer string `json:"peer"`
+
+	// Count is the number of times the local ASN is prepended to the AS_PATH.
+	// +kubebuilder:validation:Maximum=10
+	Count uint32 `json:"count"`
+}
+
+// AdvertisementConditionMode tells when a conditional advertisement is announced.
+// +kubebuilder:validation:Enum=Exist;NonExist
+type AdvertisementConditionMode string
+
+const (
+	// AdvertisementConditionExist announces the IPs only while at least one
+	// of the tracked routes exists.
+	AdvertisementConditionExist AdvertisementConditionMode = "Exist"
+	// AdvertisementConditionNonExist announces the IPs only while none of the
+	// tracked routes exists, for example to announce them to a backup peer
+	// when the primary one is down.
+	AdvertisementConditionNonExist AdvertisementConditionMode = "NonExist"
+)
+
+// AdvertisementCondition selects the routes received from the peers a conditional
+// advertisement depends on. The condition is evaluated separately for each IP family,
+// against the tracked routes of the same family as the advertised IPs.
+// +kubebuilder:validation:XValidation:rule="(has(self.prefixes) && self.prefixes.size() > 0) || (has(self.peers) && self.peers.size() > 0)",message="at least one of prefixes and peers must be set"
+type AdvertisementCondition struct {
+	// Mode tells whether the IPs are announced while the tracked routes exist or
+	// while they do not exist.
+	Mode AdvertisementConditionMode `json:"mode"`
+
+	// Prefixes are the tracked routes. When empty, any route received from the
+	// selected peers is tracked.
+	// +optional
+	Prefixes []string `json:"prefixes,omitempty"`
+
+	// Peers are the names of the BGPPeers the tracked routes must be received from.
+	// When empty, the routes received from any peer are tracked. The peers must
+	// accept the tracked routes with toReceive and must be in the same VRF as the
+	// peers the advertisement is announced to.
+	// +optional
+	Peers []string `json:"peers,omitempty"`
+}
+
+// MEDMode tells how the MED of an advertisement is computed.
+// +kubebuilder:validation:Enum=Static;ReadyLocalEndpoints
+type MEDMode string
+
+const (
+	// MEDModeStatic advertises the configured value.
+	MEDModeStatic MEDMode = "Static"
+	// MEDModeReadyLocalEndpoints advertises the configured value decreased by the
+	// number of ready endpoints of the service running on the node, down to zero,
+	// so that the nodes serving more endpoints are preferred.
+	MEDModeReadyLocalEndpoints MEDMode = "ReadyLocalEndpoints"
+)
+
+// MED configures the BGP MULTI_EXIT_DISC attribute of an advertisement.
+type MED struct {
+	// Value is the MED to advertise. In ReadyLocalEndpoints mode, it is the MED
+	// advertised by the nodes with no ready endpoints and must be greater than zero.
+	// +optional
+	Value uint32 `json:"value,omitempty"`
+
+	// Mode tells how the MED is computed. Defaults to Static.
+	// ReadyLocalEndpoints requires the default aggregation lengths.
+	// +optional
+	Mode MEDMode `json:"mode,omitempty"`
+}
+
 // BGPAdvertisementStatus defines the observed state of BGPAdvertisement.
 type BGPAdvertisementStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -0,0 +1,209 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BGPExtrasSpec defines the BGP settings that are not part of the BGPPeers,
+// but are commonly required to integrate with the network.
+type BGPExtrasSpec struct {
+	// Neighbors holds the extra settings of the sessions established with
+	// the given BGPPeers.
+	// +optional
+	Neighbors []NeighborExtras `json:"neighbors,omitempty"`
+
+	// BestPath tunes the best path selection of the BGP routers.
+	// +optional
+	BestPath *BestPathExtras `json:"bestPath,omitempty"`
+
+	// PrefixLists are static prefix-lists, matched by the import and export
+	// policies of the neighbors.
+	// +optional
+	PrefixLists []PrefixList `json:"prefixLists,omitempty"`
+
+	// Raw is a snippet of FRR configuration appended verbatim to the one
+	// generated by MetalLB, for the settings that can't be expressed otherwise.
+	// It is not validated by the webhooks, and it is not supported in native mode.
+	// +optional
+	Raw string `json:"raw,omitempty"`
+}
+
+// NeighborExtras holds the extra settings of the sessions with a BGPPeer.
+type NeighborExtras struct {
+	// Peer is the name of the BGPPeer the settings apply to.
+	Peer string `json:"peer"`
+
+	// MaximumPrefix is the number of prefixes accepted from the neighbor,
+	// after which the session is torn down.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaximumPrefix *uint32 `json:"maximumPrefix,omitempty"`
+
+	// AllowASIn is the number of times the local AS number is accepted in the
+	// AS_PATH of the prefixes received from the neighbor.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10
+	// +optional
+	AllowASIn *uint32 `json:"allowASIn,omitempty"`
+
+	// ConnectTime controls how long BGP waits between connection attempts to the neighbor.
+	// It can't be set if the BGPPeer sets it already.
+	// +kubebuilder:validation:XValidation:message="connect time should be between 1 seconds to 65535",rule="duration(self).getSeconds() >= 1 && duration(self).getSeconds() <= 65535"
+	// +kubebuilder:validation:XValidation:message="connect time should contain a whole number of seconds",rule="duration(self).getMilliseconds() % 1000 == 0"
+	// +optional
+	ConnectTime *metav1.Duration `json:"connectTime,omitempty"`
+
+	// Import is the policy applied, in order, to the prefixes received from
+	// the neighbor and accepted by the BGPPeer's toReceive. In FRR-K8s mode,
+	// the prefixes are accepted by entries of the raw configuration calling it.
+	// +optional
+	Import []PolicyEntry `json:"import,omitempty"`
+
+	// Export is the policy applied, in order, to the prefixes advertised to
+	// the neighbor. In FRR-K8s mode, the prefixes are advertised by entries
+	// of the raw configuration calling it.
+	// +optional
+	Export []PolicyEntry `json:"export,omitempty"`
+}
+
+// PolicyAction is the action taken on the prefixes matching a policy entry.
+type PolicyAction string
+
+const (
+	// PolicyPermit applies the attributes of the entry to the matching prefixes,
+	// and continues with the following entries.
+	PolicyPermit PolicyAction = "permit"
+	// PolicyDeny drops the matching prefixes.
+	PolicyDeny PolicyAction = "deny"
+)
+
+// PolicyEntry is an entry of the route-map applied to a neighbor.
+type PolicyEntry struct {
+	// Action tells what to do with the matching prefixes.
+	// +kubebuilder:validation:Enum=permit;deny
+	Action PolicyAction `json:"action"`
+
+	// PrefixList is the name of the prefix-list the prefixes must match,
+	// among the ones of the BGPExtras. When empty, all the prefixes match.
+	// +optional
+	PrefixList string `json:"prefixList,omitempty"`
+
+	// LocalPref is the local preference set to the matching prefixes.
+	// +optional
+	LocalPref *uint32 `json:"localPref,omitempty"`
+
+	// Metric is the MED set to the matching prefixes.
+	// +optional
+	Metric *uint32 `json:"metric,omitempty"`
+
+	// Communities are added to the matching prefixes. They can be standard
+	// or large communities, or the names of aliases defined in the Community CRs.
+	// +optional
+	Communities []string `json:"communities,omitempty"`
+}
+
+// BestPathExtras holds the knobs of the best path selection.
+type BestPathExtras struct {
+	// ASPathMultipathRelax allows load sharing across the paths with the same
+	// AS_PATH length, but received from different AS numbers.
+	// +optional
+	ASPathMultipathRelax bool `json:"asPathMultipathRelax,omitempty"`
+
+	// ASPathIgnore ignores the AS_PATH length when selecting the best path.
+	// +optional
+	ASPathIgnore bool `json:"asPathIgnore,omitempty"`
+
+	// CompareRouterID breaks the ties between paths received from external peers
+	// using the router ID, instead of preferring the oldest path.
+	// +optional
+	CompareRouterID bool `json:"compareRouterID,omitempty"`
+
+	// MEDMissingAsWorst treats the paths without a MED as the least preferred,
+	// instead of the most preferred.
+	// +optional
+	MEDMissingAsWorst bool `json:"medMissingAsWorst,omitempty"`
+}
+
+// PrefixList is a named list of prefixes.
+type PrefixList struct {
+	// Name is the name of the prefix-list.
+	Name string `json:"name"`
+
+	// Entries are evaluated in order, the first one matching a prefix tells
+	// if it is permitted.
+	// +kubebuilder:validation:MinItems=1
+	Entries []PrefixListEntry `json:"entries"`
+}
+
+// PrefixListEntry matches the given prefix and, with the length modifiers,
+// the ones it contains.
+type PrefixListEntry struct {
+	// Action tells if the matching prefixes are permitted by the list.
+	// +kubebuilder:validation:Enum=permit;deny
+	// +kubebuilder:default:=permit
+	// +optional
+	Action PolicyAction `json:"action,omitempty"`
+
+	// Prefix is the prefix to match, in CIDR notation.
+	Prefix string `json:"prefix"`
+
+	// LE selects the matching prefixes with a length less than or equal to the given value.
+	// +kubebuilder:validation:Maximum:=128
+	// +kubebuilder:validation:Minimum:=1
+	// +optional
+	LE uint32 `json:"le,omitempty"`
+
+	// GE selects the matching prefixes with a length greater than or equal to the given value.
+	// +kubebuilder:validation:Maximum:=128
+	// +kubebuilder:validation:Minimum:=1
+	// +optional
+	GE uint32 `json:"ge,omitempty"`
+}
+
+// BGPExtrasStatus defines the observed state of BGPExtras.
+type BGPExtrasStatus struct {
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// BGPExtras holds the BGP settings that MetalLB applies on top of the
+// BGPPeers, such as the import and export policies of the neighbors.
+// The settings of all the BGPExtras are merged, each of them can be set
+// only once.
+type BGPExtras struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BGPExtrasSpec   `json:"spec,omitempty"`
+	Status BGPExtrasStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// BGPExtrasList contains a list of BGPExtras.
+type BGPExtrasList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BGPExtras `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BGPExtras{}, &BGPExtrasList{})
+}
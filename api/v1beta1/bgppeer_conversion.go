@@ -21,7 +21,9 @@ func (src *BGPPeer) ConvertTo(dstRaw conversion.Hub) error {
 	dst.Spec.RouterID = src.Spec.RouterID
 	dst.Spec.Password = src.Spec.Password
 	dst.Spec.BFDProfile = src.Spec.BFDProfile
-	dst.Spec.EBGPMultiHop = src.Spec.EBGPMultiHop
+	if src.Spec.EBGPMultiHop {
+		dst.Spec.EBGPMultiHop = &src.Spec.EBGPMultiHop
+	}
 	dst.Spec.NodeSelectors = parseNodeSelectors(src.Spec.NodeSelectors)
 	dst.ObjectMeta = src.ObjectMeta
 	return nil
@@ -38,6 +40,10 @@ func (dst *BGPPeer) ConvertFrom(srcRaw conversion.Hub) error {
 	if src.Spec.KeepaliveTime != nil {
 		ka = *src.Spec.KeepaliveTime
 	}
+	var multiHop bool
+	if src.Spec.EBGPMultiHop != nil {
+		multiHop = *src.Spec.EBGPMultiHop
+	}
 	dst.ObjectMeta = src.ObjectMeta
 	dst.Spec.MyASN = src.Spec.MyASN
 	dst.Spec.ASN = src.Spec.ASN
@@ -49,7 +55,7 @@ func (dst *BGPPeer) ConvertFrom(srcRaw conversion.Hub) error {
 	dst.Spec.RouterID = src.Spec.RouterID
 	dst.Spec.Password = src.Spec.Password
 	dst.Spec.BFDProfile = src.Spec.BFDProfile
-	dst.Spec.EBGPMultiHop = src.Spec.EBGPMultiHop
+	dst.Spec.EBGPMultiHop = multiHop
 	dst.Spec.NodeSelectors = labelsToLegacySelector(src.Spec.NodeSelectors)
 	return nil
 }
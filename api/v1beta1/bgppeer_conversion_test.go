@@ -10,6 +10,7 @@ import (
 	"go.universe.tf/metallb/api/v1beta2"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 )
 
 const (
@@ -66,7 +67,7 @@ func TestValidateBGPPeerConvertTo(t *testing.T) {
 			HoldTime:       &v1.Duration{Duration: 180 * time.Second},
 			RouterID:       "10.20.30.40",
 			SrcAddress:     "10.20.30.40",
-			EBGPMultiHop:   true,
+			EBGPMultiHop:   ptr.To(true),
 			Password:       "nopass",
 			PasswordSecret: corev1.SecretReference{},
 			BFDProfile:     "default",
@@ -114,7 +115,7 @@ func TestValidateBGPPeerConvertFrom(t *testing.T) {
 			HoldTime:     &v1.Duration{Duration: 180 * time.Second},
 			RouterID:     "10.20.30.40",
 			SrcAddress:   "10.20.30.40",
-			EBGPMultiHop: true,
+			EBGPMultiHop: ptr.To(true),
 			Password:     "nopass",
 			PasswordSecret: corev1.SecretReference{Name: "nosecret",
 				Namespace: "metallb-system"},
@@ -0,0 +1,103 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:generate=true
+
+// MetalLBBGPPeerSessionStateStatus defines the observed state of BGPPeerSessionState.
+type MetalLBBGPPeerSessionStateStatus struct {
+	// Node indicates the node the BGP session runs on.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="Value is immutable"
+	Node string `json:"node,omitempty"`
+
+	// Peer indicates the address or the interface of the BGP peer.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="Value is immutable"
+	Peer string `json:"peer,omitempty"`
+
+	// VRF indicates the VRF the BGP session runs on, empty for the default one.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="Value is immutable"
+	VRF string `json:"vrf,omitempty"`
+
+	// BGPStatus indicates the state of the BGP session, such as Established or Active.
+	BGPStatus string `json:"bgpStatus,omitempty"`
+
+	// BFDStatus indicates the state of the BFD session associated to the BGP session,
+	// empty if BFD is not enabled.
+	BFDStatus string `json:"bfdStatus,omitempty"`
+
+	// EstablishedTime is the time the BGP session was established at, unset
+	// if the session is not established.
+	// +optional
+	EstablishedTime *metav1.Time `json:"establishedTime,omitempty"`
+
+	// PrefixesSent is the number of prefixes advertised to the peer.
+	PrefixesSent int `json:"prefixesSent,omitempty"`
+
+	// PrefixesReceived is the number of prefixes received from the peer.
+	PrefixesReceived int `json:"prefixesReceived,omitempty"`
+
+	// LastError is the last error that made the BGP session go down or fail
+	// to establish, if any.
+	LastError string `json:"lastError,omitempty"`
+
+	// Conditions report the state of the session needing attention: the prefixLimitExceeded
+	// condition is set when the peer sent more prefixes than the maxAccepted limit of its
+	// BGPPeer, which are still accepted.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.status.node`
+// +kubebuilder:printcolumn:name="Peer",type=string,JSONPath=`.status.peer`
+// +kubebuilder:printcolumn:name="VRF",type=string,JSONPath=`.status.vrf`
+// +kubebuilder:printcolumn:name="BGP",type=string,JSONPath=`.status.bgpStatus`
+// +kubebuilder:printcolumn:name="BFD",type=string,JSONPath=`.status.bfdStatus`
+// +kubebuilder:printcolumn:name="Established",type=date,JSONPath=`.status.establishedTime`
+// BGPPeerSessionState exposes the state of a BGP session, per node and peer.
+type BGPPeerSessionState struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BGPPeerSessionStateSpec          `json:"spec,omitempty"`
+	Status MetalLBBGPPeerSessionStateStatus `json:"status,omitempty"`
+}
+
+// BGPPeerSessionStateSpec defines the desired state of BGPPeerSessionState.
+type BGPPeerSessionStateSpec struct {
+}
+
+// +kubebuilder:object:root=true
+
+// BGPPeerSessionStateList contains a list of BGPPeerSessionState.
+type BGPPeerSessionStateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BGPPeerSessionState `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BGPPeerSessionState{}, &BGPPeerSessionStateList{})
+}
@@ -23,8 +23,9 @@ import (
 type CommunityAlias struct {
 	// The name of the alias for the community.
 	Name string `json:"name,omitempty"`
-	// The BGP community value corresponding to the given name. Can be a standard community of the form 1234:1234
-	// or a large community of the form large:1234:1234:1234.
+	// The BGP community value corresponding to the given name. Can be a standard community of the form 1234:1234,
+	// a large community of the form large:1234:1234:1234 or an extended community of the form target:1234:1234
+	// or bandwidth:1234.
 	Value string `json:"value,omitempty"`
 }
 
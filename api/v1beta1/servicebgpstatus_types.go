@@ -36,9 +36,75 @@ type MetalLBServiceBGPStatus struct {
 	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="Value is immutable"
 	ServiceNamespace string `json:"serviceNamespace,omitempty"`
 
-	// Peers indicate the BGP peers for which the service is configured to be advertised to.
-	// The service being actually advertised to a given peer depends on the session state and is not indicated here.
+	// Peers indicate the BGP peers the service is advertised to, meaning that the BGP session
+	// with them is established and the prefixes of the service were sent over it. The peers whose
+	// session state the BGP implementation does not report are listed as well.
 	Peers []string `json:"peers,omitempty"`
+
+	// PeerConditions indicate, for each of the BGP peers running on the node, whether the
+	// service is advertised to it and the reason when it is not.
+	// +optional
+	// +listType=map
+	// +listMapKey=peer
+	PeerConditions []ServiceBGPPeerCondition `json:"peerConditions,omitempty"`
+
+	// Prefixes indicate, for each of the prefixes of the service announced by the node, the VRFs
+	// it is announced in, including the ones it is leaked into.
+	// +optional
+	// +listType=map
+	// +listMapKey=prefix
+	Prefixes []ServiceBGPPrefix `json:"prefixes,omitempty"`
+}
+
+// ServiceBGPPrefix indicates the VRFs a prefix of a service is announced in.
+type ServiceBGPPrefix struct {
+	// Prefix is the announced prefix, in CIDR notation.
+	Prefix string `json:"prefix"`
+
+	// VRFs are the VRFs the prefix is announced in, "default" being the default VRF.
+	VRFs []string `json:"vrfs"`
+}
+
+// ServiceBGPPeerReason explains why a service is advertised or not to a BGP peer.
+// +kubebuilder:validation:Enum=Advertised;Unknown;Pending;SessionNotEstablished;Filtered;NodeExcluded;PrefixLimitExceeded
+type ServiceBGPPeerReason string
+
+const (
+	// ServiceBGPPeerAdvertised indicates the prefixes of the service were sent to the peer.
+	ServiceBGPPeerAdvertised ServiceBGPPeerReason = "Advertised"
+	// ServiceBGPPeerUnknown indicates the service is configured to be advertised to the peer,
+	// but the BGP implementation does not report the state of the session, so whether the
+	// prefixes reached the peer is unknown.
+	ServiceBGPPeerUnknown ServiceBGPPeerReason = "Unknown"
+	// ServiceBGPPeerPending indicates the BGP session is established but the prefixes of the
+	// service were not sent to the peer yet.
+	ServiceBGPPeerPending ServiceBGPPeerReason = "Pending"
+	// ServiceBGPPeerSessionNotEstablished indicates the BGP session with the peer is down.
+	ServiceBGPPeerSessionNotEstablished ServiceBGPPeerReason = "SessionNotEstablished"
+	// ServiceBGPPeerFiltered indicates none of the BGPAdvertisements of the service select the peer.
+	ServiceBGPPeerFiltered ServiceBGPPeerReason = "Filtered"
+	// ServiceBGPPeerNodeExcluded indicates the node is excluded from announcing services.
+	ServiceBGPPeerNodeExcluded ServiceBGPPeerReason = "NodeExcluded"
+	// ServiceBGPPeerPrefixLimitExceeded indicates prefixes of the service are held back, as the
+	// peer reached its limit of advertised prefixes.
+	ServiceBGPPeerPrefixLimitExceeded ServiceBGPPeerReason = "PrefixLimitExceeded"
+)
+
+// ServiceBGPPeerCondition indicates whether a service is advertised to a BGP peer.
+type ServiceBGPPeerCondition struct {
+	// Peer is the name of the BGPPeer.
+	Peer string `json:"peer"`
+
+	// Advertised indicates whether the service is advertised to the peer.
+	Advertised bool `json:"advertised"`
+
+	// Reason explains why the service is advertised or not to the peer.
+	// +optional
+	Reason ServiceBGPPeerReason `json:"reason,omitempty"`
+
+	// Message is a human readable description of the reason.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -47,7 +113,7 @@ type MetalLBServiceBGPStatus struct {
 // +kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.status.node`
 // +kubebuilder:printcolumn:name="Service Name",type=string,JSONPath=`.status.serviceName`
 // +kubebuilder:printcolumn:name="Service Namespace",type=string,JSONPath=`.status.serviceNamespace`
-// ServiceBGPStatus exposes the BGP peers a service is configured to be advertised to, per relevant node.
+// ServiceBGPStatus exposes the BGP peers a service is advertised to, per relevant node.
 type ServiceBGPStatus struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
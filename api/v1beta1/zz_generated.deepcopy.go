@@ -25,6 +25,71 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ASPathPrepend) DeepCopyInto(out *ASPathPrepend) {
+	*out = *in
+	if in.PerPeer != nil {
+		in, out := &in.PerPeer, &out.PerPeer
+		*out = make([]PeerASPathPrepend, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ASPathPrepend.
+func (in *ASPathPrepend) DeepCopy() *ASPathPrepend {
+	if in == nil {
+		return nil
+	}
+	out := new(ASPathPrepend)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdvertisementCondition) DeepCopyInto(out *AdvertisementCondition) {
+	*out = *in
+	if in.Prefixes != nil {
+		in, out := &in.Prefixes, &out.Prefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Peers != nil {
+		in, out := &in.Peers, &out.Peers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdvertisementCondition.
+func (in *AdvertisementCondition) DeepCopy() *AdvertisementCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(AdvertisementCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Aggregation) DeepCopyInto(out *Aggregation) {
+	*out = *in
+	if in.SummaryOnly != nil {
+		in, out := &in.SummaryOnly, &out.SummaryOnly
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Aggregation.
+func (in *Aggregation) DeepCopy() *Aggregation {
+	if in == nil {
+		return nil
+	}
+	out := new(Aggregation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BFDProfile) DeepCopyInto(out *BFDProfile) {
 	*out = *in
@@ -221,11 +286,51 @@ func (in *BGPAdvertisementSpec) DeepCopyInto(out *BGPAdvertisementSpec) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.Aggregation != nil {
+		in, out := &in.Aggregation, &out.Aggregation
+		*out = new(Aggregation)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Communities != nil {
 		in, out := &in.Communities, &out.Communities
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.MED != nil {
+		in, out := &in.MED, &out.MED
+		*out = new(MED)
+		**out = **in
+	}
+	if in.LinkBandwidthPerEndpoint != nil {
+		in, out := &in.LinkBandwidthPerEndpoint, &out.LinkBandwidthPerEndpoint
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.ASPathPrepend != nil {
+		in, out := &in.ASPathPrepend, &out.ASPathPrepend
+		*out = new(ASPathPrepend)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Condition != nil {
+		in, out := &in.Condition, &out.Condition
+		*out = new(AdvertisementCondition)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImportVRFs != nil {
+		in, out := &in.ImportVRFs, &out.ImportVRFs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.EVPN != nil {
+		in, out := &in.EVPN, &out.EVPN
+		*out = new(EVPN)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LabeledUnicast != nil {
+		in, out := &in.LabeledUnicast, &out.LabeledUnicast
+		*out = new(LabeledUnicast)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.IPAddressPools != nil {
 		in, out := &in.IPAddressPools, &out.IPAddressPools
 		*out = make([]string, len(*in))
@@ -284,6 +389,114 @@ func (in *BGPAdvertisementStatus) DeepCopy() *BGPAdvertisementStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BGPExtras) DeepCopyInto(out *BGPExtras) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPExtras.
+func (in *BGPExtras) DeepCopy() *BGPExtras {
+	if in == nil {
+		return nil
+	}
+	out := new(BGPExtras)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BGPExtras) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BGPExtrasList) DeepCopyInto(out *BGPExtrasList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BGPExtras, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPExtrasList.
+func (in *BGPExtrasList) DeepCopy() *BGPExtrasList {
+	if in == nil {
+		return nil
+	}
+	out := new(BGPExtrasList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BGPExtrasList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BGPExtrasSpec) DeepCopyInto(out *BGPExtrasSpec) {
+	*out = *in
+	if in.Neighbors != nil {
+		in, out := &in.Neighbors, &out.Neighbors
+		*out = make([]NeighborExtras, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BestPath != nil {
+		in, out := &in.BestPath, &out.BestPath
+		*out = new(BestPathExtras)
+		**out = **in
+	}
+	if in.PrefixLists != nil {
+		in, out := &in.PrefixLists, &out.PrefixLists
+		*out = make([]PrefixList, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPExtrasSpec.
+func (in *BGPExtrasSpec) DeepCopy() *BGPExtrasSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BGPExtrasSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BGPExtrasStatus) DeepCopyInto(out *BGPExtrasStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPExtrasStatus.
+func (in *BGPExtrasStatus) DeepCopy() *BGPExtrasStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BGPExtrasStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BGPPeer) DeepCopyInto(out *BGPPeer) {
 	*out = *in
@@ -343,6 +556,80 @@ func (in *BGPPeerList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BGPPeerSessionState) DeepCopyInto(out *BGPPeerSessionState) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPPeerSessionState.
+func (in *BGPPeerSessionState) DeepCopy() *BGPPeerSessionState {
+	if in == nil {
+		return nil
+	}
+	out := new(BGPPeerSessionState)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BGPPeerSessionState) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BGPPeerSessionStateList) DeepCopyInto(out *BGPPeerSessionStateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BGPPeerSessionState, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPPeerSessionStateList.
+func (in *BGPPeerSessionStateList) DeepCopy() *BGPPeerSessionStateList {
+	if in == nil {
+		return nil
+	}
+	out := new(BGPPeerSessionStateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BGPPeerSessionStateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BGPPeerSessionStateSpec) DeepCopyInto(out *BGPPeerSessionStateSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPPeerSessionStateSpec.
+func (in *BGPPeerSessionStateSpec) DeepCopy() *BGPPeerSessionStateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BGPPeerSessionStateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BGPPeerSpec) DeepCopyInto(out *BGPPeerSpec) {
 	*out = *in
@@ -382,6 +669,21 @@ func (in *BGPPeerStatus) DeepCopy() *BGPPeerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BestPathExtras) DeepCopyInto(out *BestPathExtras) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BestPathExtras.
+func (in *BestPathExtras) DeepCopy() *BestPathExtras {
+	if in == nil {
+		return nil
+	}
+	out := new(BestPathExtras)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Community) DeepCopyInto(out *Community) {
 	*out = *in
@@ -571,6 +873,31 @@ func (in *ConfigurationStateStatus) DeepCopy() *ConfigurationStateStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EVPN) DeepCopyInto(out *EVPN) {
+	*out = *in
+	if in.ImportRouteTargets != nil {
+		in, out := &in.ImportRouteTargets, &out.ImportRouteTargets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExportRouteTargets != nil {
+		in, out := &in.ExportRouteTargets, &out.ExportRouteTargets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EVPN.
+func (in *EVPN) DeepCopy() *EVPN {
+	if in == nil {
+		return nil
+	}
+	out := new(EVPN)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IPAddressPool) DeepCopyInto(out *IPAddressPool) {
 	*out = *in
@@ -817,6 +1144,41 @@ func (in *L2AdvertisementStatus) DeepCopy() *L2AdvertisementStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LabeledUnicast) DeepCopyInto(out *LabeledUnicast) {
+	*out = *in
+	if in.LabelIndexes != nil {
+		in, out := &in.LabelIndexes, &out.LabelIndexes
+		*out = make([]PoolLabelIndex, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LabeledUnicast.
+func (in *LabeledUnicast) DeepCopy() *LabeledUnicast {
+	if in == nil {
+		return nil
+	}
+	out := new(LabeledUnicast)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MED) DeepCopyInto(out *MED) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MED.
+func (in *MED) DeepCopy() *MED {
+	if in == nil {
+		return nil
+	}
+	out := new(MED)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MatchExpression) DeepCopyInto(out *MatchExpression) {
 	*out = *in
@@ -837,6 +1199,32 @@ func (in *MatchExpression) DeepCopy() *MatchExpression {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetalLBBGPPeerSessionStateStatus) DeepCopyInto(out *MetalLBBGPPeerSessionStateStatus) {
+	*out = *in
+	if in.EstablishedTime != nil {
+		in, out := &in.EstablishedTime, &out.EstablishedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetalLBBGPPeerSessionStateStatus.
+func (in *MetalLBBGPPeerSessionStateStatus) DeepCopy() *MetalLBBGPPeerSessionStateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MetalLBBGPPeerSessionStateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetalLBServiceBGPStatus) DeepCopyInto(out *MetalLBServiceBGPStatus) {
 	*out = *in
@@ -845,6 +1233,18 @@ func (in *MetalLBServiceBGPStatus) DeepCopyInto(out *MetalLBServiceBGPStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.PeerConditions != nil {
+		in, out := &in.PeerConditions, &out.PeerConditions
+		*out = make([]ServiceBGPPeerCondition, len(*in))
+		copy(*out, *in)
+	}
+	if in.Prefixes != nil {
+		in, out := &in.Prefixes, &out.Prefixes
+		*out = make([]ServiceBGPPrefix, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetalLBServiceBGPStatus.
@@ -877,6 +1277,50 @@ func (in *MetalLBServiceL2Status) DeepCopy() *MetalLBServiceL2Status {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NeighborExtras) DeepCopyInto(out *NeighborExtras) {
+	*out = *in
+	if in.MaximumPrefix != nil {
+		in, out := &in.MaximumPrefix, &out.MaximumPrefix
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.AllowASIn != nil {
+		in, out := &in.AllowASIn, &out.AllowASIn
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.ConnectTime != nil {
+		in, out := &in.ConnectTime, &out.ConnectTime
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Import != nil {
+		in, out := &in.Import, &out.Import
+		*out = make([]PolicyEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Export != nil {
+		in, out := &in.Export, &out.Export
+		*out = make([]PolicyEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NeighborExtras.
+func (in *NeighborExtras) DeepCopy() *NeighborExtras {
+	if in == nil {
+		return nil
+	}
+	out := new(NeighborExtras)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeSelector) DeepCopyInto(out *NodeSelector) {
 	*out = *in
@@ -906,6 +1350,66 @@ func (in *NodeSelector) DeepCopy() *NodeSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PeerASPathPrepend) DeepCopyInto(out *PeerASPathPrepend) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PeerASPathPrepend.
+func (in *PeerASPathPrepend) DeepCopy() *PeerASPathPrepend {
+	if in == nil {
+		return nil
+	}
+	out := new(PeerASPathPrepend)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyEntry) DeepCopyInto(out *PolicyEntry) {
+	*out = *in
+	if in.LocalPref != nil {
+		in, out := &in.LocalPref, &out.LocalPref
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.Metric != nil {
+		in, out := &in.Metric, &out.Metric
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.Communities != nil {
+		in, out := &in.Communities, &out.Communities
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyEntry.
+func (in *PolicyEntry) DeepCopy() *PolicyEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PoolLabelIndex) DeepCopyInto(out *PoolLabelIndex) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PoolLabelIndex.
+func (in *PoolLabelIndex) DeepCopy() *PoolLabelIndex {
+	if in == nil {
+		return nil
+	}
+	out := new(PoolLabelIndex)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PreferredNodeSelector) DeepCopyInto(out *PreferredNodeSelector) {
 	*out = *in
@@ -922,6 +1426,41 @@ func (in *PreferredNodeSelector) DeepCopy() *PreferredNodeSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrefixList) DeepCopyInto(out *PrefixList) {
+	*out = *in
+	if in.Entries != nil {
+		in, out := &in.Entries, &out.Entries
+		*out = make([]PrefixListEntry, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrefixList.
+func (in *PrefixList) DeepCopy() *PrefixList {
+	if in == nil {
+		return nil
+	}
+	out := new(PrefixList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrefixListEntry) DeepCopyInto(out *PrefixListEntry) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrefixListEntry.
+func (in *PrefixListEntry) DeepCopy() *PrefixListEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(PrefixListEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServiceAllocation) DeepCopyInto(out *ServiceAllocation) {
 	*out = *in
@@ -956,6 +1495,41 @@ func (in *ServiceAllocation) DeepCopy() *ServiceAllocation {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceBGPPeerCondition) DeepCopyInto(out *ServiceBGPPeerCondition) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceBGPPeerCondition.
+func (in *ServiceBGPPeerCondition) DeepCopy() *ServiceBGPPeerCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceBGPPeerCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceBGPPrefix) DeepCopyInto(out *ServiceBGPPrefix) {
+	*out = *in
+	if in.VRFs != nil {
+		in, out := &in.VRFs, &out.VRFs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceBGPPrefix.
+func (in *ServiceBGPPrefix) DeepCopy() *ServiceBGPPrefix {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceBGPPrefix)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServiceBGPStatus) DeepCopyInto(out *ServiceBGPStatus) {
 	*out = *in
@@ -22,12 +22,22 @@ import (
 )
 
 // BGPPeerSpec defines the desired state of Peer.
+// +kubebuilder:validation:XValidation:message="myASN must be set when no peerTemplate is referenced",rule="has(self.myASN) || has(self.peerTemplate)"
 type BGPPeerSpec struct {
 	// AS number to use for the local end of the session.
+	// Required unless it is set by the referenced PeerTemplate.
 	// +kubebuilder:validation:Minimum=0
 	// +kubebuilder:validation:Maximum=4294967295
 	// +kubebuilder:validation:Format=int64
-	MyASN uint32 `json:"myASN"`
+	// +optional
+	MyASN uint32 `json:"myASN,omitempty"`
+
+	// PeerTemplate is the name of a BGPPeerTemplate holding the settings shared with
+	// other peers. The settings of the template apply only where the peer does not
+	// set them. In FRR mode, the peers sharing a template are rendered as members
+	// of the same peer-group.
+	// +optional
+	PeerTemplate string `json:"peerTemplate,omitempty"`
 
 	// AS number to expect from the remote end of the session.
 	// ASN and DynamicASN are mutually exclusive and one of them must be specified.
@@ -65,10 +75,29 @@ type BGPPeerSpec struct {
 	// be established. No API validation takes place as that string value
 	// represents an interface name on the host and if user provides an invalid
 	// value, only the actual BGP session will not be established.
-	// Address and Interface are mutually exclusive and one of them must be specified.
+	// In native mode, the neighbor's IPv6 link-local address is discovered on
+	// the interface and IPv4 prefixes are advertised with IPv6 next hops.
+	// Address, Interface, ListenRange and AddressFrom are mutually exclusive and one of them must be specified.
 	// +optional
 	Interface string `json:"interface,omitempty"`
 
+	// ListenRange is a CIDR from which peers are allowed to establish dynamic
+	// BGP sessions. MetalLB never dials out to these peers, and accepts incoming
+	// sessions from any address in the range. Supported in native BGP mode only.
+	// +optional
+	ListenRange string `json:"listenRange,omitempty"`
+
+	// AddressFrom derives the address to dial from the node the session runs on,
+	// so that a single BGPPeer can describe a different peer on each node, such
+	// as the top of rack router of each rack.
+	// +optional
+	AddressFrom *PeerAddressSource `json:"addressFrom,omitempty"`
+
+	// Passive makes MetalLB wait for the peer to establish the BGP session
+	// instead of dialing it. Supported in native BGP mode only.
+	// +optional
+	Passive bool `json:"passive,omitempty"`
+
 	// Source address to use when establishing the session.
 	// +optional
 	SrcAddress string `json:"sourceAddress,omitempty"`
@@ -114,6 +143,13 @@ type BGPPeerSpec struct {
 	// +optional
 	PasswordSecret v1.SecretReference `json:"passwordSecret,omitempty"`
 
+	// TCPAO enables TCP Authentication Option (RFC 5925) for the session, as an
+	// alternative to TCP MD5 authentication. If a password is set too, TCP MD5 is
+	// used on kernels not supporting TCP-AO (older than Linux 6.7).
+	// Supported in native BGP mode only, for peers that are not passive.
+	// +optional
+	TCPAO *TCPAO `json:"tcpAO,omitempty"`
+
 	// The name of the BFD Profile to be used for the BFD session associated to the BGP session. If not set, the BFD session won't be set up.
 	// +optional
 	BFDProfile string `json:"bfdProfile,omitempty"`
@@ -124,11 +160,11 @@ type BGPPeerSpec struct {
 	// session. Supported for FRR-based modes (FRR-K8s, FRR) only.
 	// +optional
 	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="EnableGracefulRestart cannot be changed after creation"
-	EnableGracefulRestart bool `json:"enableGracefulRestart,omitempty"`
+	EnableGracefulRestart *bool `json:"enableGracefulRestart,omitempty"`
 
 	// To set if the BGPPeer is multi-hops away. Needed for FRR-based modes (FRR-K8s, FRR) only.
 	// +optional
-	EBGPMultiHop bool `json:"ebgpMultiHop,omitempty"`
+	EBGPMultiHop *bool `json:"ebgpMultiHop,omitempty"`
 
 	// To set if we want to peer with the BGPPeer using an interface belonging to
 	// a host vrf
@@ -147,6 +183,137 @@ type BGPPeerSpec struct {
 	// +optional
 	// +kubebuilder:default:=false
 	DualStackAddressFamily bool `json:"dualStackAddressFamily,omitempty"`
+
+	// ToReceive configures the prefixes accepted from the peer, which are installed
+	// in the routing table of the node. By default, all the incoming prefixes are denied.
+	// Supported for FRR-based modes (FRR-K8s, FRR) only.
+	// +optional
+	ToReceive *Receive `json:"toReceive,omitempty"`
+
+	// PrefixLimits guard against advertising or accepting too many prefixes, keeping
+	// the session up when they are exceeded.
+	// +optional
+	PrefixLimits *PrefixLimits `json:"prefixLimits,omitempty"`
+}
+
+// PrefixLimits caps the number of prefixes exchanged with a BGPPeer.
+type PrefixLimits struct {
+	// MaxAdvertised is the number of prefixes advertised to the peer, after which the
+	// speaker stops advertising new ones: the prefixes already advertised are kept, and
+	// the services whose prefixes are held back are reported with the PrefixLimitExceeded
+	// reason in their ServiceBGPStatus, and with an event.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxAdvertised *uint32 `json:"maxAdvertised,omitempty"`
+
+	// MaxAccepted is the number of prefixes accepted from the peer in each address family,
+	// after which FRR logs a warning instead of tearing the session down: the prefixes beyond
+	// the limit may still be accepted. The sessions exceeding it are reported with the
+	// prefixLimitExceeded condition of their BGPPeerSessionState, and with an event.
+	// It can't be set along with the maximumPrefix of the BGPExtras of the peer.
+	// Supported in FRR mode only.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxAccepted *uint32 `json:"maxAccepted,omitempty"`
+}
+
+// PeerAddressSource tells where the address of the peer is read from, on each node.
+// Exactly one of the fields must be set.
+// +kubebuilder:validation:XValidation:message="exactly one of nodeAnnotation, nodeLabel and defaultGateway must be set",rule="(has(self.nodeAnnotation) ? 1 : 0) + (has(self.nodeLabel) ? 1 : 0) + (has(self.defaultGateway) ? 1 : 0) == 1"
+type PeerAddressSource struct {
+	// NodeAnnotation is the key of the node annotation holding the address of the peer.
+	// +optional
+	NodeAnnotation string `json:"nodeAnnotation,omitempty"`
+
+	// NodeLabel is the key of the node label holding the address of the peer.
+	// As label values can't contain colons, IPv6 addresses are written with
+	// dashes in their place, for example fc00--1.
+	// +optional
+	NodeLabel string `json:"nodeLabel,omitempty"`
+
+	// DefaultGateway uses the gateway of the default route of the node for the
+	// given IP family as the address of the peer.
+	// +kubebuilder:validation:Enum=ipv4;ipv6
+	// +optional
+	DefaultGateway string `json:"defaultGateway,omitempty"`
+}
+
+// ReceiveMode tells which prefixes are accepted from a BGPPeer.
+// +kubebuilder:validation:Enum=all;filtered
+type ReceiveMode string
+
+const (
+	// ReceiveAll accepts all the prefixes sent by the peer.
+	ReceiveAll ReceiveMode = "all"
+	// ReceiveFiltered accepts only the prefixes matching the given list.
+	ReceiveFiltered ReceiveMode = "filtered"
+)
+
+// Receive configures the prefixes accepted from a BGPPeer.
+type Receive struct {
+	// Mode tells which prefixes are accepted. When set to "filtered", only the
+	// prefixes matching the given list are accepted. When set to "all", all the
+	// prefixes sent by the peer are accepted and the list must be empty.
+	// +kubebuilder:default:=filtered
+	// +optional
+	Mode ReceiveMode `json:"mode,omitempty"`
+
+	// Prefixes is the list of prefixes accepted in the filtered mode.
+	// +optional
+	Prefixes []PrefixSelector `json:"prefixes,omitempty"`
+}
+
+// PrefixSelector selects the received prefixes matching the given one. Without
+// length modifiers, only the exact prefix is selected. For example, 0.0.0.0/0 selects
+// only the default route, while 0.0.0.0/0 with le 32 selects any IPv4 prefix.
+type PrefixSelector struct {
+	// Prefix is the prefix to match, in CIDR notation.
+	Prefix string `json:"prefix"`
+
+	// LE selects the matching prefixes with a length less than or equal to the given value.
+	// +kubebuilder:validation:Maximum:=128
+	// +kubebuilder:validation:Minimum:=1
+	// +optional
+	LE uint32 `json:"le,omitempty"`
+
+	// GE selects the matching prefixes with a length greater than or equal to the given value.
+	// +kubebuilder:validation:Maximum:=128
+	// +kubebuilder:validation:Minimum:=1
+	// +optional
+	GE uint32 `json:"ge,omitempty"`
+}
+
+// TCPAO holds the TCP-AO master key tuples of a BGP session.
+type TCPAO struct {
+	// Keys are the master key tuples the session can use. To roll keys over without
+	// resetting the session, add the new key, then set it as current once the peer
+	// knows it, and finally remove the old key.
+	// +kubebuilder:validation:MinItems=1
+	Keys []TCPAOKey `json:"keys"`
+
+	// CurrentKeyID is the SendID of the key used to sign outgoing segments.
+	// Defaults to the first key.
+	// +optional
+	CurrentKeyID *uint8 `json:"currentKeyID,omitempty"`
+}
+
+// TCPAOKey is a TCP-AO master key tuple.
+type TCPAOKey struct {
+	// SendID is the KeyID of the key in the segments we send.
+	SendID uint8 `json:"sendID"`
+
+	// RecvID is the KeyID of the key in the segments the peer sends.
+	RecvID uint8 `json:"recvID"`
+
+	// Algorithm is the MAC algorithm used with the key.
+	// +kubebuilder:validation:Enum=hmac-sha1;cmac-aes128;hmac-sha256
+	// +kubebuilder:default:=hmac-sha1
+	// +optional
+	Algorithm string `json:"algorithm,omitempty"`
+
+	// KeySecret is the name of a secret in the same namespace as the MetalLB
+	// deployment, holding the master key as the key "key".
+	KeySecret v1.SecretReference `json:"keySecret"`
 }
 
 // BGPPeerStatus defines the observed state of Peer.
@@ -0,0 +1,120 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BGPPeerTemplateSpec defines the settings shared by the BGPPeers referencing
+// the template. A setting of the template is applied to a BGPPeer only when
+// the BGPPeer does not set it.
+type BGPPeerTemplateSpec struct {
+	// AS number to use for the local end of the session.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=4294967295
+	// +kubebuilder:validation:Format=int64
+	// +optional
+	MyASN uint32 `json:"myASN,omitempty"`
+
+	// AS number to expect from the remote end of the session.
+	// ASN and DynamicASN are mutually exclusive.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=4294967295
+	// +kubebuilder:validation:Format=int64
+	// +optional
+	ASN uint32 `json:"peerASN,omitempty"`
+
+	// DynamicASN detects the AS number to use for the remote end of the session
+	// without explicitly setting it via the ASN field.
+	// ASN and DynamicASN are mutually exclusive.
+	// +kubebuilder:validation:Enum=internal;external
+	// +optional
+	DynamicASN DynamicASNMode `json:"dynamicASN,omitempty"`
+
+	// Requested BGP hold time, per RFC4271.
+	// +optional
+	HoldTime *metav1.Duration `json:"holdTime,omitempty"`
+
+	// Requested BGP keepalive time, per RFC4271.
+	// +optional
+	KeepaliveTime *metav1.Duration `json:"keepaliveTime,omitempty"`
+
+	// Requested BGP connect time, controls how long BGP waits between connection attempts to a neighbor.
+	// +kubebuilder:validation:XValidation:message="connect time should be between 1 seconds to 65535",rule="duration(self).getSeconds() >= 1 && duration(self).getSeconds() <= 65535"
+	// +kubebuilder:validation:XValidation:message="connect time should contain a whole number of seconds",rule="duration(self).getMilliseconds() % 1000 == 0"
+	// +optional
+	ConnectTime *metav1.Duration `json:"connectTime,omitempty"`
+
+	// passwordSecret is name of the authentication secret for the BGPPeers.
+	// the secret must be of type "kubernetes.io/basic-auth", and created in the
+	// same namespace as the MetalLB deployment. The password is stored in the
+	// secret as the key "password". Not applied to the BGPPeers setting a password.
+	// +optional
+	PasswordSecret v1.SecretReference `json:"passwordSecret,omitempty"`
+
+	// The name of the BFD Profile to be used for the BFD session associated to the BGP session.
+	// +optional
+	BFDProfile string `json:"bfdProfile,omitempty"`
+
+	// EnableGracefulRestart allows BGP peer to continue to forward data packets
+	// along known routes while the routing protocol information is being
+	// restored. This field is immutable because it requires restart of the BGP
+	// session. Supported for FRR-based modes (FRR-K8s, FRR) only.
+	// +optional
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="EnableGracefulRestart cannot be changed after creation"
+	EnableGracefulRestart *bool `json:"enableGracefulRestart,omitempty"`
+
+	// To set if the BGPPeers are multi-hops away. Needed for FRR-based modes (FRR-K8s, FRR) only.
+	// +optional
+	EBGPMultiHop *bool `json:"ebgpMultiHop,omitempty"`
+}
+
+// BGPPeerTemplateStatus defines the observed state of BGPPeerTemplate.
+type BGPPeerTemplateStatus struct {
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="My ASN",type=string,JSONPath=`.spec.myASN`
+//+kubebuilder:printcolumn:name="ASN",type=string,JSONPath=`.spec.peerASN`
+//+kubebuilder:printcolumn:name="BFD Profile",type=string,JSONPath=`.spec.bfdProfile`
+
+// BGPPeerTemplate holds the settings shared by a group of BGPPeers, which
+// reference it by name. In FRR mode, the BGPPeers referencing the same template
+// are rendered as members of the same peer-group.
+type BGPPeerTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BGPPeerTemplateSpec   `json:"spec,omitempty"`
+	Status BGPPeerTemplateStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BGPPeerTemplateList contains a list of BGPPeerTemplate.
+type BGPPeerTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BGPPeerTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BGPPeerTemplate{}, &BGPPeerTemplateList{})
+}
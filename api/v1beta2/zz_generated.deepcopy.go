@@ -87,6 +87,11 @@ func (in *BGPPeerList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BGPPeerSpec) DeepCopyInto(out *BGPPeerSpec) {
 	*out = *in
+	if in.AddressFrom != nil {
+		in, out := &in.AddressFrom, &out.AddressFrom
+		*out = new(PeerAddressSource)
+		**out = **in
+	}
 	if in.HoldTime != nil {
 		in, out := &in.HoldTime, &out.HoldTime
 		*out = new(v1.Duration)
@@ -110,6 +115,31 @@ func (in *BGPPeerSpec) DeepCopyInto(out *BGPPeerSpec) {
 		}
 	}
 	out.PasswordSecret = in.PasswordSecret
+	if in.TCPAO != nil {
+		in, out := &in.TCPAO, &out.TCPAO
+		*out = new(TCPAO)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EnableGracefulRestart != nil {
+		in, out := &in.EnableGracefulRestart, &out.EnableGracefulRestart
+		*out = new(bool)
+		**out = **in
+	}
+	if in.EBGPMultiHop != nil {
+		in, out := &in.EBGPMultiHop, &out.EBGPMultiHop
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ToReceive != nil {
+		in, out := &in.ToReceive, &out.ToReceive
+		*out = new(Receive)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PrefixLimits != nil {
+		in, out := &in.PrefixLimits, &out.PrefixLimits
+		*out = new(PrefixLimits)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPPeerSpec.
@@ -136,3 +166,234 @@ func (in *BGPPeerStatus) DeepCopy() *BGPPeerStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BGPPeerTemplate) DeepCopyInto(out *BGPPeerTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPPeerTemplate.
+func (in *BGPPeerTemplate) DeepCopy() *BGPPeerTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(BGPPeerTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BGPPeerTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BGPPeerTemplateList) DeepCopyInto(out *BGPPeerTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BGPPeerTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPPeerTemplateList.
+func (in *BGPPeerTemplateList) DeepCopy() *BGPPeerTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(BGPPeerTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BGPPeerTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BGPPeerTemplateSpec) DeepCopyInto(out *BGPPeerTemplateSpec) {
+	*out = *in
+	if in.HoldTime != nil {
+		in, out := &in.HoldTime, &out.HoldTime
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.KeepaliveTime != nil {
+		in, out := &in.KeepaliveTime, &out.KeepaliveTime
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.ConnectTime != nil {
+		in, out := &in.ConnectTime, &out.ConnectTime
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	out.PasswordSecret = in.PasswordSecret
+	if in.EnableGracefulRestart != nil {
+		in, out := &in.EnableGracefulRestart, &out.EnableGracefulRestart
+		*out = new(bool)
+		**out = **in
+	}
+	if in.EBGPMultiHop != nil {
+		in, out := &in.EBGPMultiHop, &out.EBGPMultiHop
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPPeerTemplateSpec.
+func (in *BGPPeerTemplateSpec) DeepCopy() *BGPPeerTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BGPPeerTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BGPPeerTemplateStatus) DeepCopyInto(out *BGPPeerTemplateStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPPeerTemplateStatus.
+func (in *BGPPeerTemplateStatus) DeepCopy() *BGPPeerTemplateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BGPPeerTemplateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PeerAddressSource) DeepCopyInto(out *PeerAddressSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PeerAddressSource.
+func (in *PeerAddressSource) DeepCopy() *PeerAddressSource {
+	if in == nil {
+		return nil
+	}
+	out := new(PeerAddressSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrefixLimits) DeepCopyInto(out *PrefixLimits) {
+	*out = *in
+	if in.MaxAdvertised != nil {
+		in, out := &in.MaxAdvertised, &out.MaxAdvertised
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.MaxAccepted != nil {
+		in, out := &in.MaxAccepted, &out.MaxAccepted
+		*out = new(uint32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrefixLimits.
+func (in *PrefixLimits) DeepCopy() *PrefixLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(PrefixLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrefixSelector) DeepCopyInto(out *PrefixSelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrefixSelector.
+func (in *PrefixSelector) DeepCopy() *PrefixSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(PrefixSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Receive) DeepCopyInto(out *Receive) {
+	*out = *in
+	if in.Prefixes != nil {
+		in, out := &in.Prefixes, &out.Prefixes
+		*out = make([]PrefixSelector, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Receive.
+func (in *Receive) DeepCopy() *Receive {
+	if in == nil {
+		return nil
+	}
+	out := new(Receive)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TCPAO) DeepCopyInto(out *TCPAO) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]TCPAOKey, len(*in))
+		copy(*out, *in)
+	}
+	if in.CurrentKeyID != nil {
+		in, out := &in.CurrentKeyID, &out.CurrentKeyID
+		*out = new(uint8)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPAO.
+func (in *TCPAO) DeepCopy() *TCPAO {
+	if in == nil {
+		return nil
+	}
+	out := new(TCPAO)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TCPAOKey) DeepCopyInto(out *TCPAOKey) {
+	*out = *in
+	out.KeySecret = in.KeySecret
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPAOKey.
+func (in *TCPAOKey) DeepCopy() *TCPAOKey {
+	if in == nil {
+		return nil
+	}
+	out := new(TCPAOKey)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -396,7 +396,6 @@ func parsePeer(p peer) (*v1beta2.BGPPeer, error) {
 			NodeSelectors: nodeSels,
 			Password:      p.Password,
 			BFDProfile:    p.BFDProfile,
-			EBGPMultiHop:  p.EBGPMultiHop,
 		},
 	}
 	if p.KeepaliveTime != "" {
@@ -406,6 +405,9 @@ func parsePeer(p peer) (*v1beta2.BGPPeer, error) {
 		}
 		res.Spec.KeepaliveTime = &metav1.Duration{Duration: keepaliveTime}
 	}
+	if p.EBGPMultiHop {
+		res.Spec.EBGPMultiHop = &p.EBGPMultiHop
+	}
 
 	return res, nil
 }
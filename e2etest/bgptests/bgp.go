@@ -1727,7 +1727,7 @@ var _ = ginkgo.Describe("BGP", func() {
 						Port:          &p.Spec.Port,
 						HoldTime:      p.Spec.HoldTime,
 						KeepaliveTime: keepAliveTime,
-						EBGPMultiHop:  p.Spec.EBGPMultiHop,
+						EBGPMultiHop:  ptr.Deref(p.Spec.EBGPMultiHop, false),
 						BFDProfile:    p.Spec.BFDProfile,
 						ToReceive: frrk8sv1beta1.Receive{
 							Allowed: frrk8sv1beta1.AllowedInPrefixes{
@@ -25,6 +25,7 @@ import (
 	frrcontainer "go.universe.tf/e2etest/pkg/frr/container"
 	corev1 "k8s.io/api/core/v1"
 	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
 )
 
 var _ = ginkgo.Describe("BGP Node Selector", func() {
@@ -449,7 +450,7 @@ var _ = ginkgo.Describe("BGP Node Selector", func() {
 							ASN:           c.RouterConfig.ASN,
 							MyASN:         nodeASNs[node.Name],
 							Port:          c.RouterConfig.BGPPort,
-							EBGPMultiHop:  ebgpMultihop,
+							EBGPMultiHop:  ptr.To(ebgpMultihop),
 							VRFName:       c.RouterConfig.VRF,
 							NodeSelectors: k8s.SelectorsForNodes([]corev1.Node{node}),
 						},
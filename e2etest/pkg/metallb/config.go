@@ -12,6 +12,7 @@ import (
 	metallbv1beta2 "go.universe.tf/metallb/api/v1beta2"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 )
 
 const (
@@ -59,8 +60,8 @@ func PeersForContainers(containers []*frrcontainer.FRR, ipFamily ipfamily.Family
 					Port:                  c.RouterConfig.BGPPort,
 					Password:              c.RouterConfig.Password,
 					HoldTime:              &metav1.Duration{Duration: holdTime},
-					EnableGracefulRestart: gracefulRestart,
-					EBGPMultiHop:          ebgpMultihop,
+					EnableGracefulRestart: ptr.To(gracefulRestart),
+					EBGPMultiHop:          ptr.To(ebgpMultihop),
 					VRFName:               c.RouterConfig.VRF,
 				}}
 			for _, f := range tweak {
@@ -91,7 +92,7 @@ func WithRouterID(peers []metallbv1beta2.BGPPeer, routerID string) []metallbv1be
 // WithGracefulRestart sets the GR to true to the peers.
 func WithGracefulRestart(peers []metallbv1beta2.BGPPeer) []metallbv1beta2.BGPPeer {
 	for i := range peers {
-		peers[i].Spec.EnableGracefulRestart = true
+		peers[i].Spec.EnableGracefulRestart = ptr.To(true)
 	}
 	return peers
 }
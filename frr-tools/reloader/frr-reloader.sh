@@ -16,24 +16,37 @@ reload_frr() {
 
   kill_sleep
 
+  hash=$(sha256sum "$FILE_TO_RELOAD" | cut -d ' ' -f 1)
+
   echo "Checking the configuration file syntax"
-  if ! python3 /usr/lib/frr/frr-reload.py --test --stdout "$FILE_TO_RELOAD" 2>&1 | sed 's/password.*/password <retracted>/g'; then
+  if ! output=$(python3 /usr/lib/frr/frr-reload.py --test --stdout "$FILE_TO_RELOAD" 2>&1 | sed 's/password.*/password <retracted>/g'); then
+    echo "$output"
     echo "Syntax error spotted: aborting.. $SECONDS seconds"
-    echo -n "$(date +%s) failure"  > "$STATUSFILE"
+    report_failure "$output"
     return
   fi
+  echo "$output"
 
   echo "Applying the configuration file"
-  if ! python3 /usr/lib/frr/frr-reload.py --reload --overwrite --stdout "$FILE_TO_RELOAD" 2>&1 | sed 's/password.*/password <retracted>/g'; then
+  if ! output=$(python3 /usr/lib/frr/frr-reload.py --reload --overwrite --stdout "$FILE_TO_RELOAD" 2>&1 | sed 's/password.*/password <retracted>/g'); then
+    echo "$output"
     echo "Failed to fully apply configuration file $SECONDS seconds"
-    echo -n "$(date +%s) failure"  > "$STATUSFILE"
+    report_failure "$output"
     return
   fi
+  echo "$output"
   
   echo "FRR reloaded successfully! $SECONDS seconds"
-  echo -n "$(date +%s) success"  > "$STATUSFILE"
+  echo -n "$(date +%s) success $hash"  > "$STATUSFILE"
 } 200<"$LOCKFILE"
 
+# The output of the failed reload is written before the status, which is
+# what the speaker polls.
+report_failure() {
+  echo -n "$1" | tail -n 20 > "$ERRORFILE"
+  echo -n "$(date +%s) failure"  > "$STATUSFILE"
+}
+
 kill_sleep() {
   kill "$sleep_pid"
 }
@@ -41,6 +54,7 @@ kill_sleep() {
 clean_files() {
   rm -f "$PIDFILE"
   rm -f "$LOCKFILE"
+  rm -f "$ERRORFILE"
 }
 
 trap cleanup SIGTERM SIGINT
@@ -53,6 +67,7 @@ PIDFILE="$SHARED_VOLUME/reloader.pid"
 FILE_TO_RELOAD="$SHARED_VOLUME/frr.conf"
 LOCKFILE="$SHARED_VOLUME/lock"
 STATUSFILE="$SHARED_VOLUME/.status"
+ERRORFILE="$SHARED_VOLUME/.error"
 
 clean_files
 echo "PID is: $$, writing to $PIDFILE"
@@ -0,0 +1,138 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package bgp
+
+import (
+	"net"
+	"net/netip"
+	"slices"
+)
+
+// Aggregate collapses the advertisements having an Aggregation and the same
+// attributes into the minimal set of prefixes covering exactly their
+// addresses. The other advertisements are returned unchanged.
+func Aggregate(ads []*Advertisement) []*Advertisement {
+	type group struct {
+		ad       *Advertisement
+		prefixes map[netip.Prefix]bool
+	}
+	var (
+		res    []*Advertisement
+		groups []*group
+	)
+	for _, ad := range ads {
+		if ad.Aggregation == nil || !ad.Aggregation.SummaryOnly {
+			res = append(res, ad)
+		}
+		if ad.Aggregation == nil {
+			continue
+		}
+		prefix, ok := toPrefix(ad.Prefix)
+		if !ok {
+			continue
+		}
+		i := slices.IndexFunc(groups, func(g *group) bool { return sameAttributes(g.ad, ad) })
+		if i < 0 {
+			groups = append(groups, &group{ad: ad, prefixes: map[netip.Prefix]bool{}})
+			i = len(groups) - 1
+		}
+		groups[i].prefixes[prefix] = true
+	}
+
+	for _, g := range groups {
+		var boundary *netip.Prefix
+		if b, ok := toPrefix(g.ad.Aggregation.Boundary); ok {
+			boundary = &b
+		}
+		for _, p := range collapse(g.prefixes, boundary) {
+			if !g.ad.Aggregation.SummaryOnly && g.prefixes[p] {
+				// Already advertised as it is.
+				continue
+			}
+			ad := *g.ad
+			ad.Prefix = &net.IPNet{
+				IP:   net.IP(p.Addr().AsSlice()),
+				Mask: net.CIDRMask(p.Bits(), p.Addr().BitLen()),
+			}
+			ad.Aggregation = nil
+			res = append(res, &ad)
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+// sameAttributes tells if the two advertisements differ only by their
+// prefix and by the peers they are meant for.
+func sameAttributes(a, b *Advertisement) bool {
+	x, y := *a, *b
+	x.Prefix, y.Prefix = a.Prefix, a.Prefix
+	x.Peers, y.Peers = nil, nil
+	return x.Equal(&y)
+}
+
+// collapse merges the sibling prefixes of the set into their parent until
+// no more merges are possible, without going beyond the boundary if set.
+// The resulting prefixes are returned sorted.
+func collapse(prefixes map[netip.Prefix]bool, boundary *netip.Prefix) []netip.Prefix {
+	set := make(map[netip.Prefix]bool, len(prefixes))
+	for p := range prefixes {
+		set[p] = true
+	}
+	for bits := 128; bits > 0; bits-- {
+		if boundary != nil && bits <= boundary.Bits() {
+			break
+		}
+		for p := range set {
+			if p.Bits() != bits {
+				continue
+			}
+			s := sibling(p)
+			if !set[s] {
+				continue
+			}
+			delete(set, p)
+			delete(set, s)
+			set[netip.PrefixFrom(p.Addr(), bits-1).Masked()] = true
+		}
+	}
+
+	res := make([]netip.Prefix, 0, len(set))
+	for p := range set {
+		res = append(res, p)
+	}
+	slices.SortFunc(res, func(a, b netip.Prefix) int {
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c
+		}
+		return a.Bits() - b.Bits()
+	})
+	return res
+}
+
+// sibling returns the other half of the parent of the given prefix.
+func sibling(p netip.Prefix) netip.Prefix {
+	b := p.Addr().AsSlice()
+	i := p.Bits() - 1
+	b[i/8] ^= 0x80 >> (i % 8)
+	addr, _ := netip.AddrFromSlice(b)
+	return netip.PrefixFrom(addr, p.Bits())
+}
+
+func toPrefix(n *net.IPNet) (netip.Prefix, bool) {
+	if n == nil {
+		return netip.Prefix{}, false
+	}
+	addr, ok := netip.AddrFromSlice(n.IP)
+	if !ok {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+	ones, bits := n.Mask.Size()
+	if bits != addr.BitLen() {
+		return netip.Prefix{}, false
+	}
+	return netip.PrefixFrom(addr, ones).Masked(), true
+}
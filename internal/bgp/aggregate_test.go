@@ -0,0 +1,85 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package bgp
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAggregate(t *testing.T) {
+	ipnet := func(s string) *net.IPNet {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatalf("invalid cidr %s", s)
+		}
+		return n
+	}
+	summaryOnly := &Aggregation{SummaryOnly: true}
+	ads := func(aggregation *Aggregation, localPref uint32, prefixes ...string) []*Advertisement {
+		res := []*Advertisement{}
+		for _, p := range prefixes {
+			res = append(res, &Advertisement{Prefix: ipnet(p), LocalPref: localPref, Aggregation: aggregation})
+		}
+		return res
+	}
+
+	tests := []struct {
+		desc     string
+		ads      []*Advertisement
+		expected []*Advertisement
+	}{
+		{
+			desc: "no ads",
+		},
+		{
+			desc:     "not aggregated",
+			ads:      ads(nil, 0, "10.0.0.1/32", "10.0.0.0/32"),
+			expected: ads(nil, 0, "10.0.0.1/32", "10.0.0.0/32"),
+		},
+		{
+			desc:     "contiguous ips",
+			ads:      ads(summaryOnly, 0, "10.0.0.3/32", "10.0.0.0/32", "10.0.0.1/32", "10.0.0.2/32", "10.0.0.5/32", "10.0.0.2/32"),
+			expected: ads(nil, 0, "10.0.0.0/30", "10.0.0.5/32"),
+		},
+		{
+			desc:     "unaligned ips",
+			ads:      ads(summaryOnly, 0, "10.0.0.1/32", "10.0.0.2/32", "10.0.0.3/32", "10.0.0.4/32"),
+			expected: ads(nil, 0, "10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/32"),
+		},
+		{
+			desc:     "ipv6",
+			ads:      ads(summaryOnly, 0, "2001:db8::/128", "2001:db8::1/128", "10.0.0.0/32"),
+			expected: ads(nil, 0, "10.0.0.0/32", "2001:db8::/127"),
+		},
+		{
+			desc: "different attributes",
+			ads: append(ads(summaryOnly, 100, "10.0.0.0/32", "10.0.0.1/32"),
+				ads(summaryOnly, 200, "10.0.0.2/32", "10.0.0.3/32")...),
+			expected: append(ads(nil, 100, "10.0.0.0/31"),
+				ads(nil, 200, "10.0.0.2/31")...),
+		},
+		{
+			desc: "within the boundaries",
+			ads: append(ads(&Aggregation{SummaryOnly: true, Boundary: ipnet("10.0.0.0/31")}, 0, "10.0.0.0/32", "10.0.0.1/32"),
+				ads(&Aggregation{SummaryOnly: true, Boundary: ipnet("10.0.0.2/31")}, 0, "10.0.0.2/32", "10.0.0.3/32")...),
+			expected: ads(nil, 0, "10.0.0.0/31", "10.0.0.2/31"),
+		},
+		{
+			desc: "not summary only",
+			ads:  ads(&Aggregation{}, 0, "10.0.0.0/32", "10.0.0.1/32", "10.0.0.4/32"),
+			expected: append(ads(&Aggregation{}, 0, "10.0.0.0/32", "10.0.0.1/32", "10.0.0.4/32"),
+				ads(nil, 0, "10.0.0.0/31")...),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			res := Aggregate(tc.ads)
+			if !cmp.Equal(tc.expected, res) {
+				t.Fatalf("unexpected advertisements (-want +got)\n%s", cmp.Diff(tc.expected, res))
+			}
+		})
+	}
+}
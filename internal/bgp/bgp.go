@@ -12,6 +12,7 @@ import (
 	"go.universe.tf/metallb/internal/bgp/community"
 	"go.universe.tf/metallb/internal/config"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
 )
 
 // Advertisement represents one network path and its BGP attributes.
@@ -23,9 +24,60 @@ type Advertisement struct {
 	LocalPref uint32
 	// BGP communities to attach to the path.
 	Communities []community.BGPCommunity
+	// The MULTI_EXIT_DISC of this route, nil if not set.
+	MED *uint32
+	// The number of times the local ASN is prepended to the AS_PATH.
+	ASPathPrepend uint32
+	// Overrides ASPathPrepend for the BGPPeers in this map, by name.
+	// Sessions get the advertisement resolved by ForPeer.
+	PeerASPathPrepend map[string]uint32
 	// Used to declare the intent of announcing IPs
 	// only to the BGPPeers in this list.
 	Peers []string
+	// Makes the announcement depend on the routes in the BGP table,
+	// nil if it is unconditional.
+	Condition *Condition
+	// When set, Aggregate merges the advertisement with the others
+	// having the same attributes into covering prefixes.
+	Aggregation *Aggregation
+	// The VRFs the prefix is leaked into, from the VRF of the session.
+	// The default VRF is the empty string.
+	ImportVRFs []string
+	// When set, the prefix is announced as an EVPN Type-5 route of the
+	// L3VNI instead of as a unicast route.
+	EVPN *config.EVPN
+	// When set, the prefix is announced over labeled-unicast instead of
+	// unicast.
+	LabeledUnicast *LabeledUnicast
+}
+
+// LabeledUnicast configures the label of a prefix announced over
+// labeled-unicast.
+type LabeledUnicast struct {
+	// The label index announced in the BGP Prefix-SID, nil to announce
+	// the label allocated by the router.
+	LabelIndex *uint32
+}
+
+// Condition ties an advertisement to the routes received from the peers.
+type Condition struct {
+	// When set, the prefix is announced only while none of the tracked
+	// routes exists, otherwise only while at least one exists.
+	NonExist bool
+	// The tracked routes, in CIDR notation. Any route when empty.
+	Prefixes []string
+	// The addresses or interfaces of the peers the tracked routes are
+	// received from. Any peer when empty.
+	Peers []string
+}
+
+// Aggregation tells how an advertisement is merged by Aggregate.
+type Aggregation struct {
+	// When not nil, the covering prefixes don't extend beyond it.
+	Boundary *net.IPNet
+	// When set, only the covering prefixes are advertised, not the
+	// merged ones.
+	SummaryOnly bool
 }
 
 // Equal returns true if a and b are equivalent advertisements.
@@ -36,14 +88,67 @@ func (a *Advertisement) Equal(b *Advertisement) bool {
 	if a.LocalPref != b.LocalPref {
 		return false
 	}
+	if !ptr.Equal(a.MED, b.MED) {
+		return false
+	}
+	if a.ASPathPrepend != b.ASPathPrepend {
+		return false
+	}
+	if !reflect.DeepEqual(a.PeerASPathPrepend, b.PeerASPathPrepend) {
+		return false
+	}
 
 	if !reflect.DeepEqual(a.Peers, b.Peers) {
 		return false
 	}
+	if !reflect.DeepEqual(a.Condition, b.Condition) {
+		return false
+	}
+	if !reflect.DeepEqual(a.Aggregation, b.Aggregation) {
+		return false
+	}
+	if !reflect.DeepEqual(a.ImportVRFs, b.ImportVRFs) {
+		return false
+	}
+	if !reflect.DeepEqual(a.EVPN, b.EVPN) {
+		return false
+	}
+	if !reflect.DeepEqual(a.LabeledUnicast, b.LabeledUnicast) {
+		return false
+	}
 
 	return reflect.DeepEqual(a.Communities, b.Communities)
 }
 
+// ForPeer returns the advertisement to send to the given peer, with the
+// AS_PATH prepending resolved for it.
+func (a *Advertisement) ForPeer(peerName string) *Advertisement {
+	if len(a.PeerASPathPrepend) == 0 {
+		return a
+	}
+	res := *a
+	res.PeerASPathPrepend = nil
+	if count, ok := a.PeerASPathPrepend[peerName]; ok {
+		res.ASPathPrepend = count
+	}
+	return &res
+}
+
+// LowestMED returns the most preferred of the two MEDs, ignoring the
+// unset ones. It is used when the same prefix is advertised with
+// different MEDs, for example by services sharing the same IP.
+func LowestMED(a, b *uint32) *uint32 {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case *b < *a:
+		return b
+	}
+	return a
+}
+
 func (a *Advertisement) MatchesPeer(peerName string) bool {
 	if len(a.Peers) == 0 {
 		return true
@@ -62,10 +167,37 @@ type Session interface {
 	Set(advs ...*Advertisement) error
 }
 
+// TCPAOUpdater is implemented by sessions able to roll over their TCP-AO
+// keys without resetting the connection.
+type TCPAOUpdater interface {
+	UpdateTCPAO(tcpAO *config.TCPAO) error
+}
+
+// AdvertisementStateReporter is implemented by sessions able to tell
+// whether their advertisements reached the peer. AdvertisementState
+// returns whether the session is established and, if so, whether the
+// given prefix (in CIDR notation) was advertised over it.
+type AdvertisementStateReporter interface {
+	AdvertisementState(prefix string) (established, advertised bool)
+}
+
+// SyncTracker is implemented by session managers validating the whole
+// configuration before applying it. StartSync and EndSync bracket a batch of
+// changes, whose intermediate states may not be valid on their own.
+type SyncTracker interface {
+	StartSync()
+	EndSync()
+}
+
 type SessionParameters struct {
-	PeerAddress            string
+	PeerAddress string
+	// PeerZone is the interface the peer is reached through, when
+	// PeerAddress is a link-local address.
+	PeerZone               string
 	PeerPort               uint16
 	PeerInterface          string
+	ListenRange            *net.IPNet
+	Passive                bool
 	SourceAddress          net.IP
 	MyASN                  uint32
 	RouterID               net.IP
@@ -76,6 +208,7 @@ type SessionParameters struct {
 	ConnectTime            *time.Duration
 	Password               string
 	PasswordRef            v1.SecretReference
+	TCPAO                  *config.TCPAO
 	CurrentNode            string
 	BFDProfile             string
 	GracefulRestart        bool
@@ -85,10 +218,73 @@ type SessionParameters struct {
 	DualStackAddressFamily bool
 	DisableMP              bool
 	LocalASN               uint32
+	ToReceive              *config.Receive
+	PeerGroup              string
+	Extras                 *config.NeighborExtras
+	PrefixLimits           *config.PrefixLimits
+}
+
+// SessionState is the state of a BGP session, as seen by the backend.
+type SessionState struct {
+	// Peer is the address or the interface of the peer.
+	Peer string
+	// VRF is the VRF the session runs on, empty for the default one.
+	VRF string
+	// State is the BGP FSM state, such as Established or Active.
+	State string
+	// UpSince is the time the session was established at, zero if it is
+	// not established.
+	UpSince          time.Time
+	PrefixesSent     int
+	PrefixesReceived int
+	// LastError is the reason the session last went down or failed to
+	// establish.
+	LastError string
+	// BFDState is the state of the associated BFD session, empty if BFD
+	// is not enabled.
+	BFDState string
+	// PrefixLimit is the number of prefixes accepted from the peer in each
+	// address family before the backend warns about it, zero if not set.
+	PrefixLimit int
+	// PrefixLimitExceeded is set when the peer sent more prefixes than the
+	// limit, which the backend keeps accepting.
+	PrefixLimitExceeded bool
+}
+
+// SessionStateReporter is implemented by session managers able to report
+// the state of their sessions. The callback is invoked whenever the state
+// of any session changes.
+type SessionStateReporter interface {
+	SessionStates() []SessionState
+	SetSessionStateCallback(func())
 }
+
+// ReloadStatus is the outcome of the last reload of the configuration of
+// the backend.
+type ReloadStatus struct {
+	// Time is when the reload completed, zero if none did yet.
+	Time time.Time
+	// Failed is set when the last reload failed, with the output of the
+	// backend in Error.
+	Failed bool
+	Error  string
+	// ConfigHash is the hash of the last configuration reloaded
+	// successfully, empty if unknown.
+	ConfigHash          string
+	ConsecutiveFailures int
+}
+
+// ReloadStatusReporter is implemented by session managers reloading the
+// configuration of their backend. The callback is invoked whenever a reload
+// completes.
+type ReloadStatusReporter interface {
+	ReloadStatus() ReloadStatus
+	SetReloadStatusCallback(func())
+}
+
 type SessionManager interface {
 	NewSession(logger log.Logger, args SessionParameters) (Session, error)
 	SyncBFDProfiles(profiles map[string]*config.BFDProfile) error
-	SyncExtraInfo(extras string) error
+	SyncExtraInfo(extras config.BGPExtras) error
 	SetEventCallback(func(interface{}))
 }
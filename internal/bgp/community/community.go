@@ -3,8 +3,11 @@
 package community
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
+	"net"
 	"strconv"
 	"strings"
 )
@@ -15,10 +18,21 @@ var (
 )
 
 // largeBGPCommunityMarker is the prefix that shall be used to indicate that a given community value is of type large
-// community. If and when extended BGP communities will be supported, the largeBGPCommunityMarker allows us to
-// distinguish between extended and large communities.
+// community. It allows us to distinguish between extended and large communities.
 const largeBGPCommunityMarker = "large"
 
+// Markers of the supported extended community types.
+const (
+	routeTargetMarker   = "target"
+	linkBandwidthMarker = "bandwidth"
+)
+
+// MaxLinkBandwidth is the highest link bandwidth, in Mbps, that can be set by FRR.
+const MaxLinkBandwidth = 25600
+
+// asTrans is the 2-byte AS number used in place of 4-byte AS numbers, per RFC 6793.
+const asTrans = 23456
+
 // BGPCommunity represents a BGP community.
 type BGPCommunity interface {
 	LessThan(BGPCommunity) bool
@@ -29,7 +43,8 @@ type BGPCommunity interface {
 // Strings are parsed according to Juniper style  syntax (https://www.juniper.net/documentation/us/en/software/\
 // junos/routing-policy/bgp/topics/concept/policy-bgp-communities-extended-communities-match-conditions-overview.html
 // Legacy communities are of format "<AS number>:<community value>".
-// Extended communities (support of which is yet to be implemented) are of format "<type>:<administrator>:<assigned-number>".
+// Extended communities are of format "<type>:<administrator>:<assigned-number>", where type is "target" for route
+// targets. Link bandwidth extended communities are of format "bandwidth:<Mbps>", the administrator being the local AS.
 // Large communities are of format large:<global administrator>:<localdata part 1>:<localdata part 2>.
 func New(c string) (BGPCommunity, error) {
 	var bgpCommunity BGPCommunity
@@ -37,6 +52,9 @@ func New(c string) (BGPCommunity, error) {
 	fs := strings.Split(c, ":")
 	switch l := len(fs); l {
 	case 2:
+		if fs[0] == linkBandwidthMarker {
+			return newLinkBandwidth(c, fs[1])
+		}
 		var fields [2]uint16
 		for i := 0; i < 2; i++ {
 			b, err := strconv.ParseUint(fs[i], 10, 16)
@@ -50,6 +68,13 @@ func New(c string) (BGPCommunity, error) {
 			upperVal: fields[0],
 			lowerVal: fields[1],
 		}, nil
+	case 3:
+		if fs[0] != routeTargetMarker {
+			return bgpCommunity, fmt.Errorf("%w: invalid marker for extended community, expected community to be of "+
+				"format %s:<administrator>:<assigned-number> but got %q instead",
+				ErrInvalidCommunityValue, routeTargetMarker, c)
+		}
+		return newRouteTarget(c, fs[1], fs[2])
 	case 4:
 		if fs[0] != largeBGPCommunityMarker {
 			return bgpCommunity, fmt.Errorf("%w: invalid marker for large community, expected community to be of "+
@@ -75,6 +100,72 @@ func New(c string) (BGPCommunity, error) {
 	return bgpCommunity, fmt.Errorf("%w: %s", ErrInvalidCommunityFormat, c)
 }
 
+func newRouteTarget(c, administrator, assigned string) (BGPCommunity, error) {
+	var bgpCommunity BGPCommunity
+
+	if ip := net.ParseIP(administrator); ip != nil {
+		if ip.To4() == nil {
+			return bgpCommunity, fmt.Errorf("%w: invalid section %q of community %q, the administrator must be "+
+				"an IPv4 address", ErrInvalidCommunityValue, administrator, c)
+		}
+		v, err := strconv.ParseUint(assigned, 10, 16)
+		if err != nil {
+			return bgpCommunity, fmt.Errorf("%w: invalid section %q of community %q, err: %q",
+				ErrInvalidCommunityValue, assigned, c, err)
+		}
+		return BGPCommunityExtended{
+			kind:          routeTargetMarker,
+			administrator: administrator,
+			value:         uint32(v),
+		}, nil
+	}
+
+	asn, err := strconv.ParseUint(administrator, 10, 32)
+	if err != nil {
+		return bgpCommunity, fmt.Errorf("%w: invalid section %q of community %q, err: %q",
+			ErrInvalidCommunityValue, administrator, c, err)
+	}
+	// The assigned number of route targets with a 4-byte AS administrator is limited to 2 bytes.
+	bits := 32
+	if asn > 0xffff {
+		bits = 16
+	}
+	v, err := strconv.ParseUint(assigned, 10, bits)
+	if err != nil {
+		return bgpCommunity, fmt.Errorf("%w: invalid section %q of community %q, err: %q",
+			ErrInvalidCommunityValue, assigned, c, err)
+	}
+	return BGPCommunityExtended{
+		kind:          routeTargetMarker,
+		administrator: administrator,
+		value:         uint32(v),
+	}, nil
+}
+
+// NewLinkBandwidth returns a link bandwidth extended community of the given
+// bandwidth, in Mbps.
+func NewLinkBandwidth(mbps uint32) (BGPCommunity, error) {
+	return newLinkBandwidth(fmt.Sprintf("%s:%d", linkBandwidthMarker, mbps), strconv.FormatUint(uint64(mbps), 10))
+}
+
+func newLinkBandwidth(c, bandwidth string) (BGPCommunity, error) {
+	var bgpCommunity BGPCommunity
+
+	v, err := strconv.ParseUint(bandwidth, 10, 32)
+	if err != nil {
+		return bgpCommunity, fmt.Errorf("%w: invalid section %q of community %q, err: %q",
+			ErrInvalidCommunityValue, bandwidth, c, err)
+	}
+	if v == 0 || v > MaxLinkBandwidth {
+		return bgpCommunity, fmt.Errorf("%w: invalid link bandwidth of community %q, must be between 1 and %d Mbps",
+			ErrInvalidCommunityValue, c, MaxLinkBandwidth)
+	}
+	return BGPCommunityExtended{
+		kind:  linkBandwidthMarker,
+		value: uint32(v),
+	}, nil
+}
+
 // BGPCommunityLegacy holds the internal representation of a BGP legacy community.
 type BGPCommunityLegacy struct {
 	upperVal uint16
@@ -117,6 +208,76 @@ func (b BGPCommunityLarge) String() string {
 	return fmt.Sprintf("%d:%d:%d", b.globalAdministrator, b.localDataPart1, b.localDataPart2)
 }
 
+// ToUint32s returns the global administrator and the two local data parts of this large community.
+func (b BGPCommunityLarge) ToUint32s() [3]uint32 {
+	return [3]uint32{b.globalAdministrator, b.localDataPart1, b.localDataPart2}
+}
+
+// BGPCommunityExtended holds the internal representation of an extended BGP community.
+type BGPCommunityExtended struct {
+	kind          string
+	administrator string
+	value         uint32
+}
+
+// LessThan makes 2 different BGPCommunity objects comparable. Extended communities are considered to be greater
+// than legacy and large communities.
+func (b BGPCommunityExtended) LessThan(c BGPCommunity) bool {
+	return lessThan(b, c)
+}
+
+// String returns the string representation of this community. Extended communities will be printed as
+// "target:<administrator>:<assigned-number>" or "bandwidth:<Mbps>".
+func (b BGPCommunityExtended) String() string {
+	if b.kind == linkBandwidthMarker {
+		return fmt.Sprintf("%s:%d", b.kind, b.value)
+	}
+	return fmt.Sprintf("%s:%s:%d", b.kind, b.administrator, b.value)
+}
+
+// IsLinkBandwidth returns true if this is a link bandwidth community.
+func (b BGPCommunityExtended) IsLinkBandwidth() bool {
+	return b.kind == linkBandwidthMarker
+}
+
+// Administrator returns the administrator of a route target, either an AS number or an IPv4 address.
+func (b BGPCommunityExtended) Administrator() string {
+	return b.administrator
+}
+
+// Value returns the assigned number of a route target, or the bandwidth in Mbps
+// of a link bandwidth community.
+func (b BGPCommunityExtended) Value() uint32 {
+	return b.value
+}
+
+// ToUint64 returns the wire representation of this extended community, as per RFC 4360
+// and draft-ietf-idr-link-bandwidth. The local AS number is the administrator of link
+// bandwidth communities.
+func (b BGPCommunityExtended) ToUint64(localASN uint32) uint64 {
+	if b.kind == linkBandwidthMarker {
+		if localASN > 0xffff {
+			localASN = asTrans
+		}
+		// Transitive two-octet AS specific, link bandwidth subtype, with the
+		// bandwidth in bytes per second as an IEEE floating point number.
+		bytesPerSecond := float32(b.value) * 1000000 / 8
+		return 0x0004<<48 | uint64(localASN)<<32 | uint64(math.Float32bits(bytesPerSecond))
+	}
+
+	if ip := net.ParseIP(b.administrator); ip != nil {
+		// Transitive IPv4 address specific, route target subtype.
+		return 0x0102<<48 | uint64(binary.BigEndian.Uint32(ip.To4()))<<16 | uint64(b.value)
+	}
+	asn, _ := strconv.ParseUint(b.administrator, 10, 32)
+	if asn > 0xffff {
+		// Transitive four-octet AS specific, route target subtype.
+		return 0x0202<<48 | asn<<16 | uint64(b.value)
+	}
+	// Transitive two-octet AS specific, route target subtype.
+	return 0x0002<<48 | asn<<32 | uint64(b.value)
+}
+
 // IsLegacy returns true if this is a Legacy community.
 func IsLegacy(c BGPCommunity) bool {
 	_, ok := c.(BGPCommunityLegacy)
@@ -129,8 +290,21 @@ func IsLarge(c BGPCommunity) bool {
 	return ok
 }
 
+// IsExtended returns true if this is an Extended community.
+func IsExtended(c BGPCommunity) bool {
+	_, ok := c.(BGPCommunityExtended)
+	return ok
+}
+
 // lessThan is a helper function that compares two communities regardless of their type.
 func lessThan(b BGPCommunity, c BGPCommunity) bool {
+	if IsExtended(b) || IsExtended(c) {
+		if IsExtended(b) && IsExtended(c) {
+			return b.String() < c.String()
+		}
+		return IsExtended(c)
+	}
+
 	var bl BGPCommunityLarge
 	var cl BGPCommunityLarge
 	switch v := b.(type) {
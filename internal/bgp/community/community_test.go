@@ -37,9 +37,33 @@ func TestNewBGPCommunity(t *testing.T) {
 			input:       "large:12345:wrong:12345",
 			errorString: "invalid community value: invalid section",
 		},
-		"invalid extended community not yet implemented": {
+		"invalid extended community marker": {
 			input:       "12345:12345:12345",
-			errorString: "invalid community format: 12345:12345:12345",
+			errorString: "invalid community value: invalid marker for extended community",
+		},
+		"valid route target community": {
+			input:  "target:64512:100",
+			output: BGPCommunityExtended{kind: "target", administrator: "64512", value: 100},
+		},
+		"valid route target community with IPv4 administrator": {
+			input:  "target:10.0.0.1:100",
+			output: BGPCommunityExtended{kind: "target", administrator: "10.0.0.1", value: 100},
+		},
+		"invalid route target community with 4-byte AS administrator": {
+			input:       "target:4200000000:70000",
+			errorString: "invalid community value: invalid section",
+		},
+		"invalid route target community with IPv6 administrator": {
+			input:       "target:2001::1:100",
+			errorString: "invalid community format",
+		},
+		"valid link bandwidth community": {
+			input:  "bandwidth:1000",
+			output: BGPCommunityExtended{kind: "bandwidth", value: 1000},
+		},
+		"invalid link bandwidth community": {
+			input:       "bandwidth:30000",
+			errorString: "invalid community value: invalid link bandwidth",
 		},
 	}
 	for d, tc := range tcs {
@@ -70,10 +94,12 @@ func TestBGPCommunityLessThan(t *testing.T) {
 		right           string
 		expectedOutcome bool
 	}{
-		"compares legacy communities":           {left: "0:1234", right: "0:2345", expectedOutcome: true},
-		"compares large communities 1":          {left: "large:1234:0:0", right: "large:1234:0:1", expectedOutcome: true},
-		"compares large communities 2":          {left: "large:1235:0:0", right: "large:1234:1:0", expectedOutcome: false},
-		"compares legacy and large communities": {left: "0:1234", right: "large:123:456:789", expectedOutcome: false},
+		"compares legacy communities":              {left: "0:1234", right: "0:2345", expectedOutcome: true},
+		"compares large communities 1":             {left: "large:1234:0:0", right: "large:1234:0:1", expectedOutcome: true},
+		"compares large communities 2":             {left: "large:1235:0:0", right: "large:1234:1:0", expectedOutcome: false},
+		"compares legacy and large communities":    {left: "0:1234", right: "large:123:456:789", expectedOutcome: false},
+		"compares large and extended communities":  {left: "large:1:2:3", right: "target:1:2", expectedOutcome: true},
+		"compares extended and legacy communities": {left: "bandwidth:100", right: "0:1234", expectedOutcome: false},
 	}
 	for d, tc := range tcs {
 		leftCommunity, _ := New(tc.left)
@@ -92,6 +118,8 @@ func TestBGPCommunityString(t *testing.T) {
 	}{
 		"legacy community": {input: "0:1234", output: "0:1234"},
 		"large community":  {input: "large:1:2:3", output: "1:2:3"},
+		"route target":     {input: "target:64512:100", output: "target:64512:100"},
+		"link bandwidth":   {input: "bandwidth:100", output: "bandwidth:100"},
 	}
 	for d, tc := range tcs {
 		community, _ := New(tc.input)
@@ -101,11 +129,53 @@ func TestBGPCommunityString(t *testing.T) {
 	}
 }
 
+func TestBGPCommunityExtendedToUint64(t *testing.T) {
+	tcs := map[string]struct {
+		input    string
+		localASN uint32
+		output   uint64
+	}{
+		"2-byte AS route target":        {input: "target:64512:100", output: 0x0002_fc00_0000_0064},
+		"IPv4 route target":             {input: "target:10.0.0.1:100", output: 0x0102_0a00_0001_0064},
+		"4-byte AS route target":        {input: "target:4200000000:100", output: 0x0202_fa56_ea00_0064},
+		"link bandwidth":                {input: "bandwidth:1000", localASN: 64512, output: 0x0004_fc00_4cee_6b28},
+		"link bandwidth with 4-byte AS": {input: "bandwidth:1000", localASN: 4200000000, output: 0x0004_5ba0_4cee_6b28},
+	}
+	for d, tc := range tcs {
+		c, err := New(tc.input)
+		if err != nil {
+			t.Fatalf("%s(%s): unexpected error %q", t.Name(), d, err)
+		}
+		got := c.(BGPCommunityExtended).ToUint64(tc.localASN)
+		if got != tc.output {
+			t.Fatalf("%s(%s): expected %#x, got %#x", t.Name(), d, tc.output, got)
+		}
+	}
+}
+
+func TestNewLinkBandwidth(t *testing.T) {
+	c, err := NewLinkBandwidth(1000)
+	if err != nil {
+		t.Fatalf("%s: unexpected error %q", t.Name(), err)
+	}
+	if c.String() != "bandwidth:1000" {
+		t.Fatalf("%s: expected bandwidth:1000, got %q", t.Name(), c)
+	}
+	for _, invalid := range []uint32{0, MaxLinkBandwidth + 1} {
+		if _, err := NewLinkBandwidth(invalid); err == nil {
+			t.Fatalf("%s: expected error for %d Mbps", t.Name(), invalid)
+		}
+	}
+}
+
 func FuzzNew(f *testing.F) {
 	f.Add("0:1234")
 	f.Add("large:1:2:3")
 	f.Add("large:1235:0:0")
 	f.Add("large:12345:12345:12345")
+	f.Add("target:64512:100")
+	f.Add("target:10.0.0.1:100")
+	f.Add("bandwidth:100")
 
 	f.Fuzz(func(t *testing.T, input string) {
 		_, _ = New(input)
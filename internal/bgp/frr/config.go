@@ -6,9 +6,11 @@ import (
 	"bytes"
 	"embed"
 	"fmt"
+	"net"
 	"os"
 	"reflect"
 	"strconv"
+	"strings"
 	"syscall"
 	"text/template"
 	"time"
@@ -17,6 +19,7 @@ import (
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"go.universe.tf/metallb/internal/bgp"
 	"go.universe.tf/metallb/internal/bgp/community"
 	"go.universe.tf/metallb/internal/ipfamily"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -37,6 +40,9 @@ type frrConfig struct {
 	Hostname    string
 	Routers     []*routerConfig
 	BFDProfiles []BFDProfile
+	// The bgp bestpath commands applied to all the routers.
+	BestPath    []string
+	PrefixLists []PrefixList
 	ExtraConfig string
 }
 
@@ -55,6 +61,55 @@ type routerConfig struct {
 	VRF          string
 	IPV4Prefixes []string
 	IPV6Prefixes []string
+	PeerGroups   []peerGroupConfig
+	// The prefixes leaked into the VRF of the router, nil if there are none.
+	VRFImport *VRFImport
+	// The prefixes exported from the VRF of the router as EVPN Type-5
+	// routes, nil if there are none.
+	EVPN *EVPNExport
+	// The label index announced with the prefixes in this map, the
+	// others get the label allocated by FRR.
+	LabelIndexes map[string]string
+}
+
+// EVPNNeighbors returns the neighbors the EVPN routes are sent to.
+func (r *routerConfig) EVPNNeighbors() []string {
+	var res []string
+	for _, n := range r.Neighbors {
+		if !n.EVPN {
+			continue
+		}
+		peer := n.Addr
+		if n.Iface != "" {
+			peer = n.Iface
+		}
+		res = append(res, peer)
+	}
+	return res
+}
+
+// VRFImport imports the given prefixes from the VRFs into the one of a router.
+type VRFImport struct {
+	// The name of the route-map selecting the imported prefixes, also
+	// used for its prefix-lists.
+	RouteMap   string
+	VRFs       []string
+	PrefixesV4 []string
+	PrefixesV6 []string
+}
+
+// EVPNExport exports the given prefixes from the VRF of a router as EVPN
+// Type-5 routes of its L3VNI.
+type EVPNExport struct {
+	VNI                uint32
+	RouteDistinguisher string
+	ImportRouteTargets []string
+	ExportRouteTargets []string
+	// The name of the route-map selecting the exported prefixes, also
+	// used for its prefix-lists.
+	RouteMap   string
+	PrefixesV4 []string
+	PrefixesV6 []string
 }
 
 type BFDProfile struct {
@@ -68,29 +123,80 @@ type BFDProfile struct {
 	MinimumTTL       *uint32
 }
 
+// peerGroupConfig is a peer-group, holding the settings shared by all of
+// its members. A setting a member doesn't share stays on each member, as
+// FRR can't negate the flags a member inherits.
+type peerGroupConfig struct {
+	Name            string
+	HoldTime        *int64
+	KeepaliveTime   *int64
+	ConnectTime     int64
+	Password        string
+	BFDProfile      string
+	GracefulRestart bool
+	EBGPMultiHop    bool
+}
+
 type neighborConfig struct {
-	IPFamily                 ipfamily.Family
-	Name                     string
-	ASN                      string
-	Addr                     string
-	Iface                    string
-	SrcAddr                  string
-	Port                     uint16
-	HoldTime                 *int64
-	KeepaliveTime            *int64
-	ConnectTime              int64
-	Password                 string
-	BFDProfile               string
-	GracefulRestart          bool
-	EBGPMultiHop             bool
-	LocalASN                 uint32
-	VRFName                  string
+	IPFamily        ipfamily.Family
+	Name            string
+	ASN             string
+	Addr            string
+	Iface           string
+	SrcAddr         string
+	Port            uint16
+	HoldTime        *int64
+	KeepaliveTime   *int64
+	ConnectTime     int64
+	Password        string
+	BFDProfile      string
+	GracefulRestart bool
+	EBGPMultiHop    bool
+	LocalASN        uint32
+	VRFName         string
+	// The interface Addr is reached through, when it is link-local.
+	Zone string
+	// The peer-group the neighbor is a member of, if any. The settings
+	// it holds are not rendered on the neighbor.
+	PeerGroup                peerGroupConfig
 	PrefixesV4               []string
 	PrefixesV6               []string
 	prefixesV4Set            sets.Set[string]
 	prefixesV6Set            sets.Set[string]
 	CommunityPrefixModifiers map[string]CommunityPrefixList
 	LocalPrefPrefixModifiers map[string]LocalPrefPrefixList
+	// The lowest MED requested for each prefix.
+	meds               map[string]uint32
+	MEDPrefixModifiers map[string]MEDPrefixList
+	// The highest AS_PATH prepending requested for each prefix.
+	asPathPrepends               map[string]uint32
+	ASPathPrependPrefixModifiers map[string]ASPathPrependPrefixList
+	// The prefixes accepted from the neighbor, nil if all are denied.
+	Incoming *IncomingFilter
+	// The announcements depending on the routes received from the peers,
+	// nil if there are none.
+	Conditional    *ConditionalAdvertisement
+	advertisements []*bgp.Advertisement
+	// Zero when not set.
+	MaximumPrefix uint32
+	// Logs a warning instead of tearing the session down when the
+	// maximum number of prefixes is exceeded.
+	MaximumPrefixWarningOnly bool
+	// The maximum number of prefixes sent to the neighbor, zero when
+	// not set.
+	MaximumPrefixOut uint32
+	AllowASIn        uint32
+	// The policies called by the route-maps of the neighbor, empty if
+	// there are none.
+	ImportPolicy []RouteMapEntry
+	ExportPolicy []RouteMapEntry
+	// Set when the EVPN routes are sent to the neighbor.
+	EVPN bool
+	// The prefixes announced over labeled-unicast instead of unicast,
+	// nil if there are none.
+	Labeled              *LabeledAnnouncement
+	labeledPrefixesV4Set sets.Set[string]
+	labeledPrefixesV6Set sets.Set[string]
 }
 
 func (n *neighborConfig) ID() string {
@@ -105,6 +211,16 @@ func (n *neighborConfig) ID() string {
 	return id + vrf
 }
 
+// ImportMap is the name of the route-map holding the import policy.
+func (n *neighborConfig) ImportMap() string {
+	return n.ID() + "-import"
+}
+
+// ExportMap is the name of the route-map holding the export policy.
+func (n *neighborConfig) ExportMap() string {
+	return n.ID() + "-export"
+}
+
 func (n *neighborConfig) CommunityPrefixLists() []CommunityPrefixList {
 	return sortMap(n.CommunityPrefixModifiers)
 }
@@ -113,6 +229,14 @@ func (n *neighborConfig) LocalPrefPrefixLists() []LocalPrefPrefixList {
 	return sortMap(n.LocalPrefPrefixModifiers)
 }
 
+func (n *neighborConfig) MEDPrefixLists() []MEDPrefixList {
+	return sortMap(n.MEDPrefixModifiers)
+}
+
+func (n *neighborConfig) ASPathPrependPrefixLists() []ASPathPrependPrefixList {
+	return sortMap(n.ASPathPrependPrefixModifiers)
+}
+
 func (n *neighborConfig) ToAdvertisePrefixListV4() string {
 	return fmt.Sprintf("%s-allowed-%s", n.ID(), "ipv4")
 }
@@ -121,6 +245,196 @@ func (n *neighborConfig) ToAdvertisePrefixListV6() string {
 	return fmt.Sprintf("%s-allowed-%s", n.ID(), "ipv6")
 }
 
+func (n *neighborConfig) ToReceivePrefixListV4() string {
+	return fmt.Sprintf("%s-received-%s", n.ID(), "ipv4")
+}
+
+func (n *neighborConfig) ToReceivePrefixListV6() string {
+	return fmt.Sprintf("%s-received-%s", n.ID(), "ipv6")
+}
+
+// UnicastOutMap is the name of the route-map filtering the unicast
+// announcements, excluding the labeled ones when there are any.
+func (n *neighborConfig) UnicastOutMap() string {
+	if n.Labeled != nil {
+		return n.ID() + "-unicast-out"
+	}
+	return n.ID() + "-out"
+}
+
+// LabeledOutMap is the name of the route-map filtering the labeled-unicast
+// announcements.
+func (n *neighborConfig) LabeledOutMap() string {
+	return n.ID() + "-labeled-out"
+}
+
+func (n *neighborConfig) LabeledPrefixListV4() string {
+	return fmt.Sprintf("%s-labeled-%s", n.ID(), "ipv4")
+}
+
+func (n *neighborConfig) LabeledPrefixListV6() string {
+	return fmt.Sprintf("%s-labeled-%s", n.ID(), "ipv6")
+}
+
+func (n *neighborConfig) AdvertiseMap() string {
+	return fmt.Sprintf("%s-advertise", n.ID())
+}
+
+func (n *neighborConfig) ConditionMap() string {
+	return fmt.Sprintf("%s-condition", n.ID())
+}
+
+func (n *neighborConfig) ConditionalPrefixListV4() string {
+	return fmt.Sprintf("%s-conditional-%s", n.ID(), "ipv4")
+}
+
+func (n *neighborConfig) ConditionalPrefixListV6() string {
+	return fmt.Sprintf("%s-conditional-%s", n.ID(), "ipv6")
+}
+
+func (n *neighborConfig) TrackedPrefixListV4() string {
+	return fmt.Sprintf("%s-tracked-%s", n.ID(), "ipv4")
+}
+
+func (n *neighborConfig) TrackedPrefixListV6() string {
+	return fmt.Sprintf("%s-tracked-%s", n.ID(), "ipv6")
+}
+
+// ConditionalAdvertisement is the set of prefixes announced to a neighbor
+// only while the tracked routes exist, or do not exist.
+type ConditionalAdvertisement struct {
+	NonExist   bool
+	PrefixesV4 []string
+	PrefixesV6 []string
+	// The tracked routes, "any" matches all the routes of the family.
+	TrackedV4 []string
+	TrackedV6 []string
+	// The addresses or interfaces of the peers the tracked routes are
+	// received from, any peer when empty.
+	Peers []string
+}
+
+// LabeledAnnouncement is the set of prefixes announced to a neighbor over
+// labeled-unicast.
+type LabeledAnnouncement struct {
+	PrefixesV4 []string
+	PrefixesV6 []string
+}
+
+// MapType returns the kind of condition route-map, as expected by the
+// advertise-map neighbor command.
+func (c *ConditionalAdvertisement) MapType() string {
+	if c.NonExist {
+		return "non-exist-map"
+	}
+	return "exist-map"
+}
+
+// ConditionalAdvertisementFor returns the prefixes of the advertisements
+// announced only when their condition is met, nil if there are none. A
+// prefix also announced without a condition is always announced. FRR
+// supports a single condition per neighbor, so the advertisements must
+// share the same one.
+func ConditionalAdvertisementFor(advs []*bgp.Advertisement) (*ConditionalAdvertisement, error) {
+	var condition *bgp.Condition
+	conditional := sets.New[string]()
+	unconditional := sets.New[string]()
+	for _, adv := range advs {
+		if adv.Condition == nil {
+			unconditional.Insert(adv.Prefix.String())
+			continue
+		}
+		if condition != nil && !reflect.DeepEqual(condition, adv.Condition) {
+			return nil, errors.New("conflicting advertisement conditions")
+		}
+		condition = adv.Condition
+		conditional.Insert(adv.Prefix.String())
+	}
+	prefixes := conditional.Difference(unconditional)
+	if prefixes.Len() == 0 {
+		return nil, nil
+	}
+
+	res := &ConditionalAdvertisement{
+		NonExist: condition.NonExist,
+		Peers:    condition.Peers,
+	}
+	res.PrefixesV4, res.PrefixesV6 = splitByFamily(sets.List(prefixes))
+	if len(condition.Prefixes) == 0 {
+		res.TrackedV4 = []string{"any"}
+		res.TrackedV6 = []string{"any"}
+		return res, nil
+	}
+	res.TrackedV4, res.TrackedV6 = splitByFamily(condition.Prefixes)
+	return res, nil
+}
+
+func splitByFamily(prefixes []string) ([]string, []string) {
+	var v4, v6 []string
+	for _, p := range prefixes {
+		if ip, _, _ := net.ParseCIDR(p); ip.To4() == nil {
+			v6 = append(v6, p)
+			continue
+		}
+		v4 = append(v4, p)
+	}
+	return v4, v6
+}
+
+// IncomingFilter is the set of prefixes accepted from a neighbor.
+type IncomingFilter struct {
+	All        bool
+	PrefixesV4 []IncomingPrefix
+	PrefixesV6 []IncomingPrefix
+}
+
+type IncomingPrefix struct {
+	Prefix string
+	LE     uint32
+	GE     uint32
+}
+
+// Matcher returns the length modifiers of the prefix, as expected by
+// a prefix list entry.
+func (p IncomingPrefix) Matcher() string {
+	res := ""
+	if p.GE != 0 {
+		res += fmt.Sprintf(" ge %d", p.GE)
+	}
+	if p.LE != 0 {
+		res += fmt.Sprintf(" le %d", p.LE)
+	}
+	return res
+}
+
+// PrefixList is a static prefix-list, rendered for each family it has
+// entries of.
+type PrefixList struct {
+	Name    string
+	Entries []PrefixListEntry
+}
+
+type PrefixListEntry struct {
+	// ip or ipv6.
+	IPFamily string
+	Seq      int
+	// permit or deny.
+	Action string
+	IncomingPrefix
+}
+
+// RouteMapEntry is an entry of the route-maps implementing the policies.
+type RouteMapEntry struct {
+	Seq int
+	// permit or deny.
+	Action string
+	// The match statement, empty to match all the prefixes.
+	Match string
+	Sets  []string
+	// Tells if the evaluation continues with the following entries.
+	OnMatchNext bool
+}
+
 type PropertyPrefixList struct {
 	Name        string
 	IPFamily    string
@@ -137,6 +451,13 @@ func (c CommunityPrefixList) SetStatement() string {
 	if community.IsLarge(c.Community) {
 		return fmt.Sprintf("set large-community %s additive", c.Community.String())
 	}
+	if e, ok := c.Community.(community.BGPCommunityExtended); ok {
+		// Extended communities are always added to the existing ones.
+		if e.IsLinkBandwidth() {
+			return fmt.Sprintf("set extcommunity bandwidth %d", e.Value())
+		}
+		return fmt.Sprintf("set extcommunity rt %s:%d", e.Administrator(), e.Value())
+	}
 	return fmt.Sprintf("set community %s additive", c.Community.String())
 }
 
@@ -149,6 +470,29 @@ func (l LocalPrefPrefixList) SetStatement() string {
 	return fmt.Sprintf("set local-preference %d", l.LocalPreference)
 }
 
+type MEDPrefixList struct {
+	PropertyPrefixList
+	MED uint32
+}
+
+func (l MEDPrefixList) SetStatement() string {
+	return fmt.Sprintf("set metric %d", l.MED)
+}
+
+type ASPathPrependPrefixList struct {
+	PropertyPrefixList
+	ASN   uint32
+	Count uint32
+}
+
+func (l ASPathPrependPrefixList) SetStatement() string {
+	asns := make([]string, 0, l.Count)
+	for range l.Count {
+		asns = append(asns, strconv.FormatUint(uint64(l.ASN), 10))
+	}
+	return "set as-path prepend " + strings.Join(asns, " ")
+}
+
 // RouterName() defines the format of the key of the "Routers" map in the
 // frrConfig struct.
 func RouterName(srcAddr string, myASN uint32, vrfName string) string {
@@ -260,6 +604,15 @@ func writeConfig(config string, filename string) error {
 	return os.WriteFile(filename, []byte(config), 0600)
 }
 
+// configFile returns the path of the FRR configuration file.
+func configFile() string {
+	filename, found := os.LookupEnv("FRR_CONFIG_FILE")
+	if !found {
+		filename = configFileName
+	}
+	return filename
+}
+
 // reloadConfig requests that FRR reloads the configuration file. This is
 // called after updating the configuration.
 var reloadConfig = func() error {
@@ -291,10 +644,7 @@ var reloadConfig = func() error {
 // generates and writes a valid FRR configuration file. If this completes
 // successfully it will also force FRR to reload that configuration file.
 func generateAndReloadConfigFile(config *frrConfig, l log.Logger) error {
-	filename, found := os.LookupEnv("FRR_CONFIG_FILE")
-	if !found {
-		filename = configFileName
-	}
+	filename := configFile()
 
 	configString, err := templateConfig(config)
 	if err != nil {
@@ -0,0 +1,133 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package frr
+
+import (
+	"fmt"
+	"strings"
+
+	"go.universe.tf/metallb/internal/bgp/community"
+	metallbconfig "go.universe.tf/metallb/internal/config"
+	"go.universe.tf/metallb/internal/ipfamily"
+)
+
+// BestPathCommands returns the bgp bestpath commands enabling the given
+// knobs.
+func BestPathCommands(b *metallbconfig.BestPath) []string {
+	if b == nil {
+		return nil
+	}
+	var res []string
+	if b.ASPathIgnore {
+		res = append(res, "bgp bestpath as-path ignore")
+	}
+	if b.ASPathMultipathRelax {
+		res = append(res, "bgp bestpath as-path multipath-relax")
+	}
+	if b.CompareRouterID {
+		res = append(res, "bgp bestpath compare-routerid")
+	}
+	if b.MEDMissingAsWorst {
+		res = append(res, "bgp bestpath med missing-as-worst")
+	}
+	return res
+}
+
+// PrefixListFor translates the prefix-list, splitting its entries by family.
+func PrefixListFor(pl *metallbconfig.PrefixList) PrefixList {
+	res := PrefixList{Name: pl.Name}
+	for i, e := range pl.Entries {
+		action := "permit"
+		if e.Deny {
+			action = "deny"
+		}
+		res.Entries = append(res.Entries, PrefixListEntry{
+			IPFamily: frrIPFamily(ipfamily.ForAddress(e.Prefix.IP)),
+			Seq:      i + 1,
+			Action:   action,
+			IncomingPrefix: IncomingPrefix{
+				Prefix: e.Prefix.String(),
+				LE:     e.LE,
+				GE:     e.GE,
+			},
+		})
+	}
+	return res
+}
+
+// RouteMapEntries translates the policy to the entries of the route-map
+// called by the ones of the neighbor. The attributes of the permitted
+// prefixes accumulate, and the prefixes not denied by the policy are
+// permitted.
+func RouteMapEntries(policy []metallbconfig.PolicyEntry) []RouteMapEntry {
+	if len(policy) == 0 {
+		return nil
+	}
+	var res []RouteMapEntry
+	for _, p := range policy {
+		action := "permit"
+		if p.Deny {
+			action = "deny"
+		}
+		// A route-map entry matches a single family of prefix-lists.
+		matches := []string{""}
+		if p.PrefixList != nil {
+			matches = prefixListMatches(p.PrefixList)
+		}
+		for _, m := range matches {
+			res = append(res, RouteMapEntry{
+				Seq:         len(res) + 1,
+				Action:      action,
+				Match:       m,
+				Sets:        policySets(p),
+				OnMatchNext: !p.Deny,
+			})
+		}
+	}
+	res = append(res, RouteMapEntry{Seq: len(res) + 1, Action: "permit"})
+	return res
+}
+
+func prefixListMatches(pl *metallbconfig.PrefixList) []string {
+	var hasV4, hasV6 bool
+	for _, e := range pl.Entries {
+		if ipfamily.ForAddress(e.Prefix.IP) == ipfamily.IPv6 {
+			hasV6 = true
+			continue
+		}
+		hasV4 = true
+	}
+	var res []string
+	if hasV4 {
+		res = append(res, fmt.Sprintf("match ip address prefix-list %s", pl.Name))
+	}
+	if hasV6 {
+		res = append(res, fmt.Sprintf("match ipv6 address prefix-list %s", pl.Name))
+	}
+	return res
+}
+
+func policySets(p metallbconfig.PolicyEntry) []string {
+	var res []string
+	if p.LocalPref != nil {
+		res = append(res, fmt.Sprintf("set local-preference %d", *p.LocalPref))
+	}
+	if p.Metric != nil {
+		res = append(res, fmt.Sprintf("set metric %d", *p.Metric))
+	}
+	var standard, large []string
+	for _, c := range p.Communities {
+		if community.IsLarge(c) {
+			large = append(large, c.String())
+			continue
+		}
+		standard = append(standard, c.String())
+	}
+	if len(standard) > 0 {
+		res = append(res, fmt.Sprintf("set community %s additive", strings.Join(standard, " ")))
+	}
+	if len(large) > 0 {
+		res = append(res, fmt.Sprintf("set large-community %s additive", strings.Join(large, " ")))
+	}
+	return res
+}
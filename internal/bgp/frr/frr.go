@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kit/log"
@@ -20,6 +22,7 @@ import (
 	"go.universe.tf/metallb/internal/ipfamily"
 	"go.universe.tf/metallb/internal/logging"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
 )
 
 // As the MetalLB controller should handle messages synchronously, there should
@@ -28,10 +31,20 @@ import (
 type sessionManager struct {
 	sessions     map[string]*session
 	bfdProfiles  []BFDProfile
-	extraConfig  string
+	extras       metallbconfig.BGPExtras
 	reloadConfig chan reloadEvent
 	logLevel     string
 	sync.Mutex
+	// syncing is set while a batch of changes is in progress, whose
+	// intermediate configurations may not be valid.
+	syncing atomic.Bool
+
+	stateMu       sync.Mutex
+	states        []bgp.SessionState
+	stateCallback func()
+
+	reloadStatus   bgp.ReloadStatus
+	reloadCallback func()
 }
 
 type session struct {
@@ -168,12 +181,27 @@ func (sm *sessionManager) deleteSession(s *session) error {
 	return nil
 }
 
-func (sm *sessionManager) SyncExtraInfo(extraInfo string) error {
+func (sm *sessionManager) SyncExtraInfo(extras metallbconfig.BGPExtras) error {
 	sm.Lock()
 	defer sm.Unlock()
-	sm.extraConfig = extraInfo
+	oldExtras := sm.extras
+	sm.extras = extras
 	frrConfig, err := sm.createConfig()
 	if err != nil {
+		sm.extras = oldExtras
+		return err
+	}
+	// The extras are synced once the sessions of the configuration are in
+	// place, so that the whole configuration can be validated here too:
+	// rejecting the extras FRR would fail to load reports them as a
+	// configuration error, and keeps them out of the next reloads.
+	rendered, err := templateConfig(frrConfig)
+	if err != nil {
+		sm.extras = oldExtras
+		return err
+	}
+	if err := validateConfig(rendered); err != nil {
+		sm.extras = oldExtras
 		return err
 	}
 
@@ -181,9 +209,22 @@ func (sm *sessionManager) SyncExtraInfo(extraInfo string) error {
 	return nil
 }
 
+// StartSync marks the beginning of a batch of changes, during which the
+// configurations failing the validation are retried rather than reported,
+// as they may be transiently invalid.
+func (sm *sessionManager) StartSync() {
+	sm.syncing.Store(true)
+}
+
+// EndSync marks the end of a batch of changes.
+func (sm *sessionManager) EndSync() {
+	sm.syncing.Store(false)
+}
+
 func (sm *sessionManager) SyncBFDProfiles(profiles map[string]*metallbconfig.BFDProfile) error {
 	sm.Lock()
 	defer sm.Unlock()
+	oldProfiles := sm.bfdProfiles
 	sm.bfdProfiles = make([]BFDProfile, 0)
 	for _, p := range profiles {
 		frrProfile := ConfigBFDProfileToFRR(p)
@@ -195,6 +236,7 @@ func (sm *sessionManager) SyncBFDProfiles(profiles map[string]*metallbconfig.BFD
 
 	frrConfig, err := sm.createConfig()
 	if err != nil {
+		sm.bfdProfiles = oldProfiles
 		return err
 	}
 
@@ -212,7 +254,29 @@ func (sm *sessionManager) createConfig() (*frrConfig, error) {
 		Hostname:    hostname,
 		Loglevel:    sm.logLevel,
 		BFDProfiles: sm.bfdProfiles,
-		ExtraConfig: sm.extraConfig,
+		BestPath:    BestPathCommands(sm.extras.BestPath),
+		ExtraConfig: sm.extras.Raw,
+	}
+	prefixLists := map[string]*metallbconfig.PrefixList{}
+	for _, pl := range sm.extras.PrefixLists {
+		prefixLists[pl.Name] = pl
+	}
+
+	// leak holds the prefixes imported into a VRF from the others.
+	type leak struct {
+		// The routers of the VRFs the prefixes are imported from.
+		sources    sets.Set[string]
+		prefixesV4 sets.Set[string]
+		prefixesV6 sets.Set[string]
+	}
+
+	// l3vni holds the prefixes exported as EVPN Type-5 routes from a VRF.
+	type l3vni struct {
+		evpn *metallbconfig.EVPN
+		// The routers of the EVPN sessions the prefixes are announced to.
+		sources    sets.Set[string]
+		prefixesV4 sets.Set[string]
+		prefixesV6 sets.Set[string]
 	}
 
 	type router struct {
@@ -222,9 +286,17 @@ func (sm *sessionManager) createConfig() (*frrConfig, error) {
 		vrf          string
 		ipV4Prefixes map[string]string
 		ipV6Prefixes map[string]string
+		labelIndexes map[string]string
+		// The prefixes imported into the VRF of the router, nil if none.
+		imports *leak
+		// The prefixes exported from the VRF of the router as EVPN
+		// routes, nil if none.
+		exports *l3vni
 	}
 
 	routers := make(map[string]*router)
+	leaks := make(map[string]*leak)
+	l3vnis := make(map[string]*l3vni)
 
 	// leave it for backward compatibility
 	frrLogLevel, found := os.LookupEnv("FRR_LOGGING_LEVEL")
@@ -244,6 +316,7 @@ func (sm *sessionManager) createConfig() (*frrConfig, error) {
 				neighbors:    make(map[string]*neighborConfig),
 				ipV4Prefixes: make(map[string]string),
 				ipV6Prefixes: make(map[string]string),
+				labelIndexes: make(map[string]string),
 				vrf:          s.VRFName,
 			}
 			if s.RouterID != nil {
@@ -282,6 +355,7 @@ func (sm *sessionManager) createConfig() (*frrConfig, error) {
 				ASN:                      asnFor(s.PeerASN, s.DynamicASN),
 				Addr:                     s.PeerAddress,
 				Iface:                    s.PeerInterface,
+				Zone:                     s.PeerZone,
 				Port:                     s.PeerPort,
 				HoldTime:                 holdTime,
 				KeepaliveTime:            keepaliveTime,
@@ -292,16 +366,46 @@ func (sm *sessionManager) createConfig() (*frrConfig, error) {
 				EBGPMultiHop:             s.EBGPMultiHop,
 				LocalASN:                 s.LocalASN,
 				VRFName:                  s.VRFName,
+				PeerGroup:                peerGroupConfig{Name: s.PeerGroup},
 				PrefixesV4:               []string{},
 				PrefixesV6:               []string{},
 				prefixesV4Set:            sets.New[string](),
 				prefixesV6Set:            sets.New[string](),
 				CommunityPrefixModifiers: make(map[string]CommunityPrefixList),
 				LocalPrefPrefixModifiers: make(map[string]LocalPrefPrefixList),
+				meds:                     make(map[string]uint32),
+				asPathPrepends:           make(map[string]uint32),
+				labeledPrefixesV4Set:     sets.New[string](),
+				labeledPrefixesV6Set:     sets.New[string](),
+			}
+			if s.ToReceive != nil {
+				neighbor.Incoming = incomingFilter(s.ToReceive)
 			}
 			if s.SourceAddress != nil {
 				neighbor.SrcAddr = s.SourceAddress.String()
 			}
+			if s.Extras != nil {
+				neighbor.MaximumPrefix = ptr.Deref(s.Extras.MaximumPrefix, 0)
+				neighbor.AllowASIn = ptr.Deref(s.Extras.AllowASIn, 0)
+				neighbor.ImportPolicy = RouteMapEntries(s.Extras.Import)
+				neighbor.ExportPolicy = RouteMapEntries(s.Extras.Export)
+				// The session may be configured before the extras
+				// defining the prefix-lists it matches are synced.
+				for _, p := range slices.Concat(s.Extras.Import, s.Extras.Export) {
+					if p.PrefixList != nil {
+						prefixLists[p.PrefixList.Name] = p.PrefixList
+					}
+				}
+			}
+			// Set after the extras, which can't set the maximum prefixes
+			// accepted along with the limits.
+			if l := s.PrefixLimits; l != nil {
+				if l.MaxAccepted != 0 {
+					neighbor.MaximumPrefix = l.MaxAccepted
+					neighbor.MaximumPrefixWarningOnly = true
+				}
+				neighbor.MaximumPrefixOut = l.MaxAdvertised
+			}
 
 			rout.neighbors[neighborName] = neighbor
 		}
@@ -310,12 +414,52 @@ func (sm *sessionManager) createConfig() (*frrConfig, error) {
 			prefix := adv.Prefix.String()
 			family := ipfamily.ForAddress(adv.Prefix.IP)
 
+			// The EVPN routes are exported from the VRF of the L3VNI to
+			// the sessions of the default VRF, over the l2vpn evpn address
+			// family which carries both IP families.
+			if adv.EVPN != nil {
+				if s.VRFName != "" {
+					continue
+				}
+				neighbor.EVPN = true
+				e, ok := l3vnis[adv.EVPN.VRF]
+				if !ok {
+					e = &l3vni{evpn: adv.EVPN, sources: sets.New[string](), prefixesV4: sets.New[string](), prefixesV6: sets.New[string]()}
+					l3vnis[adv.EVPN.VRF] = e
+				}
+				e.sources.Insert(routerName)
+				if family == ipfamily.IPv6 {
+					e.prefixesV6.Insert(prefix)
+					continue
+				}
+				e.prefixesV4.Insert(prefix)
+				continue
+			}
+
 			if neighbor.IPFamily != family &&
 				neighbor.IPFamily != ipfamily.DualStack {
 				continue
 			}
 			frrFamily := frrIPFamily(family)
 
+			// The labeled-unicast address family is configured in the
+			// default VRF only. The labeled announcements still go
+			// through the neighbor's out route-map, which sets their
+			// attributes.
+			if adv.LabeledUnicast != nil {
+				if s.VRFName != "" {
+					continue
+				}
+				if family == ipfamily.IPv6 {
+					neighbor.labeledPrefixesV6Set.Insert(prefix)
+				} else {
+					neighbor.labeledPrefixesV4Set.Insert(prefix)
+				}
+				if i := adv.LabeledUnicast.LabelIndex; i != nil {
+					rout.labelIndexes[prefix] = strconv.FormatUint(uint64(*i), 10)
+				}
+			}
+
 			for _, c := range adv.Communities {
 				prefixListName := communityPrefixList(neighbor, c.String(), frrFamily)
 				if community.IsLarge(c) {
@@ -353,6 +497,22 @@ func (sm *sessionManager) createConfig() (*frrConfig, error) {
 				prefixList.prefixesSet.Insert(prefix)
 				neighbor.LocalPrefPrefixModifiers[prefixListName] = prefixList
 			}
+			if adv.MED != nil {
+				// The same prefix may be advertised with different MEDs,
+				// the lowest wins.
+				if med, ok := neighbor.meds[prefix]; ok {
+					neighbor.meds[prefix] = *bgp.LowestMED(&med, adv.MED)
+				} else {
+					neighbor.meds[prefix] = *adv.MED
+				}
+			}
+			if adv.ASPathPrepend != 0 {
+				// The same prefix may be advertised with different counts,
+				// the highest wins.
+				neighbor.asPathPrepends[prefix] = max(neighbor.asPathPrepends[prefix], adv.ASPathPrepend)
+			}
+
+			neighbor.advertisements = append(neighbor.advertisements, adv)
 
 			switch family {
 			case ipfamily.IPv4:
@@ -362,6 +522,62 @@ func (sm *sessionManager) createConfig() (*frrConfig, error) {
 				neighbor.prefixesV6Set.Insert(prefix)
 				rout.ipV6Prefixes[prefix] = prefix
 			}
+
+			for _, vrf := range adv.ImportVRFs {
+				if vrf == s.VRFName {
+					continue
+				}
+				l, ok := leaks[vrf]
+				if !ok {
+					l = &leak{sources: sets.New[string](), prefixesV4: sets.New[string](), prefixesV6: sets.New[string]()}
+					leaks[vrf] = l
+				}
+				l.sources.Insert(routerName)
+				if family == ipfamily.IPv6 {
+					l.prefixesV6.Insert(prefix)
+					continue
+				}
+				l.prefixesV4.Insert(prefix)
+			}
+		}
+	}
+
+	// The prefixes can be leaked into, or exported from, a VRF with no
+	// peers on the node, in which case its router is created with the
+	// settings of the first of the routers they come from.
+	routerForVRF := map[string]*router{}
+	for _, r := range routers {
+		routerForVRF[r.vrf] = r
+	}
+	vrfRouter := func(vrf string, sources sets.Set[string]) *router {
+		if r, ok := routerForVRF[vrf]; ok {
+			return r
+		}
+		source := routers[slices.Min(sources.UnsortedList())]
+		r := &router{
+			myASN:        source.myASN,
+			routerID:     source.routerID,
+			neighbors:    make(map[string]*neighborConfig),
+			ipV4Prefixes: make(map[string]string),
+			ipV6Prefixes: make(map[string]string),
+			labelIndexes: make(map[string]string),
+			vrf:          vrf,
+		}
+		routers[RouterName(source.routerID, source.myASN, vrf)] = r
+		routerForVRF[vrf] = r
+		return r
+	}
+	for vrf, l := range leaks {
+		vrfRouter(vrf, l.sources).imports = l
+	}
+	for vrf, e := range l3vnis {
+		r := vrfRouter(vrf, e.sources)
+		r.exports = e
+		for p := range e.prefixesV4 {
+			r.ipV4Prefixes[p] = p
+		}
+		for p := range e.prefixesV6 {
+			r.ipV6Prefixes[p] = p
 		}
 	}
 
@@ -377,6 +593,18 @@ func (sm *sessionManager) createConfig() (*frrConfig, error) {
 				m.Prefixes = sets.List(n.LocalPrefPrefixModifiers[k].prefixesSet)
 				n.LocalPrefPrefixModifiers[k] = m
 			}
+			if n.labeledPrefixesV4Set.Len() > 0 || n.labeledPrefixesV6Set.Len() > 0 {
+				n.Labeled = &LabeledAnnouncement{
+					PrefixesV4: sets.List(n.labeledPrefixesV4Set),
+					PrefixesV6: sets.List(n.labeledPrefixesV6Set),
+				}
+			}
+			n.MEDPrefixModifiers = medPrefixLists(n)
+			n.ASPathPrependPrefixModifiers = asPathPrependPrefixLists(n, r.myASN)
+			n.Conditional, err = ConditionalAdvertisementFor(n.advertisements)
+			if err != nil {
+				return nil, fmt.Errorf("neighbor %s: %w", n.ID(), err)
+			}
 		}
 		toAdd := &routerConfig{
 			MyASN:        r.myASN,
@@ -385,12 +613,61 @@ func (sm *sessionManager) createConfig() (*frrConfig, error) {
 			Neighbors:    sortMap(r.neighbors),
 			IPV4Prefixes: sortMap(r.ipV4Prefixes),
 			IPV6Prefixes: sortMap(r.ipV6Prefixes),
+			PeerGroups:   peerGroups(r.neighbors),
+			LabelIndexes: r.labelIndexes,
+		}
+		if l := r.imports; l != nil {
+			toAdd.VRFImport = &VRFImport{
+				RouteMap:   vrfImportMap(r.vrf),
+				PrefixesV4: sets.List(l.prefixesV4),
+				PrefixesV6: sets.List(l.prefixesV6),
+			}
+			for source := range l.sources {
+				toAdd.VRFImport.VRFs = append(toAdd.VRFImport.VRFs, frrVRFName(routers[source].vrf))
+			}
+			slices.Sort(toAdd.VRFImport.VRFs)
+			toAdd.VRFImport.VRFs = slices.Compact(toAdd.VRFImport.VRFs)
+		}
+		if e := r.exports; e != nil {
+			toAdd.EVPN = &EVPNExport{
+				VNI:                e.evpn.VNI,
+				RouteDistinguisher: e.evpn.RouteDistinguisher,
+				ImportRouteTargets: e.evpn.ImportRouteTargets,
+				ExportRouteTargets: e.evpn.ExportRouteTargets,
+				RouteMap:           evpnExportMap(r.vrf),
+				PrefixesV4:         sets.List(e.prefixesV4),
+				PrefixesV6:         sets.List(e.prefixesV6),
+			}
 		}
 		config.Routers = append(config.Routers, toAdd)
 	}
+	for _, pl := range sortMap(prefixLists) {
+		config.PrefixLists = append(config.PrefixLists, PrefixListFor(pl))
+	}
+
 	return config, nil
 }
 
+// frrVRFName returns the name FRR knows the VRF with.
+func frrVRFName(vrf string) string {
+	if vrf == "" {
+		return metallbconfig.DefaultVRF
+	}
+	return vrf
+}
+
+// vrfImportMap is the name of the route-map selecting the prefixes
+// imported into the VRF.
+func vrfImportMap(vrf string) string {
+	return frrVRFName(vrf) + "-vrf-import"
+}
+
+// evpnExportMap is the name of the route-map selecting the prefixes
+// exported from the VRF as EVPN routes.
+func evpnExportMap(vrf string) string {
+	return vrf + "-evpn-export"
+}
+
 func frrIPFamily(ipFamily ipfamily.Family) string {
 	if ipFamily == ipfamily.IPv6 {
 		return "ipv6"
@@ -398,6 +675,144 @@ func frrIPFamily(ipFamily ipfamily.Family) string {
 	return "ip"
 }
 
+// peerGroups returns the sorted peer-groups the neighbors are members of,
+// and sets them to their members.
+func peerGroups(neighbors map[string]*neighborConfig) []peerGroupConfig {
+	members := map[string][]*neighborConfig{}
+	for _, n := range neighbors {
+		if n.PeerGroup.Name != "" {
+			members[n.PeerGroup.Name] = append(members[n.PeerGroup.Name], n)
+		}
+	}
+	res := []peerGroupConfig{}
+	for _, name := range sets.List(sets.KeySet(members)) {
+		group := sharedSettings(name, members[name])
+		for _, n := range members[name] {
+			n.PeerGroup = group
+		}
+		res = append(res, group)
+	}
+	return res
+}
+
+// sharedSettings returns the peer-group holding the settings shared by all
+// the given members.
+func sharedSettings(name string, members []*neighborConfig) peerGroupConfig {
+	first := members[0]
+	res := peerGroupConfig{
+		Name:            name,
+		HoldTime:        first.HoldTime,
+		KeepaliveTime:   first.KeepaliveTime,
+		ConnectTime:     first.ConnectTime,
+		Password:        first.Password,
+		BFDProfile:      first.BFDProfile,
+		GracefulRestart: first.GracefulRestart,
+		EBGPMultiHop:    first.EBGPMultiHop,
+	}
+	for _, n := range members[1:] {
+		if !ptr.Equal(n.HoldTime, res.HoldTime) || !ptr.Equal(n.KeepaliveTime, res.KeepaliveTime) {
+			res.HoldTime, res.KeepaliveTime = nil, nil
+		}
+		if n.ConnectTime != res.ConnectTime {
+			res.ConnectTime = 0
+		}
+		if n.Password != res.Password {
+			res.Password = ""
+		}
+		if n.BFDProfile != res.BFDProfile {
+			res.BFDProfile = ""
+		}
+		res.GracefulRestart = res.GracefulRestart && n.GracefulRestart
+		res.EBGPMultiHop = res.EBGPMultiHop && n.EBGPMultiHop
+	}
+	// The timers are set together.
+	if res.HoldTime == nil || res.KeepaliveTime == nil {
+		res.HoldTime, res.KeepaliveTime = nil, nil
+	}
+	return res
+}
+
+// incomingFilter splits the prefixes accepted from the neighbor by
+// family.
+func incomingFilter(toReceive *metallbconfig.Receive) *IncomingFilter {
+	res := &IncomingFilter{All: toReceive.All}
+	for _, p := range toReceive.Prefixes {
+		prefix := IncomingPrefix{Prefix: p.Prefix.String(), LE: p.LE, GE: p.GE}
+		if p.Prefix.IP.To4() == nil {
+			res.PrefixesV6 = append(res.PrefixesV6, prefix)
+			continue
+		}
+		res.PrefixesV4 = append(res.PrefixesV4, prefix)
+	}
+	return res
+}
+
+// medPrefixLists groups the prefixes of the neighbor by their MED.
+func medPrefixLists(neighbor *neighborConfig) map[string]MEDPrefixList {
+	res := map[string]MEDPrefixList{}
+	for prefix, med := range neighbor.meds {
+		frrFamily := "ip"
+		if ip, _, _ := net.ParseCIDR(prefix); ip.To4() == nil {
+			frrFamily = "ipv6"
+		}
+		name := fmt.Sprintf("%s-%d-%s-med-prefixes", neighbor.ID(), med, frrFamily)
+		prefixList, ok := res[name]
+		if !ok {
+			prefixList = MEDPrefixList{
+				PropertyPrefixList: PropertyPrefixList{
+					Name:        name,
+					IPFamily:    frrFamily,
+					prefixesSet: sets.New[string](),
+				},
+				MED: med,
+			}
+		}
+		prefixList.prefixesSet.Insert(prefix)
+		res[name] = prefixList
+	}
+	for name, prefixList := range res {
+		prefixList.Prefixes = sets.List(prefixList.prefixesSet)
+		res[name] = prefixList
+	}
+	return res
+}
+
+// asPathPrependPrefixLists groups the prefixes of the neighbor by the number
+// of times the ASN the neighbor sees is prepended to their AS_PATH.
+func asPathPrependPrefixLists(neighbor *neighborConfig, myASN uint32) map[string]ASPathPrependPrefixList {
+	asn := myASN
+	if neighbor.LocalASN != 0 {
+		asn = neighbor.LocalASN
+	}
+	res := map[string]ASPathPrependPrefixList{}
+	for prefix, count := range neighbor.asPathPrepends {
+		frrFamily := "ip"
+		if ip, _, _ := net.ParseCIDR(prefix); ip.To4() == nil {
+			frrFamily = "ipv6"
+		}
+		name := fmt.Sprintf("%s-%d-%s-aspath-prefixes", neighbor.ID(), count, frrFamily)
+		prefixList, ok := res[name]
+		if !ok {
+			prefixList = ASPathPrependPrefixList{
+				PropertyPrefixList: PropertyPrefixList{
+					Name:        name,
+					IPFamily:    frrFamily,
+					prefixesSet: sets.New[string](),
+				},
+				ASN:   asn,
+				Count: count,
+			}
+		}
+		prefixList.prefixesSet.Insert(prefix)
+		res[name] = prefixList
+	}
+	for name, prefixList := range res {
+		prefixList.Prefixes = sets.List(prefixList.prefixesSet)
+		res[name] = prefixList
+	}
+	return res
+}
+
 func localPrefPrefixList(neighbor *neighborConfig, localPreference uint32, ipFamily string) string {
 	return fmt.Sprintf("%s-%d-%s-localpref-prefixes", neighbor.ID(), localPreference, ipFamily)
 }
@@ -414,7 +829,10 @@ func (sm *sessionManager) SetEventCallback(func(interface{})) {}
 
 var failureTimeout = time.Second * 5
 
-func NewSessionManager(l log.Logger, logLevel logging.Level, debounceTimeout time.Duration) bgp.SessionManager {
+// NewSessionManager returns a session manager configuring FRR. When
+// incremental is set, the configuration changes are applied over the vty
+// sockets of the daemons, falling back to reloading the configuration file.
+func NewSessionManager(l log.Logger, logLevel logging.Level, debounceTimeout time.Duration, incremental bool) bgp.SessionManager {
 	res := &sessionManager{
 		sessions:     map[string]*session{},
 		bfdProfiles:  []BFDProfile{},
@@ -424,10 +842,19 @@ func NewSessionManager(l log.Logger, logLevel logging.Level, debounceTimeout tim
 	reload := func(config *frrConfig) error {
 		return generateAndReloadConfigFile(config, l)
 	}
+	if incremental {
+		applier := newIncrementalApplier(l, runVtyConfig)
+		applier.onApplied = func(rendered string) {
+			res.reloadSucceeded(time.Now(), configHash(rendered))
+		}
+		reload = applier.apply
+	}
 
-	debouncer(reload, res.reloadConfig, debounceTimeout, failureTimeout, l)
+	debouncer(res.validated(reload, l), res.reloadConfig, debounceTimeout, failureTimeout, l)
 
-	reloadValidator(l, res.reloadConfig)
+	reloadValidator(l, res)
+
+	sessionStatePoller(l, res, runVtyCommand)
 
 	return res
 }
@@ -443,26 +870,56 @@ func mockNewSessionManager(l log.Logger, logLevel logging.Level) *sessionManager
 		return generateAndReloadConfigFile(config, l)
 	}
 
-	debouncer(reload, res.reloadConfig, testDebounceTimeout, failureTimeout, l)
+	debouncer(res.validated(reload, l), res.reloadConfig, testDebounceTimeout, failureTimeout, l)
 
 	return res
 }
 
-func reloadValidator(l log.Logger, reload chan<- reloadEvent) {
+// validated wraps the reload of the configuration so that the configurations
+// FRR would fail to load never reach the daemons, which keep running with the
+// last valid one. An invalid configuration is reported as a failed reload and
+// not retried, unless it was rendered in the middle of a batch of changes:
+// it is then retried, to be validated again once the batch is complete.
+func (sm *sessionManager) validated(reload func(config *frrConfig) error, l log.Logger) func(config *frrConfig) error {
+	return func(config *frrConfig) error {
+		rendered, err := templateConfig(config)
+		if err != nil {
+			level.Error(l).Log("op", "reload", "error", err, "cause", "template", "config", config)
+			return err
+		}
+		err = validateConfig(rendered)
+		if err != nil && sm.syncing.Load() {
+			level.Debug(l).Log("op", "reload", "error", err, "msg", "invalid configuration while syncing, retrying")
+			return err
+		}
+		if err != nil {
+			level.Error(l).Log("op", "reload", "error", err, "cause", "validation", "msg", "keeping the last valid configuration")
+			sm.reloadFailed(time.Now(), err.Error())
+			return nil
+		}
+		return reload(config)
+	}
+}
+
+func reloadValidator(l log.Logger, sm *sessionManager) {
 	var tickerIntervals = 30 * time.Second
 	var prevReloadTimeStamp string
 
 	ticker := time.NewTicker(tickerIntervals)
 	go func() {
 		for range ticker.C {
-			validateReload(l, &prevReloadTimeStamp, reload)
+			sm.validateReload(l, &prevReloadTimeStamp)
 		}
 	}()
 }
 
-const statusFileName = "/etc/frr_reloader/.status"
+const (
+	statusFileName = "/etc/frr_reloader/.status"
+	// The output of the last failed reload.
+	errorFileName = "/etc/frr_reloader/.error"
+)
 
-func validateReload(l log.Logger, prevReloadTimeStamp *string, reload chan<- reloadEvent) {
+func (sm *sessionManager) validateReload(l log.Logger, prevReloadTimeStamp *string) {
 	bytes, err := os.ReadFile(statusFileName)
 	if err != nil {
 		if !os.IsNotExist(err) {
@@ -471,8 +928,9 @@ func validateReload(l log.Logger, prevReloadTimeStamp *string, reload chan<- rel
 		return
 	}
 
+	// The hash of the reloaded configuration follows the status on success.
 	lastReloadStatus := strings.Fields(string(bytes))
-	if len(lastReloadStatus) != 2 {
+	if len(lastReloadStatus) != 2 && len(lastReloadStatus) != 3 {
 		level.Error(l).Log("op", "reload-validate", "error", err, "cause", "Fields", "bytes", string(bytes))
 		return
 	}
@@ -484,13 +942,28 @@ func validateReload(l log.Logger, prevReloadTimeStamp *string, reload chan<- rel
 
 	*prevReloadTimeStamp = timeStamp
 
+	reloadTime := time.Now()
+	if seconds, err := strconv.ParseInt(timeStamp, 10, 64); err == nil {
+		reloadTime = time.Unix(seconds, 0)
+	}
+
 	if strings.Compare(status, "failure") == 0 {
+		output, err := os.ReadFile(errorFileName)
+		if err != nil && !os.IsNotExist(err) {
+			level.Error(l).Log("op", "reload-validate", "error", err, "cause", "readFile", "fileName", errorFileName)
+		}
 		level.Error(l).Log("op", "reload-validate", "error", fmt.Errorf("reload failure"),
-			"cause", "frr reload failed", "status", status)
-		reload <- reloadEvent{useOld: true}
+			"cause", "frr reload failed", "status", status, "output", string(output))
+		sm.reloadFailed(reloadTime, string(output))
+		sm.reloadConfig <- reloadEvent{useOld: true}
 		return
 	}
 
+	hash := ""
+	if len(lastReloadStatus) == 3 {
+		hash = lastReloadStatus[2]
+	}
+	sm.reloadSucceeded(reloadTime, hash)
 	level.Info(l).Log("op", "reload-validate", "success", "reloaded config")
 }
 
@@ -19,6 +19,7 @@ import (
 	"github.com/go-kit/log"
 	"go.universe.tf/metallb/internal/bgp"
 	"go.universe.tf/metallb/internal/bgp/community"
+	metallbconfig "go.universe.tf/metallb/internal/config"
 	"go.universe.tf/metallb/internal/logging"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/utils/ptr"
@@ -1024,7 +1025,7 @@ func TestSingleSessionExtras(t *testing.T) {
 		l := log.NewNopLogger()
 		sessionManager := mockNewSessionManager(l, logging.LevelInfo)
 		defer close(sessionManager.reloadConfig)
-		err := sessionManager.SyncExtraInfo("# hello")
+		err := sessionManager.SyncExtraInfo(metallbconfig.BGPExtras{Raw: "# hello"})
 		if err != nil {
 			t.Fatalf("Could not sync extra info")
 		}
@@ -1053,6 +1054,242 @@ func TestSingleSessionExtras(t *testing.T) {
 	})
 }
 
+func TestInvalidExtras(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		testSetup(t)
+
+		l := log.NewNopLogger()
+		sessionManager := mockNewSessionManager(l, logging.LevelInfo)
+		defer close(sessionManager.reloadConfig)
+		err := sessionManager.SyncExtraInfo(metallbconfig.BGPExtras{Raw: "router bgp 100\n  neighbor 10.2.2.254 description tor\nexit\n"})
+		if err == nil {
+			t.Fatalf("Expected the extras configuring an undeclared neighbor to be rejected")
+		}
+	})
+}
+
+func TestCloseSessionReferencedByExtras(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		testSetup(t)
+
+		l := log.NewNopLogger()
+		sessionManager := mockNewSessionManager(l, logging.LevelInfo)
+		defer close(sessionManager.reloadConfig)
+		session, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress: "10.2.2.254",
+				PeerPort:    179,
+				MyASN:       100,
+				PeerASN:     200,
+				SessionName: "test-peer"})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		err = sessionManager.SyncExtraInfo(metallbconfig.BGPExtras{Raw: "router bgp 100\n  neighbor 10.2.2.254 description tor\nexit\n"})
+		if err != nil {
+			t.Fatalf("Could not sync extra info: %s", err)
+		}
+
+		// The session is removed within a sync, together with the extras
+		// referring to it: the intermediate configuration where only the
+		// session is gone is not reported as invalid.
+		sessionManager.StartSync()
+		if err := session.Close(); err != nil {
+			t.Fatalf("Could not close session: %s", err)
+		}
+		err = sessionManager.SyncExtraInfo(metallbconfig.BGPExtras{})
+		if err != nil {
+			t.Fatalf("Could not sync extra info: %s", err)
+		}
+		sessionManager.EndSync()
+
+		testCheckConfigFile(t)
+		if status := sessionManager.ReloadStatus(); status.Failed {
+			t.Fatalf("Expected the reload not to fail, got %s", status.Error)
+		}
+	})
+}
+
+func TestUnnumberedPeerGroupExtras(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		testSetup(t)
+
+		l := log.NewNopLogger()
+		sessionManager := mockNewSessionManager(l, logging.LevelInfo)
+		defer close(sessionManager.reloadConfig)
+		_, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress: "10.2.2.254",
+				PeerPort:    179,
+				MyASN:       100,
+				PeerASN:     200,
+				SessionName: "test-peer"})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		err = sessionManager.SyncExtraInfo(metallbconfig.BGPExtras{Raw: `router bgp 100
+  neighbor fabric peer-group
+  neighbor fabric remote-as external
+  neighbor eth1 interface peer-group fabric
+  address-family ipv4 unicast
+    neighbor eth1 activate
+  exit-address-family
+exit
+`})
+		if err != nil {
+			t.Fatalf("Could not sync extra info: %s", err)
+		}
+
+		testCheckConfigFile(t)
+		if status := sessionManager.ReloadStatus(); status.Failed {
+			t.Fatalf("Expected the reload not to fail, got %s", status.Error)
+		}
+	})
+}
+
+func TestInvalidConfigKeepsLastValid(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		testSetup(t)
+
+		l := log.NewNopLogger()
+		sessionManager := mockNewSessionManager(l, logging.LevelInfo)
+		defer close(sessionManager.reloadConfig)
+		session, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress: "10.2.2.254",
+				PeerPort:    179,
+				MyASN:       100,
+				PeerASN:     200,
+				SessionName: "test-peer"})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		err = sessionManager.SyncExtraInfo(metallbconfig.BGPExtras{Raw: "router bgp 100\n  neighbor 10.2.2.254 description tor\nexit\n"})
+		if err != nil {
+			t.Fatalf("Could not sync extra info: %s", err)
+		}
+		time.Sleep(2 * testDebounceTimeout)
+
+		// Removing the session outside of a sync leaves the extras
+		// referring to an undeclared neighbor: the configuration is
+		// rejected and the last valid one is kept.
+		if err := session.Close(); err != nil {
+			t.Fatalf("Could not close session: %s", err)
+		}
+		time.Sleep(2 * failureTimeout)
+
+		testCheckConfigFile(t)
+		if status := sessionManager.ReloadStatus(); !status.Failed {
+			t.Fatalf("Expected the reload of the invalid configuration to fail")
+		}
+	})
+}
+
+func TestSessionExtras(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		testSetup(t)
+
+		l := log.NewNopLogger()
+		sessionManager := mockNewSessionManager(l, logging.LevelInfo)
+		defer close(sessionManager.reloadConfig)
+
+		tenants := &metallbconfig.PrefixList{
+			Name: "tenants",
+			Entries: []metallbconfig.PrefixListEntry{
+				{PrefixSelector: metallbconfig.PrefixSelector{Prefix: &net.IPNet{IP: net.ParseIP("10.1.0.0").To4(), Mask: net.CIDRMask(16, 32)}, GE: 24}, Deny: true},
+				{PrefixSelector: metallbconfig.PrefixSelector{Prefix: &net.IPNet{IP: net.ParseIP("10.0.0.0").To4(), Mask: net.CIDRMask(8, 32)}, LE: 32}},
+				{PrefixSelector: metallbconfig.PrefixSelector{Prefix: &net.IPNet{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(32, 128)}, LE: 64}},
+			},
+		}
+		err := sessionManager.SyncExtraInfo(metallbconfig.BGPExtras{
+			BestPath:    &metallbconfig.BestPath{ASPathMultipathRelax: true, MEDMissingAsWorst: true},
+			PrefixLists: []*metallbconfig.PrefixList{tenants},
+		})
+		if err != nil {
+			t.Fatalf("Could not sync extra info: %s", err)
+		}
+
+		largeCommunity, _ := community.New("large:1:2:3")
+		session, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress:            "10.2.2.254",
+				PeerPort:               179,
+				SourceAddress:          net.ParseIP("10.1.1.254"),
+				MyASN:                  100,
+				RouterID:               net.ParseIP("10.1.1.254"),
+				PeerASN:                200,
+				CurrentNode:            "hostname",
+				DualStackAddressFamily: true,
+				ToReceive:              &metallbconfig.Receive{All: true},
+				Extras: &metallbconfig.NeighborExtras{
+					MaximumPrefix: ptr.To(uint32(100)),
+					AllowASIn:     ptr.To(uint32(2)),
+					Import: []metallbconfig.PolicyEntry{
+						{PrefixList: tenants, LocalPref: ptr.To(uint32(200)), Communities: []community.BGPCommunity{largeCommunity}},
+					},
+					Export: []metallbconfig.PolicyEntry{
+						{Deny: true, PrefixList: tenants},
+						{Metric: ptr.To(uint32(10))},
+					},
+				},
+				SessionName: "test-peer"})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		defer session.Close()
+
+		prefix := &net.IPNet{
+			IP:   net.ParseIP("172.16.1.10"),
+			Mask: classCMask,
+		}
+		err = session.Set(&bgp.Advertisement{Prefix: prefix})
+		if err != nil {
+			t.Fatalf("Could not advertise prefix: %s", err)
+		}
+
+		testCheckConfigFile(t)
+	})
+}
+
+func TestPrefixLimits(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		testSetup(t)
+
+		l := log.NewNopLogger()
+		sessionManager := mockNewSessionManager(l, logging.LevelInfo)
+		defer close(sessionManager.reloadConfig)
+		session, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress:            "10.2.2.254",
+				PeerPort:               179,
+				SourceAddress:          net.ParseIP("10.1.1.254"),
+				MyASN:                  100,
+				RouterID:               net.ParseIP("10.1.1.254"),
+				PeerASN:                200,
+				CurrentNode:            "hostname",
+				DualStackAddressFamily: true,
+				ToReceive:              &metallbconfig.Receive{All: true},
+				Extras:                 &metallbconfig.NeighborExtras{AllowASIn: ptr.To(uint32(2))},
+				PrefixLimits:           &metallbconfig.PrefixLimits{MaxAdvertised: 10, MaxAccepted: 100},
+				SessionName:            "test-peer"})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		defer session.Close()
+
+		prefix := &net.IPNet{
+			IP:   net.ParseIP("172.16.1.10"),
+			Mask: classCMask,
+		}
+		err = session.Set(&bgp.Advertisement{Prefix: prefix})
+		if err != nil {
+			t.Fatalf("Could not advertise prefix: %s", err)
+		}
+
+		testCheckConfigFile(t)
+	})
+}
+
 func TestLoggingConfiguration(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		testSetup(t)
@@ -1161,6 +1398,429 @@ func TestLargeCommunities(t *testing.T) {
 	})
 }
 
+func TestExtendedCommunities(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		testSetup(t)
+
+		l := log.NewNopLogger()
+		sessionManager := mockNewSessionManager(l, logging.LevelInfo)
+		defer close(sessionManager.reloadConfig)
+		session, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress:   "10.2.2.254",
+				PeerPort:      179,
+				SourceAddress: net.ParseIP("10.1.1.254"),
+				MyASN:         100,
+				RouterID:      net.ParseIP("10.1.1.254"),
+				PeerASN:       200,
+				HoldTime:      ptr.To(time.Second),
+				KeepAliveTime: ptr.To(time.Second),
+				Password:      "password",
+				CurrentNode:   "hostname",
+				EBGPMultiHop:  true,
+				SessionName:   "test-peer"})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		defer session.Close()
+
+		prefix := &net.IPNet{
+			IP:   net.ParseIP("172.16.1.10"),
+			Mask: classCMask,
+		}
+		communities := []community.BGPCommunity{}
+		community1, _ := community.New("target:1111:2222")
+		communities = append(communities, community1)
+		community2, _ := community.New("target:10.1.1.254:3333")
+		communities = append(communities, community2)
+		community3, _ := community.New("bandwidth:1000")
+		communities = append(communities, community3)
+		adv := &bgp.Advertisement{
+			Prefix:      prefix,
+			Communities: communities,
+			LocalPref:   300,
+		}
+
+		err = session.Set(adv)
+		if err != nil {
+			t.Fatalf("Could not advertise prefix: %s", err)
+		}
+
+		testCheckConfigFile(t)
+	})
+}
+
+func TestASPathPrepend(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		testSetup(t)
+
+		l := log.NewNopLogger()
+		sessionManager := mockNewSessionManager(l, logging.LevelInfo)
+		defer close(sessionManager.reloadConfig)
+		session, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress:            "10.2.2.254",
+				PeerPort:               179,
+				SourceAddress:          net.ParseIP("10.1.1.254"),
+				MyASN:                  100,
+				RouterID:               net.ParseIP("10.1.1.254"),
+				PeerASN:                200,
+				HoldTime:               ptr.To(time.Second),
+				KeepAliveTime:          ptr.To(time.Second),
+				CurrentNode:            "hostname",
+				DualStackAddressFamily: true,
+				SessionName:            "test-peer"})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		defer session.Close()
+
+		prefix1 := &net.IPNet{
+			IP:   net.ParseIP("172.16.1.10"),
+			Mask: classCMask,
+		}
+		prefix2 := &net.IPNet{
+			IP:   net.ParseIP("172.16.2.10"),
+			Mask: classCMask,
+		}
+		prefix3 := &net.IPNet{
+			IP:   net.ParseIP("2001:db8::10"),
+			Mask: net.CIDRMask(128, 128),
+		}
+
+		// The highest count wins when the same prefix is advertised more than once.
+		err = session.Set(
+			&bgp.Advertisement{Prefix: prefix1, ASPathPrepend: 2},
+			&bgp.Advertisement{Prefix: prefix1, ASPathPrepend: 3},
+			&bgp.Advertisement{Prefix: prefix2, ASPathPrepend: 3},
+			&bgp.Advertisement{Prefix: prefix3, ASPathPrepend: 1},
+		)
+		if err != nil {
+			t.Fatalf("Could not advertise prefix: %s", err)
+		}
+
+		testCheckConfigFile(t)
+	})
+}
+
+func TestMED(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		testSetup(t)
+
+		l := log.NewNopLogger()
+		sessionManager := mockNewSessionManager(l, logging.LevelInfo)
+		defer close(sessionManager.reloadConfig)
+		session, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress:            "10.2.2.254",
+				PeerPort:               179,
+				SourceAddress:          net.ParseIP("10.1.1.254"),
+				MyASN:                  100,
+				RouterID:               net.ParseIP("10.1.1.254"),
+				PeerASN:                200,
+				HoldTime:               ptr.To(time.Second),
+				KeepAliveTime:          ptr.To(time.Second),
+				CurrentNode:            "hostname",
+				DualStackAddressFamily: true,
+				SessionName:            "test-peer"})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		defer session.Close()
+
+		prefix1 := &net.IPNet{
+			IP:   net.ParseIP("172.16.1.10"),
+			Mask: classCMask,
+		}
+		prefix2 := &net.IPNet{
+			IP:   net.ParseIP("172.16.2.10"),
+			Mask: classCMask,
+		}
+		prefix3 := &net.IPNet{
+			IP:   net.ParseIP("2001:db8::10"),
+			Mask: net.CIDRMask(128, 128),
+		}
+
+		// The lowest MED wins when the same prefix is advertised more than once.
+		err = session.Set(
+			&bgp.Advertisement{Prefix: prefix1, MED: ptr.To[uint32](20)},
+			&bgp.Advertisement{Prefix: prefix1, MED: ptr.To[uint32](10)},
+			&bgp.Advertisement{Prefix: prefix1},
+			&bgp.Advertisement{Prefix: prefix2, MED: ptr.To[uint32](10)},
+			&bgp.Advertisement{Prefix: prefix3, MED: ptr.To[uint32](0)},
+		)
+		if err != nil {
+			t.Fatalf("Could not advertise prefix: %s", err)
+		}
+
+		testCheckConfigFile(t)
+	})
+}
+
+func TestToReceive(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		testSetup(t)
+
+		l := log.NewNopLogger()
+		sessionManager := mockNewSessionManager(l, logging.LevelInfo)
+		defer close(sessionManager.reloadConfig)
+		session, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress:            "10.2.2.254",
+				PeerPort:               179,
+				SourceAddress:          net.ParseIP("10.1.1.254"),
+				MyASN:                  100,
+				RouterID:               net.ParseIP("10.1.1.254"),
+				PeerASN:                200,
+				HoldTime:               ptr.To(time.Second),
+				KeepAliveTime:          ptr.To(time.Second),
+				CurrentNode:            "hostname",
+				DualStackAddressFamily: true,
+				ToReceive: &metallbconfig.Receive{
+					Prefixes: []metallbconfig.PrefixSelector{
+						{Prefix: &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}},
+						{Prefix: &net.IPNet{IP: net.ParseIP("192.168.0.0").To4(), Mask: net.CIDRMask(16, 32)}, GE: 24, LE: 28},
+					},
+				},
+				SessionName: "test-peer"})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		defer session.Close()
+
+		prefix1 := &net.IPNet{
+			IP:   net.ParseIP("172.16.1.10"),
+			Mask: classCMask,
+		}
+		prefix2 := &net.IPNet{
+			IP:   net.ParseIP("172.16.2.10"),
+			Mask: classCMask,
+		}
+		prefix3 := &net.IPNet{
+			IP:   net.ParseIP("2001:db8::10"),
+			Mask: net.CIDRMask(128, 128),
+		}
+
+		err = session.Set(&bgp.Advertisement{Prefix: prefix1}, &bgp.Advertisement{Prefix: prefix2}, &bgp.Advertisement{Prefix: prefix3})
+		if err != nil {
+			t.Fatalf("Could not advertise prefix: %s", err)
+		}
+
+		// All the prefixes are accepted from the second peer.
+		session2, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress:   "10.2.2.253",
+				PeerPort:      179,
+				SourceAddress: net.ParseIP("10.1.1.254"),
+				MyASN:         100,
+				RouterID:      net.ParseIP("10.1.1.254"),
+				PeerASN:       200,
+				HoldTime:      ptr.To(time.Second),
+				KeepAliveTime: ptr.To(time.Second),
+				CurrentNode:   "hostname",
+				ToReceive:     &metallbconfig.Receive{All: true},
+				SessionName:   "test-peer2"})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		defer session2.Close()
+		testCheckConfigFile(t)
+	})
+}
+
+func TestConditionalAdvertisement(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		testSetup(t)
+
+		l := log.NewNopLogger()
+		sessionManager := mockNewSessionManager(l, logging.LevelInfo)
+		defer close(sessionManager.reloadConfig)
+		primary, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress:   "10.2.2.253",
+				PeerPort:      179,
+				SourceAddress: net.ParseIP("10.1.1.254"),
+				MyASN:         100,
+				RouterID:      net.ParseIP("10.1.1.254"),
+				PeerASN:       200,
+				CurrentNode:   "hostname",
+				ToReceive:     &metallbconfig.Receive{All: true},
+				SessionName:   "primary"})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		defer primary.Close()
+
+		backup, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress:            "10.2.2.254",
+				PeerPort:               179,
+				SourceAddress:          net.ParseIP("10.1.1.254"),
+				MyASN:                  100,
+				RouterID:               net.ParseIP("10.1.1.254"),
+				PeerASN:                200,
+				CurrentNode:            "hostname",
+				DualStackAddressFamily: true,
+				SessionName:            "backup"})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		defer backup.Close()
+
+		prefix1 := &net.IPNet{
+			IP:   net.ParseIP("172.16.1.10"),
+			Mask: net.CIDRMask(32, 32),
+		}
+		prefix2 := &net.IPNet{
+			IP:   net.ParseIP("172.16.1.11"),
+			Mask: net.CIDRMask(32, 32),
+		}
+		prefix3 := &net.IPNet{
+			IP:   net.ParseIP("2001:db8::10"),
+			Mask: net.CIDRMask(128, 128),
+		}
+		// The backup peer gets the prefixes only while the primary one
+		// does not send its default route.
+		whenPrimaryDown := &bgp.Condition{
+			NonExist: true,
+			Prefixes: []string{"0.0.0.0/0"},
+			Peers:    []string{"10.2.2.253"},
+		}
+		err = primary.Set(&bgp.Advertisement{Prefix: prefix1}, &bgp.Advertisement{Prefix: prefix2})
+		if err != nil {
+			t.Fatalf("Could not advertise prefix: %s", err)
+		}
+		err = backup.Set(
+			&bgp.Advertisement{Prefix: prefix1, Condition: whenPrimaryDown},
+			&bgp.Advertisement{Prefix: prefix3, Condition: whenPrimaryDown},
+			// Announced without a condition too, so it is always announced.
+			&bgp.Advertisement{Prefix: prefix2, Condition: whenPrimaryDown},
+			&bgp.Advertisement{Prefix: prefix2},
+		)
+		if err != nil {
+			t.Fatalf("Could not advertise prefix: %s", err)
+		}
+
+		testCheckConfigFile(t)
+
+		err = backup.Set(
+			&bgp.Advertisement{Prefix: prefix1, Condition: whenPrimaryDown},
+			&bgp.Advertisement{Prefix: prefix2, Condition: &bgp.Condition{Peers: []string{"10.2.2.253"}}},
+		)
+		if err == nil {
+			t.Fatalf("Expected error for conflicting conditions")
+		}
+	})
+}
+
+func TestLabeledUnicast(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		testSetup(t)
+
+		l := log.NewNopLogger()
+		sessionManager := mockNewSessionManager(l, logging.LevelInfo)
+		defer close(sessionManager.reloadConfig)
+		session, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress:            "10.2.2.254",
+				PeerPort:               179,
+				SourceAddress:          net.ParseIP("10.1.1.254"),
+				MyASN:                  100,
+				RouterID:               net.ParseIP("10.1.1.254"),
+				PeerASN:                200,
+				CurrentNode:            "hostname",
+				DualStackAddressFamily: true,
+				SessionName:            "test-peer"})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		defer session.Close()
+
+		prefix1 := &net.IPNet{
+			IP:   net.ParseIP("172.16.1.10"),
+			Mask: net.CIDRMask(32, 32),
+		}
+		prefix2 := &net.IPNet{
+			IP:   net.ParseIP("172.16.1.11"),
+			Mask: net.CIDRMask(32, 32),
+		}
+		prefix3 := &net.IPNet{
+			IP:   net.ParseIP("2001:db8::10"),
+			Mask: net.CIDRMask(128, 128),
+		}
+		prefix4 := &net.IPNet{
+			IP:   net.ParseIP("172.16.2.10"),
+			Mask: net.CIDRMask(32, 32),
+		}
+		// The labeled prefixes keep the attributes set by the out
+		// route-map, the others are announced over unicast.
+		err = session.Set(
+			&bgp.Advertisement{Prefix: prefix1, LocalPref: 150, LabeledUnicast: &bgp.LabeledUnicast{LabelIndex: ptr.To(uint32(100))}},
+			&bgp.Advertisement{Prefix: prefix2, LabeledUnicast: &bgp.LabeledUnicast{LabelIndex: ptr.To(uint32(0))}},
+			&bgp.Advertisement{Prefix: prefix3, LabeledUnicast: &bgp.LabeledUnicast{}},
+			&bgp.Advertisement{Prefix: prefix4},
+		)
+		if err != nil {
+			t.Fatalf("Could not advertise prefix: %s", err)
+		}
+
+		testCheckConfigFile(t)
+	})
+}
+
+func TestPeerGroup(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		testSetup(t)
+
+		l := log.NewNopLogger()
+		sessionManager := mockNewSessionManager(l, logging.LevelInfo)
+		defer close(sessionManager.reloadConfig)
+		// The members share all the settings of the peer-group but the
+		// hold time, which one of them overrides.
+		for addr, holdTime := range map[string]time.Duration{"10.2.2.253": 90 * time.Second, "10.2.2.254": 180 * time.Second} {
+			session, err := sessionManager.NewSession(l,
+				bgp.SessionParameters{
+					PeerAddress:     addr,
+					PeerPort:        179,
+					SourceAddress:   net.ParseIP("10.1.1.254"),
+					MyASN:           100,
+					RouterID:        net.ParseIP("10.1.1.254"),
+					PeerASN:         200,
+					HoldTime:        ptr.To(holdTime),
+					KeepAliveTime:   ptr.To(30 * time.Second),
+					Password:        "password",
+					BFDProfile:      "default",
+					CurrentNode:     "hostname",
+					GracefulRestart: true,
+					EBGPMultiHop:    true,
+					PeerGroup:       "tor",
+					SessionName:     "peer-" + addr})
+			if err != nil {
+				t.Fatalf("Could not create session: %s", err)
+			}
+			defer session.Close()
+		}
+
+		// Not a member of the peer-group.
+		session, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress:   "10.2.2.252",
+				PeerPort:      179,
+				SourceAddress: net.ParseIP("10.1.1.254"),
+				MyASN:         100,
+				RouterID:      net.ParseIP("10.1.1.254"),
+				PeerASN:       300,
+				CurrentNode:   "hostname",
+				SessionName:   "other-peer"})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		defer session.Close()
+
+		testCheckConfigFile(t)
+	})
+}
+
 func TestManyAdvertisementsSameCommunity(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		testSetup(t)
@@ -1212,6 +1872,31 @@ func TestManyAdvertisementsSameCommunity(t *testing.T) {
 	})
 }
 
+func TestLinkLocalPeer(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		testSetup(t)
+
+		l := log.NewNopLogger()
+		sessionManager := mockNewSessionManager(l, logging.LevelInfo)
+		defer close(sessionManager.reloadConfig)
+		session, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress: "fe80::1",
+				PeerZone:    "eth0",
+				PeerPort:    179,
+				MyASN:       100,
+				PeerASN:     200,
+				SessionName: "test-peer"})
+
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		defer session.Close()
+
+		testCheckConfigFile(t)
+	})
+}
+
 func TestSingleSessionWithNoTimers(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		testSetup(t)
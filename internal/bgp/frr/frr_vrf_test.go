@@ -11,6 +11,7 @@ import (
 	"github.com/go-kit/log"
 	"go.universe.tf/metallb/internal/bgp"
 	"go.universe.tf/metallb/internal/bgp/community"
+	metallbconfig "go.universe.tf/metallb/internal/config"
 	"go.universe.tf/metallb/internal/logging"
 	"k8s.io/utils/ptr"
 )
@@ -475,3 +476,113 @@ func TestTwoAdvertisementsTwoSessionsOneVRF(t *testing.T) {
 		testCheckConfigFile(t)
 	})
 }
+
+func TestVRFImport(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		testSetup(t)
+
+		l := log.NewNopLogger()
+		sessionManager := mockNewSessionManager(l, logging.LevelInfo)
+		defer close(sessionManager.reloadConfig)
+
+		// The prefixes announced in the default VRF are leaked into red,
+		// which has no peers, and the ones announced in blue into the
+		// default VRF.
+		session, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress:            "10.2.2.254",
+				PeerPort:               179,
+				SourceAddress:          net.ParseIP("10.1.1.254"),
+				MyASN:                  100,
+				RouterID:               net.ParseIP("10.1.1.254"),
+				PeerASN:                200,
+				CurrentNode:            "hostname",
+				DualStackAddressFamily: true,
+				SessionName:            "test-peer"})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		defer session.Close()
+
+		vrfSession, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress:   "10.2.2.253",
+				PeerPort:      179,
+				SourceAddress: net.ParseIP("10.1.1.254"),
+				MyASN:         100,
+				RouterID:      net.ParseIP("10.1.1.254"),
+				PeerASN:       200,
+				CurrentNode:   "hostname",
+				VRFName:       "blue",
+				SessionName:   "test-peer-vrf"})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		defer vrfSession.Close()
+
+		_, prefix1, _ := net.ParseCIDR("172.16.1.10/32")
+		_, prefix2, _ := net.ParseCIDR("2001:db8::10/128")
+		_, prefix3, _ := net.ParseCIDR("172.16.2.10/32")
+		err = session.Set(
+			&bgp.Advertisement{Prefix: prefix1, ImportVRFs: []string{"red"}},
+			&bgp.Advertisement{Prefix: prefix2, ImportVRFs: []string{"red", "blue"}},
+		)
+		if err != nil {
+			t.Fatalf("Could not advertise prefix: %s", err)
+		}
+		err = vrfSession.Set(&bgp.Advertisement{Prefix: prefix3, ImportVRFs: []string{""}})
+		if err != nil {
+			t.Fatalf("Could not advertise prefix: %s", err)
+		}
+
+		testCheckConfigFile(t)
+	})
+}
+
+func TestEVPN(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		testSetup(t)
+
+		l := log.NewNopLogger()
+		sessionManager := mockNewSessionManager(l, logging.LevelInfo)
+		defer close(sessionManager.reloadConfig)
+
+		// The prefixes are exported from red, which has no peers, to the
+		// peer of the default VRF, which gets the unicast ones too.
+		session, err := sessionManager.NewSession(l,
+			bgp.SessionParameters{
+				PeerAddress:   "10.2.2.254",
+				PeerPort:      179,
+				SourceAddress: net.ParseIP("10.1.1.254"),
+				MyASN:         100,
+				RouterID:      net.ParseIP("10.1.1.254"),
+				PeerASN:       200,
+				CurrentNode:   "hostname",
+				SessionName:   "test-peer"})
+		if err != nil {
+			t.Fatalf("Could not create session: %s", err)
+		}
+		defer session.Close()
+
+		evpn := &metallbconfig.EVPN{
+			VRF:                "red",
+			VNI:                100,
+			RouteDistinguisher: "10.1.1.254:100",
+			ImportRouteTargets: []string{"100:100"},
+			ExportRouteTargets: []string{"100:100", "100:200"},
+		}
+		_, prefix1, _ := net.ParseCIDR("172.16.1.10/32")
+		_, prefix2, _ := net.ParseCIDR("2001:db8::10/128")
+		_, prefix3, _ := net.ParseCIDR("172.16.2.10/32")
+		err = session.Set(
+			&bgp.Advertisement{Prefix: prefix1, EVPN: evpn},
+			&bgp.Advertisement{Prefix: prefix2, EVPN: evpn},
+			&bgp.Advertisement{Prefix: prefix3},
+		)
+		if err != nil {
+			t.Fatalf("Could not advertise prefix: %s", err)
+		}
+
+		testCheckConfigFile(t)
+	})
+}
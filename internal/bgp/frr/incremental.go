@@ -0,0 +1,312 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package frr
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// incrementalApplier applies the changes between the last applied
+// configuration and the new one as vty commands, instead of rewriting
+// the configuration file and reloading FRR. The configuration file is
+// still kept up to date, so that FRR restarts with the same configuration.
+// It falls back to the reload when there is nothing to compare with, when
+// the changes can't be applied as commands, or when applying them fails.
+type incrementalApplier struct {
+	configure vtyConfigurer
+	reload    func(config *frrConfig, l log.Logger) error
+	logger    log.Logger
+	// Called with the rendered configuration when the changes are applied,
+	// as there is no reload to report the outcome of.
+	onApplied func(rendered string)
+
+	// The last configuration applied, nil if unknown.
+	applied  *frrConfig
+	rendered string
+}
+
+func newIncrementalApplier(l log.Logger, configure vtyConfigurer) *incrementalApplier {
+	return &incrementalApplier{
+		configure: configure,
+		reload:    generateAndReloadConfigFile,
+		logger:    l,
+	}
+}
+
+func (a *incrementalApplier) apply(config *frrConfig) error {
+	rendered, err := templateConfig(config)
+	if err != nil {
+		level.Error(a.logger).Log("op", "reload", "error", err, "cause", "template", "config", config)
+		return err
+	}
+	if !a.canApplyIncrementally(config) {
+		return a.fullReload(config, rendered)
+	}
+
+	commands := diffConfig(parseConfig(a.rendered), parseConfig(rendered))
+	if err := a.run(commands); err != nil {
+		level.Warn(a.logger).Log("op", "reload", "error", err, "msg", "failed to apply the configuration changes, reloading the configuration file")
+		return a.fullReload(config, rendered)
+	}
+	a.applied, a.rendered = config, rendered
+
+	err = writeConfig(rendered, configFile())
+	if err != nil {
+		level.Error(a.logger).Log("op", "reload", "error", err, "cause", "writeConfig", "config", config)
+		return err
+	}
+	level.Debug(a.logger).Log("op", "reload", "commands", len(commands), "msg", "configuration changes applied")
+	if a.onApplied != nil {
+		a.onApplied(rendered)
+	}
+	return nil
+}
+
+// canApplyIncrementally tells if the changes from the last applied
+// configuration can be applied as commands. Applying the same configuration
+// again is a retry after a failure, which requires a reload.
+func (a *incrementalApplier) canApplyIncrementally(config *frrConfig) bool {
+	if a.applied == nil || reflect.DeepEqual(a.applied, config) {
+		return false
+	}
+	// The global settings and the extra configuration may touch any
+	// daemon, only the BGP and BFD ones are applied as commands.
+	return a.applied.Loglevel == config.Loglevel &&
+		a.applied.Hostname == config.Hostname &&
+		a.applied.ExtraConfig == config.ExtraConfig
+}
+
+func (a *incrementalApplier) fullReload(config *frrConfig, rendered string) error {
+	a.applied = nil
+	if err := a.reload(config, a.logger); err != nil {
+		return err
+	}
+	a.applied, a.rendered = config, rendered
+	return nil
+}
+
+// run sends the commands to the daemons owning them, the BFD and the VRF
+// ones first so that the profiles and the VNIs exist when the BGP
+// configuration references them.
+func (a *incrementalApplier) run(commands []configCommand) error {
+	byDaemon := map[string][]configCommand{}
+	for _, c := range commands {
+		daemon := "bgpd"
+		switch {
+		case (len(c.path) > 0 && c.path[0] == "bfd") || c.command == "bfd" || c.command == "no bfd":
+			daemon = "bfdd"
+		case (len(c.path) > 0 && strings.HasPrefix(c.path[0], "vrf ")) || strings.HasPrefix(strings.TrimPrefix(c.command, "no "), "vrf "):
+			daemon = "zebra"
+		}
+		byDaemon[daemon] = append(byDaemon[daemon], c)
+	}
+	for _, daemon := range []string{"bfdd", "zebra", "bgpd"} {
+		if len(byDaemon[daemon]) == 0 {
+			continue
+		}
+		if err := a.configure(daemon, vtyScript(byDaemon[daemon])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configNode is a line of the configuration with the lines nested in it.
+type configNode struct {
+	line     string
+	children []*configNode
+	byLine   map[string]*configNode
+}
+
+func newConfigNode(line string) *configNode {
+	return &configNode{line: line, byLine: map[string]*configNode{}}
+}
+
+// child returns the nested node with the given line, adding it if missing.
+func (n *configNode) child(line string) *configNode {
+	if c, ok := n.byLine[line]; ok {
+		return c
+	}
+	c := newConfigNode(line)
+	n.children = append(n.children, c)
+	n.byLine[line] = c
+	return c
+}
+
+// parseConfig parses a rendered configuration into a tree, nesting the
+// lines by their indentation. A node appearing more than once, as the
+// address families of a router, is merged into one.
+func parseConfig(config string) *configNode {
+	type level struct {
+		indent int
+		node   *configNode
+	}
+	root := newConfigNode("")
+	stack := []level{{indent: -1, node: root}}
+	for _, l := range strings.Split(config, "\n") {
+		line := strings.TrimSpace(l)
+		if line == "" || line == "!" || strings.HasPrefix(line, "exit") {
+			continue
+		}
+		indent := len(l) - len(strings.TrimLeft(l, " "))
+		for stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		n := stack[len(stack)-1].node.child(line)
+		stack = append(stack, level{indent: indent, node: n})
+	}
+	return root
+}
+
+// configCommand is a command to run in the node entered by the given path.
+type configCommand struct {
+	path    []string
+	command string
+	// Set when the command enters a node.
+	enters bool
+}
+
+// diffConfig returns the commands turning the old configuration into the
+// new one. The lines missing from the new one are removed first, starting
+// from the most nested ones, then the new lines are added starting from the
+// outermost ones.
+func diffConfig(old, new *configNode) []configCommand {
+	var removals, additions []configCommand
+	diffRemovals(nil, old, new, &removals)
+	diffAdditions(nil, old, new, &additions)
+	return append(removals, additions...)
+}
+
+func diffRemovals(path []string, old, new *configNode, res *[]configCommand) {
+	for _, o := range old.children {
+		n, ok := new.byLine[o.line]
+		switch {
+		case ok:
+			diffRemovals(childPath(path, o.line), o, n, res)
+		case strings.HasPrefix(o.line, "address-family "):
+			// The address families can't be removed, only their lines.
+			diffRemovals(childPath(path, o.line), o, newConfigNode(o.line), res)
+		}
+	}
+
+	// Removing a neighbor removes all its lines.
+	removedNeighbors := map[string]bool{}
+	for _, o := range old.children {
+		if _, ok := new.byLine[o.line]; ok {
+			continue
+		}
+		if neighbor, ok := declaredNeighbor(o.line); ok && !declaresNeighbor(new, neighbor) {
+			removedNeighbors[neighbor] = true
+			*res = append(*res, configCommand{path: path, command: "no neighbor " + neighbor})
+		}
+	}
+	for _, o := range old.children {
+		if _, ok := new.byLine[o.line]; ok || strings.HasPrefix(o.line, "address-family ") {
+			continue
+		}
+		if fields := strings.Fields(o.line); len(fields) > 1 && fields[0] == "neighbor" && removedNeighbors[fields[1]] {
+			continue
+		}
+		if neighbor, ok := declaredNeighbor(o.line); ok && declaresNeighbor(new, neighbor) {
+			// Overridden by the new declaration.
+			continue
+		}
+		*res = append(*res, configCommand{path: path, command: negate(o.line)})
+	}
+}
+
+func diffAdditions(path []string, old, new *configNode, res *[]configCommand) {
+	for _, n := range new.children {
+		if _, ok := old.byLine[n.line]; ok {
+			continue
+		}
+		*res = append(*res, configCommand{path: path, command: n.line, enters: len(n.children) > 0 || isNodeHeader(n.line)})
+	}
+	for _, n := range new.children {
+		o, ok := old.byLine[n.line]
+		if !ok {
+			o = newConfigNode(n.line)
+		}
+		diffAdditions(childPath(path, n.line), o, n, res)
+	}
+}
+
+// declaredNeighbor returns the neighbor declared by the line, as in
+// "neighbor 192.168.1.1 remote-as 64512", "neighbor tor peer-group" or
+// "neighbor eth1 interface peer-group tor" for an unnumbered member.
+func declaredNeighbor(line string) (string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != "neighbor" {
+		return "", false
+	}
+	if fields[2] == "remote-as" {
+		return fields[1], true
+	}
+	if fields[2] == "interface" && len(fields) > 3 && (fields[3] == "remote-as" || fields[3] == "peer-group") {
+		return fields[1], true
+	}
+	if fields[2] == "peer-group" && len(fields) == 3 {
+		return fields[1], true
+	}
+	return "", false
+}
+
+func declaresNeighbor(n *configNode, neighbor string) bool {
+	for _, c := range n.children {
+		if d, ok := declaredNeighbor(c.line); ok && d == neighbor {
+			return true
+		}
+	}
+	return false
+}
+
+func isNodeHeader(line string) bool {
+	for _, prefix := range []string{"router ", "address-family ", "route-map ", "profile ", "vrf "} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return line == "bfd"
+}
+
+func negate(line string) string {
+	if positive, ok := strings.CutPrefix(line, "no "); ok {
+		return positive
+	}
+	return "no " + line
+}
+
+func childPath(path []string, line string) []string {
+	return append(slices.Clone(path), line)
+}
+
+// vtyScript returns the vty commands running the given configuration
+// commands, entering the node of each one.
+func vtyScript(commands []configCommand) []string {
+	res := []string{"configure terminal"}
+	var current []string
+	for _, c := range commands {
+		switch {
+		case slices.Equal(current, c.path):
+		case len(current) < len(c.path) && slices.Equal(current, c.path[:len(current)]):
+			// Nested in the current node, enter the remaining ones.
+			res = append(res, c.path[len(current):]...)
+		default:
+			if len(current) > 0 {
+				res = append(res, "end", "configure terminal")
+			}
+			res = append(res, c.path...)
+		}
+		current = c.path
+		res = append(res, c.command)
+		if c.enters {
+			current = childPath(c.path, c.command)
+		}
+	}
+	return append(res, "end")
+}
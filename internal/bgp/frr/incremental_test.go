@@ -0,0 +1,375 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package frr
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/google/go-cmp/cmp"
+	"go.universe.tf/metallb/internal/ipfamily"
+)
+
+// fakeVtysh records the commands sent to each daemon.
+type fakeVtysh struct {
+	commands map[string][]string
+	fail     bool
+}
+
+func (f *fakeVtysh) configure(daemon string, commands []string) error {
+	if f.fail {
+		return errors.New("failed to run command")
+	}
+	if f.commands == nil {
+		f.commands = map[string][]string{}
+	}
+	f.commands[daemon] = append(f.commands[daemon], commands...)
+	return nil
+}
+
+const incrementalBaseConfig = `router bgp 64512
+  no bgp default ipv4-unicast
+  bgp router-id 10.1.1.254
+  neighbor 10.2.2.254 remote-as 64513
+  neighbor 10.2.2.254 port 179
+  neighbor 10.2.2.254 timers 2 1
+
+  address-family ipv4 unicast
+    neighbor 10.2.2.254 activate
+    neighbor 10.2.2.254 route-map 10.2.2.254-out out
+  exit-address-family
+
+route-map 10.2.2.254-out permit 1
+  match ip address prefix-list 10.2.2.254-allowed-ipv4
+
+ip prefix-list 10.2.2.254-allowed-ipv4 seq 1 permit 192.168.10.0/32
+`
+
+func TestIncrementalDiff(t *testing.T) {
+	tests := []struct {
+		desc     string
+		old      string
+		new      string
+		expected map[string][]string
+	}{
+		{
+			desc:     "no changes",
+			old:      incrementalBaseConfig,
+			new:      incrementalBaseConfig,
+			expected: nil,
+		},
+		{
+			desc: "prefix added",
+			old:  incrementalBaseConfig,
+			new:  incrementalBaseConfig + "ip prefix-list 10.2.2.254-allowed-ipv4 seq 2 permit 192.168.10.1/32\n",
+			expected: map[string][]string{
+				"bgpd": {
+					"configure terminal",
+					"ip prefix-list 10.2.2.254-allowed-ipv4 seq 2 permit 192.168.10.1/32",
+					"end",
+				},
+			},
+		},
+		{
+			desc: "prefix removed",
+			old:  incrementalBaseConfig,
+			new:  strings.Replace(incrementalBaseConfig, "ip prefix-list 10.2.2.254-allowed-ipv4 seq 1 permit 192.168.10.0/32", "ip prefix-list 10.2.2.254-allowed-ipv4 seq 1 deny any", 1),
+			expected: map[string][]string{
+				"bgpd": {
+					"configure terminal",
+					"no ip prefix-list 10.2.2.254-allowed-ipv4 seq 1 permit 192.168.10.0/32",
+					"ip prefix-list 10.2.2.254-allowed-ipv4 seq 1 deny any",
+					"end",
+				},
+			},
+		},
+		{
+			desc: "timers changed",
+			old:  incrementalBaseConfig,
+			new:  strings.Replace(incrementalBaseConfig, "timers 2 1", "timers 30 90", 1),
+			expected: map[string][]string{
+				"bgpd": {
+					"configure terminal",
+					"router bgp 64512",
+					"no neighbor 10.2.2.254 timers 2 1",
+					"neighbor 10.2.2.254 timers 30 90",
+					"end",
+				},
+			},
+		},
+		{
+			desc: "remote asn changed",
+			old:  incrementalBaseConfig,
+			new:  strings.Replace(incrementalBaseConfig, "remote-as 64513", "remote-as 64514", 1),
+			expected: map[string][]string{
+				"bgpd": {
+					"configure terminal",
+					"router bgp 64512",
+					"neighbor 10.2.2.254 remote-as 64514",
+					"end",
+				},
+			},
+		},
+		{
+			desc: "neighbor added",
+			old:  incrementalBaseConfig,
+			new: strings.NewReplacer(
+				"  neighbor 10.2.2.254 timers 2 1\n", "  neighbor 10.2.2.254 timers 2 1\n  neighbor 10.2.2.255 remote-as 64513\n",
+				"    neighbor 10.2.2.254 activate\n", "    neighbor 10.2.2.254 activate\n    neighbor 10.2.2.255 activate\n",
+			).Replace(incrementalBaseConfig),
+			expected: map[string][]string{
+				"bgpd": {
+					"configure terminal",
+					"router bgp 64512",
+					"neighbor 10.2.2.255 remote-as 64513",
+					"address-family ipv4 unicast",
+					"neighbor 10.2.2.255 activate",
+					"end",
+				},
+			},
+		},
+		{
+			desc: "neighbor removed",
+			old:  incrementalBaseConfig,
+			new: `router bgp 64512
+  no bgp default ipv4-unicast
+  bgp router-id 10.1.1.254
+
+  address-family ipv4 unicast
+  exit-address-family
+`,
+			expected: map[string][]string{
+				"bgpd": {
+					"configure terminal",
+					"router bgp 64512",
+					"address-family ipv4 unicast",
+					"no neighbor 10.2.2.254 activate",
+					"no neighbor 10.2.2.254 route-map 10.2.2.254-out out",
+					"end",
+					"configure terminal",
+					"router bgp 64512",
+					"no neighbor 10.2.2.254",
+					"end",
+					"configure terminal",
+					"no route-map 10.2.2.254-out permit 1",
+					"no ip prefix-list 10.2.2.254-allowed-ipv4 seq 1 permit 192.168.10.0/32",
+					"end",
+				},
+			},
+		},
+		{
+			desc: "bfd profile added",
+			old:  incrementalBaseConfig,
+			new: strings.Replace(incrementalBaseConfig, "  neighbor 10.2.2.254 timers 2 1\n", "  neighbor 10.2.2.254 timers 2 1\n  neighbor 10.2.2.254 bfd profile foo\n", 1) + `
+bfd
+  profile foo
+    receive-interval 60
+    echo-mode
+`,
+			expected: map[string][]string{
+				"bfdd": {
+					"configure terminal",
+					"bfd",
+					"profile foo",
+					"receive-interval 60",
+					"echo-mode",
+					"end",
+				},
+				"bgpd": {
+					"configure terminal",
+					"router bgp 64512",
+					"neighbor 10.2.2.254 bfd profile foo",
+					"end",
+				},
+			},
+		},
+		{
+			desc: "evpn vni added",
+			old:  incrementalBaseConfig,
+			new: incrementalBaseConfig + `
+vrf red
+  vni 100
+exit-vrf
+router bgp 64512 vrf red
+  address-family l2vpn evpn
+    advertise ipv4 unicast
+  exit-address-family
+`,
+			expected: map[string][]string{
+				"zebra": {
+					"configure terminal",
+					"vrf red",
+					"vni 100",
+					"end",
+				},
+				"bgpd": {
+					"configure terminal",
+					"router bgp 64512 vrf red",
+					"address-family l2vpn evpn",
+					"advertise ipv4 unicast",
+					"end",
+				},
+			},
+		},
+		{
+			desc: "bfd profile changed",
+			old: `bfd
+  profile foo
+    receive-interval 60
+    echo-mode
+`,
+			new: `bfd
+  profile foo
+    receive-interval 70
+`,
+			expected: map[string][]string{
+				"bfdd": {
+					"configure terminal",
+					"bfd",
+					"profile foo",
+					"no receive-interval 60",
+					"no echo-mode",
+					"receive-interval 70",
+					"end",
+				},
+			},
+		},
+		{
+			desc: "negated line removed",
+			old:  incrementalBaseConfig,
+			new:  strings.Replace(incrementalBaseConfig, "  no bgp default ipv4-unicast\n", "", 1),
+			expected: map[string][]string{
+				"bgpd": {
+					"configure terminal",
+					"router bgp 64512",
+					"bgp default ipv4-unicast",
+					"end",
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			vtysh := &fakeVtysh{}
+			a := &incrementalApplier{configure: vtysh.configure}
+			err := a.run(diffConfig(parseConfig(test.old), parseConfig(test.new)))
+			if err != nil {
+				t.Fatalf("unexpected error %s", err)
+			}
+			if !cmp.Equal(test.expected, vtysh.commands) {
+				t.Fatalf("unexpected commands: %s", cmp.Diff(test.expected, vtysh.commands))
+			}
+		})
+	}
+}
+
+func TestIncrementalApplier(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "frr.conf")
+	t.Setenv("FRR_CONFIG_FILE", configFile)
+
+	config := func(hostname string, neighbors ...string) *frrConfig {
+		router := &routerConfig{MyASN: 64512, RouterID: "10.1.1.254"}
+		for _, n := range neighbors {
+			router.Neighbors = append(router.Neighbors, &neighborConfig{
+				IPFamily: ipfamily.IPv4,
+				Name:     "64513@" + n,
+				ASN:      "64513",
+				Addr:     n,
+				Port:     179,
+			})
+		}
+		return &frrConfig{Hostname: hostname, Routers: []*routerConfig{router}}
+	}
+
+	vtysh := &fakeVtysh{}
+	reloads := 0
+	var applied string
+	a := &incrementalApplier{
+		configure: vtysh.configure,
+		reload: func(config *frrConfig, _ log.Logger) error {
+			reloads++
+			return nil
+		},
+		logger:    log.NewNopLogger(),
+		onApplied: func(rendered string) { applied = rendered },
+	}
+
+	steps := []struct {
+		desc            string
+		config          *frrConfig
+		failVtysh       bool
+		expectedReloads int
+		expectCommands  bool
+	}{
+		{
+			desc:            "first configuration",
+			config:          config("host", "10.2.2.254"),
+			expectedReloads: 1,
+		},
+		{
+			desc:            "neighbor added",
+			config:          config("host", "10.2.2.254", "10.2.2.255"),
+			expectedReloads: 1,
+			expectCommands:  true,
+		},
+		{
+			desc:            "same configuration",
+			config:          config("host", "10.2.2.254", "10.2.2.255"),
+			expectedReloads: 2,
+		},
+		{
+			desc:            "hostname changed",
+			config:          config("otherhost", "10.2.2.254", "10.2.2.255"),
+			expectedReloads: 3,
+		},
+		{
+			desc:            "vtysh fails",
+			config:          config("otherhost", "10.2.2.254"),
+			failVtysh:       true,
+			expectedReloads: 4,
+		},
+		{
+			desc:            "neighbor removed",
+			config:          config("otherhost"),
+			expectedReloads: 4,
+			expectCommands:  true,
+		},
+	}
+
+	for _, s := range steps {
+		vtysh.commands = nil
+		applied = ""
+		vtysh.fail = s.failVtysh
+		if err := a.apply(s.config); err != nil {
+			t.Fatalf("%s: unexpected error %s", s.desc, err)
+		}
+		if reloads != s.expectedReloads {
+			t.Fatalf("%s: expected %d reloads, got %d", s.desc, s.expectedReloads, reloads)
+		}
+		if s.expectCommands != (len(vtysh.commands["bgpd"]) > 0) {
+			t.Fatalf("%s: unexpected commands %v", s.desc, vtysh.commands)
+		}
+		if !s.expectCommands {
+			continue
+		}
+		rendered, err := templateConfig(s.config)
+		if err != nil {
+			t.Fatalf("%s: failed to render the config %s", s.desc, err)
+		}
+		written, err := os.ReadFile(configFile)
+		if err != nil {
+			t.Fatalf("%s: failed to read the config file %s", s.desc, err)
+		}
+		if string(written) != rendered {
+			t.Fatalf("%s: the config file was not updated: %s", s.desc, cmp.Diff(rendered, string(written)))
+		}
+		if applied != rendered {
+			t.Fatalf("%s: the applied configuration was not reported", s.desc)
+		}
+	}
+}
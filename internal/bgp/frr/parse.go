@@ -8,20 +8,33 @@ import (
 	"net"
 	"sort"
 	"strconv"
+	"time"
 
 	"errors"
 )
 
 type Neighbor struct {
-	IP             net.IP
-	VRF            string
-	Connected      bool
-	LocalAS        string
-	RemoteAS       string
-	PrefixSent     int
-	Port           int
-	RemoteRouterID string
-	MsgStats       MessageStats
+	IP net.IP
+	// Interface is set instead of IP for unnumbered neighbors.
+	Interface       string
+	VRF             string
+	Connected       bool
+	State           string
+	UpSince         time.Time
+	LocalAS         string
+	RemoteAS        string
+	PrefixSent      int
+	PrefixReceived  int
+	Port            int
+	RemoteRouterID  string
+	LastResetReason string
+	MsgStats        MessageStats
+	// MaxPrefixes is the number of prefixes accepted from the neighbor in
+	// each address family before FRR acts on it, zero if not set.
+	MaxPrefixes int
+	// MaxPrefixesExceeded is set when the neighbor sent more than
+	// MaxPrefixes prefixes in any address family.
+	MaxPrefixesExceeded bool
 }
 
 type Route struct {
@@ -34,16 +47,20 @@ type Route struct {
 const bgpConnected = "Established"
 
 type FRRNeighbor struct {
-	RemoteAs          int          `json:"remoteAs"`
-	LocalAs           int          `json:"localAs"`
-	RemoteRouterID    string       `json:"remoteRouterId"`
-	BgpVersion        int          `json:"bgpVersion"`
-	BgpState          string       `json:"bgpState"`
-	PortForeign       int          `json:"portForeign"`
-	MsgStats          MessageStats `json:"messageStats"`
-	VRFName           string       `json:"vrf"`
-	AddressFamilyInfo map[string]struct {
-		SentPrefixCounter int `json:"sentPrefixCounter"`
+	RemoteAs                   int          `json:"remoteAs"`
+	LocalAs                    int          `json:"localAs"`
+	RemoteRouterID             string       `json:"remoteRouterId"`
+	BgpVersion                 int          `json:"bgpVersion"`
+	BgpState                   string       `json:"bgpState"`
+	BgpTimerUpEstablishedEpoch int64        `json:"bgpTimerUpEstablishedEpoch"`
+	PortForeign                int          `json:"portForeign"`
+	MsgStats                   MessageStats `json:"messageStats"`
+	VRFName                    string       `json:"vrf"`
+	LastResetDueTo             string       `json:"lastResetDueTo"`
+	AddressFamilyInfo          map[string]struct {
+		SentPrefixCounter     int `json:"sentPrefixCounter"`
+		AcceptedPrefixCounter int `json:"acceptedPrefixCounter"`
+		PrefixAllowedMax      int `json:"prefixAllowedMax"`
 	} `json:"addressFamilyInfo"`
 }
 
@@ -119,24 +136,9 @@ func ParseNeighbour(vtyshRes string) (*Neighbor, error) {
 		if ip == nil {
 			return nil, fmt.Errorf("failed to parse %s as ip", ip)
 		}
-		connected := true
-		if n.BgpState != bgpConnected {
-			connected = false
-		}
-		prefixSent := 0
-		for _, s := range n.AddressFamilyInfo {
-			prefixSent += s.SentPrefixCounter
-		}
-		return &Neighbor{
-			IP:             ip,
-			Connected:      connected,
-			LocalAS:        strconv.Itoa(n.LocalAs),
-			RemoteAS:       strconv.Itoa(n.RemoteAs),
-			PrefixSent:     prefixSent,
-			Port:           n.PortForeign,
-			RemoteRouterID: n.RemoteRouterID,
-			MsgStats:       n.MsgStats,
-		}, nil
+		res := neighborFromFRR(n)
+		res.IP = ip
+		return res, nil
 	}
 	return nil, errors.New("no peers were returned")
 }
@@ -152,30 +154,50 @@ func ParseNeighbours(vtyshRes string) ([]*Neighbor, error) {
 
 	res := make([]*Neighbor, 0)
 	for k, n := range toParse {
-		ip := net.ParseIP(k)
-		if ip == nil {
-			return nil, fmt.Errorf("failed to parse %s as ip", ip)
+		neighbor := neighborFromFRR(n)
+		// Unnumbered neighbors are keyed by their interface.
+		neighbor.IP = net.ParseIP(k)
+		if neighbor.IP == nil {
+			neighbor.Interface = k
 		}
-		connected := true
-		if n.BgpState != bgpConnected {
-			connected = false
+		res = append(res, neighbor)
+	}
+	return res, nil
+}
+
+func neighborFromFRR(n FRRNeighbor) *Neighbor {
+	prefixSent, prefixReceived := 0, 0
+	maxPrefixes, maxPrefixesExceeded := 0, false
+	for _, s := range n.AddressFamilyInfo {
+		prefixSent += s.SentPrefixCounter
+		prefixReceived += s.AcceptedPrefixCounter
+		if s.PrefixAllowedMax == 0 {
+			continue
 		}
-		prefixSent := 0
-		for _, s := range n.AddressFamilyInfo {
-			prefixSent += s.SentPrefixCounter
+		maxPrefixes = s.PrefixAllowedMax
+		if s.AcceptedPrefixCounter > s.PrefixAllowedMax {
+			maxPrefixesExceeded = true
 		}
-		res = append(res, &Neighbor{
-			IP:             ip,
-			Connected:      connected,
-			LocalAS:        strconv.Itoa(n.LocalAs),
-			RemoteAS:       strconv.Itoa(n.RemoteAs),
-			PrefixSent:     prefixSent,
-			Port:           n.PortForeign,
-			RemoteRouterID: n.RemoteRouterID,
-			MsgStats:       n.MsgStats,
-		})
 	}
-	return res, nil
+	res := &Neighbor{
+		Connected:       n.BgpState == bgpConnected,
+		State:           n.BgpState,
+		LocalAS:         strconv.Itoa(n.LocalAs),
+		RemoteAS:        strconv.Itoa(n.RemoteAs),
+		PrefixSent:      prefixSent,
+		PrefixReceived:  prefixReceived,
+		Port:            n.PortForeign,
+		RemoteRouterID:  n.RemoteRouterID,
+		LastResetReason: n.LastResetDueTo,
+		MsgStats:        n.MsgStats,
+
+		MaxPrefixes:         maxPrefixes,
+		MaxPrefixesExceeded: maxPrefixesExceeded,
+	}
+	if res.Connected && n.BgpTimerUpEstablishedEpoch != 0 {
+		res.UpSince = time.Unix(n.BgpTimerUpEstablishedEpoch, 0)
+	}
+	return res
 }
 
 // parseRoute takes the result of a show bgp neighbor
@@ -0,0 +1,25 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package frr
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var stats = metrics{
+	reloadFailures: prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "metallb",
+		Subsystem: "frr",
+		Name:      "reload_consecutive_failures",
+		Help:      "Number of consecutive failed reloads of the FRR configuration, 0 after a successful one",
+	}),
+}
+
+type metrics struct {
+	reloadFailures prometheus.Gauge
+}
+
+func init() {
+	crmetrics.Registry.MustRegister(stats.reloadFailures)
+}
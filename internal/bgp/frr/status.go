@@ -0,0 +1,233 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package frr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"go.universe.tf/metallb/internal/bgp"
+)
+
+var sessionStatePollInterval = 10 * time.Second
+
+// SessionStates returns the state of the BGP sessions, as last read from FRR.
+func (sm *sessionManager) SessionStates() []bgp.SessionState {
+	sm.stateMu.Lock()
+	defer sm.stateMu.Unlock()
+	return slices.Clone(sm.states)
+}
+
+// SetSessionStateCallback sets the function called whenever the state of
+// a session changes. The callback must not block.
+func (sm *sessionManager) SetSessionStateCallback(callback func()) {
+	sm.stateMu.Lock()
+	defer sm.stateMu.Unlock()
+	sm.stateCallback = callback
+}
+
+// ReloadStatus returns the outcome of the last reload of the FRR
+// configuration.
+func (sm *sessionManager) ReloadStatus() bgp.ReloadStatus {
+	sm.stateMu.Lock()
+	defer sm.stateMu.Unlock()
+	return sm.reloadStatus
+}
+
+// SetReloadStatusCallback sets the function called whenever a reload of
+// the FRR configuration completes. The callback must not block.
+func (sm *sessionManager) SetReloadStatusCallback(callback func()) {
+	sm.stateMu.Lock()
+	defer sm.stateMu.Unlock()
+	sm.reloadCallback = callback
+}
+
+func (sm *sessionManager) reloadSucceeded(t time.Time, hash string) {
+	sm.updateReloadStatus(func(s *bgp.ReloadStatus) {
+		s.Time = t
+		s.Failed = false
+		s.Error = ""
+		s.ConsecutiveFailures = 0
+		if hash != "" {
+			s.ConfigHash = hash
+		}
+	})
+}
+
+func (sm *sessionManager) reloadFailed(t time.Time, output string) {
+	sm.updateReloadStatus(func(s *bgp.ReloadStatus) {
+		s.Time = t
+		s.Failed = true
+		s.Error = output
+		s.ConsecutiveFailures++
+	})
+}
+
+func (sm *sessionManager) updateReloadStatus(update func(*bgp.ReloadStatus)) {
+	sm.stateMu.Lock()
+	update(&sm.reloadStatus)
+	failures := sm.reloadStatus.ConsecutiveFailures
+	callback := sm.reloadCallback
+	sm.stateMu.Unlock()
+
+	stats.reloadFailures.Set(float64(failures))
+	if callback != nil {
+		callback()
+	}
+}
+
+// configHash returns the hash of the rendered configuration, matching the
+// one reported by the reloader for the configuration file.
+func configHash(config string) string {
+	sum := sha256.Sum256([]byte(config))
+	return hex.EncodeToString(sum[:])
+}
+
+// sessionStatePoller periodically reads the state of the BGP sessions from
+// the FRR daemons.
+func sessionStatePoller(l log.Logger, sm *sessionManager, cli vtyCli) {
+	ticker := time.NewTicker(sessionStatePollInterval)
+	go func() {
+		for range ticker.C {
+			sm.updateSessionStates(l, cli)
+		}
+	}()
+}
+
+func (sm *sessionManager) updateSessionStates(l log.Logger, cli vtyCli) {
+	states, err := readSessionStates(cli)
+	if err != nil {
+		level.Debug(l).Log("op", "sessionStates", "error", err, "msg", "failed to read the BGP sessions state from FRR")
+		return
+	}
+
+	sm.stateMu.Lock()
+	changed := !reflect.DeepEqual(sm.states, states)
+	sm.states = states
+	callback := sm.stateCallback
+	sm.stateMu.Unlock()
+
+	if changed && callback != nil {
+		callback()
+	}
+}
+
+// readSessionStates builds the state of the BGP sessions of all the VRFs
+// from the neighbors and the BFD peers reported by FRR.
+func readSessionStates(cli vtyCli) ([]bgp.SessionState, error) {
+	res, err := cli("bgpd", "show bgp vrf all json")
+	if err != nil {
+		return nil, err
+	}
+	vrfs, err := ParseVRFs(res)
+	if err != nil {
+		return nil, err
+	}
+
+	// BFD is optional, the sessions are reported without it if bfdd
+	// can't be reached.
+	var bfdPeers []BFDPeer
+	if res, err := cli("bfdd", "show bfd peers json"); err == nil {
+		bfdPeers, _ = ParseBFDPeers(res)
+	}
+
+	states := []bgp.SessionState{}
+	for _, vrf := range vrfs {
+		res, err := cli("bgpd", fmt.Sprintf("show bgp vrf %s neighbors json", vrf))
+		if err != nil {
+			return nil, err
+		}
+		neighbors, err := ParseNeighbours(res)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range neighbors {
+			s := bgp.SessionState{
+				Peer:             n.Interface,
+				VRF:              vrf,
+				State:            n.State,
+				UpSince:          n.UpSince,
+				PrefixesSent:     n.PrefixSent,
+				PrefixesReceived: n.PrefixReceived,
+				LastError:        n.LastResetReason,
+				BFDState:         bfdStateFor(bfdPeers, n, vrf),
+
+				PrefixLimit:         n.MaxPrefixes,
+				PrefixLimitExceeded: n.MaxPrefixesExceeded,
+			}
+			if n.IP != nil {
+				s.Peer = n.IP.String()
+			}
+			if vrf == "default" {
+				s.VRF = ""
+			}
+			states = append(states, s)
+		}
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].VRF != states[j].VRF {
+			return states[i].VRF < states[j].VRF
+		}
+		return states[i].Peer < states[j].Peer
+	})
+	return states, nil
+}
+
+func bfdStateFor(peers []BFDPeer, n *Neighbor, vrf string) string {
+	for _, p := range peers {
+		if p.Vrf != vrf {
+			continue
+		}
+		if n.Interface != "" && p.Interface == n.Interface {
+			return p.Status
+		}
+		if n.IP != nil && p.Peer == n.IP.String() {
+			return p.Status
+		}
+	}
+	return ""
+}
+
+// AdvertisementState tells whether the session is established, as last read
+// from FRR, and whether the prefix is part of the configuration of the
+// session. FRR advertises the configured prefixes as soon as the session
+// is established.
+func (s *session) AdvertisementState(prefix string) (bool, bool) {
+	s.sessionManager.Lock()
+	configured := slices.ContainsFunc(s.advertised, func(adv *bgp.Advertisement) bool {
+		return adv.Prefix.String() == prefix
+	})
+	s.sessionManager.Unlock()
+
+	s.sessionManager.stateMu.Lock()
+	defer s.sessionManager.stateMu.Unlock()
+	for _, state := range s.sessionManager.states {
+		if state.VRF != s.VRFName || state.State != "Established" {
+			continue
+		}
+		if s.matchesPeer(state.Peer) {
+			return true, configured
+		}
+	}
+	return false, false
+}
+
+// matchesPeer tells if the peer reported by FRR belongs to the session.
+func (s *session) matchesPeer(peer string) bool {
+	switch {
+	case s.PeerInterface != "":
+		return peer == s.PeerInterface
+	case s.ListenRange != nil:
+		return s.ListenRange.Contains(net.ParseIP(peer))
+	default:
+		return peer == s.PeerAddress
+	}
+}
@@ -0,0 +1,320 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package frr
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/go-cmp/cmp"
+	ptu "github.com/prometheus/client_golang/prometheus/testutil"
+	"go.universe.tf/metallb/internal/bgp"
+)
+
+const defaultVRFNeighbors = `{
+  "192.168.1.1":{
+    "remoteAs":64513,
+    "localAs":64512,
+    "bgpState":"Established",
+    "bgpTimerUpEstablishedEpoch":1636386709,
+    "lastResetDueTo":"Waiting for peer OPEN",
+    "addressFamilyInfo":{
+      "ipv4Unicast":{
+        "acceptedPrefixCounter":3,
+        "sentPrefixCounter":2,
+        "prefixAllowedMax":10,
+        "prefixAllowedMaxWarning":true
+      }
+    }
+  },
+  "eth1":{
+    "remoteAs":64513,
+    "localAs":64512,
+    "bgpState":"Active",
+    "lastResetDueTo":"No AFI\/SAFI activated for peer"
+  }
+}`
+
+const redVRFNeighbors = `{
+  "10.0.0.1":{
+    "remoteAs":64514,
+    "localAs":64512,
+    "bgpState":"Connect",
+    "addressFamilyInfo":{
+      "ipv4Unicast":{
+        "acceptedPrefixCounter":5,
+        "prefixAllowedMax":4,
+        "prefixAllowedMaxWarning":true
+      },
+      "ipv6Unicast":{
+        "acceptedPrefixCounter":1,
+        "prefixAllowedMax":4,
+        "prefixAllowedMaxWarning":true
+      }
+    }
+  }
+}`
+
+const sessionBFDPeers = `[
+  {
+    "peer":"192.168.1.1",
+    "vrf":"default",
+    "status":"up"
+  },
+  {
+    "peer":"10.0.0.1",
+    "vrf":"default",
+    "status":"down"
+  }
+]`
+
+// fakeVtyCli returns a vtyCli replying to the given daemon commands.
+func fakeVtyCli(replies map[string]string) vtyCli {
+	return func(daemon, command string) (string, error) {
+		res, ok := replies[daemon+": "+command]
+		if !ok {
+			return "", fmt.Errorf("%s: unknown command %q", daemon, command)
+		}
+		return res, nil
+	}
+}
+
+func TestReadSessionStates(t *testing.T) {
+	cli := fakeVtyCli(map[string]string{
+		"bgpd: show bgp vrf all json":               `{"default":{}, "red":{}}`,
+		"bgpd: show bgp vrf default neighbors json": defaultVRFNeighbors,
+		"bgpd: show bgp vrf red neighbors json":     redVRFNeighbors,
+		"bfdd: show bfd peers json":                 sessionBFDPeers,
+	})
+
+	states, err := readSessionStates(cli)
+	if err != nil {
+		t.Fatalf("read session states: %s", err)
+	}
+	expected := []bgp.SessionState{
+		{
+			Peer:             "192.168.1.1",
+			State:            "Established",
+			UpSince:          time.Unix(1636386709, 0),
+			PrefixesSent:     2,
+			PrefixesReceived: 3,
+			LastError:        "Waiting for peer OPEN",
+			BFDState:         "up",
+			PrefixLimit:      10,
+		},
+		{
+			Peer:      "eth1",
+			State:     "Active",
+			LastError: "No AFI/SAFI activated for peer",
+		},
+		{
+			Peer:                "10.0.0.1",
+			VRF:                 "red",
+			State:               "Connect",
+			PrefixesReceived:    6,
+			PrefixLimit:         4,
+			PrefixLimitExceeded: true,
+		},
+	}
+	if !cmp.Equal(expected, states) {
+		t.Fatalf("unexpected session states (-want +got)\n%s", cmp.Diff(expected, states))
+	}
+}
+
+func TestUpdateSessionStates(t *testing.T) {
+	replies := map[string]string{
+		"bgpd: show bgp vrf all json":           `{"red":{}}`,
+		"bgpd: show bgp vrf red neighbors json": redVRFNeighbors,
+	}
+	cli := fakeVtyCli(replies)
+	failing := func(string, string) (string, error) {
+		return "", errors.New("failed")
+	}
+
+	sm := &sessionManager{}
+	changes := 0
+	sm.SetSessionStateCallback(func() { changes++ })
+
+	sm.updateSessionStates(log.NewNopLogger(), cli)
+	if changes != 1 || len(sm.SessionStates()) != 1 {
+		t.Fatalf("expected 1 change and 1 session, got %d and %v", changes, sm.SessionStates())
+	}
+
+	sm.updateSessionStates(log.NewNopLogger(), cli)
+	if changes != 1 {
+		t.Fatalf("callback called without changes")
+	}
+
+	// Failing to read from FRR keeps the last known state.
+	sm.updateSessionStates(log.NewNopLogger(), failing)
+	if changes != 1 || len(sm.SessionStates()) != 1 {
+		t.Fatalf("state changed after a failure: %d changes, %v", changes, sm.SessionStates())
+	}
+
+	replies["bgpd: show bgp vrf all json"] = `{}`
+	sm.updateSessionStates(log.NewNopLogger(), cli)
+	if changes != 2 || len(sm.SessionStates()) != 0 {
+		t.Fatalf("expected 2 changes and no sessions, got %d and %v", changes, sm.SessionStates())
+	}
+}
+
+func TestReloadStatus(t *testing.T) {
+	sm := &sessionManager{}
+	reloads := 0
+	sm.SetReloadStatusCallback(func() { reloads++ })
+
+	start := time.Unix(1700000000, 0)
+	sm.reloadSucceeded(start, "abc")
+	sm.reloadFailed(start.Add(time.Minute), "line 3: % Unknown command")
+	sm.reloadFailed(start.Add(2*time.Minute), "line 3: % Unknown command")
+
+	expected := bgp.ReloadStatus{
+		Time:                start.Add(2 * time.Minute),
+		Failed:              true,
+		Error:               "line 3: % Unknown command",
+		ConfigHash:          "abc",
+		ConsecutiveFailures: 2,
+	}
+	if !cmp.Equal(expected, sm.ReloadStatus()) {
+		t.Fatalf("unexpected reload status (-want +got)\n%s", cmp.Diff(expected, sm.ReloadStatus()))
+	}
+	if reloads != 3 {
+		t.Fatalf("expected 3 callbacks, got %d", reloads)
+	}
+	if failures := ptu.ToFloat64(stats.reloadFailures); failures != 2 {
+		t.Fatalf("expected 2 consecutive failures in the metric, got %v", failures)
+	}
+
+	// A success without hash keeps the last known one.
+	sm.reloadSucceeded(start.Add(3*time.Minute), "")
+	expected = bgp.ReloadStatus{
+		Time:       start.Add(3 * time.Minute),
+		ConfigHash: "abc",
+	}
+	if !cmp.Equal(expected, sm.ReloadStatus()) {
+		t.Fatalf("unexpected reload status (-want +got)\n%s", cmp.Diff(expected, sm.ReloadStatus()))
+	}
+	if failures := ptu.ToFloat64(stats.reloadFailures); failures != 0 {
+		t.Fatalf("expected no consecutive failures in the metric, got %v", failures)
+	}
+}
+
+func TestRunVtyCommand(t *testing.T) {
+	dir := t.TempDir()
+	l, err := net.Listen("unix", filepath.Join(dir, "bgpd.vty"))
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			command, err := bufio.NewReader(conn).ReadString(0)
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			switch command[:len(command)-1] {
+			case "show bgp vrf all json":
+				_, _ = conn.Write([]byte("{\"default\":{}}\n\x00\x00\x00\x00"))
+			default:
+				_, _ = conn.Write([]byte("% Unknown command\n\x00\x00\x00\x01"))
+			}
+			conn.Close()
+		}
+	}()
+
+	oldDir := frrSocketsDir
+	frrSocketsDir = dir
+	defer func() { frrSocketsDir = oldDir }()
+
+	res, err := runVtyCommand("bgpd", "show bgp vrf all json")
+	if err != nil {
+		t.Fatalf("run command: %s", err)
+	}
+	if res != "{\"default\":{}}\n" {
+		t.Fatalf("unexpected output %q", res)
+	}
+	if _, err := runVtyCommand("bgpd", "show foo"); err == nil {
+		t.Fatalf("expected error for failed command")
+	}
+	if _, err := runVtyCommand("bfdd", "show bfd peers json"); err == nil {
+		t.Fatalf("expected error for missing daemon")
+	}
+}
+
+func TestAdvertisementState(t *testing.T) {
+	sm := &sessionManager{
+		sessions: map[string]*session{},
+		states: []bgp.SessionState{
+			{Peer: "192.168.1.1", State: "Established"},
+			{Peer: "192.168.1.2", State: "Active"},
+			{Peer: "10.0.0.5", VRF: "red", State: "Established"},
+		},
+	}
+	_, prefix, _ := net.ParseCIDR("172.16.1.10/32")
+	newSession := func(params bgp.SessionParameters) *session {
+		return &session{
+			SessionParameters: params,
+			sessionManager:    sm,
+			advertised:        []*bgp.Advertisement{{Prefix: prefix}},
+		}
+	}
+	_, listenRange, _ := net.ParseCIDR("10.0.0.0/24")
+
+	tests := []struct {
+		desc        string
+		session     *session
+		prefix      string
+		established bool
+		advertised  bool
+	}{
+		{
+			desc:        "established",
+			session:     newSession(bgp.SessionParameters{PeerAddress: "192.168.1.1"}),
+			prefix:      "172.16.1.10/32",
+			established: true,
+			advertised:  true,
+		},
+		{
+			desc:        "established, prefix not configured",
+			session:     newSession(bgp.SessionParameters{PeerAddress: "192.168.1.1"}),
+			prefix:      "172.16.1.11/32",
+			established: true,
+		},
+		{
+			desc:    "not established",
+			session: newSession(bgp.SessionParameters{PeerAddress: "192.168.1.2"}),
+			prefix:  "172.16.1.10/32",
+		},
+		{
+			desc:    "wrong vrf",
+			session: newSession(bgp.SessionParameters{PeerAddress: "10.0.0.5"}),
+			prefix:  "172.16.1.10/32",
+		},
+		{
+			desc:        "listen range",
+			session:     newSession(bgp.SessionParameters{ListenRange: listenRange, VRFName: "red"}),
+			prefix:      "172.16.1.10/32",
+			established: true,
+			advertised:  true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			established, advertised := tc.session.AdvertisementState(tc.prefix)
+			if established != tc.established || advertised != tc.advertised {
+				t.Fatalf("expected established %v advertised %v, got %v %v", tc.established, tc.advertised, established, advertised)
+			}
+		})
+	}
+}
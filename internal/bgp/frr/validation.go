@@ -0,0 +1,283 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package frr
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// validateConfig checks the rendered configuration for the errors that would
+// make FRR reject it, so that it never reaches the daemons: invalid ASNs,
+// neighbors configured without being declared or declared more than once,
+// VRFs importing themselves, and references to route-maps, prefix-lists,
+// community-lists and peer-groups that are not defined. The BFD profiles are not checked, as
+// FRR applies the defaults until a missing one is defined.
+func validateConfig(rendered string) error {
+	root := parseConfig(rendered)
+	defs := definitionsFor(root)
+
+	var errs []error
+	for _, n := range root.children {
+		fields := strings.Fields(n.line)
+		if len(fields) < 3 || fields[0] != "router" || fields[1] != "bgp" {
+			continue
+		}
+		if asn := fields[2]; !validASN(asn) {
+			errs = append(errs, fmt.Errorf("%q: invalid ASN %s", n.line, asn))
+		}
+		errs = append(errs, validateNeighbors(n, defs)...)
+		errs = append(errs, validateVRFImports(n, defs)...)
+		errs = append(errs, validateEVPNExports(n, defs)...)
+	}
+	errs = append(errs, validateReferences(root, defs)...)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid FRR configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// definitions holds the names of the objects defined at the top level of
+// the configuration, by kind.
+type definitions struct {
+	routeMaps           map[string]bool
+	prefixListsV4       map[string]bool
+	prefixListsV6       map[string]bool
+	communityLists      map[string]bool
+	largeCommunityLists map[string]bool
+}
+
+func definitionsFor(root *configNode) definitions {
+	res := definitions{
+		routeMaps:           map[string]bool{},
+		prefixListsV4:       map[string]bool{},
+		prefixListsV6:       map[string]bool{},
+		communityLists:      map[string]bool{},
+		largeCommunityLists: map[string]bool{},
+	}
+	for _, n := range root.children {
+		fields := strings.Fields(n.line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch {
+		case fields[0] == "route-map":
+			res.routeMaps[fields[1]] = true
+		case fields[0] == "ip" && fields[1] == "prefix-list" && len(fields) > 2:
+			res.prefixListsV4[fields[2]] = true
+		case fields[0] == "ipv6" && fields[1] == "prefix-list" && len(fields) > 2:
+			res.prefixListsV6[fields[2]] = true
+		case fields[0] == "bgp" && fields[1] == "community-list":
+			res.communityLists[listName(fields)] = true
+		case fields[0] == "bgp" && fields[1] == "large-community-list":
+			res.largeCommunityLists[listName(fields)] = true
+		}
+	}
+	return res
+}
+
+// listName returns the name of a community-list, given with or without
+// its type as in "bgp community-list standard NAME permit 1:1".
+func listName(fields []string) string {
+	if len(fields) > 3 && (fields[2] == "standard" || fields[2] == "expanded") {
+		return fields[3]
+	}
+	if len(fields) > 2 {
+		return fields[2]
+	}
+	return ""
+}
+
+// validateNeighbors checks the neighbors of the given router, including
+// the ones configured in its address families.
+func validateNeighbors(router *configNode, defs definitions) []error {
+	var errs []error
+	declared := map[string]string{}
+	peerGroups := map[string]bool{}
+	for _, n := range router.children {
+		neighbor, ok := declaredNeighbor(n.line)
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(n.line)
+		if asn := fields[len(fields)-1]; fields[len(fields)-2] == "remote-as" && !validRemoteASN(asn) {
+			errs = append(errs, fmt.Errorf("%q: invalid ASN %s", n.line, asn))
+		}
+		// A peer-group is given its remote ASN after being declared.
+		if peerGroups[neighbor] && fields[2] == "remote-as" {
+			continue
+		}
+		if other, ok := declared[neighbor]; ok {
+			errs = append(errs, fmt.Errorf("%q: neighbor %s already declared as %q", n.line, neighbor, other))
+			continue
+		}
+		declared[neighbor] = n.line
+		if strings.HasSuffix(n.line, " peer-group") {
+			peerGroups[neighbor] = true
+		}
+	}
+
+	// A neighbor is also declared by joining a peer-group, inheriting its
+	// remote ASN, as in "neighbor 10.2.2.253 peer-group tor" or
+	// "neighbor eth1 interface peer-group tor".
+	members := map[string]bool{}
+	for _, n := range router.children {
+		if group, ok := peerGroupOf(n.line); ok {
+			members[strings.Fields(n.line)[1]] = true
+			if !peerGroups[group] {
+				errs = append(errs, fmt.Errorf("%q: peer-group %s is not declared", n.line, group))
+			}
+		}
+	}
+
+	lines := router.children
+	for _, n := range router.children {
+		if strings.HasPrefix(n.line, "address-family ") {
+			lines = append(lines, n.children...)
+		}
+	}
+	for _, n := range lines {
+		fields := strings.Fields(n.line)
+		if len(fields) < 3 || fields[0] != "neighbor" {
+			continue
+		}
+		neighbor := fields[1]
+		if _, ok := declared[neighbor]; !ok && !members[neighbor] {
+			errs = append(errs, fmt.Errorf("%q: neighbor %s is not declared", n.line, neighbor))
+			continue
+		}
+		switch {
+		case fields[2] == "local-as" && len(fields) > 3 && !validASN(fields[3]):
+			errs = append(errs, fmt.Errorf("%q: invalid ASN %s", n.line, fields[3]))
+		case fields[2] == "route-map" && len(fields) > 3 && !defs.routeMaps[fields[3]]:
+			errs = append(errs, fmt.Errorf("%q: route-map %s is not defined", n.line, fields[3]))
+		case fields[2] == "prefix-list" && len(fields) > 3 && !defs.prefixListsV4[fields[3]] && !defs.prefixListsV6[fields[3]]:
+			errs = append(errs, fmt.Errorf("%q: prefix-list %s is not defined", n.line, fields[3]))
+		case fields[2] == "advertise-map":
+			// neighbor X advertise-map NAME exist-map|non-exist-map NAME
+			for _, i := range []int{3, 5} {
+				if len(fields) > i && !defs.routeMaps[fields[i]] {
+					errs = append(errs, fmt.Errorf("%q: route-map %s is not defined", n.line, fields[i]))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// peerGroupOf returns the peer-group the line makes the neighbor join.
+func peerGroupOf(line string) (string, bool) {
+	fields := strings.Fields(line)
+	switch {
+	case len(fields) == 4 && fields[0] == "neighbor" && fields[2] == "peer-group":
+		return fields[3], true
+	case len(fields) == 5 && fields[0] == "neighbor" && fields[2] == "interface" && fields[3] == "peer-group":
+		return fields[4], true
+	}
+	return "", false
+}
+
+// validateVRFImports checks the VRFs imported by the given router, which
+// can't import its own, and the route-maps filtering them.
+func validateVRFImports(router *configNode, defs definitions) []error {
+	vrf := "default"
+	if fields := strings.Fields(router.line); len(fields) == 5 && fields[3] == "vrf" {
+		vrf = fields[4]
+	}
+	var errs []error
+	for _, af := range router.children {
+		if !strings.HasPrefix(af.line, "address-family ") {
+			continue
+		}
+		for _, n := range af.children {
+			fields := strings.Fields(n.line)
+			if len(fields) < 3 || fields[0] != "import" || fields[1] != "vrf" {
+				continue
+			}
+			switch {
+			case fields[2] == "route-map" && len(fields) == 4 && !defs.routeMaps[fields[3]]:
+				errs = append(errs, fmt.Errorf("%q: route-map %s is not defined", n.line, fields[3]))
+			case fields[2] == vrf:
+				errs = append(errs, fmt.Errorf("%q: vrf %s can't import itself", n.line, vrf))
+			}
+		}
+	}
+	return errs
+}
+
+// validateEVPNExports checks the route-maps filtering the EVPN routes
+// exported by the given router.
+func validateEVPNExports(router *configNode, defs definitions) []error {
+	var errs []error
+	for _, af := range router.children {
+		if af.line != "address-family l2vpn evpn" {
+			continue
+		}
+		for _, n := range af.children {
+			fields := strings.Fields(n.line)
+			if len(fields) == 5 && fields[0] == "advertise" && fields[3] == "route-map" && !defs.routeMaps[fields[4]] {
+				errs = append(errs, fmt.Errorf("%q: route-map %s is not defined", n.line, fields[4]))
+			}
+		}
+	}
+	return errs
+}
+
+// validateReferences checks the objects referenced by the route-maps,
+// including the ones they call.
+func validateReferences(root *configNode, defs definitions) []error {
+	var errs []error
+	for _, rm := range root.children {
+		if !strings.HasPrefix(rm.line, "route-map ") {
+			continue
+		}
+		for _, n := range rm.children {
+			fields := strings.Fields(n.line)
+			if len(fields) == 2 && fields[0] == "call" && !defs.routeMaps[fields[1]] {
+				errs = append(errs, fmt.Errorf("%q in %q: route-map %s is not defined", n.line, rm.line, fields[1]))
+				continue
+			}
+			if len(fields) < 3 || fields[0] != "match" {
+				continue
+			}
+			name := fields[len(fields)-1]
+			switch {
+			case fields[1] == "ip" && len(fields) == 5 && fields[3] == "prefix-list" && !defs.prefixListsV4[name]:
+				errs = append(errs, fmt.Errorf("%q in %q: prefix-list %s is not defined", n.line, rm.line, name))
+			case fields[1] == "ipv6" && len(fields) == 5 && fields[3] == "prefix-list" && !defs.prefixListsV6[name]:
+				errs = append(errs, fmt.Errorf("%q in %q: prefix-list %s is not defined", n.line, rm.line, name))
+			case fields[1] == "community" && !defs.communityLists[fields[2]]:
+				errs = append(errs, fmt.Errorf("%q in %q: community-list %s is not defined", n.line, rm.line, fields[2]))
+			case fields[1] == "large-community" && !defs.largeCommunityLists[fields[2]]:
+				errs = append(errs, fmt.Errorf("%q in %q: large-community-list %s is not defined", n.line, rm.line, fields[2]))
+			}
+		}
+	}
+	return errs
+}
+
+// validASN tells if the ASN is valid, either as a number or in the asdot
+// notation.
+func validASN(asn string) bool {
+	if high, low, ok := strings.Cut(asn, "."); ok {
+		h, err := strconv.ParseUint(high, 10, 16)
+		if err != nil {
+			return false
+		}
+		l, err := strconv.ParseUint(low, 10, 16)
+		return err == nil && (h != 0 || l != 0)
+	}
+	n, err := strconv.ParseUint(asn, 10, 32)
+	return err == nil && n != 0
+}
+
+func validRemoteASN(asn string) bool {
+	switch asn {
+	case "internal", "external", "auto":
+		return true
+	}
+	return validASN(asn)
+}
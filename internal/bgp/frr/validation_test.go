@@ -0,0 +1,218 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package frr
+
+import (
+	"strings"
+	"testing"
+
+	metallbconfig "go.universe.tf/metallb/internal/config"
+)
+
+const validationBaseConfig = `router bgp 64512
+  no bgp default ipv4-unicast
+  neighbor tor peer-group
+  neighbor 10.2.2.253 remote-as 64513
+  neighbor 10.2.2.253 peer-group tor
+  neighbor 10.2.2.254 remote-as external
+  neighbor 10.2.2.254 local-as 1.10
+
+  address-family ipv4 unicast
+    neighbor 10.2.2.254 activate
+    neighbor 10.2.2.254 route-map 10.2.2.254-out out
+    neighbor 10.2.2.254 advertise-map 10.2.2.254-advertise non-exist-map 10.2.2.254-condition
+  exit-address-family
+
+route-map 10.2.2.254-out permit 1
+  match ip address prefix-list 10.2.2.254-allowed-ipv4
+  match community 10.2.2.254-community
+
+route-map 10.2.2.254-advertise permit 1
+  match ipv6 address prefix-list 10.2.2.254-allowed-ipv6
+
+route-map 10.2.2.254-condition permit 1
+
+ip prefix-list 10.2.2.254-allowed-ipv4 seq 1 permit 192.168.10.0/32
+ipv6 prefix-list 10.2.2.254-allowed-ipv6 seq 1 deny any
+bgp community-list standard 10.2.2.254-community permit 1111:2222
+`
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		desc     string
+		config   string
+		expected []string
+	}{
+		{
+			desc:   "valid",
+			config: validationBaseConfig,
+		},
+		{
+			desc: "extras configuring an undeclared neighbor",
+			config: validationBaseConfig + `router bgp 64512
+  neighbor 10.2.2.255 timers 30 90
+`,
+			expected: []string{"neighbor 10.2.2.255 is not declared"},
+		},
+		{
+			desc: "neighbor declared twice",
+			config: validationBaseConfig + `router bgp 64512
+  neighbor 10.2.2.254 remote-as 64514
+`,
+			expected: []string{"neighbor 10.2.2.254 already declared"},
+		},
+		{
+			desc:     "invalid router ASN",
+			config:   strings.Replace(validationBaseConfig, "router bgp 64512", "router bgp 4294967296", 1),
+			expected: []string{"invalid ASN 4294967296"},
+		},
+		{
+			desc:     "invalid remote ASN",
+			config:   strings.Replace(validationBaseConfig, "remote-as 64513", "remote-as 0", 1),
+			expected: []string{"invalid ASN 0"},
+		},
+		{
+			desc:     "invalid local ASN",
+			config:   strings.Replace(validationBaseConfig, "local-as 1.10", "local-as 65536.1", 1),
+			expected: []string{"invalid ASN 65536.1"},
+		},
+		{
+			desc:     "undefined route-map",
+			config:   strings.Replace(validationBaseConfig, "route-map 10.2.2.254-out permit 1", "route-map other permit 1", 1),
+			expected: []string{"route-map 10.2.2.254-out is not defined"},
+		},
+		{
+			desc:     "undefined conditional advertisement route-map",
+			config:   strings.Replace(validationBaseConfig, "route-map 10.2.2.254-condition permit 1", "", 1),
+			expected: []string{"route-map 10.2.2.254-condition is not defined"},
+		},
+		{
+			desc:     "undefined prefix-list",
+			config:   strings.Replace(validationBaseConfig, "ip prefix-list 10.2.2.254-allowed-ipv4", "ipv6 prefix-list 10.2.2.254-allowed-ipv4", 1),
+			expected: []string{"prefix-list 10.2.2.254-allowed-ipv4 is not defined"},
+		},
+		{
+			desc:     "undefined community-list",
+			config:   strings.Replace(validationBaseConfig, "bgp community-list standard 10.2.2.254-community", "bgp large-community-list standard 10.2.2.254-community", 1),
+			expected: []string{"community-list 10.2.2.254-community is not defined"},
+		},
+		{
+			desc:     "undeclared peer-group",
+			config:   strings.Replace(validationBaseConfig, "  neighbor tor peer-group\n", "", 1),
+			expected: []string{"peer-group tor is not declared"},
+		},
+		{
+			desc: "peer-group with unnumbered member",
+			config: strings.Replace(validationBaseConfig, "  neighbor 10.2.2.254 remote-as external\n",
+				"  neighbor tor remote-as external\n  neighbor eth1 interface peer-group tor\n  neighbor eth1 timers 30 90\n  neighbor 10.2.2.254 remote-as external\n", 1),
+		},
+		{
+			desc:     "unnumbered member of an undeclared peer-group",
+			config:   strings.Replace(validationBaseConfig, "  neighbor 10.2.2.254 remote-as external\n", "  neighbor eth1 interface peer-group spine\n  neighbor 10.2.2.254 remote-as external\n", 1),
+			expected: []string{"peer-group spine is not declared"},
+		},
+		{
+			desc: "vrf import",
+			config: validationBaseConfig + `router bgp 64512 vrf red
+  address-family ipv4 unicast
+    import vrf route-map 10.2.2.254-condition
+    import vrf default
+  exit-address-family
+`,
+		},
+		{
+			desc: "vrf import with undefined route-map",
+			config: validationBaseConfig + `router bgp 64512 vrf red
+  address-family ipv4 unicast
+    import vrf route-map red-vrf-import
+    import vrf default
+  exit-address-family
+`,
+			expected: []string{"route-map red-vrf-import is not defined"},
+		},
+		{
+			desc: "vrf importing itself",
+			config: validationBaseConfig + `router bgp 64512 vrf red
+  address-family ipv4 unicast
+    import vrf red
+  exit-address-family
+`,
+			expected: []string{"vrf red can't import itself"},
+		},
+		{
+			desc: "evpn export",
+			config: validationBaseConfig + `vrf red
+  vni 100
+exit-vrf
+router bgp 64512 vrf red
+  address-family l2vpn evpn
+    advertise ipv4 unicast route-map 10.2.2.254-condition
+    route-target export 64512:100
+  exit-address-family
+`,
+		},
+		{
+			desc: "evpn export with undefined route-map",
+			config: validationBaseConfig + `router bgp 64512 vrf red
+  address-family l2vpn evpn
+    advertise ipv6 unicast route-map red-evpn-export
+  exit-address-family
+`,
+			expected: []string{"route-map red-evpn-export is not defined"},
+		},
+		{
+			desc: "multiple errors",
+			config: validationBaseConfig + `router bgp 64512
+  neighbor 10.2.2.255 timers 30 90
+  neighbor 10.2.2.254 prefix-list missing in
+`,
+			expected: []string{"neighbor 10.2.2.255 is not declared", "prefix-list missing is not defined"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			err := validateConfig(test.config)
+			if len(test.expected) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			for _, e := range test.expected {
+				if !strings.Contains(err.Error(), e) {
+					t.Fatalf("expected error containing %q, got %s", e, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncExtraInfoRejectsInvalidConfig(t *testing.T) {
+	sm := &sessionManager{
+		sessions:     map[string]*session{},
+		bfdProfiles:  []BFDProfile{},
+		reloadConfig: make(chan reloadEvent, 1),
+	}
+
+	valid := metallbconfig.BGPExtras{Raw: "ip prefix-list extra seq 1 permit 192.168.10.0/32"}
+	if err := sm.SyncExtraInfo(valid); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	<-sm.reloadConfig
+
+	if err := sm.SyncExtraInfo(metallbconfig.BGPExtras{Raw: "router bgp 64512\n  neighbor 10.2.2.255 timers 30 90"}); err == nil {
+		t.Fatalf("expected error for an undeclared neighbor")
+	}
+	if sm.extras.Raw != valid.Raw {
+		t.Fatalf("expected the last valid extra config to be kept, got %q", sm.extras.Raw)
+	}
+	select {
+	case e := <-sm.reloadConfig:
+		t.Fatalf("unexpected reload of an invalid config %v", e.config)
+	default:
+	}
+}
@@ -0,0 +1,98 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package frr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"time"
+)
+
+// vtyCli runs a command against the given FRR daemon and returns its output.
+type vtyCli func(daemon, command string) (string, error)
+
+// frrSocketsDir is where the FRR daemons expose their vty sockets.
+var frrSocketsDir = "/var/run/frr"
+
+const vtyTimeout = 10 * time.Second
+
+// runVtyCommand runs a command the way vtysh does, talking directly to the
+// vty socket of the daemon: the command is sent null terminated and the
+// daemon replies with its output followed by three null bytes and the
+// command status.
+func runVtyCommand(daemon, command string) (string, error) {
+	conn, err := dialVty(daemon)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return vtyExec(conn, daemon, command)
+}
+
+// vtyConfigurer runs the given configuration commands against the given FRR
+// daemon, in order, stopping at the first failing one.
+type vtyConfigurer func(daemon string, commands []string) error
+
+// runVtyConfig runs the given configuration commands over a single vty
+// session, so that the commands entering a node apply to the following ones.
+// As vtysh does, the session is moved out of the view mode first.
+func runVtyConfig(daemon string, commands []string) error {
+	conn, err := dialVty(daemon)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	for _, c := range append([]string{"enable"}, commands...) {
+		if _, err := vtyExec(conn, daemon, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dialVty(daemon string) (net.Conn, error) {
+	conn, err := net.DialTimeout("unix", filepath.Join(frrSocketsDir, daemon+".vty"), vtyTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(vtyTimeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func vtyExec(conn net.Conn, daemon, command string) (string, error) {
+	if _, err := conn.Write(append([]byte(command), 0)); err != nil {
+		return "", fmt.Errorf("failed to send %q to %s: %w", command, daemon, err)
+	}
+
+	var out bytes.Buffer
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		out.Write(buf[:n])
+		res := out.Bytes()
+		if l := len(res); l >= 4 && bytes.Equal(res[l-4:l-1], []byte{0, 0, 0}) {
+			if status := res[l-1]; status != 0 {
+				return "", fmt.Errorf("%s failed to run %q, status %d: %s", daemon, command, status, res[:l-4])
+			}
+			return string(res[:l-4]), nil
+		}
+		if errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("%s closed the connection while running %q", daemon, command)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+var (
+	_ vtyCli        = runVtyCommand
+	_ vtyConfigurer = runVtyConfig
+)
@@ -3,13 +3,17 @@
 package frr
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"maps"
 	"net"
 	"reflect"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/go-kit/log"
@@ -23,13 +27,21 @@ import (
 	"go.universe.tf/metallb/internal/logging"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 type sessionManager struct {
 	sessions        map[string]*session
 	bfdProfiles     []frr.BFDProfile
+	extras          metallbconfig.BGPExtras
 	targetNamespace string
 	nodeToConfigure string
+	// configPerPeer splits the configuration in one FRRConfiguration per
+	// peer, plus the node wide one.
+	configPerPeer bool
+	// sessionStates holds the state of each BGP session, keyed by peer and
+	// VRF, as reported by FRR-K8s.
+	sessionStates map[string]string
 	sync.Mutex
 	configChangedCallback func(interface{})
 	logger                log.Logger
@@ -42,6 +54,29 @@ func (sm *sessionManager) SetEventCallback(callback func(interface{})) {
 	sm.configChangedCallback = callback
 }
 
+type router struct {
+	myASN     uint32
+	routerID  string
+	neighbors map[string]frrv1beta1.Neighbor
+	vrf       string
+	prefixes  map[string]string
+	// The advertisements of each neighbor, used to render the
+	// conditional ones.
+	advertisements map[string][]*bgp.Advertisement
+	// The extras of each neighbor, rendered as raw configuration.
+	extras map[string]*metallbconfig.NeighborExtras
+	// The prefixes accepted from each neighbor with an import policy,
+	// rendered as raw configuration.
+	toReceive map[string]*metallbconfig.Receive
+	// The interface the link-local address of each neighbor is reached
+	// through, rendered as raw configuration.
+	zones map[string]string
+	// The neighbors the EVPN routes are sent to, and the ones exported
+	// from the VRF of the router, rendered as raw configuration.
+	evpnNeighbors map[string]bool
+	evpn          *frr.EVPNExport
+}
+
 type session struct {
 	bgp.SessionParameters
 	sessionManager *sessionManager
@@ -167,11 +202,14 @@ func (sm *sessionManager) deleteSession(s *session) error {
 	return nil
 }
 
-func (sm *sessionManager) SyncExtraInfo(extraInfo string) error {
-	if extraInfo != "" {
-		return errors.New("extra info not supported in frr-k8s mode")
-	}
-	return nil
+// SyncExtraInfo sets the extras rendered in the configuration of the node.
+// Unlike the other settings, the raw extras are not translated to the
+// FRR-K8s API: they are appended verbatim to its raw configuration.
+func (sm *sessionManager) SyncExtraInfo(extras metallbconfig.BGPExtras) error {
+	sm.Lock()
+	defer sm.Unlock()
+	sm.extras = extras
+	return sm.updateConfig()
 }
 
 func (sm *sessionManager) SyncBFDProfiles(profiles map[string]*metallbconfig.BFDProfile) error {
@@ -209,15 +247,20 @@ func (sm *sessionManager) updateConfig() error {
 		},
 	}
 
-	type router struct {
-		myASN     uint32
-		routerID  string
-		neighbors map[string]frrv1beta1.Neighbor
-		vrf       string
-		prefixes  map[string]string
+	// l3vni holds the prefixes exported as EVPN Type-5 routes from a VRF.
+	type l3vni struct {
+		evpn *metallbconfig.EVPN
+		// The routers of the EVPN sessions the prefixes are announced to.
+		sources map[string]bool
+		// The family of each prefix.
+		prefixes map[string]ipfamily.Family
 	}
 
 	routers := make(map[string]*router)
+	// The peer each neighbor belongs to, when configuring the peers
+	// separately.
+	peerOfNeighbor := make(map[string]string)
+	l3vnis := make(map[string]*l3vni)
 
 	for _, s := range sm.sessions {
 		var neighbor frrv1beta1.Neighbor
@@ -227,10 +270,15 @@ func (sm *sessionManager) updateConfig() error {
 		routerName := frr.RouterName(s.RouterID.String(), s.MyASN, s.VRFName)
 		if rout, exist = routers[routerName]; !exist {
 			rout = &router{
-				myASN:     s.MyASN,
-				neighbors: make(map[string]frrv1beta1.Neighbor),
-				prefixes:  make(map[string]string),
-				vrf:       s.VRFName,
+				myASN:          s.MyASN,
+				neighbors:      make(map[string]frrv1beta1.Neighbor),
+				prefixes:       make(map[string]string),
+				vrf:            s.VRFName,
+				advertisements: make(map[string][]*bgp.Advertisement),
+				extras:         make(map[string]*metallbconfig.NeighborExtras),
+				toReceive:      make(map[string]*metallbconfig.Receive),
+				zones:          make(map[string]string),
+				evpnNeighbors:  make(map[string]bool),
 			}
 			if s.RouterID != nil {
 				rout.routerID = s.RouterID.String()
@@ -279,12 +327,20 @@ func (sm *sessionManager) updateConfig() error {
 					PrefixesWithLocalPref: make([]frrv1beta1.LocalPrefPrefixes, 0),
 					PrefixesWithCommunity: make([]frrv1beta1.CommunityPrefixes, 0),
 				},
-				Password: s.Password,
+				ToReceive: toReceive(s.ToReceive),
+				Password:  s.Password,
 				PasswordSecret: frrv1beta1.SecretReference{
 					Name:      s.PasswordRef.Name,
 					Namespace: s.PasswordRef.Namespace,
 				},
 			}
+			// The import policy can only be called from entries rendered
+			// as raw configuration, which accept the prefixes instead of
+			// FRR-K8s.
+			if s.Extras != nil && len(s.Extras.Import) > 0 {
+				neighbor.ToReceive = frrv1beta1.Receive{}
+				rout.toReceive[neighborName] = s.ToReceive
+			}
 		}
 
 		neighborFamily := ipfamily.ForAddress(net.ParseIP(s.PeerAddress))
@@ -293,22 +349,50 @@ func (sm *sessionManager) updateConfig() error {
 			neighborFamily = ipfamily.DualStack
 		}
 
-		/* As 'session.advertised' is a map, we can be sure there are no
-		   duplicate prefixes and can, therefore, just add them to the
-		   'neighbor.Advertisements' list. */
+		/* The same prefix may come from several advertisements, the
+		   duplicates are removed from the 'neighbor.Advertisements' list. */
 		prefixesForCommunity := map[string][]string{}
 		prefixesForLocalPref := map[uint32][]string{}
 		for _, adv := range s.advertised {
 			family := ipfamily.ForAddress(adv.Prefix.IP)
 
+			// The EVPN routes are exported from the VRF of the L3VNI to
+			// the sessions of the default VRF, over the l2vpn evpn address
+			// family which carries both IP families.
+			if adv.EVPN != nil {
+				if s.VRFName != "" {
+					continue
+				}
+				rout.evpnNeighbors[neighborName] = true
+				e, ok := l3vnis[adv.EVPN.VRF]
+				if !ok {
+					e = &l3vni{evpn: adv.EVPN, sources: map[string]bool{}, prefixes: map[string]ipfamily.Family{}}
+					l3vnis[adv.EVPN.VRF] = e
+				}
+				e.sources[routerName] = true
+				e.prefixes[adv.Prefix.String()] = family
+				continue
+			}
+
 			if neighborFamily != family &&
 				neighborFamily != ipfamily.DualStack {
 				continue
 			}
 
 			prefix := adv.Prefix.String()
-			neighbor.ToAdvertise.Allowed.Prefixes = append(neighbor.ToAdvertise.Allowed.Prefixes, prefix)
 			rout.prefixes[prefix] = prefix
+			rout.advertisements[neighborName] = append(rout.advertisements[neighborName], adv)
+		}
+		// The advertisements of the neighbor may come from several sessions,
+		// its prefixes are computed from all of them.
+		neighbor.ToAdvertise.Allowed.Prefixes = make([]string, 0)
+		rawPrefixes := rawPolicyPrefixes(rout.advertisements[neighborName], s.Extras != nil && len(s.Extras.Export) > 0)
+		for _, adv := range rout.advertisements[neighborName] {
+			prefix := adv.Prefix.String()
+			if rawPrefixes.Has(prefix) {
+				continue
+			}
+			neighbor.ToAdvertise.Allowed.Prefixes = append(neighbor.ToAdvertise.Allowed.Prefixes, prefix)
 
 			for _, c := range adv.Communities {
 				comm := c.String()
@@ -327,9 +411,211 @@ func (sm *sessionManager) updateConfig() error {
 		sort.Strings(neighbor.ToAdvertise.Allowed.Prefixes)
 
 		rout.neighbors[neighborName] = neighbor
+		if sm.configPerPeer && s.SessionName != "" {
+			peerOfNeighbor[neighborName] = s.SessionName
+		}
+		if s.Extras != nil {
+			rout.extras[neighborName] = s.Extras
+		}
+		if s.PeerZone != "" {
+			rout.zones[neighborName] = s.PeerZone
+		}
+	}
+
+	// The EVPN routes are exported from the router of the VRF, created with
+	// the settings of the first of the routers they are sent from when the
+	// VRF has no peers on the node.
+	routerForVRF := map[string]*router{}
+	for _, r := range routers {
+		routerForVRF[r.vrf] = r
+	}
+	for vrf, e := range l3vnis {
+		r, ok := routerForVRF[vrf]
+		if !ok {
+			source := routers[slices.Min(slices.Collect(maps.Keys(e.sources)))]
+			r = &router{
+				myASN:          source.myASN,
+				routerID:       source.routerID,
+				neighbors:      make(map[string]frrv1beta1.Neighbor),
+				prefixes:       make(map[string]string),
+				vrf:            vrf,
+				advertisements: make(map[string][]*bgp.Advertisement),
+				extras:         make(map[string]*metallbconfig.NeighborExtras),
+				toReceive:      make(map[string]*metallbconfig.Receive),
+				zones:          make(map[string]string),
+				evpnNeighbors:  make(map[string]bool),
+			}
+			routers[frr.RouterName(source.routerID, source.myASN, vrf)] = r
+		}
+		r.evpn = &frr.EVPNExport{
+			VNI:                e.evpn.VNI,
+			RouteDistinguisher: e.evpn.RouteDistinguisher,
+			ImportRouteTargets: e.evpn.ImportRouteTargets,
+			ExportRouteTargets: e.evpn.ExportRouteTargets,
+			RouteMap:           "metallb-" + vrf + "-evpn-export",
+		}
+		for _, p := range slices.Sorted(maps.Keys(e.prefixes)) {
+			r.prefixes[p] = p
+			if e.prefixes[p] == ipfamily.IPv6 {
+				r.evpn.PrefixesV6 = append(r.evpn.PrefixesV6, p)
+				continue
+			}
+			r.evpn.PrefixesV4 = append(r.evpn.PrefixesV4, p)
+		}
+	}
+
+	if !sm.configPerPeer {
+		if err := sm.render(&newConfig, routers, true); err != nil {
+			return err
+		}
+		sm.configChangedCallback(newConfig)
+		if sm.logLevel == logging.LevelDebug {
+			sm.dumpConfig(newConfig)
+		}
+		return nil
+	}
+
+	// The node wide configuration holds the neighbors of the sessions not
+	// tied to a peer, and each peer gets its own configuration.
+	configs := []frrv1beta1.FRRConfiguration{}
+	neighborsOfPeer := map[string]map[string]bool{"": {}}
+	for name, peer := range peerOfNeighbor {
+		if _, ok := neighborsOfPeer[peer]; !ok {
+			neighborsOfPeer[peer] = map[string]bool{}
+		}
+		neighborsOfPeer[peer][name] = true
+	}
+	for _, peer := range slices.Sorted(maps.Keys(neighborsOfPeer)) {
+		config := newConfig
+		subset := map[string]*router{}
+		for name, r := range routers {
+			sub := r.subset(neighborsOfPeer[peer], peer == "")
+			if peer != "" && len(sub.neighbors) == 0 {
+				continue
+			}
+			subset[name] = sub
+		}
+		if peer != "" {
+			config.ObjectMeta = metav1.ObjectMeta{
+				Name:      PeerConfigName(sm.nodeToConfigure, peer),
+				Namespace: sm.targetNamespace,
+				Labels: map[string]string{
+					NodeLabel: LabelValue(sm.nodeToConfigure),
+					PeerLabel: LabelValue(peer),
+				},
+				Annotations: map[string]string{
+					PeerAnnotation: peer,
+				},
+			}
+			config.Spec.BGP.Routers = make([]frrv1beta1.Router, 0)
+			config.Spec.BGP.BFDProfiles = make([]frrv1beta1.BFDProfile, 0)
+		}
+		if err := sm.render(&config, subset, peer == ""); err != nil {
+			return err
+		}
+		configs = append(configs, config)
+	}
+
+	sm.configChangedCallback(configs)
+	if sm.logLevel == logging.LevelDebug {
+		for _, c := range configs {
+			sm.dumpConfig(c)
+		}
+	}
+	return nil
+}
+
+// subset returns a copy of the router holding only the given neighbors and
+// the prefixes advertised to them, along with the EVPN routes exported from
+// its VRF if withEVPN is set.
+func (r *router) subset(neighbors map[string]bool, withEVPN bool) *router {
+	res := &router{
+		myASN:          r.myASN,
+		routerID:       r.routerID,
+		neighbors:      make(map[string]frrv1beta1.Neighbor),
+		vrf:            r.vrf,
+		prefixes:       make(map[string]string),
+		advertisements: make(map[string][]*bgp.Advertisement),
+		extras:         make(map[string]*metallbconfig.NeighborExtras),
+		toReceive:      make(map[string]*metallbconfig.Receive),
+		zones:          make(map[string]string),
+		evpnNeighbors:  make(map[string]bool),
+	}
+	for name := range neighbors {
+		neighbor, ok := r.neighbors[name]
+		if !ok {
+			continue
+		}
+		res.neighbors[name] = neighbor
+		for _, p := range neighbor.ToAdvertise.Allowed.Prefixes {
+			res.prefixes[p] = p
+		}
+		if ads, ok := r.advertisements[name]; ok {
+			res.advertisements[name] = ads
+			// The prefixes advertised through raw configuration are
+			// not part of the ones allowed by the neighbor.
+			for _, adv := range ads {
+				res.prefixes[adv.Prefix.String()] = adv.Prefix.String()
+			}
+		}
+		if e, ok := r.extras[name]; ok {
+			res.extras[name] = e
+		}
+		if rcv, ok := r.toReceive[name]; ok {
+			res.toReceive[name] = rcv
+		}
+		if z, ok := r.zones[name]; ok {
+			res.zones[name] = z
+		}
+		if r.evpnNeighbors[name] {
+			res.evpnNeighbors[name] = true
+		}
+	}
+	if withEVPN && r.evpn != nil {
+		res.evpn = r.evpn
+		for _, p := range slices.Concat(r.evpn.PrefixesV4, r.evpn.PrefixesV6) {
+			res.prefixes[p] = p
+		}
 	}
+	return res
+}
 
+// render fills the configuration with the given routers, and with the node
+// wide settings (the BFD profiles, the prefix lists, the bestpath knobs and
+// the raw configuration of the extras) if withNodeSettings is set.
+func (sm *sessionManager) render(config *frrv1beta1.FRRConfiguration, routers map[string]*router, withNodeSettings bool) error {
+	var raw strings.Builder
+	var bestPath []string
+	if withNodeSettings {
+		for _, pl := range sm.extras.PrefixLists {
+			for _, e := range frr.PrefixListFor(pl).Entries {
+				fmt.Fprintf(&raw, "%s prefix-list %s seq %d %s %s%s\n", e.IPFamily, pl.Name, e.Seq, e.Action, e.Prefix, e.Matcher())
+			}
+		}
+		bestPath = frr.BestPathCommands(sm.extras.BestPath)
+	}
 	for _, r := range sortMap(routers) {
+		for _, name := range slices.Sorted(maps.Keys(r.advertisements)) {
+			conditional, err := frr.ConditionalAdvertisementFor(r.advertisements[name])
+			if err != nil {
+				return fmt.Errorf("neighbor %s: %w", name, err)
+			}
+			if conditional != nil {
+				writeConditionalAdvertisement(&raw, r.myASN, r.vrf, r.neighbors[name], conditional)
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(r.advertisements)) {
+			var export []metallbconfig.PolicyEntry
+			if e, ok := r.extras[name]; ok {
+				export = e.Export
+			}
+			writeOutgoingPolicy(&raw, r.myASN, r.vrf, r.neighbors[name], r.advertisements[name], export)
+		}
+		for _, name := range slices.Sorted(maps.Keys(r.toReceive)) {
+			writeIncomingPolicy(&raw, r.vrf, r.neighbors[name], r.toReceive[name], r.extras[name].Import)
+		}
+		writeRouterExtras(&raw, r.myASN, r.vrf, bestPath, r.neighbors, r.extras, r.zones)
+		writeEVPN(&raw, r.myASN, r.vrf, r.neighbors, r.evpnNeighbors, r.evpn)
 		toAdd := frrv1beta1.Router{
 			ASN:       r.myASN,
 			ID:        r.routerID,
@@ -337,9 +623,20 @@ func (sm *sessionManager) updateConfig() error {
 			Neighbors: sortMap(r.neighbors),
 			Prefixes:  sortMap(r.prefixes),
 		}
-		newConfig.Spec.BGP.Routers = append(newConfig.Spec.BGP.Routers, toAdd)
+		config.Spec.BGP.Routers = append(config.Spec.BGP.Routers, toAdd)
+	}
+
+	if !withNodeSettings {
+		config.Spec.Raw.Config = raw.String()
+		return nil
 	}
 
+	if sm.extras.Raw != "" {
+		raw.WriteString(sm.extras.Raw)
+		raw.WriteString("\n")
+	}
+	config.Spec.Raw.Config = raw.String()
+
 	for _, bfd := range sm.bfdProfiles {
 		toAdd := frrv1beta1.BFDProfile{
 			Name:             bfd.Name,
@@ -352,18 +649,398 @@ func (sm *sessionManager) updateConfig() error {
 			PassiveMode:      &bfd.PassiveMode,
 		}
 
-		newConfig.Spec.BGP.BFDProfiles = append(newConfig.Spec.BGP.BFDProfiles, toAdd)
+		config.Spec.BGP.BFDProfiles = append(config.Spec.BGP.BFDProfiles, toAdd)
 	}
-	sort.Slice(newConfig.Spec.BGP.BFDProfiles, func(i, j int) bool {
-		return newConfig.Spec.BGP.BFDProfiles[i].Name < newConfig.Spec.BGP.BFDProfiles[j].Name
+	sort.Slice(config.Spec.BGP.BFDProfiles, func(i, j int) bool {
+		return config.Spec.BGP.BFDProfiles[i].Name < config.Spec.BGP.BFDProfiles[j].Name
 	})
+	return nil
+}
 
-	sm.configChangedCallback(newConfig)
-	if sm.logLevel == logging.LevelDebug {
-		sm.dumpConfig(newConfig)
+// writeConditionalAdvertisement renders the conditional advertisement of the
+// neighbor as raw FRR configuration, as the FRR-K8s API can't express it.
+func writeConditionalAdvertisement(b *strings.Builder, asn uint32, vrf string, neighbor frrv1beta1.Neighbor, c *frr.ConditionalAdvertisement) {
+	peer := neighbor.Address
+	if neighbor.Interface != "" {
+		peer = neighbor.Interface
+	}
+	id := "metallb-" + peer
+	if vrf != "" {
+		id += "-" + vrf
 	}
+	advertiseMap := id + "-advertise"
+	conditionMap := id + "-condition"
 
-	return nil
+	writePrefixList := func(family, name string, prefixes []string) {
+		if len(prefixes) == 0 {
+			fmt.Fprintf(b, "%s prefix-list %s seq 1 deny any\n", family, name)
+			return
+		}
+		for i, p := range prefixes {
+			fmt.Fprintf(b, "%s prefix-list %s seq %d permit %s\n", family, name, i+1, p)
+		}
+	}
+	writePrefixList("ip", id+"-conditional-ipv4", c.PrefixesV4)
+	writePrefixList("ipv6", id+"-conditional-ipv6", c.PrefixesV6)
+	fmt.Fprintf(b, "route-map %s permit 1\n  match ip address prefix-list %s-conditional-ipv4\n", advertiseMap, id)
+	fmt.Fprintf(b, "route-map %s permit 2\n  match ipv6 address prefix-list %s-conditional-ipv6\n", advertiseMap, id)
+
+	writePrefixList("ip", id+"-tracked-ipv4", c.TrackedV4)
+	writePrefixList("ipv6", id+"-tracked-ipv6", c.TrackedV6)
+	seq := 1
+	writeCondition := func(match string) {
+		fmt.Fprintf(b, "route-map %s permit %d\n  match ip address prefix-list %s-tracked-ipv4\n%s", conditionMap, seq, id, match)
+		fmt.Fprintf(b, "route-map %s permit %d\n  match ipv6 address prefix-list %s-tracked-ipv6\n%s", conditionMap, seq+1, id, match)
+		seq += 2
+	}
+	if len(c.Peers) == 0 {
+		writeCondition("")
+	}
+	for _, p := range c.Peers {
+		writeCondition(fmt.Sprintf("  match peer %s\n", p))
+	}
+
+	if vrf != "" {
+		fmt.Fprintf(b, "router bgp %d vrf %s\n", asn, vrf)
+	} else {
+		fmt.Fprintf(b, "router bgp %d\n", asn)
+	}
+	for _, f := range neighborFamilies(neighbor) {
+		fmt.Fprintf(b, "  address-family %s unicast\n", f)
+		fmt.Fprintf(b, "    neighbor %s advertise-map %s %s %s\n", peer, advertiseMap, c.MapType(), conditionMap)
+		b.WriteString("  exit-address-family\n")
+	}
+	b.WriteString("exit\n")
+}
+
+// rawPolicySeq is the sequence number of the first entry added to the
+// outgoing route-map FRR-K8s generates for a neighbor, far enough from the
+// ones FRR-K8s numbers from 1.
+const rawPolicySeq = 60000
+
+// needsRawPolicy tells if the advertisement carries attributes the FRR-K8s
+// API can't express. Its prefix is then left out of the toAdvertise of the
+// neighbor, and advertised through raw configuration instead.
+func needsRawPolicy(adv *bgp.Advertisement) bool {
+	return adv.MED != nil || adv.ASPathPrepend != 0 ||
+		slices.ContainsFunc(adv.Communities, community.IsExtended)
+}
+
+// rawPolicyPrefixes returns the prefixes of the advertisements that need a
+// raw policy, all of them if the neighbor has an export policy which can only
+// be called from the raw entries. The same prefix may come from several
+// advertisements, in which case all of them are rendered as raw
+// configuration, as the entries FRR-K8s generates for the prefix would
+// otherwise match first.
+func rawPolicyPrefixes(ads []*bgp.Advertisement, all bool) sets.Set[string] {
+	res := sets.New[string]()
+	for _, adv := range ads {
+		if all || needsRawPolicy(adv) {
+			res.Insert(adv.Prefix.String())
+		}
+	}
+	return res
+}
+
+// writeOutgoingPolicy renders as raw FRR configuration the advertisements to
+// the neighbor that need it. Their prefixes are permitted, with all their
+// attributes set, by entries appended to the outgoing route-map FRR-K8s
+// generates for the neighbor, which doesn't match them. The entries
+// permitting the prefixes call the export policy of the neighbor, if any.
+func writeOutgoingPolicy(b *strings.Builder, asn uint32, vrf string, neighbor frrv1beta1.Neighbor, ads []*bgp.Advertisement, export []metallbconfig.PolicyEntry) {
+	routeMap := routeMapID(neighbor, vrf) + "-out"
+	id := "metallb-" + routeMap
+	exportMap := "metallb-" + routeMapID(neighbor, vrf) + "-export"
+
+	type modifier struct {
+		family   string
+		set      string
+		prefixes sets.Set[string]
+	}
+	modifiers := map[string]*modifier{}
+	allowed := map[string]sets.Set[string]{"ip": sets.New[string](), "ipv6": sets.New[string]()}
+	// The same prefix may be advertised with different MEDs, the lowest
+	// wins, and with different counts, the highest wins.
+	meds := map[string]uint32{}
+	asPathPrepends := map[string]uint32{}
+	addModifier := func(name, family, set, prefix string) {
+		name = fmt.Sprintf("%s-%s-%s", id, name, family)
+		m, ok := modifiers[name]
+		if !ok {
+			m = &modifier{family: family, set: set, prefixes: sets.New[string]()}
+			modifiers[name] = m
+		}
+		m.prefixes.Insert(prefix)
+	}
+	rawPrefixes := rawPolicyPrefixes(ads, len(export) > 0)
+	for _, adv := range ads {
+		prefix := adv.Prefix.String()
+		if !rawPrefixes.Has(prefix) {
+			continue
+		}
+		family := "ip"
+		if adv.Prefix.IP.To4() == nil {
+			family = "ipv6"
+		}
+		allowed[family].Insert(prefix)
+		for _, c := range adv.Communities {
+			name := c.String()
+			if community.IsLarge(c) {
+				name = "large:" + name
+			}
+			addModifier(name+"-community", family, frr.CommunityPrefixList{Community: c}.SetStatement(), prefix)
+		}
+		if adv.LocalPref != 0 {
+			addModifier(fmt.Sprintf("%d-localpref", adv.LocalPref), family, fmt.Sprintf("set local-preference %d", adv.LocalPref), prefix)
+		}
+		if adv.MED != nil {
+			if med, ok := meds[prefix]; ok {
+				meds[prefix] = *bgp.LowestMED(&med, adv.MED)
+			} else {
+				meds[prefix] = *adv.MED
+			}
+		}
+		if adv.ASPathPrepend != 0 {
+			asPathPrepends[prefix] = max(asPathPrepends[prefix], adv.ASPathPrepend)
+		}
+	}
+	for prefix, med := range meds {
+		family := "ip"
+		if ip, _, _ := net.ParseCIDR(prefix); ip.To4() == nil {
+			family = "ipv6"
+		}
+		addModifier(fmt.Sprintf("%d-med", med), family, frr.MEDPrefixList{MED: med}.SetStatement(), prefix)
+	}
+	for prefix, count := range asPathPrepends {
+		family := "ip"
+		if ip, _, _ := net.ParseCIDR(prefix); ip.To4() == nil {
+			family = "ipv6"
+		}
+		set := frr.ASPathPrependPrefixList{ASN: asn, Count: count}.SetStatement()
+		addModifier(fmt.Sprintf("%d-aspath", count), family, set, prefix)
+	}
+
+	seq := rawPolicySeq
+	for _, name := range slices.Sorted(maps.Keys(modifiers)) {
+		m := modifiers[name]
+		for i, p := range sets.List(m.prefixes) {
+			fmt.Fprintf(b, "%s prefix-list %s seq %d permit %s\n", m.family, name, i+1, p)
+		}
+		fmt.Fprintf(b, "route-map %s permit %d\n  match %s address prefix-list %s\n  %s\n  on-match next\n", routeMap, seq, m.family, name, m.set)
+		seq++
+	}
+	for _, family := range []string{"ip", "ipv6"} {
+		if allowed[family].Len() == 0 {
+			continue
+		}
+		name := fmt.Sprintf("%s-allowed-%s", id, family)
+		for i, p := range sets.List(allowed[family]) {
+			fmt.Fprintf(b, "%s prefix-list %s seq %d permit %s\n", family, name, i+1, p)
+		}
+		fmt.Fprintf(b, "route-map %s permit %d\n  match %s address prefix-list %s\n", routeMap, seq, family, name)
+		if len(export) > 0 {
+			fmt.Fprintf(b, "  call %s\n", exportMap)
+		}
+		seq++
+	}
+	writePolicy(b, exportMap, export)
+}
+
+// writeIncomingPolicy renders as raw FRR configuration the prefixes accepted
+// from a neighbor with an import policy. They are permitted by entries
+// appended to the incoming route-map FRR-K8s generates for the neighbor,
+// which denies all of them, and calling the import policy.
+func writeIncomingPolicy(b *strings.Builder, vrf string, neighbor frrv1beta1.Neighbor, toReceive *metallbconfig.Receive, policy []metallbconfig.PolicyEntry) {
+	routeMap := routeMapID(neighbor, vrf) + "-in"
+	importMap := "metallb-" + routeMapID(neighbor, vrf) + "-import"
+
+	seq := rawPolicySeq
+	for _, family := range []ipfamily.Family{ipfamily.IPv4, ipfamily.IPv6} {
+		frrFamily := "ip"
+		if family == ipfamily.IPv6 {
+			frrFamily = "ipv6"
+		}
+		name := fmt.Sprintf("metallb-%s-allowed-%s", routeMap, frrFamily)
+		var entries []string
+		switch {
+		case toReceive == nil:
+		case toReceive.All:
+			entries = append(entries, "any")
+		default:
+			for _, p := range toReceive.Prefixes {
+				if ipfamily.ForAddress(p.Prefix.IP) != family {
+					continue
+				}
+				prefix := frr.IncomingPrefix{Prefix: p.Prefix.String(), LE: p.LE, GE: p.GE}
+				entries = append(entries, prefix.Prefix+prefix.Matcher())
+			}
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		for i, e := range entries {
+			fmt.Fprintf(b, "%s prefix-list %s seq %d permit %s\n", frrFamily, name, i+1, e)
+		}
+		fmt.Fprintf(b, "route-map %s permit %d\n  match %s address prefix-list %s\n  call %s\n", routeMap, seq, frrFamily, name, importMap)
+		seq++
+	}
+	writePolicy(b, importMap, policy)
+}
+
+// writePolicy renders the route-map holding the given policy.
+func writePolicy(b *strings.Builder, name string, policy []metallbconfig.PolicyEntry) {
+	for _, e := range frr.RouteMapEntries(policy) {
+		fmt.Fprintf(b, "route-map %s %s %d\n", name, e.Action, e.Seq)
+		if e.Match != "" {
+			fmt.Fprintf(b, "  %s\n", e.Match)
+		}
+		for _, s := range e.Sets {
+			fmt.Fprintf(b, "  %s\n", s)
+		}
+		if e.OnMatchNext {
+			b.WriteString("  on-match next\n")
+		}
+	}
+}
+
+// routeMapID returns the name FRR-K8s gives to the route-maps of the
+// neighbor, before their direction: its address or interface, and its VRF.
+func routeMapID(neighbor frrv1beta1.Neighbor, vrf string) string {
+	res := neighbor.Address
+	if neighbor.Interface != "" {
+		res = neighbor.Interface
+	}
+	if vrf != "" {
+		res += "-" + vrf
+	}
+	return res
+}
+
+// writeRouterExtras renders the bestpath knobs of the router, the interfaces
+// its link-local neighbors are reached through and the extras of its
+// neighbors that the FRR-K8s API can't express as raw FRR configuration.
+func writeRouterExtras(b *strings.Builder, asn uint32, vrf string, bestPath []string, neighbors map[string]frrv1beta1.Neighbor, extras map[string]*metallbconfig.NeighborExtras, zones map[string]string) {
+	var neighborLines strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(zones)) {
+		if neighbor, ok := neighbors[name]; ok {
+			fmt.Fprintf(&neighborLines, "  neighbor %s interface %s\n", neighbor.Address, zones[name])
+		}
+	}
+	var families strings.Builder
+	for _, f := range []ipfamily.Family{ipfamily.IPv4, ipfamily.IPv6} {
+		var lines strings.Builder
+		for _, name := range slices.Sorted(maps.Keys(extras)) {
+			neighbor := neighbors[name]
+			if !slices.Contains(neighborFamilies(neighbor), f) {
+				continue
+			}
+			peer := neighbor.Address
+			if neighbor.Interface != "" {
+				peer = neighbor.Interface
+			}
+			if e := extras[name]; e.MaximumPrefix != nil {
+				fmt.Fprintf(&lines, "    neighbor %s maximum-prefix %d\n", peer, *e.MaximumPrefix)
+			}
+			if e := extras[name]; e.AllowASIn != nil {
+				fmt.Fprintf(&lines, "    neighbor %s allowas-in %d\n", peer, *e.AllowASIn)
+			}
+		}
+		if lines.Len() > 0 {
+			fmt.Fprintf(&families, "  address-family %s unicast\n%s  exit-address-family\n", f, lines.String())
+		}
+	}
+	if len(bestPath) == 0 && neighborLines.Len() == 0 && families.Len() == 0 {
+		return
+	}
+
+	if vrf != "" {
+		fmt.Fprintf(b, "router bgp %d vrf %s\n", asn, vrf)
+	} else {
+		fmt.Fprintf(b, "router bgp %d\n", asn)
+	}
+	for _, c := range bestPath {
+		fmt.Fprintf(b, "  %s\n", c)
+	}
+	b.WriteString(neighborLines.String())
+	b.WriteString(families.String())
+	b.WriteString("exit\n")
+}
+
+// writeEVPN renders the EVPN configuration of the router as raw FRR
+// configuration, as the FRR-K8s API can't express it: the neighbors the
+// EVPN routes are sent to, and the routes exported from the VRF of the
+// router along with the L3VNI it is bound to.
+func writeEVPN(b *strings.Builder, asn uint32, vrf string, neighbors map[string]frrv1beta1.Neighbor, evpnNeighbors map[string]bool, e *frr.EVPNExport) {
+	if len(evpnNeighbors) == 0 && e == nil {
+		return
+	}
+
+	if e != nil {
+		fmt.Fprintf(b, "vrf %s\n  vni %d\nexit-vrf\n", vrf, e.VNI)
+		for i, p := range e.PrefixesV4 {
+			fmt.Fprintf(b, "ip prefix-list %s-ipv4 seq %d permit %s\n", e.RouteMap, i+1, p)
+		}
+		for i, p := range e.PrefixesV6 {
+			fmt.Fprintf(b, "ipv6 prefix-list %s-ipv6 seq %d permit %s\n", e.RouteMap, i+1, p)
+		}
+		if len(e.PrefixesV4) > 0 {
+			fmt.Fprintf(b, "route-map %s permit 1\n  match ip address prefix-list %s-ipv4\n", e.RouteMap, e.RouteMap)
+		}
+		if len(e.PrefixesV6) > 0 {
+			fmt.Fprintf(b, "route-map %s permit 2\n  match ipv6 address prefix-list %s-ipv6\n", e.RouteMap, e.RouteMap)
+		}
+	}
+
+	if vrf != "" {
+		fmt.Fprintf(b, "router bgp %d vrf %s\n", asn, vrf)
+	} else {
+		fmt.Fprintf(b, "router bgp %d\n", asn)
+	}
+	b.WriteString("  address-family l2vpn evpn\n")
+	for _, name := range slices.Sorted(maps.Keys(evpnNeighbors)) {
+		neighbor := neighbors[name]
+		peer := neighbor.Address
+		if neighbor.Interface != "" {
+			peer = neighbor.Interface
+		}
+		fmt.Fprintf(b, "    neighbor %s activate\n", peer)
+	}
+	if len(evpnNeighbors) > 0 {
+		b.WriteString("    advertise-all-vni\n")
+	}
+	if e != nil {
+		if len(e.PrefixesV4) > 0 {
+			fmt.Fprintf(b, "    advertise ipv4 unicast route-map %s\n", e.RouteMap)
+		}
+		if len(e.PrefixesV6) > 0 {
+			fmt.Fprintf(b, "    advertise ipv6 unicast route-map %s\n", e.RouteMap)
+		}
+		if e.RouteDistinguisher != "" {
+			fmt.Fprintf(b, "    rd %s\n", e.RouteDistinguisher)
+		}
+		for _, rt := range e.ImportRouteTargets {
+			fmt.Fprintf(b, "    route-target import %s\n", rt)
+		}
+		for _, rt := range e.ExportRouteTargets {
+			fmt.Fprintf(b, "    route-target export %s\n", rt)
+		}
+	}
+	b.WriteString("  exit-address-family\nexit\n")
+}
+
+// neighborFamilies returns the address families enabled for the neighbor.
+func neighborFamilies(neighbor frrv1beta1.Neighbor) []ipfamily.Family {
+	family := ipfamily.ForAddress(net.ParseIP(neighbor.Address))
+	if neighbor.Interface != "" || neighbor.DualStackAddressFamily {
+		family = ipfamily.DualStack
+	}
+	var res []ipfamily.Family
+	for _, f := range []ipfamily.Family{ipfamily.IPv4, ipfamily.IPv6} {
+		if family == f || family == ipfamily.DualStack {
+			res = append(res, f)
+		}
+	}
+	return res
 }
 
 func (sm *sessionManager) dumpConfig(config frrv1beta1.FRRConfiguration) {
@@ -374,6 +1051,26 @@ func (sm *sessionManager) dumpConfig(config frrv1beta1.FRRConfiguration) {
 	level.Debug(sm.logger).Log("component", "frrk8s", "event", "sent new config to the controller", "config", toDump)
 }
 
+// toReceive translates the prefixes accepted from the peer, all of them
+// being denied when nil.
+func toReceive(r *metallbconfig.Receive) frrv1beta1.Receive {
+	if r == nil {
+		return frrv1beta1.Receive{}
+	}
+	if r.All {
+		return frrv1beta1.Receive{Allowed: frrv1beta1.AllowedInPrefixes{Mode: frrv1beta1.AllowAll}}
+	}
+	res := frrv1beta1.Receive{Allowed: frrv1beta1.AllowedInPrefixes{Mode: frrv1beta1.AllowRestricted}}
+	for _, p := range r.Prefixes {
+		res.Allowed.Prefixes = append(res.Allowed.Prefixes, frrv1beta1.PrefixSelector{
+			Prefix: p.Prefix.String(),
+			LE:     p.LE,
+			GE:     p.GE,
+		})
+	}
+	return res
+}
+
 func toAdvertiseWithCommunity(prefixesForCommunity map[string][]string) []frrv1beta1.CommunityPrefixes {
 	res := []frrv1beta1.CommunityPrefixes{}
 	for c, prefixes := range prefixesForCommunity {
@@ -411,11 +1108,16 @@ func toAdvertiseWithLocalPref(prefixesForLocalPref map[uint32][]string) []frrv1b
 	return res
 }
 
-func NewSessionManager(l log.Logger, logLevel logging.Level, node, namespace string) bgp.SessionManager {
+// NewSessionManager returns a session manager producing the FRRConfigurations
+// of the node. When perPeer is set, each peer is configured by its own
+// FRRConfiguration, next to the node wide one.
+func NewSessionManager(l log.Logger, logLevel logging.Level, node, namespace string, perPeer bool) bgp.SessionManager {
 	res := &sessionManager{
 		sessions:        map[string]*session{},
+		sessionStates:   map[string]string{},
 		nodeToConfigure: node,
 		targetNamespace: namespace,
+		configPerPeer:   perPeer,
 		logger:          l,
 		logLevel:        logLevel,
 	}
@@ -423,10 +1125,47 @@ func NewSessionManager(l log.Logger, logLevel logging.Level, node, namespace str
 	return res
 }
 
+// The labels of the FRRConfigurations scoped to a single peer. Their values
+// are the names of the node and of the peer, as returned by LabelValue.
+const (
+	NodeLabel = "metallb.io/node"
+	PeerLabel = "metallb.io/peer"
+)
+
+// PeerAnnotation holds the name of the peer the FRRConfiguration is scoped
+// to, as the value of PeerLabel may be hashed.
+const PeerAnnotation = "metallb.io/peer-name"
+
+// maxLabelValueLength is the maximum length of a label value.
+const maxLabelValueLength = 63
+
 func ConfigName(node string) string {
 	return "metallb-" + node
 }
 
+// PeerConfigName returns the name of the FRRConfiguration of the given peer
+// on the node. The peer is identified by a hash of both names, as joining
+// them would make different node / peer pairs collide.
+func PeerConfigName(node, peer string) string {
+	return ConfigName(node) + "-" + hash(node+"/"+peer)
+}
+
+// LabelValue returns the given name if it fits in a label value, otherwise
+// its head followed by its hash.
+func LabelValue(name string) string {
+	if len(name) <= maxLabelValueLength {
+		return name
+	}
+	h := hash(name)
+	return name[:maxLabelValueLength-len(h)-1] + "-" + h
+}
+
+// hash returns a short hex encoded hash of the given string.
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
+
 func sortMap[T any](toSort map[string]T) []T {
 	keys := make([]string, 0)
 	for k := range toSort {
@@ -12,6 +12,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -19,6 +20,7 @@ import (
 	frrv1beta1 "github.com/metallb/frr-k8s/api/v1beta1"
 	"go.universe.tf/metallb/internal/bgp"
 	"go.universe.tf/metallb/internal/bgp/community"
+	metallbconfig "go.universe.tf/metallb/internal/config"
 	"go.universe.tf/metallb/internal/logging"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -92,7 +94,7 @@ func validateGoldenFile(t *testing.T) {
 
 func newTestSessionManager(t *testing.T) bgp.SessionManager {
 	l := log.NewNopLogger()
-	sessionManager := NewSessionManager(l, logging.LevelDebug, testNodeName, testNamespace)
+	sessionManager := NewSessionManager(l, logging.LevelDebug, testNodeName, testNamespace, false)
 	configFile, _ := testGenerateFileNames(t)
 	sessionManager.SetEventCallback(func(config interface{}) {
 		frrConfig, ok := config.(frrv1beta1.FRRConfiguration)
@@ -167,6 +169,225 @@ func TestSingleEBGPSessionMultiHop(t *testing.T) {
 	testCheckConfigFile(t)
 }
 
+func TestSingleEBGPSessionToReceive(t *testing.T) {
+	sessionManager := newTestSessionManager(t)
+	l := log.NewNopLogger()
+
+	_, defaultRoute, _ := net.ParseCIDR("0.0.0.0/0")
+	_, prefix, _ := net.ParseCIDR("192.168.0.0/16")
+	session, err := sessionManager.NewSession(l,
+		bgp.SessionParameters{
+			PeerAddress:   "10.2.2.254",
+			PeerPort:      179,
+			SourceAddress: net.ParseIP("10.1.1.254"),
+			MyASN:         100,
+			RouterID:      net.ParseIP("10.1.1.254"),
+			PeerASN:       200,
+			HoldTime:      ptr.To(time.Second),
+			KeepAliveTime: ptr.To(time.Second),
+			CurrentNode:   "hostname",
+			ToReceive: &metallbconfig.Receive{
+				Prefixes: []metallbconfig.PrefixSelector{
+					{Prefix: defaultRoute},
+					{Prefix: prefix, GE: 24, LE: 28},
+				},
+			},
+			SessionName: "test-peer"})
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err)
+	}
+	defer session.Close()
+
+	testCheckConfigFile(t)
+}
+
+func TestConditionalAdvertisement(t *testing.T) {
+	sessionManager := newTestSessionManager(t)
+	l := log.NewNopLogger()
+
+	primary, err := sessionManager.NewSession(l,
+		bgp.SessionParameters{
+			PeerAddress:   "10.2.2.253",
+			PeerPort:      179,
+			SourceAddress: net.ParseIP("10.1.1.254"),
+			MyASN:         100,
+			RouterID:      net.ParseIP("10.1.1.254"),
+			PeerASN:       200,
+			CurrentNode:   "hostname",
+			ToReceive:     &metallbconfig.Receive{All: true},
+			SessionName:   "primary"})
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err)
+	}
+	defer primary.Close()
+
+	backup, err := sessionManager.NewSession(l,
+		bgp.SessionParameters{
+			PeerAddress:            "10.2.2.254",
+			PeerPort:               179,
+			SourceAddress:          net.ParseIP("10.1.1.254"),
+			MyASN:                  100,
+			RouterID:               net.ParseIP("10.1.1.254"),
+			PeerASN:                200,
+			CurrentNode:            "hostname",
+			DualStackAddressFamily: true,
+			SessionName:            "backup"})
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err)
+	}
+	defer backup.Close()
+
+	_, prefix1, _ := net.ParseCIDR("172.16.1.10/32")
+	_, prefix2, _ := net.ParseCIDR("2001:db8::10/128")
+	whenPrimaryDown := &bgp.Condition{
+		NonExist: true,
+		Peers:    []string{"10.2.2.253"},
+	}
+	err = primary.Set(&bgp.Advertisement{Prefix: prefix1})
+	if err != nil {
+		t.Fatalf("Could not advertise prefix: %s", err)
+	}
+	err = backup.Set(
+		&bgp.Advertisement{Prefix: prefix1, Condition: whenPrimaryDown},
+		&bgp.Advertisement{Prefix: prefix2, Condition: whenPrimaryDown},
+	)
+	if err != nil {
+		t.Fatalf("Could not advertise prefix: %s", err)
+	}
+
+	testCheckConfigFile(t)
+}
+
+func TestRawExtras(t *testing.T) {
+	sessionManager := newTestSessionManager(t)
+	l := log.NewNopLogger()
+
+	// The raw extras, from the bgpextras ConfigMap or the BGPExtras, are
+	// appended verbatim to the raw configuration of the node.
+	err := sessionManager.SyncExtraInfo(metallbconfig.BGPExtras{
+		Raw: "router bgp 100\n  neighbor 10.2.2.254 description tor\nexit",
+	})
+	if err != nil {
+		t.Fatalf("Could not sync extra info: %s", err)
+	}
+
+	session, err := sessionManager.NewSession(l,
+		bgp.SessionParameters{
+			PeerAddress: "10.2.2.254",
+			PeerPort:    179,
+			MyASN:       100,
+			PeerASN:     200,
+			CurrentNode: "hostname",
+			SessionName: "test-peer"})
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err)
+	}
+	defer session.Close()
+
+	testCheckConfigFile(t)
+}
+
+func TestSessionExtras(t *testing.T) {
+	sessionManager := newTestSessionManager(t)
+	l := log.NewNopLogger()
+
+	err := sessionManager.SyncExtraInfo(metallbconfig.BGPExtras{
+		Raw:      "# hello",
+		BestPath: &metallbconfig.BestPath{ASPathMultipathRelax: true},
+		PrefixLists: []*metallbconfig.PrefixList{
+			{
+				Name: "tenants",
+				Entries: []metallbconfig.PrefixListEntry{
+					{PrefixSelector: metallbconfig.PrefixSelector{Prefix: &net.IPNet{IP: net.ParseIP("10.0.0.0").To4(), Mask: net.CIDRMask(8, 32)}, LE: 32}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Could not sync extra info: %s", err)
+	}
+
+	session, err := sessionManager.NewSession(l,
+		bgp.SessionParameters{
+			PeerAddress:            "10.2.2.254",
+			PeerPort:               179,
+			SourceAddress:          net.ParseIP("10.1.1.254"),
+			MyASN:                  100,
+			RouterID:               net.ParseIP("10.1.1.254"),
+			PeerASN:                200,
+			CurrentNode:            "hostname",
+			DualStackAddressFamily: true,
+			Extras: &metallbconfig.NeighborExtras{
+				MaximumPrefix: ptr.To(uint32(100)),
+				AllowASIn:     ptr.To(uint32(2)),
+			},
+			SessionName: "test-peer"})
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err)
+	}
+	defer session.Close()
+
+	testCheckConfigFile(t)
+}
+
+func TestSessionPolicies(t *testing.T) {
+	sessionManager := newTestSessionManager(t)
+	l := log.NewNopLogger()
+
+	tenants := &metallbconfig.PrefixList{
+		Name: "tenants",
+		Entries: []metallbconfig.PrefixListEntry{
+			{PrefixSelector: metallbconfig.PrefixSelector{Prefix: &net.IPNet{IP: net.ParseIP("10.1.0.0").To4(), Mask: net.CIDRMask(16, 32)}, LE: 32}},
+		},
+	}
+	err := sessionManager.SyncExtraInfo(metallbconfig.BGPExtras{
+		PrefixLists: []*metallbconfig.PrefixList{tenants},
+	})
+	if err != nil {
+		t.Fatalf("Could not sync extra info: %s", err)
+	}
+
+	standardCommunity, _ := community.New("1111:2222")
+	session, err := sessionManager.NewSession(l,
+		bgp.SessionParameters{
+			PeerAddress:   "10.2.2.254",
+			PeerPort:      179,
+			SourceAddress: net.ParseIP("10.1.1.254"),
+			MyASN:         100,
+			RouterID:      net.ParseIP("10.1.1.254"),
+			PeerASN:       200,
+			CurrentNode:   "hostname",
+			ToReceive: &metallbconfig.Receive{
+				Prefixes: []metallbconfig.PrefixSelector{
+					{Prefix: &net.IPNet{IP: net.ParseIP("10.0.0.0").To4(), Mask: net.CIDRMask(8, 32)}, LE: 32},
+				},
+			},
+			Extras: &metallbconfig.NeighborExtras{
+				Import: []metallbconfig.PolicyEntry{
+					{Deny: true, PrefixList: tenants},
+					{LocalPref: ptr.To(uint32(200))},
+				},
+				Export: []metallbconfig.PolicyEntry{
+					{Metric: ptr.To(uint32(10)), Communities: []community.BGPCommunity{standardCommunity}},
+				},
+			},
+			SessionName: "test-peer"})
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err)
+	}
+	defer session.Close()
+
+	err = session.Set(&bgp.Advertisement{
+		Prefix:    &net.IPNet{IP: net.ParseIP("172.16.1.10"), Mask: net.CIDRMask(32, 32)},
+		LocalPref: 300,
+	})
+	if err != nil {
+		t.Fatalf("Could not advertise prefix: %s", err)
+	}
+
+	testCheckConfigFile(t)
+}
+
 func TestSingleEBGPSessionOneHop(t *testing.T) {
 	l := log.NewNopLogger()
 	sessionManager := newTestSessionManager(t)
@@ -913,6 +1134,205 @@ func TestLargeCommunities(t *testing.T) {
 	testCheckConfigFile(t)
 }
 
+func TestExtendedCommunities(t *testing.T) {
+	l := log.NewNopLogger()
+	sessionManager := newTestSessionManager(t)
+
+	session, err := sessionManager.NewSession(l,
+		bgp.SessionParameters{
+			PeerAddress:   "10.2.2.254",
+			PeerPort:      179,
+			SourceAddress: net.ParseIP("10.1.1.254"),
+			MyASN:         100,
+			RouterID:      net.ParseIP("10.1.1.254"),
+			PeerASN:       200,
+			HoldTime:      ptr.To(time.Second),
+			KeepAliveTime: ptr.To(time.Second),
+			Password:      "password",
+			CurrentNode:   "hostname",
+			EBGPMultiHop:  true,
+			SessionName:   "test-peer"})
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err)
+	}
+	defer session.Close()
+
+	prefix := &net.IPNet{
+		IP:   net.ParseIP("172.16.1.10"),
+		Mask: classCMask,
+	}
+	communities := []community.BGPCommunity{}
+	community1, _ := community.New("target:1111:2222")
+	communities = append(communities, community1)
+	community2, _ := community.New("bandwidth:1000")
+	communities = append(communities, community2)
+	community3, _ := community.New("3333:4444")
+	communities = append(communities, community3)
+	adv := &bgp.Advertisement{
+		Prefix:      prefix,
+		Communities: communities,
+		LocalPref:   300,
+	}
+
+	err = session.Set(adv)
+	if err != nil {
+		t.Fatalf("Could not advertise prefix: %s", err)
+	}
+
+	testCheckConfigFile(t)
+}
+
+func TestASPathPrepend(t *testing.T) {
+	l := log.NewNopLogger()
+	sessionManager := newTestSessionManager(t)
+
+	session, err := sessionManager.NewSession(l,
+		bgp.SessionParameters{
+			PeerAddress:   "10.2.2.254",
+			PeerPort:      179,
+			SourceAddress: net.ParseIP("10.1.1.254"),
+			MyASN:         100,
+			RouterID:      net.ParseIP("10.1.1.254"),
+			PeerASN:       200,
+			CurrentNode:   "hostname",
+			SessionName:   "test-peer"})
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err)
+	}
+	defer session.Close()
+
+	community1, _ := community.New("1111:2222")
+	adv1 := &bgp.Advertisement{
+		Prefix:        &net.IPNet{IP: net.ParseIP("172.16.1.10"), Mask: classCMask},
+		Communities:   []community.BGPCommunity{community1},
+		ASPathPrepend: 3,
+	}
+	adv2 := &bgp.Advertisement{
+		Prefix: &net.IPNet{IP: net.ParseIP("172.16.2.10"), Mask: classCMask},
+	}
+	// The prefix of an advertisement with AS path prepending is rendered as
+	// raw configuration, even when advertised without it too.
+	adv3 := &bgp.Advertisement{
+		Prefix:        &net.IPNet{IP: net.ParseIP("172.16.2.10"), Mask: classCMask},
+		ASPathPrepend: 2,
+	}
+
+	err = session.Set(adv1, adv2, adv3)
+	if err != nil {
+		t.Fatalf("Could not advertise prefix: %s", err)
+	}
+
+	testCheckConfigFile(t)
+}
+
+func TestMED(t *testing.T) {
+	l := log.NewNopLogger()
+	sessionManager := newTestSessionManager(t)
+
+	session, err := sessionManager.NewSession(l,
+		bgp.SessionParameters{
+			PeerAddress:            "10.2.2.254",
+			PeerPort:               179,
+			SourceAddress:          net.ParseIP("10.1.1.254"),
+			MyASN:                  100,
+			RouterID:               net.ParseIP("10.1.1.254"),
+			PeerASN:                200,
+			CurrentNode:            "hostname",
+			DualStackAddressFamily: true,
+			SessionName:            "test-peer"})
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err)
+	}
+	defer session.Close()
+
+	adv1 := &bgp.Advertisement{
+		Prefix:    &net.IPNet{IP: net.ParseIP("172.16.1.10"), Mask: classCMask},
+		LocalPref: 200,
+		MED:       ptr.To(uint32(10)),
+	}
+	adv2 := &bgp.Advertisement{
+		Prefix: &net.IPNet{IP: net.ParseIP("2001:db8::10"), Mask: net.CIDRMask(128, 128)},
+		MED:    ptr.To(uint32(20)),
+	}
+	// The prefix of an advertisement with a MED is rendered as raw
+	// configuration, even when advertised without it too.
+	adv3 := &bgp.Advertisement{
+		Prefix: &net.IPNet{IP: net.ParseIP("2001:db8::10"), Mask: net.CIDRMask(128, 128)},
+	}
+
+	err = session.Set(adv1, adv2, adv3)
+	if err != nil {
+		t.Fatalf("Could not advertise prefix: %s", err)
+	}
+
+	testCheckConfigFile(t)
+}
+
+func TestRawPolicySharedPrefix(t *testing.T) {
+	l := log.NewNopLogger()
+	sessionManager := newTestSessionManager(t)
+
+	session, err := sessionManager.NewSession(l,
+		bgp.SessionParameters{
+			PeerAddress:   "10.2.2.254",
+			PeerPort:      179,
+			SourceAddress: net.ParseIP("10.1.1.254"),
+			MyASN:         100,
+			RouterID:      net.ParseIP("10.1.1.254"),
+			PeerASN:       200,
+			CurrentNode:   "hostname",
+			SessionName:   "test-peer"})
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err)
+	}
+	defer session.Close()
+
+	// Only one of the advertisements of the prefix needs a raw policy,
+	// both are rendered as raw configuration.
+	community1, _ := community.New("1111:2222")
+	extCommunity, _ := community.New("target:64512:100")
+	adv1 := &bgp.Advertisement{
+		Prefix:      &net.IPNet{IP: net.ParseIP("172.16.1.10"), Mask: classCMask},
+		Communities: []community.BGPCommunity{community1},
+		LocalPref:   200,
+	}
+	adv2 := &bgp.Advertisement{
+		Prefix:      &net.IPNet{IP: net.ParseIP("172.16.1.10"), Mask: classCMask},
+		Communities: []community.BGPCommunity{extCommunity},
+	}
+	adv3 := &bgp.Advertisement{
+		Prefix: &net.IPNet{IP: net.ParseIP("172.16.3.10"), Mask: classCMask},
+	}
+
+	err = session.Set(adv1, adv2, adv3)
+	if err != nil {
+		t.Fatalf("Could not advertise prefix: %s", err)
+	}
+
+	testCheckConfigFile(t)
+}
+
+func TestLinkLocalPeer(t *testing.T) {
+	l := log.NewNopLogger()
+	sessionManager := newTestSessionManager(t)
+
+	session, err := sessionManager.NewSession(l,
+		bgp.SessionParameters{
+			PeerAddress: "fe80::1",
+			PeerZone:    "eth0",
+			PeerPort:    179,
+			MyASN:       100,
+			PeerASN:     200,
+			CurrentNode: "hostname",
+			SessionName: "test-peer"})
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err)
+	}
+	defer session.Close()
+
+	testCheckConfigFile(t)
+}
+
 func TestSingleSessionWithNoTimers(t *testing.T) {
 	l := log.NewNopLogger()
 	sessionManager := newTestSessionManager(t)
@@ -1017,3 +1437,105 @@ func TestSingleSessionWithInterface(t *testing.T) {
 
 	testCheckConfigFile(t)
 }
+
+func TestConfigPerPeer(t *testing.T) {
+	l := log.NewNopLogger()
+	sessionManager := NewSessionManager(l, logging.LevelDebug, testNodeName, testNamespace, true)
+	configFile, _ := testGenerateFileNames(t)
+	sessionManager.SetEventCallback(func(config interface{}) {
+		configs, ok := config.([]frrv1beta1.FRRConfiguration)
+		if !ok {
+			t.Fatal("passed value is not a list of frr configurations")
+		}
+		toDump, err := json.MarshalIndent(configs, "", "    ")
+		if err != nil {
+			t.Fatalf("failed to marshal the configurations")
+		}
+		if err := os.WriteFile(configFile, toDump, 0644); err != nil {
+			t.Fatalf("failed to write %s", configFile)
+		}
+	})
+
+	err := sessionManager.SyncExtraInfo(metallbconfig.BGPExtras{
+		Raw:      "# hello",
+		BestPath: &metallbconfig.BestPath{ASPathMultipathRelax: true},
+	})
+	if err != nil {
+		t.Fatalf("Could not sync extra info: %s", err)
+	}
+	err = sessionManager.SyncBFDProfiles(map[string]*metallbconfig.BFDProfile{
+		"bfd": {Name: "bfd"},
+	})
+	if err != nil {
+		t.Fatalf("Could not sync the BFD profiles: %s", err)
+	}
+
+	session1, err := sessionManager.NewSession(l,
+		bgp.SessionParameters{
+			PeerAddress:   "10.2.2.254",
+			PeerPort:      179,
+			SourceAddress: net.ParseIP("10.1.1.254"),
+			MyASN:         100,
+			RouterID:      net.ParseIP("10.1.1.254"),
+			PeerASN:       200,
+			CurrentNode:   "hostname",
+			BFDProfile:    "bfd",
+			Extras: &metallbconfig.NeighborExtras{
+				AllowASIn: ptr.To(uint32(2)),
+			},
+			SessionName: "peer1"})
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err)
+	}
+	defer session1.Close()
+
+	session2, err := sessionManager.NewSession(l,
+		bgp.SessionParameters{
+			PeerAddress:   "10.2.2.253",
+			PeerPort:      179,
+			SourceAddress: net.ParseIP("10.1.1.254"),
+			MyASN:         100,
+			RouterID:      net.ParseIP("10.1.1.254"),
+			PeerASN:       300,
+			CurrentNode:   "hostname",
+			VRFName:       "red",
+			SessionName:   "peer2"})
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err)
+	}
+	defer session2.Close()
+
+	err = session1.Set(&bgp.Advertisement{
+		Prefix:    &net.IPNet{IP: net.ParseIP("172.16.1.10"), Mask: net.CIDRMask(32, 32)},
+		LocalPref: 300,
+	})
+	if err != nil {
+		t.Fatalf("Could not advertise prefix: %s", err)
+	}
+	err = session2.Set(&bgp.Advertisement{
+		Prefix: &net.IPNet{IP: net.ParseIP("172.16.1.11"), Mask: net.CIDRMask(32, 32)},
+	})
+	if err != nil {
+		t.Fatalf("Could not advertise prefix: %s", err)
+	}
+
+	testCheckConfigFile(t)
+}
+
+func TestPeerConfigName(t *testing.T) {
+	if PeerConfigName("worker", "1-peer") == PeerConfigName("worker-1", "peer") {
+		t.Fatal("the names of the configurations of different node / peer pairs collide")
+	}
+
+	longPeer := strings.Repeat("peer", 20)
+	value := LabelValue(longPeer)
+	if len(value) > maxLabelValueLength {
+		t.Fatalf("label value %s is longer than %d", value, maxLabelValueLength)
+	}
+	if value == LabelValue(longPeer+"2") {
+		t.Fatal("the label values of different long names collide")
+	}
+	if LabelValue("peer1") != "peer1" {
+		t.Fatalf("expected short label values to be kept, got %s", LabelValue("peer1"))
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/go-kit/log"
 	"go.universe.tf/metallb/internal/bgp"
 	"go.universe.tf/metallb/internal/bgp/community"
+	metallbconfig "go.universe.tf/metallb/internal/config"
 	"k8s.io/utils/ptr"
 )
 
@@ -437,3 +438,43 @@ func TestTwoAdvertisementsTwoSessionsOneVRF(t *testing.T) {
 
 	testCheckConfigFile(t)
 }
+
+func TestEVPN(t *testing.T) {
+	l := log.NewNopLogger()
+	sessionManager := newTestSessionManager(t)
+	session, err := sessionManager.NewSession(l,
+		bgp.SessionParameters{
+			PeerAddress:   "10.2.2.254",
+			PeerPort:      179,
+			SourceAddress: net.ParseIP("10.1.1.254"),
+			MyASN:         100,
+			RouterID:      net.ParseIP("10.1.1.254"),
+			PeerASN:       200,
+			CurrentNode:   "hostname",
+			SessionName:   "test-peer"})
+	if err != nil {
+		t.Fatalf("Could not create session: %s", err)
+	}
+	defer session.Close()
+
+	evpn := &metallbconfig.EVPN{
+		VRF:                "red",
+		VNI:                100,
+		RouteDistinguisher: "10.1.1.254:100",
+		ImportRouteTargets: []string{"100:100"},
+		ExportRouteTargets: []string{"100:100", "100:200"},
+	}
+	_, prefix1, _ := net.ParseCIDR("172.16.1.10/32")
+	_, prefix2, _ := net.ParseCIDR("2001:db8::10/128")
+	_, prefix3, _ := net.ParseCIDR("172.16.2.10/32")
+	err = session.Set(
+		&bgp.Advertisement{Prefix: prefix1, EVPN: evpn},
+		&bgp.Advertisement{Prefix: prefix2, EVPN: evpn},
+		&bgp.Advertisement{Prefix: prefix3},
+	)
+	if err != nil {
+		t.Fatalf("Could not advertise prefix: %s", err)
+	}
+
+	testCheckConfigFile(t)
+}
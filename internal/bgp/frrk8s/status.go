@@ -0,0 +1,65 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package frr
+
+import (
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+
+	frrv1beta1 "github.com/metallb/frr-k8s/api/v1beta1"
+	"go.universe.tf/metallb/internal/bgp"
+)
+
+// SyncSessionStates records the state of the BGP sessions of the node, as
+// reported by FRR-K8s through its BGPSessionState resources. It returns
+// true if the state of any of the sessions changed.
+func (sm *sessionManager) SyncSessionStates(states []frrv1beta1.BGPSessionStateStatus) bool {
+	sm.Lock()
+	defer sm.Unlock()
+	newStates := make(map[string]string, len(states))
+	for _, s := range states {
+		newStates[sessionStateKey(s.Peer, s.VRF)] = s.BGPStatus
+	}
+	if maps.Equal(sm.sessionStates, newStates) {
+		return false
+	}
+	sm.sessionStates = newStates
+	return true
+}
+
+// AdvertisementState tells whether the session is established, as last
+// reported by FRR-K8s, and whether the prefix is part of the configuration
+// of the session. FRR advertises the configured prefixes as soon as the
+// session is established.
+func (s *session) AdvertisementState(prefix string) (bool, bool) {
+	s.sessionManager.Lock()
+	defer s.sessionManager.Unlock()
+	peer := s.PeerInterface
+	if peer == "" {
+		peer = labelFormatForPeer(s.PeerAddress)
+	}
+	if s.sessionManager.sessionStates[sessionStateKey(peer, s.VRFName)] != "Established" {
+		return false, false
+	}
+	configured := slices.ContainsFunc(s.advertised, func(adv *bgp.Advertisement) bool {
+		return adv.Prefix.String() == prefix
+	})
+	return true, configured
+}
+
+func sessionStateKey(peer, vrf string) string {
+	return peer + "/" + vrf
+}
+
+// labelFormatForPeer returns the address of the peer in the format FRR-K8s
+// reports it with, where the colons of the IPv6 addresses are replaced by
+// dashes as they can't be part of a label value.
+func labelFormatForPeer(peer string) string {
+	addr, err := netip.ParseAddr(peer)
+	if err != nil || addr.Is4() {
+		return peer
+	}
+	return strings.ReplaceAll(addr.StringExpanded(), ":", "-")
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package frr
+
+import (
+	"net"
+	"testing"
+
+	frrv1beta1 "github.com/metallb/frr-k8s/api/v1beta1"
+	"go.universe.tf/metallb/internal/bgp"
+)
+
+func TestAdvertisementState(t *testing.T) {
+	sm := &sessionManager{
+		sessions:      map[string]*session{},
+		sessionStates: map[string]string{},
+	}
+	changed := sm.SyncSessionStates([]frrv1beta1.BGPSessionStateStatus{
+		{Peer: "192.168.1.1", BGPStatus: "Established"},
+		{Peer: "192.168.1.2", BGPStatus: "Active"},
+		{Peer: "10.0.0.5", VRF: "red", BGPStatus: "Established"},
+		{Peer: "2001-0db8-0000-0000-0000-0000-0000-0001", BGPStatus: "Established"},
+		{Peer: "eth0", BGPStatus: "Established"},
+	})
+	if !changed {
+		t.Fatalf("expected the states to change")
+	}
+	_, prefix, _ := net.ParseCIDR("172.16.1.10/32")
+	newSession := func(params bgp.SessionParameters) *session {
+		return &session{
+			SessionParameters: params,
+			sessionManager:    sm,
+			advertised:        []*bgp.Advertisement{{Prefix: prefix}},
+		}
+	}
+
+	tests := []struct {
+		desc        string
+		session     *session
+		prefix      string
+		established bool
+		advertised  bool
+	}{
+		{
+			desc:        "established",
+			session:     newSession(bgp.SessionParameters{PeerAddress: "192.168.1.1"}),
+			prefix:      "172.16.1.10/32",
+			established: true,
+			advertised:  true,
+		},
+		{
+			desc:        "established, prefix not configured",
+			session:     newSession(bgp.SessionParameters{PeerAddress: "192.168.1.1"}),
+			prefix:      "172.16.1.11/32",
+			established: true,
+		},
+		{
+			desc:    "not established",
+			session: newSession(bgp.SessionParameters{PeerAddress: "192.168.1.2"}),
+			prefix:  "172.16.1.10/32",
+		},
+		{
+			desc:    "not reported",
+			session: newSession(bgp.SessionParameters{PeerAddress: "192.168.1.3"}),
+			prefix:  "172.16.1.10/32",
+		},
+		{
+			desc:    "wrong vrf",
+			session: newSession(bgp.SessionParameters{PeerAddress: "10.0.0.5"}),
+			prefix:  "172.16.1.10/32",
+		},
+		{
+			desc:        "vrf",
+			session:     newSession(bgp.SessionParameters{PeerAddress: "10.0.0.5", VRFName: "red"}),
+			prefix:      "172.16.1.10/32",
+			established: true,
+			advertised:  true,
+		},
+		{
+			desc:        "ipv6",
+			session:     newSession(bgp.SessionParameters{PeerAddress: "2001:db8::1"}),
+			prefix:      "172.16.1.10/32",
+			established: true,
+			advertised:  true,
+		},
+		{
+			desc:        "unnumbered",
+			session:     newSession(bgp.SessionParameters{PeerInterface: "eth0"}),
+			prefix:      "172.16.1.10/32",
+			established: true,
+			advertised:  true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			established, advertised := tc.session.AdvertisementState(tc.prefix)
+			if established != tc.established || advertised != tc.advertised {
+				t.Fatalf("expected established %v advertised %v, got %v %v", tc.established, tc.advertised, established, advertised)
+			}
+		})
+	}
+
+	changed = sm.SyncSessionStates([]frrv1beta1.BGPSessionStateStatus{
+		{Peer: "192.168.1.1", BGPStatus: "Established"},
+		{Peer: "192.168.1.2", BGPStatus: "Active"},
+		{Peer: "10.0.0.5", VRF: "red", BGPStatus: "Established"},
+		{Peer: "2001-0db8-0000-0000-0000-0000-0000-0001", BGPStatus: "Established"},
+		{Peer: "eth0", BGPStatus: "Established"},
+	})
+	if changed {
+		t.Fatalf("expected the states not to change")
+	}
+}
@@ -0,0 +1,439 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package native
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"go.universe.tf/metallb/internal/bgp"
+	"go.universe.tf/metallb/internal/safeconvert"
+	"golang.org/x/sys/unix"
+)
+
+// register adds s to the sessions that accept connections initiated by
+// their peer.
+func (sm *sessionManager) register(s *session) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.sessions[s] = true
+	if err := sm.syncListener(); err != nil {
+		delete(sm.sessions, s)
+		return err
+	}
+	return nil
+}
+
+func (sm *sessionManager) unregister(s *session) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	delete(sm.sessions, s)
+	if err := sm.syncListener(); err != nil {
+		level.Error(sm.logger).Log("op", "unregister", "error", err, "msg", "failed to update BGP listener")
+	}
+}
+
+// syncListener listens for incoming BGP connections as long as a
+// passive or dynamic session exists. Once listening, connections from
+// the peers of the other sessions are accepted too, and go through
+// connection collision resolution.
+// Must be called with sm.mu held.
+func (sm *sessionManager) syncListener() error {
+	needed := len(sm.dynamic) > 0
+	for s := range sm.sessions {
+		if s.Passive {
+			needed = true
+			break
+		}
+	}
+
+	if !needed {
+		if sm.listener != nil {
+			sm.listener.Close()
+			sm.listener = nil
+			sm.md5Keys = nil
+		}
+		return nil
+	}
+
+	if sm.listener == nil {
+		ln, err := net.Listen("tcp", sm.listenAddr)
+		if err != nil {
+			return fmt.Errorf("listening for BGP connections on %q: %w", sm.listenAddr, err)
+		}
+		sm.listener = ln.(*net.TCPListener)
+		sm.md5Keys = map[string]string{}
+		go sm.accept(sm.listener)
+	}
+	return sm.syncMD5Keys()
+}
+
+// syncMD5Keys sets on the listener the TCP MD5 keys of the peers
+// allowed to connect, and removes the stale ones.
+// Must be called with sm.mu held.
+func (sm *sessionManager) syncMD5Keys() error {
+	want := map[string]string{}
+	for s := range sm.sessions {
+		ip := net.ParseIP(s.PeerAddress)
+		if s.Password == "" || ip == nil {
+			continue
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		bits := 8 * len(ip)
+		want[(&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}).String()] = s.Password
+	}
+	for d := range sm.dynamic {
+		if d.Password != "" {
+			want[d.ListenRange.String()] = d.Password
+		}
+	}
+
+	for prefix, password := range want {
+		if sm.md5Keys[prefix] == password {
+			continue
+		}
+		if err := setListenerMD5(sm.listener, prefix, password); err != nil {
+			return fmt.Errorf("setting TCP MD5 key for %s: %w", prefix, err)
+		}
+		sm.md5Keys[prefix] = password
+	}
+	for prefix := range sm.md5Keys {
+		if _, ok := want[prefix]; ok {
+			continue
+		}
+		if err := setListenerMD5(sm.listener, prefix, ""); err != nil {
+			return fmt.Errorf("removing TCP MD5 key for %s: %w", prefix, err)
+		}
+		delete(sm.md5Keys, prefix)
+	}
+	return nil
+}
+
+// accept serves the connections initiated by peers, until ln is closed.
+func (sm *sessionManager) accept(ln *net.TCPListener) {
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			level.Error(sm.logger).Log("op", "accept", "error", err, "msg", "failed to accept BGP connection")
+			time.Sleep(time.Second)
+			continue
+		}
+		go sm.handleIncoming(conn)
+	}
+}
+
+// handleIncoming hands conn over to the session of the peer it comes
+// from.
+func (sm *sessionManager) handleIncoming(conn net.Conn) {
+	raddr, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		conn.Close()
+		return
+	}
+	ip := raddr.IP
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	s, d := sm.sessionFor(ip, raddr.Zone)
+	switch {
+	case s != nil:
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.open(ctx, conn, false); err != nil {
+			level.Error(s.logger).Log("op", "accept", "error", err, "msg", "failed to establish BGP session initiated by peer")
+		}
+	case d != nil:
+		d.accept(conn, ip)
+	default:
+		level.Warn(sm.logger).Log("op", "accept", "peer", raddr, "msg", "rejecting BGP connection from unknown peer")
+		conn.Close()
+	}
+}
+
+// sessionFor returns the session configured for the peer at ip, or
+// else the dynamic session with the most specific range containing ip.
+func (sm *sessionManager) sessionFor(ip net.IP, zone string) (*session, *dynamicSession) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	for s := range sm.sessions {
+		if s.PeerInterface != "" {
+			if zone == s.PeerInterface && ip.IsLinkLocalUnicast() {
+				return s, nil
+			}
+			continue
+		}
+		if ip.Equal(net.ParseIP(s.PeerAddress)) {
+			return s, nil
+		}
+	}
+
+	var (
+		best    *dynamicSession
+		bestLen = -1
+	)
+	for d := range sm.dynamic {
+		if !d.ListenRange.Contains(ip) {
+			continue
+		}
+		if ones, _ := d.ListenRange.Mask.Size(); ones > bestLen {
+			best, bestLen = d, ones
+		}
+	}
+	return nil, best
+}
+
+// dynamicSession is a BGP session with all the peers connecting from a
+// prefix range. Each of them gets its own passive session, which lasts
+// as long as its connection.
+type dynamicSession struct {
+	bgp.SessionParameters
+
+	logger  log.Logger
+	manager *sessionManager
+
+	mu        sync.Mutex
+	closed    bool
+	advs      []*bgp.Advertisement
+	neighbors map[string]*session
+}
+
+func (sm *sessionManager) newDynamicSession(l log.Logger, args bgp.SessionParameters) (*dynamicSession, error) {
+	d := &dynamicSession{
+		SessionParameters: args,
+		logger:            log.With(l, "listenRange", args.ListenRange, "localASN", args.MyASN, "peerASN", args.PeerASN),
+		manager:           sm,
+		neighbors:         map[string]*session{},
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.dynamic[d] = true
+	if err := sm.syncListener(); err != nil {
+		delete(sm.dynamic, d)
+		return nil, err
+	}
+	return d, nil
+}
+
+// Set updates the set of Advertisements that the current and future
+// neighbors should receive.
+func (d *dynamicSession) Set(advs ...*bgp.Advertisement) error {
+	for _, adv := range advs {
+		if err := validate(adv); err != nil {
+			return err
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.advs = advs
+	for _, s := range d.neighbors {
+		if err := s.Set(advs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AdvertisementState tells whether the session is established with any of
+// the neighbors, and the prefix was sent to all the established ones.
+func (d *dynamicSession) AdvertisementState(prefix string) (bool, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	established, advertised := false, true
+	for _, s := range d.neighbors {
+		up, sent := s.AdvertisementState(prefix)
+		if !up {
+			continue
+		}
+		established = true
+		advertised = advertised && sent
+	}
+	return established, established && advertised
+}
+
+// Close shuts down the sessions with all the neighbors, and stops
+// accepting new ones.
+func (d *dynamicSession) Close() error {
+	d.mu.Lock()
+	d.closed = true
+	neighbors := d.neighbors
+	d.neighbors = map[string]*session{}
+	d.mu.Unlock()
+
+	for _, s := range neighbors {
+		s.Close()
+	}
+
+	sm := d.manager
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	delete(sm.dynamic, d)
+	if err := sm.syncListener(); err != nil {
+		level.Error(sm.logger).Log("op", "close", "error", err, "msg", "failed to update BGP listener")
+	}
+	return nil
+}
+
+// accept establishes a session with the neighbor at ip over conn.
+func (d *dynamicSession) accept(conn net.Conn, ip net.IP) {
+	key := ip.String()
+
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		conn.Close()
+		return
+	}
+	s, existing := d.neighbors[key]
+	if !existing {
+		params := d.SessionParameters
+		params.PeerAddress = key
+		params.ListenRange = nil
+		params.Passive = true
+		s = newSession(d.logger, params)
+		s.onStateChange = d.manager.sessionStateChanged
+		if err := s.Set(d.advs...); err != nil {
+			level.Error(s.logger).Log("op", "accept", "error", err, "msg", "failed to set advertisements for dynamic neighbor")
+		}
+		d.neighbors[key] = s
+	}
+	d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.open(ctx, conn, false); err != nil {
+		level.Error(s.logger).Log("op", "accept", "error", err, "msg", "failed to establish BGP session initiated by dynamic neighbor")
+		if !existing {
+			d.removeIfIdle(key, s)
+		}
+		return
+	}
+	if !existing {
+		go s.sendKeepalives()
+		go d.serve(key, s)
+	}
+}
+
+// serve pumps route updates to a neighbor until its connection is lost
+// and not replaced by a new one.
+func (d *dynamicSession) serve(key string, s *session) {
+	defer stats.DeleteSession(s.peerName)
+	for {
+		stats.SessionUp(s.peerName)
+		level.Info(s.logger).Log("event", "sessionUp", "msg", "BGP session established")
+		s.notifyStateChange()
+
+		if !s.sendUpdates() {
+			return
+		}
+		stats.SessionDown(s.peerName)
+		level.Warn(s.logger).Log("event", "sessionDown", "msg", "BGP session down")
+		s.notifyStateChange()
+
+		if d.removeIfIdle(key, s) {
+			s.notifyStateChange()
+			return
+		}
+	}
+}
+
+// removeIfIdle closes and forgets the session of a neighbor which is
+// not connected.
+func (d *dynamicSession) removeIfIdle(key string, s *session) bool {
+	d.mu.Lock()
+	s.mu.Lock()
+	idle := s.conn == nil
+	s.mu.Unlock()
+	if idle && d.neighbors[key] == s {
+		delete(d.neighbors, key)
+	}
+	d.mu.Unlock()
+
+	if idle {
+		s.Close()
+	}
+	return idle
+}
+
+// setListenerMD5 sets the TCP MD5 key for the peers in prefix on the
+// listening socket. An empty key removes it.
+func setListenerMD5(ln *net.TCPListener, prefix, key string) error {
+	_, ipnet, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return err
+	}
+	rc, err := ln.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var sockErr error
+	err = rc.Control(func(fd uintptr) {
+		family, err := unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_DOMAIN)
+		if err != nil {
+			sockErr = os.NewSyscallError("getsockopt", err)
+			return
+		}
+		sig, err := buildPrefixMD5Sig(family, ipnet, key)
+		if err != nil {
+			sockErr = err
+			return
+		}
+		sockErr = os.NewSyscallError("setsockopt", unix.SetsockoptTCPMD5Sig(int(fd), unix.IPPROTO_TCP, unix.TCP_MD5SIG_EXT, sig))
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// buildPrefixMD5Sig builds the TCP MD5 key for the peers in prefix, for
+// a socket of the given address family. IPv4 peers of IPv6 sockets are
+// keyed by their IPv4-mapped address.
+func buildPrefixMD5Sig(family int, prefix *net.IPNet, key string) (*unix.TCPMD5Sig, error) {
+	t := unix.TCPMD5Sig{Flags: unix.TCP_MD5SIG_FLAG_PREFIX}
+	switch {
+	case family == unix.AF_INET6:
+		t.Addr.Family = unix.AF_INET6
+		copy(t.Addr.Data[6:], prefix.IP.To16())
+	case prefix.IP.To4() != nil:
+		t.Addr.Family = unix.AF_INET
+		copy(t.Addr.Data[2:], prefix.IP.To4())
+	default:
+		return nil, fmt.Errorf("cannot set key for IPv6 prefix %s on an IPv4 socket", prefix)
+	}
+
+	var err error
+	ones, _ := prefix.Mask.Size()
+	t.Prefixlen, err = safeconvert.IntToUInt8(ones)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prefix length %w", err)
+	}
+	t.Keylen, err = safeconvert.IntToUInt16(len(key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid keyLen %w", err)
+	}
+	copy(t.Key[0:], []byte(key))
+
+	return &t, nil
+}
@@ -0,0 +1,371 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package native
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"go.universe.tf/metallb/internal/bgp"
+)
+
+func testSessionManager() *sessionManager {
+	return &sessionManager{
+		logger:     log.NewNopLogger(),
+		listenAddr: "127.0.0.1:0",
+		sessions:   map[*session]bool{},
+		dynamic:    map[*dynamicSession]bool{},
+	}
+}
+
+// dialPeer connects to the manager's listener as a BGP speaker with the
+// given ASN, and waits for the session to be established.
+func dialPeer(t *testing.T, sm *sessionManager, asn uint32) net.Conn {
+	t.Helper()
+	sm.mu.Lock()
+	addr := sm.listener.Addr().String()
+	sm.mu.Unlock()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial %s: %s", addr, err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("set deadline: %s", err)
+	}
+	if err := sendOpen(conn, asn, net.ParseIP("10.0.0.100"), 90*time.Second, false); err != nil {
+		t.Fatalf("send OPEN: %s", err)
+	}
+	if _, err := readOpen(conn); err != nil {
+		t.Fatalf("read OPEN: %s", err)
+	}
+	if typ := readMessageType(t, conn); typ != 4 {
+		t.Fatalf("expected KEEPALIVE, got message type %d", typ)
+	}
+	if err := sendKeepalive(conn); err != nil {
+		t.Fatalf("send KEEPALIVE: %s", err)
+	}
+	return conn
+}
+
+// readMessageType reads a BGP message, skipping keepalives unless it is
+// the first message, and returns its type.
+func readMessageType(t *testing.T, r io.Reader) uint8 {
+	t.Helper()
+	hdr := struct {
+		Marker1, Marker2 uint64
+		Len              uint16
+		Type             uint8
+	}{}
+	if err := binary.Read(r, binary.BigEndian, &hdr); err != nil {
+		t.Fatalf("read message header: %s", err)
+	}
+	if _, err := io.CopyN(io.Discard, r, int64(hdr.Len)-19); err != nil {
+		t.Fatalf("read message body: %s", err)
+	}
+	return hdr.Type
+}
+
+func testAdvertisement() *bgp.Advertisement {
+	_, prefix, _ := net.ParseCIDR("172.16.0.0/24")
+	return &bgp.Advertisement{Prefix: prefix}
+}
+
+func TestPassiveSession(t *testing.T) {
+	sm := testSessionManager()
+	s, err := sm.NewSession(log.NewNopLogger(), bgp.SessionParameters{
+		PeerAddress: "127.0.0.1",
+		PeerPort:    179,
+		Passive:     true,
+		MyASN:       64512,
+		PeerASN:     64513,
+		RouterID:    net.ParseIP("10.0.0.1"),
+	})
+	if err != nil {
+		t.Fatalf("new session: %s", err)
+	}
+	defer s.Close()
+
+	if err := s.Set(testAdvertisement()); err != nil {
+		t.Fatalf("set advertisements: %s", err)
+	}
+
+	conn := dialPeer(t, sm, 64513)
+	defer conn.Close()
+	if typ := readMessageType(t, conn); typ != 2 {
+		t.Fatalf("expected UPDATE, got message type %d", typ)
+	}
+
+	s.Close()
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.listener != nil {
+		t.Fatalf("listener still running after the passive session was closed")
+	}
+}
+
+func TestDynamicSession(t *testing.T) {
+	sm := testSessionManager()
+	_, listenRange, _ := net.ParseCIDR("127.0.0.0/8")
+	s, err := sm.NewSession(log.NewNopLogger(), bgp.SessionParameters{
+		ListenRange: listenRange,
+		PeerPort:    179,
+		MyASN:       64512,
+		PeerASN:     64513,
+		RouterID:    net.ParseIP("10.0.0.1"),
+	})
+	if err != nil {
+		t.Fatalf("new session: %s", err)
+	}
+	defer s.Close()
+
+	if err := s.Set(testAdvertisement()); err != nil {
+		t.Fatalf("set advertisements: %s", err)
+	}
+
+	conn := dialPeer(t, sm, 64513)
+	defer conn.Close()
+	if typ := readMessageType(t, conn); typ != 2 {
+		t.Fatalf("expected UPDATE, got message type %d", typ)
+	}
+
+	d := s.(*dynamicSession)
+	d.mu.Lock()
+	_, ok := d.neighbors["127.0.0.1"]
+	d.mu.Unlock()
+	if !ok {
+		t.Fatalf("no session for dynamic neighbor 127.0.0.1")
+	}
+}
+
+func TestSessionStates(t *testing.T) {
+	sm := testSessionManager()
+	changed := make(chan struct{}, 1)
+	sm.SetSessionStateCallback(func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	waitForState := func(state string) bgp.SessionState {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for {
+			states := sm.SessionStates()
+			if len(states) != 1 {
+				t.Fatalf("expected 1 session state, got %d", len(states))
+			}
+			if states[0].State == state {
+				return states[0]
+			}
+			select {
+			case <-changed:
+			case <-timeout:
+				t.Fatalf("session never reached state %s, last %+v", state, states[0])
+			}
+		}
+	}
+
+	s, err := sm.NewSession(log.NewNopLogger(), bgp.SessionParameters{
+		PeerAddress: "127.0.0.1",
+		PeerPort:    179,
+		Passive:     true,
+		MyASN:       64512,
+		PeerASN:     64513,
+		RouterID:    net.ParseIP("10.0.0.1"),
+	})
+	if err != nil {
+		t.Fatalf("new session: %s", err)
+	}
+	defer s.Close()
+	if err := s.Set(testAdvertisement()); err != nil {
+		t.Fatalf("set advertisements: %s", err)
+	}
+
+	state := waitForState("Active")
+	if state.Peer != "127.0.0.1" || !state.UpSince.IsZero() {
+		t.Fatalf("unexpected state before the peer connected: %+v", state)
+	}
+
+	conn := dialPeer(t, sm, 64513)
+	if typ := readMessageType(t, conn); typ != 2 {
+		t.Fatalf("expected UPDATE, got message type %d", typ)
+	}
+	state = waitForState("Established")
+	if state.UpSince.IsZero() || state.PrefixesSent != 1 {
+		t.Fatalf("unexpected state after the peer connected: %+v", state)
+	}
+
+	conn.Close()
+	state = waitForState("Active")
+	if !state.UpSince.IsZero() {
+		t.Fatalf("session down but up since %s", state.UpSince)
+	}
+}
+
+func TestNumberedIPv6PeerWithoutExtendedNextHop(t *testing.T) {
+	sm := testSessionManager()
+	sm.listenAddr = "[::1]:0"
+	s, err := sm.NewSession(log.NewNopLogger(), bgp.SessionParameters{
+		PeerAddress: "::1",
+		PeerPort:    179,
+		Passive:     true,
+		MyASN:       64512,
+		PeerASN:     64513,
+		RouterID:    net.ParseIP("10.0.0.1"),
+	})
+	if err != nil {
+		t.Skipf("no IPv6 loopback: %s", err)
+	}
+	defer s.Close()
+	if err := s.Set(testAdvertisement()); err != nil {
+		t.Fatalf("set advertisements: %s", err)
+	}
+
+	// The peer doesn't support extended next hops: the session stays up,
+	// but the IPv4 prefixes are not sent over it.
+	conn := dialPeer(t, sm, 64513)
+	defer conn.Close()
+	if err := conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond)); err != nil {
+		t.Fatalf("set deadline: %s", err)
+	}
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Fatalf("unexpected message sent to the peer")
+	}
+
+	states := sm.SessionStates()
+	if len(states) != 1 || states[0].State != "Established" || states[0].PrefixesSent != 0 {
+		t.Fatalf("unexpected session states %+v", states)
+	}
+	established, advertised := s.(*session).AdvertisementState("172.16.0.0/24")
+	if !established || advertised {
+		t.Fatalf("expected the session to be established without the prefix, got %t %t", established, advertised)
+	}
+}
+
+func TestUnknownPeerRejected(t *testing.T) {
+	sm := testSessionManager()
+	s, err := sm.NewSession(log.NewNopLogger(), bgp.SessionParameters{
+		PeerAddress: "10.0.0.2",
+		PeerPort:    179,
+		Passive:     true,
+		MyASN:       64512,
+		PeerASN:     64513,
+	})
+	if err != nil {
+		t.Fatalf("new session: %s", err)
+	}
+	defer s.Close()
+
+	sm.mu.Lock()
+	addr := sm.listener.Addr().String()
+	sm.mu.Unlock()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial %s: %s", addr, err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("set deadline: %s", err)
+	}
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected connection from unknown peer to be closed, got %v", err)
+	}
+}
+
+func TestNewConnectionWins(t *testing.T) {
+	low, high := net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")
+	tests := []struct {
+		desc             string
+		confirmed        bool
+		existingOutgoing bool
+		outgoing         bool
+		localID          net.IP
+		remoteID         net.IP
+		want             bool
+	}{
+		{
+			desc:             "confirmed connection is kept",
+			confirmed:        true,
+			existingOutgoing: true,
+			outgoing:         false,
+			localID:          low,
+			remoteID:         high,
+			want:             false,
+		},
+		{
+			desc:             "higher remote identifier, incoming connection wins",
+			existingOutgoing: true,
+			outgoing:         false,
+			localID:          low,
+			remoteID:         high,
+			want:             true,
+		},
+		{
+			desc:             "higher remote identifier, outgoing connection loses",
+			existingOutgoing: false,
+			outgoing:         true,
+			localID:          low,
+			remoteID:         high,
+			want:             false,
+		},
+		{
+			desc:             "higher local identifier, outgoing connection wins",
+			existingOutgoing: false,
+			outgoing:         true,
+			localID:          high,
+			remoteID:         low,
+			want:             true,
+		},
+		{
+			desc:             "higher local identifier, incoming connection loses",
+			existingOutgoing: true,
+			outgoing:         false,
+			localID:          high,
+			remoteID:         low,
+			want:             false,
+		},
+		{
+			desc:             "same direction replaces stale connection",
+			existingOutgoing: false,
+			outgoing:         false,
+			localID:          high,
+			remoteID:         low,
+			want:             true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := newConnectionWins(tc.confirmed, tc.existingOutgoing, tc.outgoing, tc.localID, tc.remoteID)
+			if got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuildPrefixMD5Sig(t *testing.T) {
+	_, v4, _ := net.ParseCIDR("192.168.1.0/24")
+	sig, err := buildPrefixMD5Sig(10, v4, "secret")
+	if err != nil {
+		t.Fatalf("build key: %s", err)
+	}
+	// IPv4 peers of IPv6 sockets are keyed by their IPv4-mapped address,
+	// with the IPv4 prefix length.
+	want := net.ParseIP("::ffff:192.168.1.0").To16()
+	if got := net.IP(sig.Addr.Data[6:22]); !got.Equal(want) {
+		t.Errorf("wrong address, want %s got %s", want, got)
+	}
+	if sig.Prefixlen != 24 || sig.Keylen != 6 {
+		t.Errorf("wrong prefix or key length, got %d and %d", sig.Prefixlen, sig.Keylen)
+	}
+
+	_, v6, _ := net.ParseCIDR("2001:db8::/64")
+	if _, err := buildPrefixMD5Sig(2, v6, "secret"); err == nil {
+		t.Errorf("expected error setting IPv6 key on IPv4 socket")
+	}
+}
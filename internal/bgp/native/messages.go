@@ -15,7 +15,7 @@ import (
 	"go.universe.tf/metallb/internal/safeconvert"
 )
 
-func sendOpen(w io.Writer, asn uint32, routerID net.IP, holdTime time.Duration) error {
+func sendOpen(w io.Writer, asn uint32, routerID net.IP, holdTime time.Duration, extendedNextHop bool) error {
 	if routerID.To4() == nil {
 		panic("non-ipv4 address used as RouterID")
 	}
@@ -53,6 +53,13 @@ func sendOpen(w io.Writer, asn uint32, routerID net.IP, holdTime time.Duration)
 		CapType uint8
 		CapLen  uint8
 		ASN32   uint32
+
+		// ADD-PATH, send only, for IPv4 unicast.
+		AddPathType uint8
+		AddPathLen  uint8
+		AddPathAFI  uint16
+		AddPathSAFI uint8
+		AddPathMode uint8
 	}{
 		Marker1: 0xffffffffffffffff,
 		Marker2: 0xffffffffffffffff,
@@ -64,9 +71,9 @@ func sendOpen(w io.Writer, asn uint32, routerID net.IP, holdTime time.Duration)
 		HoldTime: uint16(holdTime.Seconds()),
 		// RouterID filled below
 
-		OptsLen: 20,
+		OptsLen: 26,
 		OptType: 2, // Capabilities
-		OptLen:  18,
+		OptLen:  24,
 
 		MP4Type: 1, // BGP Multi-protocol Extensions
 		MP4Len:  4,
@@ -81,10 +88,39 @@ func sendOpen(w io.Writer, asn uint32, routerID net.IP, holdTime time.Duration)
 		CapType: 65, // 4-byte ASN
 		CapLen:  4,
 		ASN32:   asn,
+
+		AddPathType: 69, // ADD-PATH
+		AddPathLen:  4,
+		AddPathAFI:  1, // IPv4
+		AddPathSAFI: 1, // Unicast
+		AddPathMode: 2, // Send
+	}
+
+	// Capability: extended next hop encoding (RFC 8950) for IPv4
+	// unicast prefixes with IPv6 next hops.
+	extNH := struct {
+		Type    uint8
+		Len     uint8
+		AFI     uint16
+		SAFI    uint16
+		NextHop uint16
+	}{
+		Type:    5,
+		Len:     6,
+		AFI:     1, // IPv4
+		SAFI:    1, // Unicast
+		NextHop: 2, // IPv6
+	}
+
+	size := binary.Size(msg)
+	if extendedNextHop {
+		size += binary.Size(extNH)
+		msg.OptsLen += uint8(binary.Size(extNH))
+		msg.OptLen += uint8(binary.Size(extNH))
 	}
 
 	var err error
-	msg.Len, err = safeconvert.IntToUInt16(binary.Size(msg))
+	msg.Len, err = safeconvert.IntToUInt16(size)
 	if err != nil {
 		return fmt.Errorf("invalid message len %w", err)
 	}
@@ -94,16 +130,31 @@ func sendOpen(w io.Writer, asn uint32, routerID net.IP, holdTime time.Duration)
 	}
 	copy(msg.RouterID[:], routerID.To4())
 
-	return binary.Write(w, binary.BigEndian, msg)
+	var b bytes.Buffer
+	if err := binary.Write(&b, binary.BigEndian, msg); err != nil {
+		return err
+	}
+	if extendedNextHop {
+		if err := binary.Write(&b, binary.BigEndian, extNH); err != nil {
+			return err
+		}
+	}
+	_, err = io.Copy(w, &b)
+	return err
 }
 
 type openResult struct {
 	asn      uint32
 	holdTime time.Duration
+	routerID net.IP
 	mp4      bool
 	mp6      bool
 	// Four-byte ASN supported
 	fbasn bool
+	// IPv4 unicast prefixes with IPv6 next hops supported
+	extendedNextHop bool
+	// IPv4 unicast paths with path identifiers can be received
+	addPath bool
 }
 
 var notificationCodes = map[uint16]string{
@@ -212,7 +263,9 @@ func readOpen(r io.Reader) (*openResult, error) {
 	ret := &openResult{
 		asn:      uint32(open.ASN16),
 		holdTime: time.Duration(open.HoldTime) * time.Second,
+		routerID: make(net.IP, 4),
 	}
+	binary.BigEndian.PutUint32(ret.routerID, open.RouterID)
 
 	if err := readOptions(lr, ret); err != nil {
 		return nil, err
@@ -283,6 +336,31 @@ func readCapabilities(r io.Reader, ret *openResult) error {
 			case af.AFI == 2 && af.SAFI == 1:
 				ret.mp6 = true
 			}
+		case 5:
+			for lr.N > 0 {
+				nh := struct{ AFI, SAFI, NextHopAFI uint16 }{}
+				if err := binary.Read(&lr, binary.BigEndian, &nh); err != nil {
+					return err
+				}
+				if nh.AFI == 1 && nh.SAFI == 1 && nh.NextHopAFI == 2 {
+					ret.extendedNextHop = true
+				}
+			}
+		case 69:
+			for lr.N > 0 {
+				ap := struct {
+					AFI  uint16
+					SAFI uint8
+					Mode uint8
+				}{}
+				if err := binary.Read(&lr, binary.BigEndian, &ap); err != nil {
+					return err
+				}
+				// Mode 1 is receive, 3 is send and receive.
+				if ap.AFI == 1 && ap.SAFI == 1 && ap.Mode&1 != 0 {
+					ret.addPath = true
+				}
+			}
 		default:
 			// TODO: only ignore capabilities that we know are fine to
 			// ignore.
@@ -296,7 +374,11 @@ func readCapabilities(r io.Reader, ret *openResult) error {
 	}
 }
 
-func sendUpdate(w io.Writer, asn uint32, ibgp, fbasn bool, nextHop net.IP, adv *bgp.Advertisement) error {
+// pathID is the identifier of the single path we advertise for each
+// prefix, when the peer accepts ADD-PATH (RFC 7911) identifiers.
+const pathID uint32 = 1
+
+func sendUpdate(w io.Writer, asn uint32, ibgp, fbasn, addPath bool, nextHop net.IP, adv *bgp.Advertisement) error {
 	var b bytes.Buffer
 
 	hdr := struct {
@@ -314,7 +396,7 @@ func sendUpdate(w io.Writer, asn uint32, ibgp, fbasn bool, nextHop net.IP, adv *
 		return err
 	}
 	l := b.Len()
-	if err := encodePathAttrs(&b, asn, ibgp, fbasn, nextHop, adv); err != nil {
+	if err := encodePathAttrs(&b, asn, ibgp, fbasn, addPath, nextHop, adv); err != nil {
 		return err
 	}
 
@@ -323,7 +405,11 @@ func sendUpdate(w io.Writer, asn uint32, ibgp, fbasn bool, nextHop net.IP, adv *
 		return err
 	}
 	binary.BigEndian.PutUint16(b.Bytes()[21:23], toWrite)
-	encodePrefixes(&b, []*net.IPNet{adv.Prefix})
+	if nextHop.To4() != nil {
+		// With an IPv6 next hop, the prefix is carried in the
+		// MP_REACH_NLRI attribute instead.
+		encodePrefixes(&b, addPath, []*net.IPNet{adv.Prefix})
+	}
 
 	toWrite, err = safeconvert.IntToUInt16(b.Len())
 	if err != nil {
@@ -337,8 +423,13 @@ func sendUpdate(w io.Writer, asn uint32, ibgp, fbasn bool, nextHop net.IP, adv *
 	return nil
 }
 
-func encodePrefixes(b *bytes.Buffer, pfxs []*net.IPNet) {
+// encodePrefixes writes pfxs as NLRI, each preceded by its path
+// identifier if addPath is set.
+func encodePrefixes(b *bytes.Buffer, addPath bool, pfxs []*net.IPNet) {
 	for _, pfx := range pfxs {
+		if addPath {
+			b.Write(binary.BigEndian.AppendUint32(nil, pathID))
+		}
 		o, _ := pfx.Mask.Size()
 		b.WriteByte(byte(o))
 		b.Write(pfx.IP.To4()[:bytesForBits(o)])
@@ -352,47 +443,83 @@ func bytesForBits(n int) int {
 	return ((n + 7) &^ 7) / 8
 }
 
-func encodePathAttrs(b *bytes.Buffer, asn uint32, ibgp, fbasn bool, nextHop net.IP, adv *bgp.Advertisement) error {
+// encodeASPath writes an AS_PATH made of a single AS_SEQUENCE repeating
+// the given ASN, or an empty AS_PATH if count is zero.
+func encodeASPath(b *bytes.Buffer, asn uint32, fbasn bool, count int) error {
+	if count == 0 {
+		b.WriteByte(0) // empty AS path
+		return nil
+	}
+	asLen := 2
+	if fbasn {
+		asLen = 4
+	}
+	attrLen, err := safeconvert.IntToUInt8(2 + count*asLen)
+	if err != nil {
+		return fmt.Errorf("AS path too long: %w", err)
+	}
+	segLen, err := safeconvert.IntToUInt8(count)
+	if err != nil {
+		return fmt.Errorf("AS path too long: %w", err)
+	}
 	b.Write([]byte{
-		0x40, 1, // mandatory, origin
-		1, // len
-		0, // igb
-
-		0x40, 2, // mandatory, as-path
+		attrLen,
+		2,      // AS_SEQUENCE
+		segLen, // len (in number of ASes)
 	})
-	if ibgp {
-		b.WriteByte(0) // empty AS path
-	} else {
+	for range count {
 		if fbasn {
-			b.Write([]byte{
-				6, // len (1x 4-byte ASN)
-				2, // AS_SEQUENCE
-				1, // len (in number of ASes)
-			})
 			if err := binary.Write(b, binary.BigEndian, asn); err != nil {
 				return err
 			}
-		} else {
-			b.Write([]byte{
-				4, // len (1x 2-byte ASN)
-				2, // AS_SEQUENCE
-				1, // len (in number of ASes)
-			})
-			asnToWrite, err := safeconvert.Uint32ToInt16(asn)
-			if err != nil {
-				return fmt.Errorf("invalid asn: %w", err)
-			}
-			if err := binary.Write(b, binary.BigEndian, asnToWrite); err != nil {
-				return err
-			}
+			continue
+		}
+		asnToWrite, err := safeconvert.Uint32ToInt16(asn)
+		if err != nil {
+			return fmt.Errorf("invalid asn: %w", err)
+		}
+		if err := binary.Write(b, binary.BigEndian, asnToWrite); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+func encodePathAttrs(b *bytes.Buffer, asn uint32, ibgp, fbasn, addPath bool, nextHop net.IP, adv *bgp.Advertisement) error {
 	b.Write([]byte{
-		0x40, 3, // mandatory, next-hop
-		4, // len
+		0x40, 1, // mandatory, origin
+		1, // len
+		0, // igb
+
+		0x40, 2, // mandatory, as-path
 	})
+	// eBGP peers get the local ASN in the path, which is prepended as
+	// many more times as requested for both eBGP and iBGP peers.
+	pathLen := int(adv.ASPathPrepend)
+	if !ibgp {
+		pathLen++
+	}
+	if err := encodeASPath(b, asn, fbasn, pathLen); err != nil {
+		return err
+	}
+	if nextHop.To4() != nil {
+		b.Write([]byte{
+			0x40, 3, // mandatory, next-hop
+			4, // len
+		})
+
+		b.Write(nextHop.To4())
+	}
 
-	b.Write(nextHop)
+	if adv.MED != nil {
+		b.Write([]byte{
+			0x80, 4, // optional, multi-exit-disc
+			4, // len
+		})
+		if err := binary.Write(b, binary.BigEndian, *adv.MED); err != nil {
+			return err
+		}
+	}
 
 	if ibgp {
 		b.Write([]byte{
@@ -404,40 +531,95 @@ func encodePathAttrs(b *bytes.Buffer, asn uint32, ibgp, fbasn bool, nextHop net.
 		}
 	}
 
-	if len(adv.Communities) > 0 {
-		// Convert communities to legacy communities uint32 representation. Throw an error if any non legacy type
-		// communities were found.
-		var legacyCommunities []uint32
-		for _, c := range adv.Communities {
-			legacyCommunity, ok := c.(community.BGPCommunityLegacy)
-			if !ok {
-				return fmt.Errorf("invalid community type for BGP native mode, community %s is not a legacy BGP "+
-					"Community", c)
-			}
-			legacyCommunities = append(legacyCommunities, legacyCommunity.ToUint32())
+	// Split communities by type, converting them to their wire representation.
+	var legacyCommunities []uint32
+	var extendedCommunities []uint64
+	var largeCommunities [][3]uint32
+	for _, c := range adv.Communities {
+		switch v := c.(type) {
+		case community.BGPCommunityLegacy:
+			legacyCommunities = append(legacyCommunities, v.ToUint32())
+		case community.BGPCommunityExtended:
+			extendedCommunities = append(extendedCommunities, v.ToUint64(asn))
+		case community.BGPCommunityLarge:
+			largeCommunities = append(largeCommunities, v.ToUint32s())
+		default:
+			return fmt.Errorf("invalid community type for BGP native mode, community %s", c)
 		}
-		b.Write([]byte{
-			0xc0, 8, // optional transitive, communities
-		})
+	}
 
-		toWrite, err := safeconvert.IntToUInt8(len(legacyCommunities) * 4)
-		if err != nil {
+	if len(legacyCommunities) > 0 {
+		if err := encodeCommunities(b, 8, legacyCommunities); err != nil { // communities
 			return fmt.Errorf("invalid size of legacy communities: %w", err)
 		}
-		if err := binary.Write(b, binary.BigEndian, toWrite); err != nil {
+	}
+
+	if nextHop.To4() == nil {
+		if err := encodeMPReach(b, addPath, nextHop, adv.Prefix); err != nil {
 			return err
 		}
-		for _, c := range legacyCommunities {
-			if err := binary.Write(b, binary.BigEndian, c); err != nil {
-				return err
-			}
+	}
+
+	if len(extendedCommunities) > 0 {
+		if err := encodeCommunities(b, 16, extendedCommunities); err != nil { // extended communities
+			return fmt.Errorf("invalid size of extended communities: %w", err)
+		}
+	}
+
+	if len(largeCommunities) > 0 {
+		if err := encodeCommunities(b, 32, largeCommunities); err != nil { // large communities
+			return fmt.Errorf("invalid size of large communities: %w", err)
 		}
 	}
 
 	return nil
 }
 
-func sendWithdraw(w io.Writer, prefixes []*net.IPNet) error {
+// encodeCommunities writes an optional transitive attribute of the given
+// type, holding the big endian representation of the communities.
+func encodeCommunities(b *bytes.Buffer, attrType uint8, communities any) error {
+	var attr bytes.Buffer
+	if err := binary.Write(&attr, binary.BigEndian, communities); err != nil {
+		return err
+	}
+	l, err := safeconvert.IntToUInt8(attr.Len())
+	if err != nil {
+		return err
+	}
+	b.Write([]byte{
+		0xc0, attrType, // optional transitive
+		l,
+	})
+	_, err = attr.WriteTo(b)
+	return err
+}
+
+// encodeMPReach writes an MP_REACH_NLRI attribute announcing an IPv4
+// unicast prefix with an IPv6 next hop, as per RFC 8950.
+func encodeMPReach(b *bytes.Buffer, addPath bool, nextHop net.IP, prefix *net.IPNet) error {
+	var attr bytes.Buffer
+	attr.Write([]byte{
+		0, 1, // AFI IPv4
+		1,  // SAFI unicast
+		16, // next hop len
+	})
+	attr.Write(nextHop.To16())
+	attr.WriteByte(0) // reserved
+	encodePrefixes(&attr, addPath, []*net.IPNet{prefix})
+
+	l, err := safeconvert.IntToUInt8(attr.Len())
+	if err != nil {
+		return fmt.Errorf("invalid MP_REACH_NLRI len: %w", err)
+	}
+	b.Write([]byte{
+		0x80, 14, // optional non-transitive, mp_reach_nlri
+		l,
+	})
+	_, err = attr.WriteTo(b)
+	return err
+}
+
+func sendWithdraw(w io.Writer, addPath bool, prefixes []*net.IPNet) error {
 	var b bytes.Buffer
 
 	hdr := struct {
@@ -454,7 +636,7 @@ func sendWithdraw(w io.Writer, prefixes []*net.IPNet) error {
 		return err
 	}
 	l := b.Len()
-	encodePrefixes(&b, prefixes)
+	encodePrefixes(&b, addPath, prefixes)
 	toWrite, err := safeconvert.IntToUInt16(b.Len() - l)
 	if err != nil {
 		return fmt.Errorf("invalid buffer %w", err)
@@ -489,3 +671,21 @@ func sendKeepalive(w io.Writer) error {
 	}
 	return binary.Write(w, binary.BigEndian, msg)
 }
+
+// sendNotification sends a NOTIFICATION message with the given error
+// code and subcode, as listed in notificationCodes.
+func sendNotification(w io.Writer, code uint16) error {
+	msg := struct {
+		Marker1, Marker2 uint64
+		Len              uint16
+		Type             uint8
+		Code             uint16
+	}{
+		Marker1: 0xffffffffffffffff,
+		Marker2: 0xffffffffffffffff,
+		Len:     21,
+		Type:    3,
+		Code:    code,
+	}
+	return binary.Write(w, binary.BigEndian, msg)
+}
@@ -13,6 +13,7 @@ import (
 
 	"go.universe.tf/metallb/internal/bgp"
 	"go.universe.tf/metallb/internal/bgp/community"
+	"k8s.io/utils/ptr"
 )
 
 // Just test that sendOpen and readOpen can at least talk to each other.
@@ -20,7 +21,7 @@ func TestOpen(t *testing.T) {
 	var b bytes.Buffer
 	wantHold := 4 * time.Second
 	wantASN := uint32(12345)
-	if err := sendOpen(&b, wantASN, net.ParseIP("1.2.3.4"), wantHold); err != nil {
+	if err := sendOpen(&b, wantASN, net.ParseIP("1.2.3.4"), wantHold, false); err != nil {
 		t.Fatalf("Send open: %s", err)
 	}
 	op, err := readOpen(&b)
@@ -35,6 +36,25 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestOpenExtendedNextHop(t *testing.T) {
+	for _, want := range []bool{true, false} {
+		var b bytes.Buffer
+		if err := sendOpen(&b, 12345, net.ParseIP("1.2.3.4"), 4*time.Second, want); err != nil {
+			t.Fatalf("Send open: %s", err)
+		}
+		op, err := readOpen(&b)
+		if err != nil {
+			t.Fatalf("Read open: %s", err)
+		}
+		if op.extendedNextHop != want {
+			t.Errorf("Wrong extended next hop capability, want %v, got %v", want, op.extendedNextHop)
+		}
+		if !op.fbasn || !op.mp4 || !op.mp6 {
+			t.Errorf("Missing capabilities in OPEN, got %#v", op)
+		}
+	}
+}
+
 func TestPcapInterop(t *testing.T) {
 	ms, err := filepath.Glob("testdata/open-*")
 	if err != nil {
@@ -99,7 +119,7 @@ func TestOpenFourByteASN(t *testing.T) {
 	}
 }
 
-// TestSendUpdate makes sure that sendUpdate fails if large communities are provided. The E2E tests take care of
+// TestSendUpdate makes sure that sendUpdate accepts all the community types. The E2E tests take care of
 // testing further functionality. A decodeUpdate method would be needed for a more complete unit test.
 func TestSendUpdate(t *testing.T) {
 	tcs := map[string]struct {
@@ -130,7 +150,7 @@ func TestSendUpdate(t *testing.T) {
 			},
 			errorString: "",
 		},
-		"send update with large communities should result in an error": {
+		"send update with large communities should succeed": {
 			asn:     65000,
 			ibgp:    false,
 			fbasn:   false,
@@ -148,12 +168,26 @@ func TestSendUpdate(t *testing.T) {
 				}(),
 				Peers: []string{},
 			},
-			errorString: "invalid community type for BGP native mode",
+			errorString: "",
+		},
+		"send update with ipv6 link-local next hop should succeed": {
+			asn:     65000,
+			ibgp:    false,
+			fbasn:   true,
+			nextHop: net.ParseIP("fe80::1"),
+			adv: &bgp.Advertisement{
+				Prefix: func() *net.IPNet {
+					_, ipnet, _ := net.ParseCIDR("172.16.0.0/24")
+					return ipnet
+				}(),
+				Peers: []string{},
+			},
+			errorString: "",
 		},
 	}
 	for d, tc := range tcs {
 		var b bytes.Buffer
-		err := sendUpdate(&b, tc.asn, tc.ibgp, tc.fbasn, tc.nextHop, tc.adv)
+		err := sendUpdate(&b, tc.asn, tc.ibgp, tc.fbasn, false, tc.nextHop, tc.adv)
 		if tc.errorString == "" && err != nil {
 			t.Fatalf("%s(%s): send update, err: %q", t.Name(), d, err)
 		}
@@ -163,6 +197,213 @@ func TestSendUpdate(t *testing.T) {
 	}
 }
 
+func TestSendUpdateCommunities(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR("172.16.0.0/24")
+	var communities []community.BGPCommunity
+	for _, s := range []string{"0:1234", "target:64512:100", "bandwidth:1000", "large:123:234:567"} {
+		c, err := community.New(s)
+		if err != nil {
+			t.Fatalf("parse community %s: %s", s, err)
+		}
+		communities = append(communities, c)
+	}
+	var b bytes.Buffer
+	err := sendUpdate(&b, 65000, false, true, false, net.ParseIP("192.168.123.10"), &bgp.Advertisement{Prefix: prefix, Communities: communities})
+	if err != nil {
+		t.Fatalf("send update: %s", err)
+	}
+
+	wantAttrs := [][]byte{
+		{0xc0, 8, 4, 0, 0, 0x04, 0xd2},
+		{0xc0, 16, 16,
+			0x00, 0x02, 0xfc, 0x00, 0x00, 0x00, 0x00, 0x64,
+			0x00, 0x04, 0xfd, 0xe8, 0x4c, 0xee, 0x6b, 0x28},
+		{0xc0, 32, 12, 0, 0, 0, 123, 0, 0, 0, 234, 0, 0, 0x02, 0x37},
+	}
+	for _, want := range wantAttrs {
+		if !bytes.Contains(b.Bytes(), want) {
+			t.Errorf("update does not contain attribute %x, got %x", want, b.Bytes())
+		}
+	}
+}
+
+func TestSendUpdateASPathPrepend(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR("172.16.0.0/24")
+	tests := []struct {
+		desc  string
+		ibgp  bool
+		fbasn bool
+		want  []byte
+	}{
+		{
+			desc:  "ebgp, four bytes asn",
+			fbasn: true,
+			want:  []byte{0x40, 2, 14, 2, 3, 0, 0, 0xfd, 0xe8, 0, 0, 0xfd, 0xe8, 0, 0, 0xfd, 0xe8},
+		},
+		{
+			desc: "ebgp, two bytes asn",
+			want: []byte{0x40, 2, 8, 2, 3, 0xfd, 0xe8, 0xfd, 0xe8, 0xfd, 0xe8},
+		},
+		{
+			desc:  "ibgp",
+			ibgp:  true,
+			fbasn: true,
+			want:  []byte{0x40, 2, 10, 2, 2, 0, 0, 0xfd, 0xe8, 0, 0, 0xfd, 0xe8},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			var b bytes.Buffer
+			err := sendUpdate(&b, 65000, tc.ibgp, tc.fbasn, false, net.ParseIP("192.168.123.10"), &bgp.Advertisement{Prefix: prefix, ASPathPrepend: 2})
+			if err != nil {
+				t.Fatalf("send update: %s", err)
+			}
+			if !bytes.Contains(b.Bytes(), tc.want) {
+				t.Fatalf("update does not contain AS path %x, got %x", tc.want, b.Bytes())
+			}
+		})
+	}
+}
+
+func TestSendUpdateMED(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR("172.16.0.0/24")
+	med := []byte{0x80, 4, 4, 0, 0, 0, 42}
+
+	var b bytes.Buffer
+	err := sendUpdate(&b, 65000, false, true, false, net.ParseIP("192.168.123.10"), &bgp.Advertisement{Prefix: prefix, MED: ptr.To[uint32](42)})
+	if err != nil {
+		t.Fatalf("send update: %s", err)
+	}
+	if !bytes.Contains(b.Bytes(), med) {
+		t.Fatalf("update does not contain MED %x, got %x", med, b.Bytes())
+	}
+
+	b.Reset()
+	err = sendUpdate(&b, 65000, false, true, false, net.ParseIP("192.168.123.10"), &bgp.Advertisement{Prefix: prefix})
+	if err != nil {
+		t.Fatalf("send update: %s", err)
+	}
+	if bytes.Contains(b.Bytes(), med[:3]) {
+		t.Fatalf("update contains a MED without one being set: %x", b.Bytes())
+	}
+}
+
+func TestSendUpdateExtendedNextHop(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR("172.16.0.0/24")
+	nextHop := net.ParseIP("fe80::1")
+	var b bytes.Buffer
+	err := sendUpdate(&b, 65000, false, true, false, nextHop, &bgp.Advertisement{Prefix: prefix})
+	if err != nil {
+		t.Fatalf("send update: %s", err)
+	}
+
+	// The IPv4 prefix must be carried in MP_REACH_NLRI with the IPv6
+	// next hop, and not in the trailing NLRI section.
+	wantMPReach := []byte{0x80, 14, 25, 0, 1, 1, 16}
+	wantMPReach = append(wantMPReach, nextHop.To16()...)
+	wantMPReach = append(wantMPReach, 0, 24, 172, 16, 0)
+	if !bytes.HasSuffix(b.Bytes(), wantMPReach) {
+		t.Fatalf("update does not end with MP_REACH_NLRI, want suffix %x, got %x", wantMPReach, b.Bytes())
+	}
+	if bytes.Contains(b.Bytes(), []byte{0x40, 3, 4}) {
+		t.Fatalf("update contains an IPv4 NEXT_HOP attribute: %x", b.Bytes())
+	}
+}
+
+func TestReadCapabilitiesAddPath(t *testing.T) {
+	tests := []struct {
+		desc string
+		cap  []byte
+		want bool
+	}{
+		{
+			desc: "receive ipv4 unicast",
+			cap:  []byte{69, 4, 0, 1, 1, 1},
+			want: true,
+		},
+		{
+			desc: "send and receive ipv4 unicast",
+			cap:  []byte{69, 4, 0, 1, 1, 3},
+			want: true,
+		},
+		{
+			desc: "send only ipv4 unicast",
+			cap:  []byte{69, 4, 0, 1, 1, 2},
+			want: false,
+		},
+		{
+			desc: "receive ipv6 unicast only",
+			cap:  []byte{69, 4, 0, 2, 1, 1},
+			want: false,
+		},
+		{
+			desc: "receive ipv6 and ipv4 unicast",
+			cap:  []byte{69, 8, 0, 2, 1, 1, 0, 1, 1, 1},
+			want: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			var op openResult
+			if err := readCapabilities(bytes.NewReader(tc.cap), &op); err != nil {
+				t.Fatalf("read capabilities: %s", err)
+			}
+			if op.addPath != tc.want {
+				t.Errorf("wrong ADD-PATH capability, want %v got %v", tc.want, op.addPath)
+			}
+		})
+	}
+}
+
+func TestOpenAdvertisesAddPathSend(t *testing.T) {
+	var b bytes.Buffer
+	if err := sendOpen(&b, 12345, net.ParseIP("1.2.3.4"), 4*time.Second, false); err != nil {
+		t.Fatalf("Send open: %s", err)
+	}
+	if !bytes.Contains(b.Bytes(), []byte{69, 4, 0, 1, 1, 2}) {
+		t.Fatalf("OPEN does not advertise ADD-PATH send for IPv4 unicast: %x", b.Bytes())
+	}
+	if _, err := readOpen(&b); err != nil {
+		t.Fatalf("Read open: %s", err)
+	}
+}
+
+func TestSendUpdateAddPath(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR("172.16.0.0/24")
+	var b bytes.Buffer
+	err := sendUpdate(&b, 65000, false, true, true, net.ParseIP("192.168.123.10"), &bgp.Advertisement{Prefix: prefix})
+	if err != nil {
+		t.Fatalf("send update: %s", err)
+	}
+	want := []byte{0, 0, 0, 1, 24, 172, 16, 0}
+	if !bytes.HasSuffix(b.Bytes(), want) {
+		t.Fatalf("update does not end with NLRI with path identifier, want suffix %x, got %x", want, b.Bytes())
+	}
+
+	b.Reset()
+	err = sendUpdate(&b, 65000, false, true, true, net.ParseIP("fe80::1"), &bgp.Advertisement{Prefix: prefix})
+	if err != nil {
+		t.Fatalf("send update: %s", err)
+	}
+	if !bytes.HasSuffix(b.Bytes(), append([]byte{0}, want...)) {
+		t.Fatalf("MP_REACH_NLRI does not end with NLRI with path identifier, want suffix %x, got %x", want, b.Bytes())
+	}
+}
+
+func TestSendWithdrawAddPath(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR("172.16.0.0/24")
+	var b bytes.Buffer
+	if err := sendWithdraw(&b, true, []*net.IPNet{prefix}); err != nil {
+		t.Fatalf("send withdraw: %s", err)
+	}
+	// Withdrawn routes length, withdrawn route with path identifier,
+	// and empty path attributes.
+	want := []byte{0, 8, 0, 0, 0, 1, 24, 172, 16, 0, 0, 0}
+	if got := b.Bytes()[19:]; !bytes.Equal(got, want) {
+		t.Fatalf("wrong withdraw body, want %x got %x", want, got)
+	}
+}
+
 func FuzzReadOpen(f *testing.F) {
 	ms, err := filepath.Glob("testdata/open-*")
 	if err != nil {
@@ -13,7 +13,9 @@ import (
 	"net"
 	"os"
 	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -32,31 +34,62 @@ var errClosed = errors.New("session closed")
 type session struct {
 	bgp.SessionParameters
 	peerFBASNSupport bool
+	peerAddPath      bool
+	// peerExtendedNextHop tells if the peer accepts IPv4 prefixes with
+	// IPv6 next hops.
+	peerExtendedNextHop bool
 
-	logger log.Logger
+	logger  log.Logger
+	manager *sessionManager
 
 	newHoldTime chan bool
 	backoff     backoff
+	// connected is signaled when the peer establishes the session, to
+	// cut short any pending reconnection backoff.
+	connected chan struct{}
 
 	mu             sync.Mutex
 	cond           *sync.Cond
 	closed         bool
 	conn           net.Conn
+	connOutgoing   bool
+	confirmed      bool
 	actualHoldTime time.Duration
 	nextHop        net.IP
 	advertised     map[string]*bgp.Advertisement
 	new            map[string]*bgp.Advertisement
+	upSince        time.Time
+	lastError      string
 
 	// peerName identifies this BGP session to be used for metrics
 	peerName string
+	// onStateChange is called whenever the session goes up or down,
+	// or its advertised prefixes change.
+	onStateChange func()
 }
 
-// The 'Native' implementation does not require a session manager .
+// sessionManager keeps track of the native sessions, and accepts the
+// connections initiated by their peers.
 type sessionManager struct {
+	logger     log.Logger
+	listenAddr string
+
+	mu       sync.Mutex
+	listener *net.TCPListener
+	md5Keys  map[string]string
+	sessions map[*session]bool
+	dynamic  map[*dynamicSession]bool
+
+	stateCallback atomic.Pointer[func()]
 }
 
 func NewSessionManager(l log.Logger) bgp.SessionManager {
-	return &sessionManager{}
+	return &sessionManager{
+		logger:     l,
+		listenAddr: ":179",
+		sessions:   map[*session]bool{},
+		dynamic:    map[*dynamicSession]bool{},
+	}
 }
 
 // NewSession() creates a BGP session using the given session parameters.
@@ -71,21 +104,42 @@ func (sm *sessionManager) NewSession(l log.Logger, args bgp.SessionParameters) (
 		ht := 90 * time.Second
 		sessionsParams.HoldTime = &ht
 	}
+	if args.ListenRange != nil {
+		return sm.newDynamicSession(l, sessionsParams)
+	}
+
+	ret := newSession(l, sessionsParams)
+	ret.manager = sm
+	ret.onStateChange = sm.sessionStateChanged
+	if err := sm.register(ret); err != nil {
+		return nil, err
+	}
+	go ret.sendKeepalives()
+	go ret.run()
+
+	return ret, nil
+}
+
+// newSession creates a session, without connecting it.
+func newSession(l log.Logger, args bgp.SessionParameters) *session {
+	peer := args.PeerAddress
+	if args.PeerInterface != "" {
+		peer = args.PeerInterface
+	}
 	ret := &session{
-		SessionParameters: sessionsParams,
-		logger:            log.With(l, "peer", args.PeerAddress, "localASN", args.MyASN, "peerASN", args.PeerASN),
+		SessionParameters: args,
+		logger:            log.With(l, "peer", peer, "localASN", args.MyASN, "peerASN", args.PeerASN),
 		newHoldTime:       make(chan bool, 1),
+		connected:         make(chan struct{}, 1),
 		advertised:        map[string]*bgp.Advertisement{},
-		peerName:          fmt.Sprintf("%s:%d", args.PeerAddress, args.PeerPort),
+		peerName:          fmt.Sprintf("%s:%d", peer, args.PeerPort),
 	}
 	ret.cond = sync.NewCond(&ret.mu)
-	go ret.sendKeepalives()
-	go ret.run()
 
 	stats.sessionUp.WithLabelValues(ret.peerName).Set(0)
 	stats.prefixes.WithLabelValues(ret.peerName).Set(0)
 
-	return ret, nil
+	return ret
 }
 
 func (sm *sessionManager) SyncBFDProfiles(profiles map[string]*config.BFDProfile) error {
@@ -95,8 +149,8 @@ func (sm *sessionManager) SyncBFDProfiles(profiles map[string]*config.BFDProfile
 	return nil
 }
 
-func (sm *sessionManager) SyncExtraInfo(extras string) error {
-	if extras != "" {
+func (sm *sessionManager) SyncExtraInfo(extras config.BGPExtras) error {
+	if extras.Raw != "" || extras.BestPath != nil || len(extras.PrefixLists) > 0 {
 		return errors.New("bgp extra info not supported in native mode")
 	}
 	return nil
@@ -104,6 +158,108 @@ func (sm *sessionManager) SyncExtraInfo(extras string) error {
 
 func (sm *sessionManager) SetEventCallback(func(interface{})) {}
 
+// SetSessionStateCallback sets the function called whenever the state of
+// a session changes. The callback must not block.
+func (sm *sessionManager) SetSessionStateCallback(callback func()) {
+	sm.stateCallback.Store(&callback)
+}
+
+func (sm *sessionManager) sessionStateChanged() {
+	if callback := sm.stateCallback.Load(); callback != nil && *callback != nil {
+		(*callback)()
+	}
+}
+
+// SessionStates returns the state of the sessions, including the ones with
+// the neighbors of dynamic sessions.
+func (sm *sessionManager) SessionStates() []bgp.SessionState {
+	sm.mu.Lock()
+	sessions := make([]*session, 0, len(sm.sessions))
+	for s := range sm.sessions {
+		sessions = append(sessions, s)
+	}
+	dynamic := make([]*dynamicSession, 0, len(sm.dynamic))
+	for d := range sm.dynamic {
+		dynamic = append(dynamic, d)
+	}
+	sm.mu.Unlock()
+
+	for _, d := range dynamic {
+		d.mu.Lock()
+		for _, s := range d.neighbors {
+			sessions = append(sessions, s)
+		}
+		d.mu.Unlock()
+	}
+
+	res := make([]bgp.SessionState, 0, len(sessions))
+	for _, s := range sessions {
+		res = append(res, s.state())
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Peer < res[j].Peer })
+	return res
+}
+
+// state returns the current state of the session.
+func (s *session) state() bgp.SessionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	peer := s.PeerAddress
+	if s.PeerInterface != "" {
+		peer = s.PeerInterface
+	}
+	res := bgp.SessionState{
+		Peer:      peer,
+		State:     "Active",
+		LastError: s.lastError,
+	}
+	if s.conn != nil {
+		res.State = "Established"
+		res.UpSince = s.upSince
+		for _, adv := range s.advertised {
+			if s.canAdvertise(adv) {
+				res.PrefixesSent++
+			}
+		}
+	}
+	return res
+}
+
+// AdvertisementState tells whether the session is established and the
+// prefix was sent to the peer over it.
+func (s *session) AdvertisementState(prefix string) (bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return false, false
+	}
+	adv := s.advertised[prefix]
+	return true, adv != nil && s.canAdvertise(adv)
+}
+
+// canAdvertise tells if the advertisement can be sent over the current
+// connection, as IPv4 prefixes need the peer to support extended next
+// hops when our next hop is IPv6.
+func (s *session) canAdvertise(adv *bgp.Advertisement) bool {
+	return adv.Prefix.IP.To4() == nil || s.nextHop.To4() != nil || s.peerExtendedNextHop
+}
+
+func (s *session) notifyStateChange() {
+	if s.onStateChange != nil {
+		s.onStateChange()
+	}
+}
+
+// setLastError records the reason the session went down or failed to
+// establish.
+func (s *session) setLastError(err error) {
+	s.mu.Lock()
+	s.lastError = err.Error()
+	s.mu.Unlock()
+	s.notifyStateChange()
+}
+
 // run tries to stay connected to the peer, and pumps route updates to it.
 func (s *session) run() {
 	defer stats.DeleteSession(s.peerName)
@@ -113,20 +269,25 @@ func (s *session) run() {
 				return
 			}
 			level.Error(s.logger).Log("op", "connect", "error", err, "msg", "failed to connect to peer")
-			backoff := s.backoff.Duration()
-			time.Sleep(backoff)
+			s.setLastError(err)
+			select {
+			case <-time.After(s.backoff.Duration()):
+			case <-s.connected:
+			}
 			continue
 		}
 		stats.SessionUp(s.peerName)
 		s.backoff.Reset()
 
 		level.Info(s.logger).Log("event", "sessionUp", "msg", "BGP session established")
+		s.notifyStateChange()
 
 		if !s.sendUpdates() {
 			return
 		}
 		stats.SessionDown(s.peerName)
 		level.Warn(s.logger).Log("event", "sessionDown", "msg", "BGP session down")
+		s.notifyStateChange()
 	}
 }
 
@@ -139,19 +300,27 @@ func (s *session) sendUpdates() bool {
 	if s.closed {
 		return false
 	}
-	if s.conn == nil {
+	// The connection may be replaced by one the peer initiated, in
+	// which case we start over to send the full state on it.
+	conn := s.conn
+	if conn == nil {
 		return true
 	}
 
 	ibgp := s.MyASN == s.PeerASN
 	fbasn := s.peerFBASNSupport
+	addPath := s.peerAddPath
 
 	if s.new != nil {
 		s.advertised, s.new = s.new, nil
 	}
 
 	for c, adv := range s.advertised {
-		if err := sendUpdate(s.conn, s.MyASN, ibgp, fbasn, s.nextHop, adv); err != nil {
+		if !s.canAdvertise(adv) {
+			continue
+		}
+		if err := sendUpdate(conn, s.MyASN, ibgp, fbasn, addPath, s.nextHop, adv); err != nil {
+			s.lastError = err.Error()
 			s.abort()
 			level.Error(s.logger).Log("op", "sendUpdate", "ip", c, "error", err, "msg", "failed to send BGP update")
 			return true
@@ -159,16 +328,17 @@ func (s *session) sendUpdates() bool {
 		stats.UpdateSent(s.peerName)
 	}
 	stats.AdvertisedPrefixes(s.peerName, len(s.advertised))
+	s.notifyStateChange()
 
 	for {
-		for s.new == nil && s.conn != nil {
+		for s.new == nil && s.conn == conn {
 			s.cond.Wait()
 		}
 
 		if s.closed {
 			return false
 		}
-		if s.conn == nil {
+		if s.conn != conn {
 			return true
 		}
 		if s.new == nil {
@@ -183,8 +353,12 @@ func (s *session) sendUpdates() bool {
 				// advertisement, nothing to do.
 				continue
 			}
+			if !s.canAdvertise(adv) {
+				continue
+			}
 
-			if err := sendUpdate(s.conn, s.MyASN, ibgp, fbasn, s.nextHop, adv); err != nil {
+			if err := sendUpdate(conn, s.MyASN, ibgp, fbasn, addPath, s.nextHop, adv); err != nil {
+				s.lastError = err.Error()
 				s.abort()
 				level.Error(s.logger).Log("op", "sendUpdate", "prefix", c, "error", err, "msg", "failed to send BGP update")
 				return true
@@ -199,7 +373,8 @@ func (s *session) sendUpdates() bool {
 			}
 		}
 		if len(wdr) > 0 {
-			if err := sendWithdraw(s.conn, wdr); err != nil {
+			if err := sendWithdraw(conn, addPath, wdr); err != nil {
+				s.lastError = err.Error()
 				s.abort()
 				for _, pfx := range wdr {
 					level.Error(s.logger).Log("op", "sendWithdraw", "prefix", pfx, "error", err, "msg", "failed to send BGP withdraw")
@@ -210,71 +385,121 @@ func (s *session) sendUpdates() bool {
 		}
 		s.advertised, s.new = s.new, nil
 		stats.AdvertisedPrefixes(s.peerName, len(s.advertised))
+		s.notifyStateChange()
 	}
 }
 
-// connect establishes the BGP session with the peer.
-// Sets TCP_MD5 sockopt if password is !="".
+// connect establishes the BGP session with the peer. Passive sessions
+// wait for the peer to connect, others dial it unless the peer already
+// connected to us.
+// Sets the TCP-AO or TCP_MD5 sockopts if configured.
 func (s *session) connect() error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	for s.Passive && s.conn == nil && !s.closed {
+		s.cond.Wait()
+	}
+	closed, established := s.closed, s.conn != nil
+	s.mu.Unlock()
 
-	if s.closed {
+	if closed {
 		return errClosed
 	}
+	if established {
+		return nil
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	deadline, _ := ctx.Deadline()
-	conn, err := dialMD5(ctx, fmt.Sprintf("%s:%d", s.PeerAddress, s.PeerPort), s.SourceAddress, s.Password)
+
+	peerAddr, err := s.peerAddress(ctx)
+	if err != nil {
+		return err
+	}
+	conn, err := dialTCP(ctx, net.JoinHostPort(peerAddr, strconv.Itoa(int(s.PeerPort))), s.SourceAddress, s.authenticate)
 	if err != nil {
-		return fmt.Errorf("dial %q: %s", s.PeerAddress, err)
+		return fmt.Errorf("dial %q: %s", peerAddr, err)
 	}
+	return s.open(ctx, conn, true)
+}
 
-	if err = conn.SetDeadline(deadline); err != nil {
+// open exchanges OPEN messages with the peer over conn, which was
+// initiated by us if outgoing is true, and makes it the session's
+// connection unless it loses the connection collision resolution.
+// conn is closed on failure.
+func (s *session) open(ctx context.Context, conn net.Conn, outgoing bool) error {
+	peerAddr := conn.RemoteAddr().String()
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
 		conn.Close()
-		return fmt.Errorf("setting deadline on conn to %q: %s", s.PeerAddress, err)
+		return fmt.Errorf("setting deadline on conn to %q: %s", peerAddr, err)
 	}
 
 	addr, ok := conn.LocalAddr().(*net.TCPAddr)
 	if !ok {
 		conn.Close()
-		return fmt.Errorf("getting local addr for default nexthop to %q: %s", s.PeerAddress, err)
+		return fmt.Errorf("getting local addr for default nexthop to %q", peerAddr)
 	}
-	s.nextHop = addr.IP
+	nextHop := addr.IP
 
+	var err error
 	routerID := s.RouterID
 	if routerID == nil {
-		routerID, err = getRouterID(s.nextHop, s.CurrentNode)
+		routerID, err = getRouterID(nextHop, s.CurrentNode)
 		if err != nil {
+			conn.Close()
 			return err
 		}
 	}
 
-	if err = sendOpen(conn, s.MyASN, routerID, *s.HoldTime); err != nil {
+	// IPv4 prefixes can only be announced with an IPv6 next hop if
+	// the peer supports RFC 8950 extended next hop encoding. Unnumbered
+	// sessions have no other next hop to offer, so they require it.
+	extendedNextHop := nextHop.To4() == nil
+	if err = sendOpen(conn, s.MyASN, routerID, *s.HoldTime, extendedNextHop); err != nil {
 		conn.Close()
-		return fmt.Errorf("send OPEN to %q: %s", s.PeerAddress, err)
+		return fmt.Errorf("send OPEN to %q: %s", peerAddr, err)
 	}
 
 	op, err := readOpen(conn)
 	if err != nil {
 		conn.Close()
-		return fmt.Errorf("read OPEN from %q: %s", s.PeerAddress, err)
+		return fmt.Errorf("read OPEN from %q: %s", peerAddr, err)
 	}
 	if op.asn != s.PeerASN {
 		conn.Close()
 		return fmt.Errorf("unexpected peer ASN %d, want %d", op.asn, s.PeerASN)
 	}
-	s.peerFBASNSupport = op.fbasn
-	if s.MyASN > 65536 && !s.peerFBASNSupport {
+	if s.MyASN > 65536 && !op.fbasn {
 		conn.Close()
 		return fmt.Errorf("peer does not support 4-byte ASNs")
 	}
+	if s.PeerInterface != "" && extendedNextHop && !op.extendedNextHop {
+		conn.Close()
+		return fmt.Errorf("peer does not support IPv4 prefixes with IPv6 next hops")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		conn.Close()
+		return errClosed
+	}
+	if s.conn != nil {
+		if !newConnectionWins(s.confirmed, s.connOutgoing, outgoing, routerID, op.routerID) {
+			_ = sendNotification(conn, 0x0607)
+			conn.Close()
+			return fmt.Errorf("connection collision with %q, keeping the existing connection", peerAddr)
+		}
+		level.Info(s.logger).Log("event", "connectionCollision", "msg", "closing existing connection in favor of the new one")
+		_ = sendNotification(s.conn, 0x0607)
+		s.abort()
+	}
 
 	// BGP session is established, clear the connect timeout deadline.
 	if err := conn.SetDeadline(time.Time{}); err != nil {
 		conn.Close()
-		return fmt.Errorf("clearing deadline on conn to %q: %s", s.PeerAddress, err)
+		return fmt.Errorf("clearing deadline on conn to %q: %s", peerAddr, err)
 	}
 
 	// Consume BGP messages until the connection closes.
@@ -283,7 +508,7 @@ func (s *session) connect() error {
 	// Send one keepalive to say that yes, we accept the OPEN.
 	if err := sendKeepalive(conn); err != nil {
 		conn.Close()
-		return fmt.Errorf("accepting peer OPEN from %q: %s", s.PeerAddress, err)
+		return fmt.Errorf("accepting peer OPEN from %q: %s", peerAddr, err)
 	}
 
 	// Set up regular keepalives from now on.
@@ -296,10 +521,71 @@ func (s *session) connect() error {
 	default:
 	}
 
+	s.nextHop = nextHop
+	s.upSince = time.Now()
+	s.lastError = ""
+	if extendedNextHop && !op.extendedNextHop {
+		s.lastError = "peer does not support IPv4 prefixes with IPv6 next hops, not advertising them"
+		level.Warn(s.logger).Log("event", "sessionUp", "msg", s.lastError)
+	}
+	s.peerFBASNSupport = op.fbasn
+	s.peerAddPath = op.addPath
+	s.peerExtendedNextHop = op.extendedNextHop
 	s.conn = conn
+	s.connOutgoing = outgoing
+	s.confirmed = false
+	s.cond.Broadcast()
+	if !outgoing {
+		select {
+		case s.connected <- struct{}{}:
+		default:
+		}
+	}
 	return nil
 }
 
+// newConnectionWins tells whether a new connection to the peer should
+// replace the existing one, per RFC 4271 section 6.8. A connection on
+// which the peer confirmed our OPEN is kept. Otherwise, the connection
+// initiated by the speaker with the higher BGP identifier is kept.
+func newConnectionWins(confirmed, existingOutgoing, outgoing bool, localID, remoteID net.IP) bool {
+	if confirmed {
+		return false
+	}
+	if existingOutgoing == outgoing {
+		// Both initiated by the same side, the existing
+		// connection is stale.
+		return true
+	}
+	keepOutgoing := routerIDValue(localID) > routerIDValue(remoteID)
+	return outgoing == keepOutgoing
+}
+
+func routerIDValue(id net.IP) uint32 {
+	id = id.To4()
+	if id == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(id)
+}
+
+// peerAddress returns the address to dial to reach the peer. For
+// unnumbered sessions, it discovers the link-local address of the
+// neighbor on the session's interface.
+func (s *session) peerAddress(ctx context.Context) (string, error) {
+	if s.PeerInterface == "" && s.PeerZone != "" {
+		return s.PeerAddress + "%" + s.PeerZone, nil
+	}
+	if s.PeerInterface == "" {
+		return s.PeerAddress, nil
+	}
+	ip, err := discoverNeighbor(ctx, s.PeerInterface)
+	if err != nil {
+		return "", fmt.Errorf("discovering neighbor on %q: %w", s.PeerInterface, err)
+	}
+	return ip.String() + "%" + s.PeerInterface, nil
+}
+
 func hashRouterID(hostname string) (net.IP, error) {
 	buf := new(bytes.Buffer)
 	err := binary.Write(buf, binary.LittleEndian, crc32.ChecksumIEEE([]byte(hostname)))
@@ -423,6 +709,7 @@ func (s *session) consumeBGP(conn io.ReadCloser) {
 		}
 	}()
 
+	confirmed := false
 	for {
 		hdr := struct {
 			Marker1, Marker2 uint64
@@ -437,10 +724,25 @@ func (s *session) consumeBGP(conn io.ReadCloser) {
 			// TODO: propagate
 			return
 		}
+		if !confirmed && (hdr.Type == 2 || hdr.Type == 4) {
+			// The peer accepted our OPEN, this connection now
+			// wins any collision.
+			confirmed = true
+			s.mu.Lock()
+			if s.conn == conn {
+				s.confirmed = true
+			}
+			s.mu.Unlock()
+		}
 		if hdr.Type == 3 {
 			// TODO: propagate better than just logging directly.
 			err := readNotification(conn)
 			level.Error(s.logger).Log("event", "peerNotification", "error", err, "msg", "peer sent notification, closing session")
+			s.mu.Lock()
+			if s.conn == conn {
+				s.lastError = err.Error()
+			}
+			s.mu.Unlock()
 			return
 		}
 		if _, err := io.Copy(io.Discard, io.LimitReader(conn, int64(hdr.Len)-19)); err != nil {
@@ -455,8 +757,25 @@ func validate(adv *bgp.Advertisement) error {
 		return fmt.Errorf("cannot advertise non-v4 prefix %q", adv.Prefix)
 	}
 
-	if len(adv.Communities) > 63 {
-		return fmt.Errorf("max supported communities is 63, got %d", len(adv.Communities))
+	var legacy, extended, large int
+	for _, c := range adv.Communities {
+		switch {
+		case community.IsExtended(c):
+			extended++
+		case community.IsLarge(c):
+			large++
+		default:
+			legacy++
+		}
+	}
+	if legacy > 63 {
+		return fmt.Errorf("max supported communities is 63, got %d", legacy)
+	}
+	if extended > 31 {
+		return fmt.Errorf("max supported extended communities is 31, got %d", extended)
+	}
+	if large > 21 {
+		return fmt.Errorf("max supported large communities is 21, got %d", large)
 	}
 	return nil
 }
@@ -484,6 +803,8 @@ func (s *session) Set(advs ...*bgp.Advertisement) error {
 
 		merged := *existing
 		merged.Communities = mergeCommunities(existing.Communities, adv.Communities)
+		merged.ASPathPrepend = max(existing.ASPathPrepend, adv.ASPathPrepend)
+		merged.MED = bgp.LowestMED(existing.MED, adv.MED)
 		if err := validate(&merged); err != nil {
 			return fmt.Errorf("invalid merged advertisement for prefix %s: %w", key, err)
 		}
@@ -521,6 +842,7 @@ func (s *session) abort() {
 	if s.conn != nil {
 		s.conn.Close()
 		s.conn = nil
+		s.upSince = time.Time{}
 		stats.SessionDown(s.peerName)
 	}
 	// Next time we retry the connection, we can just skip straight to
@@ -536,17 +858,84 @@ func (s *session) abort() {
 // Close shuts down the BGP session.
 func (s *session) Close() error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	s.closed = true
 	s.abort()
+	s.mu.Unlock()
+
+	if s.manager != nil {
+		s.manager.unregister(s)
+	}
+	return nil
+}
+
+// authenticate sets the TCP-AO or TCP MD5 options for the peer on the socket
+// about to connect to it. TCP MD5 is used instead of TCP-AO if the kernel
+// does not support it and a password is set.
+func (s *session) authenticate(fd int, peer net.IP) error {
+	s.mu.Lock()
+	tcpAO := s.TCPAO
+	s.mu.Unlock()
+
+	if tcpAO != nil {
+		err := setTCPAO(fd, peer, tcpAO)
+		if !errors.Is(err, errTCPAOUnsupported) || s.Password == "" {
+			return err
+		}
+		level.Warn(s.logger).Log("op", "connect", "msg", "TCP-AO not supported by the kernel, falling back to TCP MD5")
+	}
+
+	if s.Password != "" {
+		sig, err := buildTCPMD5Sig(peer, s.Password)
+		if err != nil {
+			return err
+		}
+		// Better way may be available in  Go 1.11, see go-review.googlesource.com/c/go/+/72810
+		if err = os.NewSyscallError("setsockopt", unix.SetsockoptTCPMD5Sig(fd, unix.IPPROTO_TCP, unix.TCP_MD5SIG, sig)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateTCPAO rolls over the TCP-AO keys of the session, applying them to
+// the established connection if any.
+func (s *session) UpdateTCPAO(tcpAO *config.TCPAO) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.TCPAO == nil || tcpAO == nil {
+		return errors.New("TCP-AO cannot be enabled or disabled on a running session")
+	}
+	if s.conn != nil {
+		tcpConn, ok := s.conn.(syscall.Conn)
+		if !ok {
+			return errors.New("connection does not support socket options")
+		}
+		raw, err := tcpConn.SyscallConn()
+		if err != nil {
+			return err
+		}
+		peer := s.conn.RemoteAddr().(*net.TCPAddr).IP
+		var rolloverErr error
+		err = raw.Control(func(fd uintptr) {
+			rolloverErr = rolloverTCPAO(int(fd), peer, s.TCPAO, tcpAO)
+		})
+		if err != nil {
+			return err
+		}
+		if rolloverErr != nil {
+			return rolloverErr
+		}
+	}
+	s.TCPAO = tcpAO
 	return nil
 }
 
-// DialTCP does the part of creating a connection manually,  including setting the
-// proper TCP MD5 options when the password is not empty. Works by manipulating
+// dialTCP does the part of creating a connection manually, including calling
+// auth to set the TCP-AO or TCP MD5 options before connecting. Works by manipulating
 // the low level FD's, skipping the net.Conn API as it has not hooks to set
 // the necessary sockopts for TCP MD5.
-func dialMD5(ctx context.Context, addr string, srcAddr net.IP, password string) (net.Conn, error) {
+func dialTCP(ctx context.Context, addr string, srcAddr net.IP, auth func(fd int, peer net.IP) error) (net.Conn, error) {
 	// If srcAddr exists on any of the local network interfaces, use it as the
 	// source address of the TCP socket. Otherwise, use the IPv6 unspecified
 	// address ("::") to let the kernel figure out the source address.
@@ -587,19 +976,16 @@ func dialMD5(ctx context.Context, addr string, srcAddr net.IP, password string)
 		la = lsockaddr
 	} else {
 		family = unix.AF_INET6
-		rsockaddr := &unix.SockaddrInet6{Port: raddr.Port}
+		rzone, err := zoneID(raddr.Zone)
+		if err != nil {
+			return nil, err
+		}
+		rsockaddr := &unix.SockaddrInet6{Port: raddr.Port, ZoneId: rzone}
 		copy(rsockaddr.Addr[:], raddr.IP.To16())
 		ra = rsockaddr
-		var zone uint32
-		if laddr.Zone != "" {
-			intf, errs := net.InterfaceByName(laddr.Zone)
-			if errs != nil {
-				return nil, errs
-			}
-			zone, err = safeconvert.IntToUInt32(intf.Index)
-			if err != nil {
-				return nil, fmt.Errorf("invalid interface index %d", intf.Index)
-			}
+		zone, err := zoneID(laddr.Zone)
+		if err != nil {
+			return nil, err
 		}
 		lsockaddr := &unix.SockaddrInet6{ZoneId: zone}
 		copy(lsockaddr.Addr[:], laddr.IP.To16())
@@ -627,15 +1013,8 @@ func dialMD5(ctx context.Context, addr string, srcAddr net.IP, password string)
 		}
 	}()
 
-	if password != "" {
-		sig, err := buildTCPMD5Sig(raddr.IP, password)
-		if err != nil {
-			return nil, err
-		}
-		// Better way may be available in  Go 1.11, see go-review.googlesource.com/c/go/+/72810
-		if err = os.NewSyscallError("setsockopt", unix.SetsockoptTCPMD5Sig(fd, unix.IPPROTO_TCP, unix.TCP_MD5SIG, sig)); err != nil {
-			return nil, err
-		}
+	if err = auth(fd, raddr.IP); err != nil {
+		return nil, err
 	}
 
 	if err = unix.Bind(fd, la); err != nil {
@@ -711,6 +1090,23 @@ func dialMD5(ctx context.Context, addr string, srcAddr net.IP, password string)
 	}
 }
 
+// zoneID returns the index of the interface named by an IPv6 zone, or 0
+// if the zone is empty.
+func zoneID(zone string) (uint32, error) {
+	if zone == "" {
+		return 0, nil
+	}
+	intf, err := net.InterfaceByName(zone)
+	if err != nil {
+		return 0, err
+	}
+	id, err := safeconvert.IntToUInt32(intf.Index)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interface index %d", intf.Index)
+	}
+	return id, nil
+}
+
 func buildTCPMD5Sig(addr net.IP, key string) (*unix.TCPMD5Sig, error) {
 	t := unix.TCPMD5Sig{}
 	if addr.To4() != nil {
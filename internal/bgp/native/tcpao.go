@@ -0,0 +1,222 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package native
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"go.universe.tf/metallb/internal/config"
+	"go.universe.tf/metallb/internal/safeconvert"
+	"golang.org/x/sys/unix"
+)
+
+// TCP-AO socket options and limits, from include/uapi/linux/tcp.h.
+const (
+	tcpAOAddKey    = 38
+	tcpAODelKey    = 39
+	tcpAOInfo      = 40
+	tcpAOMaxKeyLen = 80
+	tcpAOMacLen    = 12
+)
+
+var errTCPAOUnsupported = errors.New("TCP-AO not supported by the kernel")
+
+// tcpAOAlgorithms maps the API algorithm names to the kernel crypto ones.
+var tcpAOAlgorithms = map[string]string{
+	"hmac-sha1":   "hmac(sha1)",
+	"cmac-aes128": "cmac(aes128)",
+	"hmac-sha256": "hmac(sha256)",
+}
+
+// tcpAOAdd is struct tcp_ao_add.
+type tcpAOAdd struct {
+	Addr      [128]byte
+	AlgName   [64]byte
+	Ifindex   int32
+	Flags     uint32
+	Reserved2 uint16
+	Prefix    uint8
+	SndID     uint8
+	RcvID     uint8
+	MacLen    uint8
+	KeyFlags  uint8
+	KeyLen    uint8
+	Key       [tcpAOMaxKeyLen]byte
+}
+
+// tcpAODel is struct tcp_ao_del.
+type tcpAODel struct {
+	Addr       [128]byte
+	Ifindex    int32
+	Flags      uint32
+	Reserved2  uint16
+	Prefix     uint8
+	SndID      uint8
+	RcvID      uint8
+	CurrentKey uint8
+	RNext      uint8
+	KeyFlags   uint8
+}
+
+// tcpAOInfoOpt is struct tcp_ao_info_opt.
+type tcpAOInfoOpt struct {
+	Flags      uint32
+	Reserved2  uint16
+	CurrentKey uint8
+	RNext      uint8
+	Counters   [5]uint64
+}
+
+// tcpAOFlags returns the set_current and set_rnext bitfields shared by
+// the TCP-AO structs, which are allocated from the least significant bit
+// on little endian architectures and from the most significant one
+// otherwise.
+func tcpAOFlags(setCurrent, setRNext bool) uint32 {
+	var current, rnext uint32 = 1, 1 << 1
+	if binary.NativeEndian.Uint16([]byte{0, 1}) == 1 {
+		current, rnext = 1<<31, 1<<30
+	}
+	var flags uint32
+	if setCurrent {
+		flags |= current
+	}
+	if setRNext {
+		flags |= rnext
+	}
+	return flags
+}
+
+// tcpAOSockaddr fills a struct sockaddr_storage with the peer address,
+// laid out as sockaddr_in or sockaddr_in6, and returns its prefix length.
+func tcpAOSockaddr(addr net.IP) ([128]byte, uint8) {
+	var ret [128]byte
+	if addr.To4() != nil {
+		binary.NativeEndian.PutUint16(ret[:], unix.AF_INET)
+		copy(ret[4:], addr.To4())
+		return ret, 32
+	}
+	binary.NativeEndian.PutUint16(ret[:], unix.AF_INET6)
+	copy(ret[8:], addr.To16())
+	return ret, 128
+}
+
+func buildTCPAOAdd(addr net.IP, key config.TCPAOKey, setCurrent bool) (*tcpAOAdd, error) {
+	alg, ok := tcpAOAlgorithms[key.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unknown TCP-AO algorithm %q", key.Algorithm)
+	}
+	if len(key.Key) > tcpAOMaxKeyLen {
+		return nil, fmt.Errorf("TCP-AO key longer than %d bytes", tcpAOMaxKeyLen)
+	}
+	keyLen, err := safeconvert.IntToUInt8(len(key.Key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid keyLen %w", err)
+	}
+	ret := &tcpAOAdd{
+		Flags:  tcpAOFlags(setCurrent, setCurrent),
+		SndID:  key.SendID,
+		RcvID:  key.RecvID,
+		MacLen: tcpAOMacLen,
+		KeyLen: keyLen,
+	}
+	ret.Addr, ret.Prefix = tcpAOSockaddr(addr)
+	copy(ret.AlgName[:], alg)
+	copy(ret.Key[:], key.Key)
+	return ret, nil
+}
+
+func setsockoptStruct(fd, opt int, v any) error {
+	var b bytes.Buffer
+	if err := binary.Write(&b, binary.NativeEndian, v); err != nil {
+		return err
+	}
+	err := unix.SetsockoptString(fd, unix.IPPROTO_TCP, opt, b.String())
+	if errors.Is(err, unix.ENOPROTOOPT) {
+		return errTCPAOUnsupported
+	}
+	return os.NewSyscallError("setsockopt", err)
+}
+
+// setTCPAO installs the TCP-AO keys for the given peer on a socket that is
+// not connected yet.
+func setTCPAO(fd int, addr net.IP, tcpAO *config.TCPAO) error {
+	for _, k := range tcpAO.Keys {
+		add, err := buildTCPAOAdd(addr, k, k.SendID == tcpAO.CurrentKeyID)
+		if err != nil {
+			return err
+		}
+		if err := setsockoptStruct(fd, tcpAOAddKey, add); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rolloverTCPAO moves the keys of a connected socket from the old to the
+// new configuration: new keys are added first, then the current key is
+// switched and the keys no longer configured are removed last, so the
+// session never runs without a valid key.
+// Changing the material or the algorithm of an existing key is not
+// possible without resetting the connection.
+func rolloverTCPAO(fd int, addr net.IP, oldAO, newAO *config.TCPAO) error {
+	oldKeys := map[uint8]config.TCPAOKey{}
+	for _, k := range oldAO.Keys {
+		oldKeys[k.SendID] = k
+	}
+	newKeys := map[uint8]bool{}
+	for _, k := range newAO.Keys {
+		newKeys[k.SendID] = true
+		old, ok := oldKeys[k.SendID]
+		if ok && old != k {
+			return fmt.Errorf("TCP-AO key %d changed", k.SendID)
+		}
+		if ok {
+			continue
+		}
+		add, err := buildTCPAOAdd(addr, k, false)
+		if err != nil {
+			return err
+		}
+		if err := setsockoptStruct(fd, tcpAOAddKey, add); err != nil {
+			return err
+		}
+	}
+
+	if newAO.CurrentKeyID != oldAO.CurrentKeyID {
+		current := newAO.CurrentKeyID
+		var rnext uint8
+		for _, k := range newAO.Keys {
+			if k.SendID == current {
+				rnext = k.RecvID
+			}
+		}
+		info := &tcpAOInfoOpt{
+			Flags:      tcpAOFlags(true, true),
+			CurrentKey: current,
+			RNext:      rnext,
+		}
+		if err := setsockoptStruct(fd, tcpAOInfo, info); err != nil {
+			return err
+		}
+	}
+
+	for _, k := range oldAO.Keys {
+		if newKeys[k.SendID] {
+			continue
+		}
+		del := &tcpAODel{
+			SndID: k.SendID,
+			RcvID: k.RecvID,
+		}
+		del.Addr, del.Prefix = tcpAOSockaddr(addr)
+		if err := setsockoptStruct(fd, tcpAODelKey, del); err != nil {
+			return err
+		}
+	}
+	return nil
+}
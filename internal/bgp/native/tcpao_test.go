@@ -0,0 +1,157 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package native
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.universe.tf/metallb/internal/config"
+	"golang.org/x/sys/unix"
+)
+
+func TestTCPAOStructSizes(t *testing.T) {
+	// Sizes of the structs in include/uapi/linux/tcp.h.
+	tests := []struct {
+		desc string
+		v    any
+		size int
+	}{
+		{"tcp_ao_add", tcpAOAdd{}, 288},
+		{"tcp_ao_del", tcpAODel{}, 144},
+		{"tcp_ao_info_opt", tcpAOInfoOpt{}, 48},
+	}
+	for _, tc := range tests {
+		if got := binary.Size(tc.v); got != tc.size {
+			t.Errorf("%s: wrong size, want %d got %d", tc.desc, tc.size, got)
+		}
+	}
+}
+
+func TestBuildTCPAOAdd(t *testing.T) {
+	key := config.TCPAOKey{SendID: 3, RecvID: 4, Algorithm: "hmac-sha256", Key: "secret"}
+	add, err := buildTCPAOAdd(net.ParseIP("2001:db8::1"), key, true)
+	if err != nil {
+		t.Fatalf("build key: %s", err)
+	}
+	if got := binary.NativeEndian.Uint16(add.Addr[:]); got != unix.AF_INET6 {
+		t.Errorf("wrong family, got %d", got)
+	}
+	if got := net.IP(add.Addr[8:24]); !got.Equal(net.ParseIP("2001:db8::1")) {
+		t.Errorf("wrong address, got %s", got)
+	}
+	if add.Prefix != 128 || add.SndID != 3 || add.RcvID != 4 || add.KeyLen != 6 || add.MacLen != tcpAOMacLen {
+		t.Errorf("wrong key fields: %+v", add)
+	}
+	if got := string(add.AlgName[:len("hmac(sha256)")]); got != "hmac(sha256)" {
+		t.Errorf("wrong algorithm, got %q", got)
+	}
+	if add.Flags != tcpAOFlags(true, true) {
+		t.Errorf("key not set as current")
+	}
+
+	key.Algorithm = "md5"
+	if _, err := buildTCPAOAdd(net.ParseIP("192.168.1.1"), key, false); err == nil {
+		t.Errorf("expected error with unknown algorithm")
+	}
+}
+
+func TestTCPAOLoopback(t *testing.T) {
+	keys := &config.TCPAO{
+		Keys: []config.TCPAOKey{
+			{SendID: 1, RecvID: 1, Algorithm: "hmac-sha1", Key: "key1"},
+		},
+		CurrentKeyID: 1,
+	}
+	loopback := net.ParseIP("127.0.0.1")
+	lc := net.ListenConfig{
+		Control: func(_, _ string, c syscall.RawConn) error {
+			var err error
+			if cerr := c.Control(func(fd uintptr) {
+				err = setTCPAO(int(fd), loopback, keys)
+			}); cerr != nil {
+				return cerr
+			}
+			return err
+		},
+	}
+	l, err := lc.Listen(context.Background(), "tcp4", "127.0.0.1:0")
+	if errors.Is(err, errTCPAOUnsupported) || errors.Is(err, unix.EPERM) {
+		t.Skipf("TCP-AO not available: %s", err)
+	}
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			close(accepted)
+			return
+		}
+		accepted <- c
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := dialTCP(ctx, l.Addr().String(), nil, func(fd int, peer net.IP) error {
+		return setTCPAO(fd, peer, keys)
+	})
+	if err != nil {
+		t.Fatalf("dial: %s", err)
+	}
+	defer conn.Close()
+	server, ok := <-accepted
+	if !ok {
+		t.Fatalf("accept failed")
+	}
+	defer server.Close()
+
+	rolled := &config.TCPAO{
+		Keys: []config.TCPAOKey{
+			{SendID: 2, RecvID: 2, Algorithm: "hmac-sha1", Key: "key2"},
+		},
+		CurrentKeyID: 2,
+	}
+	for _, c := range []net.Conn{conn, server} {
+		raw, err := c.(syscall.Conn).SyscallConn()
+		if err != nil {
+			t.Fatalf("syscall conn: %s", err)
+		}
+		var rolloverErr error
+		if err := raw.Control(func(fd uintptr) {
+			rolloverErr = rolloverTCPAO(int(fd), loopback, keys, rolled)
+		}); err != nil {
+			t.Fatalf("control: %s", err)
+		}
+		if rolloverErr != nil {
+			t.Fatalf("rollover: %s", rolloverErr)
+		}
+	}
+
+	if _, err := conn.Write([]byte("hello")); err != nil {
+		t.Fatalf("write after rollover: %s", err)
+	}
+	if err := server.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("set deadline: %s", err)
+	}
+	buf := make([]byte, 5)
+	if _, err := server.Read(buf); err != nil {
+		t.Fatalf("read after rollover: %s", err)
+	}
+}
+
+func TestRolloverTCPAOChangedKey(t *testing.T) {
+	oldAO := &config.TCPAO{Keys: []config.TCPAOKey{{SendID: 1, RecvID: 1, Algorithm: "hmac-sha1", Key: "key1"}}, CurrentKeyID: 1}
+	newAO := &config.TCPAO{Keys: []config.TCPAOKey{{SendID: 1, RecvID: 1, Algorithm: "hmac-sha1", Key: "other"}}, CurrentKeyID: 1}
+	if err := rolloverTCPAO(-1, net.ParseIP("127.0.0.1"), oldAO, newAO); err == nil {
+		t.Errorf("expected error when changing an existing key")
+	}
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package native
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/mdlayher/ndp"
+)
+
+// allRouters is the link-local scope all-routers multicast address, used as
+// destination for router solicitations.
+var allRouters = net.ParseIP("ff02::2")
+
+// discoverNeighbor finds the IPv6 link-local address of the BGP speaker on
+// the other end of iface, for unnumbered BGP sessions.
+//
+// Unnumbered peers (e.g. FRR with "neighbor <iface> interface") send
+// router advertisements on the link, so we solicit one and use the
+// source address of the first router advertisement or neighbor
+// discovery message we receive.
+func discoverNeighbor(ctx context.Context, iface string) (net.IP, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("looking up interface %q: %w", iface, err)
+	}
+	conn, self, err := ndp.Dial(ifi, ndp.LinkLocal)
+	if err != nil {
+		return nil, fmt.Errorf("creating NDP listener on %q: %w", iface, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	rs := &ndp.RouterSolicitation{
+		Options: []ndp.Option{
+			&ndp.LinkLayerAddress{
+				Direction: ndp.Source,
+				Addr:      ifi.HardwareAddr,
+			},
+		},
+	}
+	if err := conn.WriteTo(rs, nil, allRouters); err != nil {
+		return nil, fmt.Errorf("sending router solicitation on %q: %w", iface, err)
+	}
+
+	return neighborFromNDP(func() (ndp.Message, net.IP, error) {
+		msg, _, from, err := conn.ReadFrom()
+		return msg, from, err
+	}, self)
+}
+
+// neighborFromNDP reads NDP messages using next until one reveals the
+// link-local address of a neighbor other than self.
+func neighborFromNDP(next func() (ndp.Message, net.IP, error), self net.IP) (net.IP, error) {
+	for {
+		msg, from, err := next()
+		if err != nil {
+			var nerr net.Error
+			if errors.As(err, &nerr) && nerr.Timeout() {
+				return nil, fmt.Errorf("timeout waiting for neighbor advertisement")
+			}
+			return nil, err
+		}
+		if !from.IsLinkLocalUnicast() || from.Equal(self) {
+			continue
+		}
+		switch msg.(type) {
+		case *ndp.RouterAdvertisement, *ndp.NeighborSolicitation, *ndp.NeighborAdvertisement:
+			return from, nil
+		}
+	}
+}
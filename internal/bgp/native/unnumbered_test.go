@@ -0,0 +1,82 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package native
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/mdlayher/ndp"
+)
+
+func TestNeighborFromNDP(t *testing.T) {
+	self := net.ParseIP("fe80::1")
+	type msg struct {
+		m    ndp.Message
+		from net.IP
+	}
+	tests := []struct {
+		desc    string
+		msgs    []msg
+		want    net.IP
+		wantErr bool
+	}{
+		{
+			desc: "router advertisement",
+			msgs: []msg{
+				{&ndp.RouterAdvertisement{}, net.ParseIP("fe80::2")},
+			},
+			want: net.ParseIP("fe80::2"),
+		},
+		{
+			desc: "skips own messages and non link-local sources",
+			msgs: []msg{
+				{&ndp.RouterSolicitation{}, self},
+				{&ndp.NeighborSolicitation{}, net.ParseIP("2001:db8::2")},
+				{&ndp.NeighborAdvertisement{}, self},
+				{&ndp.NeighborSolicitation{}, net.ParseIP("fe80::3")},
+			},
+			want: net.ParseIP("fe80::3"),
+		},
+		{
+			desc: "skips router solicitations",
+			msgs: []msg{
+				{&ndp.RouterSolicitation{}, net.ParseIP("fe80::4")},
+				{&ndp.RouterAdvertisement{}, net.ParseIP("fe80::5")},
+			},
+			want: net.ParseIP("fe80::5"),
+		},
+		{
+			desc:    "timeout",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			msgs := test.msgs
+			next := func() (ndp.Message, net.IP, error) {
+				if len(msgs) == 0 {
+					return nil, nil, os.ErrDeadlineExceeded
+				}
+				m := msgs[0]
+				msgs = msgs[1:]
+				return m.m, m.from, nil
+			}
+			got, err := neighborFromNDP(next, self)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got neighbor %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !got.Equal(test.want) {
+				t.Fatalf("wrong neighbor, want %s, got %s", test.want, got)
+			}
+		})
+	}
+}
@@ -17,10 +17,13 @@ package config // import "go.universe.tf/metallb/internal/config"
 import (
 	"bytes"
 	"fmt"
+	"maps"
+	"math/big"
 	"net"
 	"reflect"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -32,6 +35,7 @@ import (
 	metallbv1beta2 "go.universe.tf/metallb/api/v1beta2"
 	"go.universe.tf/metallb/internal/bgp/community"
 	"go.universe.tf/metallb/internal/ipfamily"
+	"go.universe.tf/metallb/internal/safeconvert"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -43,6 +47,7 @@ import (
 type ClusterResources struct {
 	Pools           []metallbv1beta1.IPAddressPool    `json:"ipaddresspools"`
 	Peers           []metallbv1beta2.BGPPeer          `json:"bgppeers"`
+	PeerTemplates   []metallbv1beta2.BGPPeerTemplate  `json:"bgppeertemplates"`
 	BFDProfiles     []metallbv1beta1.BFDProfile       `json:"bfdprofiles"`
 	BGPAdvs         []metallbv1beta1.BGPAdvertisement `json:"bgpadvertisements"`
 	L2Advs          []metallbv1beta1.L2Advertisement  `json:"l2advertisements"`
@@ -51,6 +56,7 @@ type ClusterResources struct {
 	Nodes           []corev1.Node                     `json:"nodes"`
 	Namespaces      []corev1.Namespace                `json:"namespaces"`
 	BGPExtras       corev1.ConfigMap                  `json:"bgpextras"`
+	Extras          []metallbv1beta1.BGPExtras        `json:"extras"`
 }
 
 // ForOptions holds optional parameters for the For config parser.
@@ -70,7 +76,63 @@ type Config struct {
 	// BFD profiles that can be used by peers.
 	BFDProfiles map[string]*BFDProfile
 	// Protocol dependent extra config. Currently used only by FRR
-	BGPExtras string
+	BGPExtras BGPExtras
+}
+
+// BGPExtras is the extra BGP configuration that does not belong to a
+// specific peer.
+type BGPExtras struct {
+	// Raw FRR configuration, appended to the generated one.
+	Raw string
+	// Optional knobs of the best path selection.
+	BestPath *BestPath
+	// Static prefix-lists, sorted by name.
+	PrefixLists []*PrefixList
+}
+
+// BestPath holds the knobs of the best path selection.
+type BestPath struct {
+	ASPathMultipathRelax bool
+	ASPathIgnore         bool
+	CompareRouterID      bool
+	MEDMissingAsWorst    bool
+}
+
+// PrefixList is a named list of prefixes, evaluated in order.
+type PrefixList struct {
+	Name    string
+	Entries []PrefixListEntry
+}
+
+// PrefixListEntry permits or denies the prefixes it selects.
+type PrefixListEntry struct {
+	PrefixSelector
+	Deny bool
+}
+
+// NeighborExtras is the extra configuration of the sessions with a peer.
+type NeighborExtras struct {
+	// The number of prefixes accepted from the peer before tearing the
+	// session down, unlimited if nil.
+	MaximumPrefix *uint32
+	// The number of times the local ASN is accepted in the AS_PATH of the
+	// received prefixes, nil if it is not.
+	AllowASIn *uint32
+	// The policies applied to the received and to the advertised prefixes.
+	Import []PolicyEntry
+	Export []PolicyEntry
+}
+
+// PolicyEntry is an entry of the policy applied to the prefixes exchanged
+// with a peer. A matching prefix is dropped if Deny is set, otherwise it
+// gets the given attributes.
+type PolicyEntry struct {
+	Deny bool
+	// The prefixes matched by the entry, all of them when nil.
+	PrefixList  *PrefixList
+	LocalPref   *uint32
+	Metric      *uint32
+	Communities []community.BGPCommunity
 }
 
 // Pools contains address pools and its namespace/service specific allocations.
@@ -94,6 +156,14 @@ const (
 
 const bgpExtrasField = "extras"
 
+// maxASPathPrepend is the highest number of times the local ASN can be
+// prepended to the AS_PATH of an advertisement.
+const maxASPathPrepend = 10
+
+// DefaultVRF is the name of the default VRF, when it is referenced along
+// with the other ones.
+const DefaultVRF = "default"
+
 var Protocols = []Proto{
 	BGP, Layer2,
 }
@@ -102,6 +172,8 @@ var Protocols = []Proto{
 type Peer struct {
 	// Peer name.
 	Name string
+	// The name of the peer template the peer was merged with, if any.
+	PeerTemplate string
 	// AS number to use for the local end of the session.
 	MyASN uint32
 	// AS number to expect from the remote end of the session.
@@ -113,6 +185,14 @@ type Peer struct {
 	// Iface is the Interface to use for Unnumbered BGP peering.
 	// Addr field must be nil.
 	Iface string
+	// ListenRange is the prefix dynamic peers are accepted from.
+	// Addr and Iface fields must be empty.
+	ListenRange *net.IPNet
+	// AddrFrom tells where the address to dial is read from on each
+	// node. Addr, Iface and ListenRange fields must be empty.
+	AddrFrom *PeerAddressSource
+	// Passive peers are not dialed, they establish the session themselves.
+	Passive bool
 	// Source address to use when establishing the session.
 	SrcAddr net.IP
 	// Port to dial when establishing the session.
@@ -134,6 +214,8 @@ type Peer struct {
 	SecretPassword string
 	// Optional reference to the secret that holds the password.
 	PasswordRef corev1.SecretReference
+	// Optional TCP-AO keys, with the key material read from their secrets.
+	TCPAO *TCPAO
 	// The optional BFD profile to be used for this BGP session
 	BFDProfile string
 	// Optional EnableGracefulRestart enable BGP graceful restart functionality at the peer level.
@@ -150,6 +232,65 @@ type Peer struct {
 	// "neighbor <peer> local-as <ASN> no-prepend replace-as".
 	// Supported for FRR mode only.
 	LocalASN uint32
+	// Optional prefixes accepted from the peer. All the incoming
+	// prefixes are denied when nil.
+	ToReceive *Receive
+	// Optional extra configuration of the sessions, from the BGPExtras.
+	Extras *NeighborExtras
+	// Optional limits of the prefixes exchanged with the peer.
+	PrefixLimits *PrefixLimits
+}
+
+// PrefixLimits caps the number of prefixes exchanged with a peer, zero
+// meaning unlimited.
+type PrefixLimits struct {
+	// The prefixes beyond this number are not advertised to the peer.
+	MaxAdvertised uint32
+	// A warning is logged when the peer sends more prefixes than this
+	// number, the session being kept up.
+	MaxAccepted uint32
+}
+
+// PeerAddressSource tells where the address of a peer is read from,
+// on the node the session runs on. Only one of the fields is set.
+type PeerAddressSource struct {
+	// The key of the node annotation holding the address.
+	NodeAnnotation string
+	// The key of the node label holding the address.
+	NodeLabel string
+	// The family of the default route whose gateway is the address.
+	DefaultGateway ipfamily.Family
+}
+
+// Receive is the set of prefixes accepted from a peer.
+type Receive struct {
+	// All tells that all the prefixes sent by the peer are accepted.
+	All bool
+	// The prefixes accepted when All is false.
+	Prefixes []PrefixSelector
+}
+
+// PrefixSelector selects the prefixes matching Prefix, with a length
+// between GE and LE when they are not zero.
+type PrefixSelector struct {
+	Prefix *net.IPNet
+	LE     uint32
+	GE     uint32
+}
+
+// TCPAO is the TCP Authentication Option configuration of a session.
+type TCPAO struct {
+	Keys []TCPAOKey
+	// The SendID of the key used to sign outgoing segments.
+	CurrentKeyID uint8
+}
+
+// TCPAOKey is a TCP-AO master key tuple.
+type TCPAOKey struct {
+	SendID    uint8
+	RecvID    uint8
+	Algorithm string
+	Key       string
 }
 
 // Pool is the configuration of an IP address pool.
@@ -203,11 +344,39 @@ type BGPAdvertisement struct {
 	// Optional, defaults to 128 (i.e. no aggregation) if not
 	// specified.
 	AggregationLengthV6 int
+	// Collapses the announced IPs into the minimal set of covering
+	// prefixes, nil if disabled.
+	Aggregation *Aggregation
 	// Value of the LOCAL_PREF BGP path attribute. Used only when
 	// advertising to IBGP peers (i.e. Peer.MyASN == Peer.ASN).
 	LocalPref uint32
 	// Value of the COMMUNITIES path attribute.
 	Communities map[community.BGPCommunity]bool
+	// Value of the MULTI_EXIT_DISC BGP path attribute, nil if not set.
+	MED *uint32
+	// When set, the MED is decreased by the number of ready endpoints
+	// of the service running on the node.
+	MEDFromReadyLocalEndpoints bool
+	// When not zero, a link bandwidth community of this value (in Mbps)
+	// multiplied by the number of ready endpoints of the service running
+	// on the node is added to the communities.
+	LinkBandwidthPerEndpoint uint32
+	// The number of times the local ASN is prepended to the AS_PATH.
+	ASPathPrepend uint32
+	// Overrides ASPathPrepend for the BGPPeers in this map, by name.
+	PeerASPathPrepend map[string]uint32
+	// Makes the advertisement depend on the routes received from the
+	// peers, nil if it is unconditional.
+	Condition *AdvertisementCondition
+	// The VRFs the announced IPs are leaked into, from the VRF of the
+	// peers they are announced to. The default VRF is the empty string.
+	ImportVRFs []string
+	// Announces the IPs as EVPN Type-5 routes instead of as unicast
+	// routes, nil if disabled.
+	EVPN *EVPN
+	// Announces the IPs over labeled-unicast instead of unicast, nil if
+	// disabled.
+	LabeledUnicast *LabeledUnicast
 	// The map of nodes allowed for this advertisement
 	Nodes map[string]bool
 	// Used to declare the intent of announcing IPs
@@ -217,6 +386,67 @@ type BGPAdvertisement struct {
 	ServiceSelectors []labels.Selector
 }
 
+// Aggregation configures the automatic aggregation of the IPs announced
+// by a node.
+type Aggregation struct {
+	// Only the IPs within the same CIDR or range of a pool are merged.
+	WithinPools bool
+	// Only the covering prefixes are announced, not the single IPs.
+	SummaryOnly bool
+}
+
+// AdvertisementCondition ties a BGP advertisement to the routes received
+// from the peers.
+type AdvertisementCondition struct {
+	// When set, the advertisement is announced only while none of the
+	// tracked routes exists, otherwise only while at least one exists.
+	NonExist bool
+	// The tracked routes, any route when empty.
+	Prefixes []*net.IPNet
+	// The names of the BGPPeers the tracked routes are received from,
+	// any peer when empty.
+	Peers []string
+}
+
+// EVPN configures the L3VNI the IPs of a BGP advertisement are announced
+// in as EVPN Type-5 routes.
+type EVPN struct {
+	// The VRF bound to the L3VNI, never the default one.
+	VRF string
+	VNI uint32
+	// Derived by FRR when empty.
+	RouteDistinguisher string
+	// Derived by FRR from the ASN and the VNI when empty.
+	ImportRouteTargets []string
+	ExportRouteTargets []string
+}
+
+// LabeledUnicast configures the labels of the IPs a BGP advertisement
+// announces over labeled-unicast.
+type LabeledUnicast struct {
+	// The label index of the first IP of the pools in this map, by name.
+	// The IPs of the other pools get the label allocated by FRR.
+	LabelIndexBases map[string]uint32
+}
+
+// LabelIndex returns the label index of the given IP of the pool, false
+// if the pool has no label index base.
+func (l *LabeledUnicast) LabelIndex(pool *Pool, ip net.IP) (uint32, bool) {
+	base, ok := l.LabelIndexBases[pool.Name]
+	if !ok {
+		return 0, false
+	}
+	offset, ok := poolIPOffset(pool, ip)
+	if !ok {
+		return 0, false
+	}
+	index := offset.Add(offset, big.NewInt(int64(base)))
+	if !index.IsUint64() || index.Uint64() > maxLabelIndex {
+		return 0, false
+	}
+	return uint32(index.Uint64()), true
+}
+
 type L2Advertisement struct {
 	// The map of nodes allowed for this advertisement
 	Nodes map[string]bool
@@ -249,7 +479,14 @@ func (p *Pools) IsEmpty(pool string) bool {
 
 // Parse loads and validates a Config from bs.
 func For(resources ClusterResources, validate Validate, opts ForOptions) (*Config, error) {
-	err := validate(resources)
+	// The peers are validated with the settings of their templates.
+	peers, err := peersWithTemplates(resources.Peers, resources.PeerTemplates)
+	if err != nil {
+		return nil, err
+	}
+	resources.Peers = peers
+
+	err = validate(resources)
 	if err != nil {
 		return nil, err
 	}
@@ -271,7 +508,10 @@ func For(resources ClusterResources, validate Validate, opts ForOptions) (*Confi
 		return nil, err
 	}
 
-	cfg.BGPExtras = bgpExtrasFor(resources)
+	cfg.BGPExtras, err = bgpExtrasFor(resources, cfg.Peers)
+	if err != nil {
+		return nil, err
+	}
 
 	err = validateConfig(cfg)
 	if err != nil {
@@ -320,6 +560,63 @@ func peersFor(resources ClusterResources, BFDProfiles map[string]*BFDProfile, op
 	return res, nil
 }
 
+// peersWithTemplates returns a copy of the given peers, each one merged with
+// the template it references. The settings of the peer win over the ones of
+// the template, the boolean ones are enabled if either of them enables them.
+func peersWithTemplates(peers []metallbv1beta2.BGPPeer, templates []metallbv1beta2.BGPPeerTemplate) ([]metallbv1beta2.BGPPeer, error) {
+	byName := map[string]*metallbv1beta2.BGPPeerTemplate{}
+	for i, t := range templates {
+		if _, ok := byName[t.Name]; ok {
+			return nil, fmt.Errorf("found duplicate peer template name %s", t.Name)
+		}
+		byName[t.Name] = &templates[i]
+	}
+
+	res := make([]metallbv1beta2.BGPPeer, 0, len(peers))
+	for _, p := range peers {
+		if p.Spec.PeerTemplate == "" {
+			res = append(res, p)
+			continue
+		}
+		t, ok := byName[p.Spec.PeerTemplate]
+		if !ok {
+			return nil, TransientError{fmt.Sprintf("peer %s referencing non existing peer template %s", p.Name, p.Spec.PeerTemplate)}
+		}
+		merged := p.DeepCopy()
+		spec, tmpl := &merged.Spec, t.Spec.DeepCopy()
+		if spec.MyASN == 0 {
+			spec.MyASN = tmpl.MyASN
+		}
+		if spec.ASN == 0 && spec.DynamicASN == "" {
+			spec.ASN = tmpl.ASN
+			spec.DynamicASN = tmpl.DynamicASN
+		}
+		if spec.HoldTime == nil {
+			spec.HoldTime = tmpl.HoldTime
+		}
+		if spec.KeepaliveTime == nil {
+			spec.KeepaliveTime = tmpl.KeepaliveTime
+		}
+		if spec.ConnectTime == nil {
+			spec.ConnectTime = tmpl.ConnectTime
+		}
+		if spec.Password == "" && spec.PasswordSecret.Name == "" {
+			spec.PasswordSecret = tmpl.PasswordSecret
+		}
+		if spec.BFDProfile == "" {
+			spec.BFDProfile = tmpl.BFDProfile
+		}
+		if spec.EnableGracefulRestart == nil {
+			spec.EnableGracefulRestart = tmpl.EnableGracefulRestart
+		}
+		if spec.EBGPMultiHop == nil {
+			spec.EBGPMultiHop = tmpl.EBGPMultiHop
+		}
+		res = append(res, *merged)
+	}
+	return res, nil
+}
+
 func poolsFor(resources ClusterResources) (*Pools, error) {
 	pools := make(map[string]*Pool)
 	communities, err := communitiesFromCrs(resources.Communities)
@@ -371,11 +668,222 @@ func poolsFor(resources ClusterResources) (*Pools, error) {
 		ByServiceSelector: poolsByServiceSelector(pools)}, nil
 }
 
-func bgpExtrasFor(resources ClusterResources) string {
-	if resources.BGPExtras.Data == nil {
-		return ""
+// bgpExtrasFor merges the raw extras of the ConfigMap with the BGPExtras,
+// setting the extra configuration of the neighbors to the peers.
+func bgpExtrasFor(resources ClusterResources, peers map[string]*Peer) (BGPExtras, error) {
+	res := BGPExtras{}
+	var raw []string
+	if resources.BGPExtras.Data != nil && resources.BGPExtras.Data[bgpExtrasField] != "" {
+		raw = append(raw, resources.BGPExtras.Data[bgpExtrasField])
+	}
+
+	prefixLists := map[string]*PrefixList{}
+	for _, e := range resources.Extras {
+		for _, pl := range e.Spec.PrefixLists {
+			if _, ok := prefixLists[pl.Name]; ok {
+				return BGPExtras{}, fmt.Errorf("duplicate definition of prefix-list %q in bgpextras %s", pl.Name, e.Name)
+			}
+			parsed, err := prefixListFromCR(pl)
+			if err != nil {
+				return BGPExtras{}, fmt.Errorf("parsing bgpextras %s: %w", e.Name, err)
+			}
+			prefixLists[pl.Name] = parsed
+		}
+		if b := e.Spec.BestPath; b != nil {
+			if res.BestPath != nil {
+				return BGPExtras{}, fmt.Errorf("bestPath of bgpextras %s already set by another bgpextras", e.Name)
+			}
+			res.BestPath = &BestPath{
+				ASPathMultipathRelax: b.ASPathMultipathRelax,
+				ASPathIgnore:         b.ASPathIgnore,
+				CompareRouterID:      b.CompareRouterID,
+				MEDMissingAsWorst:    b.MEDMissingAsWorst,
+			}
+		}
+		if e.Spec.Raw != "" {
+			raw = append(raw, e.Spec.Raw)
+		}
+	}
+	res.Raw = strings.Join(raw, "\n")
+	for _, name := range slices.Sorted(maps.Keys(prefixLists)) {
+		res.PrefixLists = append(res.PrefixLists, prefixLists[name])
+	}
+
+	communities, err := communitiesFromCrs(resources.Communities)
+	if err != nil {
+		return BGPExtras{}, err
+	}
+	extrasForPeer := map[string]string{}
+	for _, e := range resources.Extras {
+		for _, n := range e.Spec.Neighbors {
+			if other, ok := extrasForPeer[n.Peer]; ok {
+				return BGPExtras{}, fmt.Errorf("peer %s has extras set by both bgpextras %s and %s", n.Peer, other, e.Name)
+			}
+			extrasForPeer[n.Peer] = e.Name
+
+			// The peer may not be configured on this node.
+			peer, ok := peers[n.Peer]
+			extras, err := neighborExtrasFromCR(n, peer, prefixLists, communities)
+			if err != nil {
+				return BGPExtras{}, fmt.Errorf("parsing bgpextras %s: %w", e.Name, err)
+			}
+			if !ok {
+				continue
+			}
+			if n.ConnectTime != nil {
+				if peer.ConnectTime != nil {
+					return BGPExtras{}, fmt.Errorf("connect time of peer %s set by both the peer and bgpextras %s", n.Peer, e.Name)
+				}
+				peer.ConnectTime = ptr.To(n.ConnectTime.Duration)
+			}
+			if n.MaximumPrefix != nil && peer.PrefixLimits != nil && peer.PrefixLimits.MaxAccepted != 0 {
+				return BGPExtras{}, fmt.Errorf("maximum prefixes accepted from peer %s set by both the peer and bgpextras %s", n.Peer, e.Name)
+			}
+			peer.Extras = extras
+		}
+	}
+	return res, nil
+}
+
+func prefixListFromCR(pl metallbv1beta1.PrefixList) (*PrefixList, error) {
+	if pl.Name == "" {
+		return nil, errors.New("prefix-list with no name")
+	}
+	if len(pl.Entries) == 0 {
+		return nil, fmt.Errorf("prefix-list %q has no entries", pl.Name)
+	}
+	res := &PrefixList{Name: pl.Name}
+	for _, e := range pl.Entries {
+		selector, err := prefixSelectorFromCR(e.Prefix, e.LE, e.GE)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entry of prefix-list %q: %w", pl.Name, err)
+		}
+		deny, err := isDenyAction(e.Action)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entry of prefix-list %q: %w", pl.Name, err)
+		}
+		res.Entries = append(res.Entries, PrefixListEntry{PrefixSelector: selector, Deny: deny})
+	}
+	return res, nil
+}
+
+// prefixSelectorFromCR parses a prefix with its length modifiers.
+func prefixSelectorFromCR(p string, le, ge uint32) (PrefixSelector, error) {
+	ip, prefix, err := net.ParseCIDR(p)
+	if err != nil {
+		return PrefixSelector{}, fmt.Errorf("invalid prefix %q: %w", p, err)
+	}
+	if !ip.Equal(prefix.IP) {
+		return PrefixSelector{}, fmt.Errorf("invalid prefix %q, expected %s", p, prefix)
+	}
+	ones, bits := prefix.Mask.Size()
+	length, err := safeconvert.IntToUInt32(ones)
+	if err != nil {
+		return PrefixSelector{}, err
+	}
+	maxLength, err := safeconvert.IntToUInt32(bits)
+	if err != nil {
+		return PrefixSelector{}, err
+	}
+	if ge != 0 && (ge <= length || ge > maxLength) {
+		return PrefixSelector{}, fmt.Errorf("invalid ge %d for prefix %s, must be between %d and %d", ge, prefix, length+1, bits)
+	}
+	if le != 0 && (le < length || le > maxLength) {
+		return PrefixSelector{}, fmt.Errorf("invalid le %d for prefix %s, must be between %d and %d", le, prefix, length, bits)
+	}
+	if ge != 0 && le != 0 && ge > le {
+		return PrefixSelector{}, fmt.Errorf("invalid prefix %s, ge %d is greater than le %d", prefix, ge, le)
+	}
+	return PrefixSelector{Prefix: prefix, LE: le, GE: ge}, nil
+}
+
+// neighborExtrasFromCR parses the extras of a peer, given as nil when it
+// is not configured.
+func neighborExtrasFromCR(n metallbv1beta1.NeighborExtras, peer *Peer, prefixLists map[string]*PrefixList, communities map[string]community.BGPCommunity) (*NeighborExtras, error) {
+	if n.Peer == "" {
+		return nil, errors.New("neighbor extras with no peer")
+	}
+	// The import policy applies to the prefixes accepted by toReceive, all
+	// the others are denied.
+	if peer != nil && peer.ToReceive == nil && len(n.Import) > 0 {
+		return nil, fmt.Errorf("import policy set for peer %s, which does not receive any prefix as toReceive is not set", n.Peer)
+	}
+	if n.MaximumPrefix != nil && *n.MaximumPrefix == 0 {
+		return nil, fmt.Errorf("invalid maximumPrefix 0 for peer %s", n.Peer)
+	}
+	if n.AllowASIn != nil && (*n.AllowASIn == 0 || *n.AllowASIn > 10) {
+		return nil, fmt.Errorf("invalid allowASIn %d for peer %s, must be between 1 and 10", *n.AllowASIn, n.Peer)
+	}
+	if n.ConnectTime != nil {
+		if n.ConnectTime.Duration < time.Second || n.ConnectTime.Duration > 65535*time.Second {
+			return nil, fmt.Errorf("invalid connect time %s for peer %s, must be between 1s and 65535s", n.ConnectTime.Duration, n.Peer)
+		}
+		if n.ConnectTime.Duration%time.Second != 0 {
+			return nil, fmt.Errorf("invalid connect time %s for peer %s, must be a whole number of seconds", n.ConnectTime.Duration, n.Peer)
+		}
+	}
+
+	res := &NeighborExtras{
+		MaximumPrefix: n.MaximumPrefix,
+		AllowASIn:     n.AllowASIn,
+	}
+	var err error
+	res.Import, err = policyFromCR(n.Import, prefixLists, communities)
+	if err != nil {
+		return nil, fmt.Errorf("invalid import policy for peer %s: %w", n.Peer, err)
+	}
+	res.Export, err = policyFromCR(n.Export, prefixLists, communities)
+	if err != nil {
+		return nil, fmt.Errorf("invalid export policy for peer %s: %w", n.Peer, err)
+	}
+	return res, nil
+}
+
+func policyFromCR(entries []metallbv1beta1.PolicyEntry, prefixLists map[string]*PrefixList, communities map[string]community.BGPCommunity) ([]PolicyEntry, error) {
+	var res []PolicyEntry
+	for i, e := range entries {
+		deny, err := isDenyAction(e.Action)
+		if err != nil {
+			return nil, fmt.Errorf("entry #%d: %w", i+1, err)
+		}
+		entry := PolicyEntry{
+			Deny:      deny,
+			LocalPref: e.LocalPref,
+			Metric:    e.Metric,
+		}
+		if deny && (e.LocalPref != nil || e.Metric != nil || len(e.Communities) > 0) {
+			return nil, fmt.Errorf("entry #%d denies the prefixes, it can't set their attributes", i+1)
+		}
+		if e.PrefixList != "" {
+			pl, ok := prefixLists[e.PrefixList]
+			if !ok {
+				return nil, TransientError{fmt.Sprintf("entry #%d referencing non existing prefix-list %s", i+1, e.PrefixList)}
+			}
+			entry.PrefixList = pl
+		}
+		for _, c := range e.Communities {
+			v, err := getCommunityValue(c, communities)
+			if err != nil {
+				return nil, fmt.Errorf("entry #%d: %w", i+1, err)
+			}
+			if community.IsExtended(v) {
+				return nil, fmt.Errorf("entry #%d: extended community %s not supported", i+1, c)
+			}
+			entry.Communities = append(entry.Communities, v)
+		}
+		res = append(res, entry)
+	}
+	return res, nil
+}
+
+func isDenyAction(action metallbv1beta1.PolicyAction) (bool, error) {
+	switch action {
+	case metallbv1beta1.PolicyPermit, "":
+		return false, nil
+	case metallbv1beta1.PolicyDeny:
+		return true, nil
 	}
-	return resources.BGPExtras.Data[bgpExtrasField]
+	return false, fmt.Errorf("invalid action %q", action)
 }
 
 func communitiesFromCrs(cs []metallbv1beta1.Community) (map[string]community.BGPCommunity, error) {
@@ -408,17 +916,25 @@ func peerFromCR(p metallbv1beta2.BGPPeer, passwordSecrets map[string]corev1.Secr
 	if p.Spec.DynamicASN != "" && p.Spec.DynamicASN != metallbv1beta2.InternalASNMode && p.Spec.DynamicASN != metallbv1beta2.ExternalASNMode {
 		return nil, fmt.Errorf("invalid dynamicASN %s", p.Spec.DynamicASN)
 	}
-	if p.Spec.ASN == p.Spec.MyASN && p.Spec.EBGPMultiHop {
+	if p.Spec.ASN == p.Spec.MyASN && ptr.Deref(p.Spec.EBGPMultiHop, false) {
 		return nil, errors.New("invalid ebgp-multihop parameter set for an ibgp peer")
 	}
-	if p.Spec.Address == "" && p.Spec.Interface == "" {
-		return nil, fmt.Errorf("peer has no Address or Interface specified")
+	if p.Spec.Address == "" && p.Spec.Interface == "" && p.Spec.ListenRange == "" && p.Spec.AddressFrom == nil {
+		return nil, fmt.Errorf("peer has no Address, Interface, ListenRange or AddressFrom specified")
 	}
 
 	if p.Spec.Address != "" && p.Spec.Interface != "" {
 		return nil, fmt.Errorf("peer has both Address and Interface specified")
 	}
 
+	if p.Spec.ListenRange != "" && (p.Spec.Address != "" || p.Spec.Interface != "") {
+		return nil, fmt.Errorf("peer has ListenRange specified together with Address or Interface")
+	}
+
+	if p.Spec.AddressFrom != nil && (p.Spec.Address != "" || p.Spec.Interface != "" || p.Spec.ListenRange != "") {
+		return nil, fmt.Errorf("peer has AddressFrom specified together with Address, Interface or ListenRange")
+	}
+
 	holdTime, keepaliveTime, err := parseTimers(p.Spec.HoldTime, p.Spec.KeepaliveTime)
 	if err != nil {
 		return nil, fmt.Errorf("invalid BGPPeer timers: %w", err)
@@ -432,6 +948,14 @@ func peerFromCR(p metallbv1beta2.BGPPeer, passwordSecrets map[string]corev1.Secr
 		}
 	}
 
+	var listenRange *net.IPNet
+	if p.Spec.ListenRange != "" {
+		_, listenRange, err = net.ParseCIDR(p.Spec.ListenRange)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BGPPeer listen range %q", p.Spec.ListenRange)
+		}
+	}
+
 	// Ideally we would set a default RouterID here, instead of having
 	// to do it elsewhere in the code. Unfortunately, we don't know
 	// the node IP here.
@@ -476,6 +1000,26 @@ func peerFromCR(p metallbv1beta2.BGPPeer, passwordSecrets map[string]corev1.Secr
 		}
 	}
 
+	tcpAO, err := tcpAOFromCR(p, passwordSecrets)
+	if err != nil {
+		return nil, err
+	}
+
+	toReceive, err := receiveFromCR(p)
+	if err != nil {
+		return nil, err
+	}
+
+	addrFrom, err := addressFromCR(p)
+	if err != nil {
+		return nil, err
+	}
+
+	prefixLimits, err := prefixLimitsFromCR(p)
+	if err != nil {
+		return nil, err
+	}
+
 	var connectTime *time.Duration
 	if p.Spec.ConnectTime != nil {
 		connectTime = ptr.To(p.Spec.ConnectTime.Duration)
@@ -483,11 +1027,15 @@ func peerFromCR(p metallbv1beta2.BGPPeer, passwordSecrets map[string]corev1.Secr
 
 	return &Peer{
 		Name:                   p.Name,
+		PeerTemplate:           p.Spec.PeerTemplate,
 		MyASN:                  p.Spec.MyASN,
 		ASN:                    p.Spec.ASN,
 		DynamicASN:             string(p.Spec.DynamicASN),
 		Addr:                   ip,
 		Iface:                  p.Spec.Interface,
+		ListenRange:            listenRange,
+		AddrFrom:               addrFrom,
+		Passive:                p.Spec.Passive,
 		SrcAddr:                src,
 		Port:                   p.Spec.Port,
 		HoldTime:               holdTime,
@@ -498,13 +1046,16 @@ func peerFromCR(p metallbv1beta2.BGPPeer, passwordSecrets map[string]corev1.Secr
 		SecretPassword:         secretPassword,
 		Password:               p.Spec.Password,
 		PasswordRef:            p.Spec.PasswordSecret,
+		TCPAO:                  tcpAO,
 		BFDProfile:             p.Spec.BFDProfile,
-		EnableGracefulRestart:  p.Spec.EnableGracefulRestart,
-		EBGPMultiHop:           p.Spec.EBGPMultiHop,
+		EnableGracefulRestart:  ptr.Deref(p.Spec.EnableGracefulRestart, false),
+		EBGPMultiHop:           ptr.Deref(p.Spec.EBGPMultiHop, false),
 		VRF:                    p.Spec.VRFName,
 		DualStackAddressFamily: p.Spec.DualStackAddressFamily,
 		DisableMP:              p.Spec.DisableMP,
 		LocalASN:               p.Spec.LocalASN,
+		ToReceive:              toReceive,
+		PrefixLimits:           prefixLimits,
 	}, nil
 }
 
@@ -527,6 +1078,152 @@ func passwordFromSecretForPeer(p metallbv1beta2.BGPPeer, passwordSecrets map[str
 	return string(srcPass), nil
 }
 
+func addressFromCR(p metallbv1beta2.BGPPeer) (*PeerAddressSource, error) {
+	from := p.Spec.AddressFrom
+	if from == nil {
+		return nil, nil
+	}
+	set := 0
+	for _, v := range []string{from.NodeAnnotation, from.NodeLabel, from.DefaultGateway} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("exactly one of nodeAnnotation, nodeLabel and defaultGateway must be set in the addressFrom of peer %q/%q", p.Namespace, p.Name)
+	}
+	family := ipfamily.Family(from.DefaultGateway)
+	if family != "" && family != ipfamily.IPv4 && family != ipfamily.IPv6 {
+		return nil, fmt.Errorf("invalid defaultGateway family %q for peer %q/%q", from.DefaultGateway, p.Namespace, p.Name)
+	}
+	return &PeerAddressSource{
+		NodeAnnotation: from.NodeAnnotation,
+		NodeLabel:      from.NodeLabel,
+		DefaultGateway: family,
+	}, nil
+}
+
+func prefixLimitsFromCR(p metallbv1beta2.BGPPeer) (*PrefixLimits, error) {
+	l := p.Spec.PrefixLimits
+	if l == nil {
+		return nil, nil
+	}
+	res := &PrefixLimits{
+		MaxAdvertised: ptr.Deref(l.MaxAdvertised, 0),
+		MaxAccepted:   ptr.Deref(l.MaxAccepted, 0),
+	}
+	if l.MaxAdvertised != nil && res.MaxAdvertised == 0 {
+		return nil, fmt.Errorf("invalid prefixLimits maxAdvertised 0 for peer %q/%q", p.Namespace, p.Name)
+	}
+	if l.MaxAccepted != nil && res.MaxAccepted == 0 {
+		return nil, fmt.Errorf("invalid prefixLimits maxAccepted 0 for peer %q/%q", p.Namespace, p.Name)
+	}
+	return res, nil
+}
+
+func receiveFromCR(p metallbv1beta2.BGPPeer) (*Receive, error) {
+	if p.Spec.ToReceive == nil {
+		return nil, nil
+	}
+	switch p.Spec.ToReceive.Mode {
+	case metallbv1beta2.ReceiveAll:
+		if len(p.Spec.ToReceive.Prefixes) > 0 {
+			return nil, fmt.Errorf("toReceive prefixes set with mode %s for peer %q/%q", metallbv1beta2.ReceiveAll, p.Namespace, p.Name)
+		}
+		return &Receive{All: true}, nil
+	case "", metallbv1beta2.ReceiveFiltered:
+	default:
+		return nil, fmt.Errorf("invalid toReceive mode %q for peer %q/%q", p.Spec.ToReceive.Mode, p.Namespace, p.Name)
+	}
+
+	ret := &Receive{}
+	for _, s := range p.Spec.ToReceive.Prefixes {
+		ip, prefix, err := net.ParseCIDR(s.Prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid toReceive prefix %q for peer %q/%q: %w", s.Prefix, p.Namespace, p.Name, err)
+		}
+		if !ip.Equal(prefix.IP) {
+			return nil, fmt.Errorf("invalid toReceive prefix %q for peer %q/%q, expected %s", s.Prefix, p.Namespace, p.Name, prefix)
+		}
+		ones, bits := prefix.Mask.Size()
+		length, err := safeconvert.IntToUInt32(ones)
+		if err != nil {
+			return nil, err
+		}
+		maxLength, err := safeconvert.IntToUInt32(bits)
+		if err != nil {
+			return nil, err
+		}
+		if s.GE != 0 && (s.GE <= length || s.GE > maxLength) {
+			return nil, fmt.Errorf("invalid toReceive ge %d for prefix %s of peer %q/%q, must be between %d and %d", s.GE, prefix, p.Namespace, p.Name, length+1, bits)
+		}
+		if s.LE != 0 && (s.LE < length || s.LE > maxLength) {
+			return nil, fmt.Errorf("invalid toReceive le %d for prefix %s of peer %q/%q, must be between %d and %d", s.LE, prefix, p.Namespace, p.Name, length, bits)
+		}
+		if s.GE != 0 && s.LE != 0 && s.GE > s.LE {
+			return nil, fmt.Errorf("invalid toReceive prefix %s for peer %q/%q, ge %d is greater than le %d", prefix, p.Namespace, p.Name, s.GE, s.LE)
+		}
+		ret.Prefixes = append(ret.Prefixes, PrefixSelector{Prefix: prefix, LE: s.LE, GE: s.GE})
+	}
+	return ret, nil
+}
+
+func tcpAOFromCR(p metallbv1beta2.BGPPeer, secrets map[string]corev1.Secret) (*TCPAO, error) {
+	if p.Spec.TCPAO == nil {
+		return nil, nil
+	}
+	if p.Spec.Passive || p.Spec.ListenRange != "" {
+		return nil, fmt.Errorf("tcpAO is not supported for passive peer %q/%q", p.Namespace, p.Name)
+	}
+	if len(p.Spec.TCPAO.Keys) == 0 {
+		return nil, fmt.Errorf("no tcpAO keys set for peer %q/%q", p.Namespace, p.Name)
+	}
+
+	ret := &TCPAO{CurrentKeyID: p.Spec.TCPAO.Keys[0].SendID}
+	sendIDs, recvIDs := map[uint8]bool{}, map[uint8]bool{}
+	for _, k := range p.Spec.TCPAO.Keys {
+		if sendIDs[k.SendID] || recvIDs[k.RecvID] {
+			return nil, fmt.Errorf("duplicate tcpAO key ids %d/%d for peer %q/%q", k.SendID, k.RecvID, p.Namespace, p.Name)
+		}
+		sendIDs[k.SendID], recvIDs[k.RecvID] = true, true
+
+		algorithm := k.Algorithm
+		if algorithm == "" {
+			algorithm = "hmac-sha1"
+		}
+		if algorithm != "hmac-sha1" && algorithm != "cmac-aes128" && algorithm != "hmac-sha256" {
+			return nil, fmt.Errorf("invalid tcpAO algorithm %q for peer %q/%q", k.Algorithm, p.Namespace, p.Name)
+		}
+
+		secret, ok := secrets[k.KeySecret.Name]
+		if !ok {
+			return nil, TransientError{Message: fmt.Sprintf("tcpAO key secret %q not found for peer config %q/%q", k.KeySecret.Name, p.Namespace, p.Name)}
+		}
+		key, ok := secret.Data["key"]
+		if !ok || len(key) == 0 {
+			return nil, fmt.Errorf("key not specified in the secret %q/%q", secret.Namespace, secret.Name)
+		}
+		if len(key) > 80 {
+			return nil, fmt.Errorf("key in the secret %q/%q is longer than 80 bytes", secret.Namespace, secret.Name)
+		}
+
+		ret.Keys = append(ret.Keys, TCPAOKey{
+			SendID:    k.SendID,
+			RecvID:    k.RecvID,
+			Algorithm: algorithm,
+			Key:       string(key),
+		})
+	}
+
+	if p.Spec.TCPAO.CurrentKeyID != nil {
+		ret.CurrentKeyID = *p.Spec.TCPAO.CurrentKeyID
+		if !sendIDs[ret.CurrentKeyID] {
+			return nil, fmt.Errorf("tcpAO current key id %d does not match any key for peer %q/%q", ret.CurrentKeyID, p.Namespace, p.Name)
+		}
+	}
+	return ret, nil
+}
+
 func addressPoolFromCR(p metallbv1beta1.IPAddressPool, namespaces []corev1.Namespace) (*Pool, error) {
 	if p.Name == "" {
 		return nil, errors.New("missing pool name")
@@ -889,8 +1586,88 @@ func bgpAdvertisementFromCR(crdAd metallbv1beta1.BGPAdvertisement, communities m
 		}
 	}
 
+	if a := crdAd.Spec.Aggregation; a != nil {
+		if ad.AggregationLength != 32 || ad.AggregationLengthV6 != 128 {
+			return nil, fmt.Errorf("aggregation and aggregationLength are mutually exclusive, both cannot be set in %s", crdAd.Name)
+		}
+		ad.Aggregation = &Aggregation{
+			WithinPools: a.WithinPools,
+			SummaryOnly: ptr.Deref(a.SummaryOnly, true),
+		}
+	}
+
 	ad.LocalPref = crdAd.Spec.LocalPref
 
+	if med := crdAd.Spec.MED; med != nil {
+		switch med.Mode {
+		case "", metallbv1beta1.MEDModeStatic:
+		case metallbv1beta1.MEDModeReadyLocalEndpoints:
+			if med.Value == 0 {
+				return nil, fmt.Errorf("invalid MED in %s, the value must be greater than zero in %s mode", crdAd.Name, med.Mode)
+			}
+			if ad.AggregationLength != 32 || ad.AggregationLengthV6 != 128 {
+				return nil, fmt.Errorf("MED mode %s and aggregationLength are mutually exclusive, both cannot be set in %s", med.Mode, crdAd.Name)
+			}
+			ad.MEDFromReadyLocalEndpoints = true
+		default:
+			return nil, fmt.Errorf("invalid MED mode %q in %s", med.Mode, crdAd.Name)
+		}
+		ad.MED = ptr.To(med.Value)
+	}
+
+	if p := crdAd.Spec.ASPathPrepend; p != nil {
+		if p.Count > maxASPathPrepend {
+			return nil, fmt.Errorf("invalid AS path prepend count %d in %s, must be at most %d", p.Count, crdAd.Name, maxASPathPrepend)
+		}
+		ad.ASPathPrepend = p.Count
+		for _, pp := range p.PerPeer {
+			if pp.Count > maxASPathPrepend {
+				return nil, fmt.Errorf("invalid AS path prepend count %d for peer %s in %s, must be at most %d", pp.Count, pp.Peer, crdAd.Name, maxASPathPrepend)
+			}
+			if _, ok := ad.PeerASPathPrepend[pp.Peer]; ok {
+				return nil, fmt.Errorf("duplicate AS path prepend for peer %s in %s", pp.Peer, crdAd.Name)
+			}
+			if ad.PeerASPathPrepend == nil {
+				ad.PeerASPathPrepend = map[string]uint32{}
+			}
+			ad.PeerASPathPrepend[pp.Peer] = pp.Count
+		}
+	}
+
+	if crdAd.Spec.Condition != nil {
+		ad.Condition, err = advertisementConditionFromCR(crdAd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateDuplicate(crdAd.Spec.ImportVRFs, "importVRFs"); err != nil {
+		return nil, err
+	}
+	for _, vrf := range crdAd.Spec.ImportVRFs {
+		switch vrf {
+		case "":
+			return nil, fmt.Errorf("invalid empty VRF in the importVRFs of %s, use %q for the default VRF", crdAd.Name, DefaultVRF)
+		case DefaultVRF:
+			vrf = ""
+		}
+		ad.ImportVRFs = append(ad.ImportVRFs, vrf)
+	}
+
+	if crdAd.Spec.EVPN != nil {
+		ad.EVPN, err = evpnFromCR(crdAd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if crdAd.Spec.LabeledUnicast != nil {
+		ad.LabeledUnicast, err = labeledUnicastFromCR(crdAd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if len(crdAd.Spec.Peers) > 0 {
 		ad.Peers = make([]string, 0, len(crdAd.Spec.Peers))
 		ad.Peers = append(ad.Peers, crdAd.Spec.Peers...)
@@ -904,6 +1681,21 @@ func bgpAdvertisementFromCR(crdAd metallbv1beta1.BGPAdvertisement, communities m
 		ad.Communities[v] = true
 	}
 
+	if bw := crdAd.Spec.LinkBandwidthPerEndpoint; bw != nil {
+		if *bw == 0 || *bw > community.MaxLinkBandwidth {
+			return nil, fmt.Errorf("invalid linkBandwidthPerEndpoint %d in %s, must be between 1 and %d", *bw, crdAd.Name, community.MaxLinkBandwidth)
+		}
+		if ad.AggregationLength != 32 || ad.AggregationLengthV6 != 128 {
+			return nil, fmt.Errorf("linkBandwidthPerEndpoint and aggregationLength are mutually exclusive, both cannot be set in %s", crdAd.Name)
+		}
+		for c := range ad.Communities {
+			if e, ok := c.(community.BGPCommunityExtended); ok && e.IsLinkBandwidth() {
+				return nil, fmt.Errorf("linkBandwidthPerEndpoint and the link bandwidth community %s are mutually exclusive, both cannot be set in %s", c, crdAd.Name)
+			}
+		}
+		ad.LinkBandwidthPerEndpoint = *bw
+	}
+
 	selected, err := selectedNodes(nodes, crdAd.Spec.NodeSelectors)
 	if err != nil {
 		return nil, errors.Join(err, fmt.Errorf("failed to parse node selector for %s", crdAd.Name))
@@ -919,6 +1711,193 @@ func bgpAdvertisementFromCR(crdAd metallbv1beta1.BGPAdvertisement, communities m
 	return ad, nil
 }
 
+func advertisementConditionFromCR(crdAd metallbv1beta1.BGPAdvertisement) (*AdvertisementCondition, error) {
+	c := crdAd.Spec.Condition
+	ret := &AdvertisementCondition{}
+	switch c.Mode {
+	case metallbv1beta1.AdvertisementConditionExist:
+	case metallbv1beta1.AdvertisementConditionNonExist:
+		ret.NonExist = true
+	default:
+		return nil, fmt.Errorf("invalid condition mode %q in %s", c.Mode, crdAd.Name)
+	}
+	if len(c.Prefixes) == 0 && len(c.Peers) == 0 {
+		return nil, fmt.Errorf("invalid condition in %s, at least one of prefixes and peers must be set", crdAd.Name)
+	}
+	if err := validateDuplicate(c.Prefixes, "condition prefixes"); err != nil {
+		return nil, err
+	}
+	if err := validateDuplicate(c.Peers, "condition peers"); err != nil {
+		return nil, err
+	}
+	for _, p := range c.Prefixes {
+		ip, prefix, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition prefix %q in %s: %w", p, crdAd.Name, err)
+		}
+		if !ip.Equal(prefix.IP) {
+			return nil, fmt.Errorf("invalid condition prefix %q in %s, expected %s", p, crdAd.Name, prefix)
+		}
+		ret.Prefixes = append(ret.Prefixes, prefix)
+	}
+	if len(c.Peers) > 0 {
+		ret.Peers = append([]string{}, c.Peers...)
+	}
+	return ret, nil
+}
+
+// maxVNI is the highest VXLAN network identifier, 24 bits long.
+const maxVNI = 1<<24 - 1
+
+// evpnFromCR parses the EVPN settings of the advertisement, rejecting the
+// attributes that don't apply to the routes exported from a VRF.
+func evpnFromCR(crdAd metallbv1beta1.BGPAdvertisement) (*EVPN, error) {
+	e := crdAd.Spec.EVPN
+	// The EVPN routes are exported from the VRF, the attributes set on the
+	// unicast announcements and the leaks don't apply to them.
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"localPref", crdAd.Spec.LocalPref != 0},
+		{"communities", len(crdAd.Spec.Communities) > 0},
+		{"med", crdAd.Spec.MED != nil},
+		{"linkBandwidthPerEndpoint", crdAd.Spec.LinkBandwidthPerEndpoint != nil},
+		{"asPathPrepend", crdAd.Spec.ASPathPrepend != nil},
+		{"condition", crdAd.Spec.Condition != nil},
+		{"importVRFs", len(crdAd.Spec.ImportVRFs) > 0},
+	} {
+		if f.set {
+			return nil, fmt.Errorf("evpn and %s are mutually exclusive, both cannot be set in %s", f.name, crdAd.Name)
+		}
+	}
+
+	switch e.VRF {
+	case "":
+		return nil, fmt.Errorf("missing evpn vrf in %s", crdAd.Name)
+	case DefaultVRF:
+		return nil, fmt.Errorf("invalid evpn vrf %q in %s, the L3VNI must be bound to a VRF other than the default one", e.VRF, crdAd.Name)
+	}
+	if e.VNI == 0 || e.VNI > maxVNI {
+		return nil, fmt.Errorf("invalid evpn vni %d in %s, must be between 1 and %d", e.VNI, crdAd.Name, maxVNI)
+	}
+	if e.RouteDistinguisher != "" {
+		if _, err := community.New("target:" + e.RouteDistinguisher); err != nil {
+			return nil, errors.Join(err, fmt.Errorf("invalid evpn route distinguisher %q in %s", e.RouteDistinguisher, crdAd.Name))
+		}
+	}
+	for _, rts := range []struct {
+		name   string
+		values []string
+	}{
+		{"evpn importRouteTargets", e.ImportRouteTargets},
+		{"evpn exportRouteTargets", e.ExportRouteTargets},
+	} {
+		if err := validateDuplicate(rts.values, rts.name); err != nil {
+			return nil, err
+		}
+		for _, rt := range rts.values {
+			if _, err := community.New("target:" + rt); err != nil {
+				return nil, errors.Join(err, fmt.Errorf("invalid route target %q in the %s of %s", rt, rts.name, crdAd.Name))
+			}
+		}
+	}
+
+	res := &EVPN{
+		VRF:                e.VRF,
+		VNI:                e.VNI,
+		RouteDistinguisher: e.RouteDistinguisher,
+	}
+	if len(e.ImportRouteTargets) > 0 {
+		res.ImportRouteTargets = append([]string{}, e.ImportRouteTargets...)
+	}
+	if len(e.ExportRouteTargets) > 0 {
+		res.ExportRouteTargets = append([]string{}, e.ExportRouteTargets...)
+	}
+	return res, nil
+}
+
+// maxLabelIndex is the highest label index of a BGP Prefix-SID accepted by
+// FRR, the MPLS labels below 16 being reserved.
+const maxLabelIndex = 1<<20 - 16
+
+func labeledUnicastFromCR(crdAd metallbv1beta1.BGPAdvertisement) (*LabeledUnicast, error) {
+	l := crdAd.Spec.LabeledUnicast
+	// The labeled-unicast announcements replace the unicast ones in the
+	// default VRF, they can't depend on a condition nor be leaked.
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"condition", crdAd.Spec.Condition != nil},
+		{"importVRFs", len(crdAd.Spec.ImportVRFs) > 0},
+		{"evpn", crdAd.Spec.EVPN != nil},
+	} {
+		if f.set {
+			return nil, fmt.Errorf("labeledUnicast and %s are mutually exclusive, both cannot be set in %s", f.name, crdAd.Name)
+		}
+	}
+
+	res := &LabeledUnicast{}
+	if len(l.LabelIndexes) == 0 {
+		return res, nil
+	}
+	// A label index identifies a single IP.
+	if crdAd.Spec.Aggregation != nil ||
+		(crdAd.Spec.AggregationLength != nil && *crdAd.Spec.AggregationLength != 32) ||
+		(crdAd.Spec.AggregationLengthV6 != nil && *crdAd.Spec.AggregationLengthV6 != 128) {
+		return nil, fmt.Errorf("labeledUnicast labelIndexes and aggregation are mutually exclusive, both cannot be set in %s", crdAd.Name)
+	}
+	res.LabelIndexBases = map[string]uint32{}
+	for _, i := range l.LabelIndexes {
+		if i.IPAddressPool == "" {
+			return nil, fmt.Errorf("missing ipAddressPool in the labeledUnicast labelIndexes of %s", crdAd.Name)
+		}
+		if _, ok := res.LabelIndexBases[i.IPAddressPool]; ok {
+			return nil, fmt.Errorf("duplicate label index for pool %s in %s", i.IPAddressPool, crdAd.Name)
+		}
+		if i.Base > maxLabelIndex {
+			return nil, fmt.Errorf("invalid label index base %d for pool %s in %s, must be at most %d", i.Base, i.IPAddressPool, crdAd.Name, maxLabelIndex)
+		}
+		res.LabelIndexBases[i.IPAddressPool] = i.Base
+	}
+	return res, nil
+}
+
+// poolSize returns the number of IPs of the pool.
+func poolSize(pool *Pool) *big.Int {
+	size := big.NewInt(0)
+	for _, cidr := range pool.CIDR {
+		ones, bits := cidr.Mask.Size()
+		size.Add(size, new(big.Int).Lsh(big.NewInt(1), uint(bits-ones)))
+	}
+	return size
+}
+
+// poolIPOffset returns the position of the IP among the ones of the pool,
+// walking its CIDRs in order, false if the pool doesn't contain it.
+func poolIPOffset(pool *Pool, ip net.IP) (*big.Int, bool) {
+	offset := big.NewInt(0)
+	for _, cidr := range pool.CIDR {
+		ones, bits := cidr.Mask.Size()
+		if cidr.Contains(ip) {
+			first := new(big.Int).SetBytes(ipAsFamily(cidr.IP, bits))
+			return offset.Add(offset, new(big.Int).Sub(new(big.Int).SetBytes(ipAsFamily(ip, bits)), first)), true
+		}
+		offset.Add(offset, new(big.Int).Lsh(big.NewInt(1), uint(bits-ones)))
+	}
+	return nil, false
+}
+
+// ipAsFamily returns the 4 or 16 bytes form of the IP, matching the
+// length in bits of the CIDR it belongs to.
+func ipAsFamily(ip net.IP, bits int) net.IP {
+	if bits == net.IPv4len*8 {
+		return ip.To4()
+	}
+	return ip.To16()
+}
+
 // getCommunityValue returns the BGPCommunity from the communities map if it exists there. Otherwise, it creates a
 // new BGP community object from the provided communityString.
 func getCommunityValue(communityString string, communities map[string]community.BGPCommunity) (community.BGPCommunity, error) {
@@ -990,7 +1969,7 @@ func validateBGPAdvPerPool(adv *BGPAdvertisement, pool *Pool) error {
 		}
 	}
 
-	// Do not verify that BGP ADVs set a unique local preference value when service selectors are used,
+	// Do not verify that BGP ADVs set unique local preference and MED values when service selectors are used,
 	// because in that case we don't know which services will be advertised via this advertisement.
 	if len(adv.ServiceSelectors) > 0 {
 		return nil
@@ -1004,6 +1983,11 @@ func validateBGPAdvPerPool(adv *BGPAdvertisement, pool *Pool) error {
 				"already set for the same type of BGP update. Check existing BGP advertisements "+
 				"with common pools and aggregation lengths", adv.LocalPref, bgpAdv.LocalPref)
 		}
+		if !BGPAdvertisementsHaveCompatibleMED(adv, bgpAdv, pool) {
+			return fmt.Errorf("invalid MED %s: MED %s was "+
+				"already set for the same type of BGP update. Check existing BGP advertisements "+
+				"with common pools and aggregation lengths", MEDString(adv), MEDString(bgpAdv))
+		}
 	}
 
 	return nil
@@ -1015,10 +1999,35 @@ func BGPAdvertisementsHaveCompatibleLocalPref(newAdv, adv *BGPAdvertisement, poo
 	if newAdv.LocalPref == adv.LocalPref {
 		return true
 	}
+	return !bgpAdvertisementsCollide(newAdv, adv, pool)
+}
 
-	if isAggrLengthDifferent(newAdv, adv, pool) {
+// BGPAdvertisementsHaveCompatibleMED checks if two BGP advertisements have compatible MEDs,
+// assuming both target at least one shared service.
+func BGPAdvertisementsHaveCompatibleMED(newAdv, adv *BGPAdvertisement, pool *Pool) bool {
+	if ptr.Equal(newAdv.MED, adv.MED) && newAdv.MEDFromReadyLocalEndpoints == adv.MEDFromReadyLocalEndpoints {
 		return true
 	}
+	return !bgpAdvertisementsCollide(newAdv, adv, pool)
+}
+
+// MEDString returns a human readable representation of the MED of the advertisement.
+func MEDString(adv *BGPAdvertisement) string {
+	switch {
+	case adv.MED == nil:
+		return "unset"
+	case adv.MEDFromReadyLocalEndpoints:
+		return fmt.Sprintf("%d minus the ready local endpoints", *adv.MED)
+	}
+	return strconv.FormatUint(uint64(*adv.MED), 10)
+}
+
+// bgpAdvertisementsCollide tells if the two advertisements produce the same
+// type of BGP update, sent to the same peers from the same nodes.
+func bgpAdvertisementsCollide(newAdv, adv *BGPAdvertisement, pool *Pool) bool {
+	if isAggrLengthDifferent(newAdv, adv, pool) {
+		return false
+	}
 
 	// BGP ADVs with different set of BGP peers do not collide.
 	if len(newAdv.Peers) != 0 && len(adv.Peers) != 0 {
@@ -1030,18 +2039,18 @@ func BGPAdvertisementsHaveCompatibleLocalPref(newAdv, adv *BGPAdvertisement, poo
 			}
 		}
 		if !equalPeer {
-			return true
+			return false
 		}
 	}
 
 	// BGP ADVs with different set of nodes do not collide.
 	for node := range newAdv.Nodes {
 		if _, ok := adv.Nodes[node]; ok {
-			return false
+			return true
 		}
 	}
 
-	return true
+	return false
 }
 
 func isAggrLengthDifferent(newAdv, adv *BGPAdvertisement, pool *Pool) bool {
@@ -3,6 +3,7 @@
 package config
 
 import (
+	"fmt"
 	"net"
 	"sort"
 	"strings"
@@ -13,6 +14,7 @@ import (
 	"go.universe.tf/metallb/api/v1beta1"
 	"go.universe.tf/metallb/api/v1beta2"
 	"go.universe.tf/metallb/internal/bgp/community"
+	"go.universe.tf/metallb/internal/ipfamily"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -80,8 +82,8 @@ func TestParse(t *testing.T) {
 							ConnectTime:           ptr.To(metav1.Duration{Duration: time.Second}),
 							RouterID:              "10.20.30.40",
 							SrcAddress:            "10.20.30.40",
-							EnableGracefulRestart: true,
-							EBGPMultiHop:          true,
+							EnableGracefulRestart: ptr.To(true),
+							EBGPMultiHop:          ptr.To(true),
 							VRFName:               "foo",
 						},
 					},
@@ -93,8 +95,8 @@ func TestParse(t *testing.T) {
 							MyASN:                 100,
 							ASN:                   200,
 							Address:               "2.3.4.5",
-							EnableGracefulRestart: false,
-							EBGPMultiHop:          false,
+							EnableGracefulRestart: ptr.To(false),
+							EBGPMultiHop:          ptr.To(false),
 							ConnectTime:           ptr.To(metav1.Duration{Duration: time.Second}),
 							NodeSelectors: []metav1.LabelSelector{
 								{
@@ -759,7 +761,7 @@ func TestParse(t *testing.T) {
 							MyASN:        42,
 							ASN:          42,
 							Address:      "1.2.3.4",
-							EBGPMultiHop: true,
+							EBGPMultiHop: ptr.To(true),
 						},
 					},
 				},
@@ -1074,7 +1076,7 @@ func TestParse(t *testing.T) {
 							MyASN:        100,
 							ASN:          200,
 							Address:      "2.3.4.5",
-							EBGPMultiHop: false,
+							EBGPMultiHop: ptr.To(false),
 							NodeSelectors: []metav1.LabelSelector{
 								{
 									MatchLabels: map[string]string{
@@ -1876,6 +1878,46 @@ func TestParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc: "bad extended community literal (bandwidth out of range) - in the community CR",
+			crs: ClusterResources{
+				Communities: []v1beta1.Community{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "community",
+						},
+						Spec: v1beta1.CommunitySpec{
+							Communities: []v1beta1.CommunityAlias{
+								{
+									Name:  "bar",
+									Value: "bandwidth:100000",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "bad extended community literal (unknown type) - in the community CR",
+			crs: ClusterResources{
+				Communities: []v1beta1.Community{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "community",
+						},
+						Spec: v1beta1.CommunitySpec{
+							Communities: []v1beta1.CommunityAlias{
+								{
+									Name:  "bar",
+									Value: "origin:1234:1234",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			desc: "bad community literal (asn part doesn't fit) - in the community CR",
 			crs: ClusterResources{
@@ -2534,7 +2576,7 @@ func TestParse(t *testing.T) {
 							HoldTime:     ptr.To(metav1.Duration{Duration: 180 * time.Second}),
 							RouterID:     "10.20.30.40",
 							SrcAddress:   "10.20.30.40",
-							EBGPMultiHop: true,
+							EBGPMultiHop: ptr.To(true),
 							BFDProfile:   "nondefault",
 						},
 					},
@@ -2639,7 +2681,7 @@ func TestParse(t *testing.T) {
 							HoldTime:     ptr.To(metav1.Duration{Duration: 180 * time.Second}),
 							RouterID:     "10.20.30.40",
 							SrcAddress:   "10.20.30.40",
-							EBGPMultiHop: true,
+							EBGPMultiHop: ptr.To(true),
 							VRFName:      "foo",
 						},
 					},
@@ -3593,307 +3635,2039 @@ func TestParse(t *testing.T) {
 			},
 		},
 		{
-			desc: "peer with dynamic asn",
+			desc: "advertisement with AS path prepend",
 			crs: ClusterResources{
-
-				Peers: []v1beta2.BGPPeer{
+				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "peer1",
+							Name: "pool1",
 						},
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:      42,
-							DynamicASN: v1beta2.InternalASNMode,
-							Address:    "1.2.3.4",
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							ASPathPrepend: &v1beta1.ASPathPrepend{
+								Count: 2,
+								PerPeer: []v1beta1.PeerASPathPrepend{
+									{Peer: "peer1", Count: 0},
+									{Peer: "peer2", Count: 5},
+								},
+							},
 						},
 					},
 				},
 			},
 			want: &Config{
-				Peers: map[string]*Peer{
-					"peer1": {
-						Name:          "peer1",
-						MyASN:         42,
-						DynamicASN:    "internal",
-						Addr:          net.ParseIP("1.2.3.4"),
-						NodeSelectors: []labels.Selector{labels.Everything()},
-						EBGPMultiHop:  false,
+				Peers: map[string]*Peer{},
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						AutoAssign: true,
+						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								Name:                "adv1",
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								Communities:         map[community.BGPCommunity]bool{},
+								ASPathPrepend:       2,
+								PeerASPathPrepend:   map[string]uint32{"peer1": 0, "peer2": 5},
+								Nodes:               map[string]bool{},
+							},
+						},
 					},
-				},
-				Pools:       &Pools{ByName: map[string]*Pool{}},
+				}},
 				BFDProfiles: map[string]*BFDProfile{},
 			},
 		},
 		{
-			desc: "peer without asn or dynamic asn",
+			desc: "advertisement with too long AS path prepend",
 			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
+				Pools: []v1beta1.IPAddressPool{
 					{
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:      42,
-							ASN:        0,
-							DynamicASN: "",
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							ASPathPrepend: &v1beta1.ASPathPrepend{
+								PerPeer: []v1beta1.PeerASPathPrepend{
+									{Peer: "peer1", Count: 11},
+								},
+							},
 						},
 					},
 				},
 			},
 		},
 		{
-			desc: "peer with both asn and dynamic asn",
+			desc: "advertisement with duplicate peer AS path prepend",
 			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
+				Pools: []v1beta1.IPAddressPool{
 					{
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:      42,
-							ASN:        42,
-							DynamicASN: v1beta2.InternalASNMode,
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							ASPathPrepend: &v1beta1.ASPathPrepend{
+								PerPeer: []v1beta1.PeerASPathPrepend{
+									{Peer: "peer1", Count: 1},
+									{Peer: "peer1", Count: 2},
+								},
+							},
 						},
 					},
 				},
 			},
 		},
 		{
-			desc: "unnumbered peer without address with interface ok",
+			desc: "advertisement with MED",
 			crs: ClusterResources{
-
-				Peers: []v1beta2.BGPPeer{
+				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "peer1",
+							Name: "pool1",
 						},
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:      42,
-							DynamicASN: v1beta2.InternalASNMode,
-							Interface:  "net0",
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							MED: &v1beta1.MED{
+								Value: 100,
+								Mode:  v1beta1.MEDModeReadyLocalEndpoints,
+							},
 						},
 					},
 				},
 			},
 			want: &Config{
-				Peers: map[string]*Peer{
-					"peer1": {
-						Name:          "peer1",
-						Iface:         "net0",
-						MyASN:         42,
-						DynamicASN:    "internal",
-						NodeSelectors: []labels.Selector{labels.Everything()},
-						EBGPMultiHop:  false,
+				Peers: map[string]*Peer{},
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						AutoAssign: true,
+						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								Name:                       "adv1",
+								AggregationLength:          32,
+								AggregationLengthV6:        128,
+								Communities:                map[community.BGPCommunity]bool{},
+								MED:                        ptr.To[uint32](100),
+								MEDFromReadyLocalEndpoints: true,
+								Nodes:                      map[string]bool{},
+							},
+						},
 					},
-				},
-				Pools:       &Pools{ByName: map[string]*Pool{}},
+				}},
 				BFDProfiles: map[string]*BFDProfile{},
 			},
 		},
 		{
-			desc: "unnumbered peer without address without interface nok",
+			desc: "advertisement with ready local endpoints MED and no value",
 			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
+				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "invalid",
+							Name: "pool1",
 						},
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:      42,
-							DynamicASN: "internal",
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
 						},
 					},
 				},
-			},
-		},
-		{
-			desc: "unnumbered peer with address with interface nok",
-			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
+				BGPAdvs: []v1beta1.BGPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "invalid",
+							Name: "adv1",
 						},
-						Spec: v1beta2.BGPPeerSpec{
-							Address:    "1.2.3.4",
-							Interface:  "net0",
-							MyASN:      42,
-							DynamicASN: "internal",
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							MED: &v1beta1.MED{
+								Mode: v1beta1.MEDModeReadyLocalEndpoints,
+							},
 						},
 					},
 				},
 			},
 		},
 		{
-			desc: "peer with DisableMP field",
+			desc: "advertisement with ready local endpoints MED and aggregation length",
 			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
+				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "peer1",
+							Name: "pool1",
 						},
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:     42,
-							ASN:       142,
-							Address:   "1.2.3.4",
-							DisableMP: true,
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
 						},
 					},
 				},
-			},
-			want: &Config{
-				Peers: map[string]*Peer{
-					"peer1": {
-						Name:                   "peer1",
-						MyASN:                  42,
-						ASN:                    142,
-						Addr:                   net.ParseIP("1.2.3.4"),
-						NodeSelectors:          []labels.Selector{labels.Everything()},
-						DisableMP:              true,
-						DualStackAddressFamily: false,
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools:    []string{"pool1"},
+							AggregationLength: ptr.To[int32](24),
+							MED: &v1beta1.MED{
+								Value: 100,
+								Mode:  v1beta1.MEDModeReadyLocalEndpoints,
+							},
+						},
 					},
 				},
-				Pools:       &Pools{ByName: map[string]*Pool{}},
-				BFDProfiles: map[string]*BFDProfile{},
 			},
 		},
 		{
-			desc: "peer with localASN field",
+			desc: "different MED",
 			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
+				Nodes: []corev1.Node{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "peer1",
+							Name: "node1",
+						},
+					},
+				},
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							MED: &v1beta1.MED{Value: 10},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv2",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							MED: &v1beta1.MED{Value: 20},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "conditional advertisement",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "primary",
 						},
 						Spec: v1beta2.BGPPeerSpec{
-							MyASN:    100,
-							ASN:      200,
-							Address:  "1.2.3.4",
-							LocalASN: 65410,
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.4",
+							ToReceive: &v1beta2.Receive{
+								Mode: v1beta2.ReceiveAll,
+							},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "backup",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.5",
+						},
+					},
+				},
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							Peers:          []string{"backup"},
+							Condition: &v1beta1.AdvertisementCondition{
+								Mode:     v1beta1.AdvertisementConditionNonExist,
+								Prefixes: []string{"0.0.0.0/0"},
+								Peers:    []string{"primary"},
+							},
 						},
 					},
 				},
 			},
 			want: &Config{
 				Peers: map[string]*Peer{
-					"peer1": {
-						Name:          "peer1",
-						MyASN:         100,
-						ASN:           200,
+					"primary": {
+						Name:          "primary",
+						MyASN:         42,
+						ASN:           43,
 						Addr:          net.ParseIP("1.2.3.4"),
 						NodeSelectors: []labels.Selector{labels.Everything()},
-						LocalASN:      65410,
+						ToReceive:     &Receive{All: true},
+					},
+					"backup": {
+						Name:          "backup",
+						MyASN:         42,
+						ASN:           43,
+						Addr:          net.ParseIP("1.2.3.5"),
+						NodeSelectors: []labels.Selector{labels.Everything()},
 					},
 				},
-				Pools:       &Pools{ByName: map[string]*Pool{}},
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						AutoAssign: true,
+						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								Name:                "adv1",
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								Communities:         map[community.BGPCommunity]bool{},
+								Peers:               []string{"backup"},
+								Condition: &AdvertisementCondition{
+									NonExist: true,
+									Prefixes: []*net.IPNet{ipnet("0.0.0.0/0")},
+									Peers:    []string{"primary"},
+								},
+								Nodes: map[string]bool{},
+							},
+						},
+					},
+				}},
 				BFDProfiles: map[string]*BFDProfile{},
 			},
 		},
-	}
-
-	for _, test := range tests {
-		t.Run(test.desc, func(t *testing.T) {
-			got, err := For(test.crs, DontValidate, test.opts)
-			if err != nil && test.want != nil {
-				t.Errorf("%q: parse failed: %s", test.desc, err)
-				return
-			}
-			if test.want == nil && err == nil {
-				t.Errorf("%q: parse unexpectedly succeeded", test.desc)
-				return
-			}
-			selectorComparer := cmp.Comparer(func(x, y labels.Selector) bool {
-				if x == nil {
-					return y == nil
-				}
-				if y == nil {
-					return x == nil
-				}
-				// Nothing() and Everything() have the same string
-				// representation, stupidly. So, compare explicitly for
-				// Nothing.
-				if x == labels.Nothing() {
-					return y == labels.Nothing()
-				}
-				if y == labels.Nothing() {
-					return x == labels.Nothing()
-				}
-				return x.String() == y.String()
-			})
-			// We don't care about comparing cidrPerAddress as it's calculated
-			cidrPerAddressComparer := cmp.Comparer(func(x, y map[string][]*net.IPNet) bool {
-				return true
-			})
-
-			if diff := cmp.Diff(test.want, got, selectorComparer, cidrPerAddressComparer, cmp.AllowUnexported(Pool{})); diff != "" {
-				t.Errorf("%q: parse returned wrong result (-want, +got)\n%s", test.desc, diff)
-			}
-		})
-	}
-}
-
-func TestContainsAdvertisement(t *testing.T) {
-	tests := []struct {
-		desc    string
-		advs    []*L2Advertisement
-		toCheck *L2Advertisement
-		expect  bool
-	}{
 		{
-			desc: "Contain",
-			advs: []*L2Advertisement{
-				{
-					Nodes: map[string]bool{
-						"nodeA": true,
-						"nodeB": true,
+			desc: "conditional advertisement with an invalid mode",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "primary",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.4",
+							ToReceive: &v1beta2.Receive{
+								Mode: v1beta2.ReceiveAll,
+							},
+						},
 					},
-					Interfaces:    []string{"eth0", "eth1"},
-					AllInterfaces: false,
-				},
-				{
-					Nodes: map[string]bool{
-						"nodeB": true,
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "backup",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.5",
+						},
 					},
-					Interfaces:    []string{},
-					AllInterfaces: true,
-				},
-			},
-			toCheck: &L2Advertisement{
-				Nodes: map[string]bool{
-					"nodeB": true,
-					"nodeA": true,
 				},
-				Interfaces:    []string{"eth1", "eth0"},
-				AllInterfaces: false,
-			},
-			expect: true,
-		},
-		{
-			desc: "Not contain: Nodes don't equal",
-			advs: []*L2Advertisement{
-				{
-					Nodes: map[string]bool{
-						"nodeA": true,
-						"nodeB": true,
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
 					},
-					Interfaces:    []string{"eth0", "eth1"},
-					AllInterfaces: false,
 				},
-			},
-			toCheck: &L2Advertisement{
-				Nodes: map[string]bool{
-					"nodeB": true,
-					"nodeC": true,
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							Peers:          []string{"backup"},
+							Condition: &v1beta1.AdvertisementCondition{
+								Mode:  "Maybe",
+								Peers: []string{"primary"},
+							},
+						},
+					},
 				},
-				Interfaces:    []string{"eth1", "eth0"},
-				AllInterfaces: false,
 			},
-			expect: false,
 		},
 		{
-			desc: "Not contain: Interfaces don't equal",
-			advs: []*L2Advertisement{
-				{
-					Nodes: map[string]bool{
-						"nodeA": true,
-						"nodeB": true,
+			desc: "conditional advertisement without prefixes and peers",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "primary",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.4",
+							ToReceive: &v1beta2.Receive{
+								Mode: v1beta2.ReceiveAll,
+							},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "backup",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.5",
+						},
 					},
-					Interfaces:    []string{"eth0", "eth1"},
-					AllInterfaces: false,
+				},
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							Peers:          []string{"backup"},
+							Condition: &v1beta1.AdvertisementCondition{
+								Mode: v1beta1.AdvertisementConditionExist,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "conditional advertisement with an invalid prefix",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "primary",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.4",
+							ToReceive: &v1beta2.Receive{
+								Mode: v1beta2.ReceiveAll,
+							},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "backup",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.5",
+						},
+					},
+				},
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							Peers:          []string{"backup"},
+							Condition: &v1beta1.AdvertisementCondition{
+								Mode:     v1beta1.AdvertisementConditionExist,
+								Prefixes: []string{"10.0.0.1/8"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "conditional advertisement on a missing peer",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "primary",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.4",
+							ToReceive: &v1beta2.Receive{
+								Mode: v1beta2.ReceiveAll,
+							},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "backup",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.5",
+						},
+					},
+				},
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							Peers:          []string{"backup"},
+							Condition: &v1beta1.AdvertisementCondition{
+								Mode:  v1beta1.AdvertisementConditionNonExist,
+								Peers: []string{"missing"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "conditional advertisement on a peer not receiving routes",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "primary",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.4",
+							ToReceive: &v1beta2.Receive{
+								Mode: v1beta2.ReceiveAll,
+							},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "backup",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.5",
+						},
+					},
+				},
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							Peers:          []string{"backup"},
+							Condition: &v1beta1.AdvertisementCondition{
+								Mode:  v1beta1.AdvertisementConditionNonExist,
+								Peers: []string{"backup"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "conditional advertisement on a dynamic peer",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "primary",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:       42,
+							ASN:         43,
+							ListenRange: "1.2.4.0/24",
+							ToReceive: &v1beta2.Receive{
+								Mode: v1beta2.ReceiveAll,
+							},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "backup",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.5",
+						},
+					},
+				},
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							Peers:          []string{"backup"},
+							Condition: &v1beta1.AdvertisementCondition{
+								Mode:  v1beta1.AdvertisementConditionNonExist,
+								Peers: []string{"primary"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "conditional advertisement on a peer in a different vrf",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "primary",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.4",
+							VRFName: "red",
+							ToReceive: &v1beta2.Receive{
+								Mode: v1beta2.ReceiveAll,
+							},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "backup",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.5",
+						},
+					},
+				},
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							Peers:          []string{"backup"},
+							Condition: &v1beta1.AdvertisementCondition{
+								Mode:     v1beta1.AdvertisementConditionNonExist,
+								Prefixes: []string{"0.0.0.0/0"},
+								Peers:    []string{"primary"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "different conditions for the same peer",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "primary",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.4",
+							ToReceive: &v1beta2.Receive{
+								Mode: v1beta2.ReceiveAll,
+							},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "backup",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.5",
+						},
+					},
+				},
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							Peers:          []string{"backup"},
+							Condition: &v1beta1.AdvertisementCondition{
+								Mode:     v1beta1.AdvertisementConditionNonExist,
+								Prefixes: []string{"0.0.0.0/0"},
+								Peers:    []string{"primary"},
+							},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv2",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							Peers:          nil,
+							Condition: &v1beta1.AdvertisementCondition{
+								Mode:  v1beta1.AdvertisementConditionExist,
+								Peers: []string{"primary"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "advertisement with aggregation",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							Aggregation: &v1beta1.Aggregation{
+								WithinPools: true,
+							},
+						},
+					},
+				},
+			},
+			want: &Config{
+				Peers: map[string]*Peer{},
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						AutoAssign: true,
+						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								Name:                "adv1",
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								Aggregation: &Aggregation{
+									WithinPools: true,
+									SummaryOnly: true,
+								},
+								Communities: map[community.BGPCommunity]bool{},
+								Nodes:       map[string]bool{},
+							},
+						},
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+			},
+		},
+		{
+			desc: "advertisement with aggregation and aggregation length",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools:    []string{"pool1"},
+							AggregationLength: ptr.To[int32](24),
+							Aggregation: &v1beta1.Aggregation{
+								SummaryOnly: ptr.To(false),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "advertisement with link bandwidth per endpoint",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools:           []string{"pool1"},
+							LinkBandwidthPerEndpoint: ptr.To[uint32](100),
+						},
+					},
+				},
+			},
+			want: &Config{
+				Peers: map[string]*Peer{},
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						AutoAssign: true,
+						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								Name:                     "adv1",
+								AggregationLength:        32,
+								AggregationLengthV6:      128,
+								Communities:              map[community.BGPCommunity]bool{},
+								LinkBandwidthPerEndpoint: 100,
+								Nodes:                    map[string]bool{},
+							},
+						},
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+			},
+		},
+		{
+			desc: "advertisement with link bandwidth per endpoint and aggregation length",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools:           []string{"pool1"},
+							AggregationLengthV6:      ptr.To[int32](64),
+							LinkBandwidthPerEndpoint: ptr.To[uint32](100),
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "advertisement with link bandwidth per endpoint and link bandwidth community",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools:           []string{"pool1"},
+							Communities:              []string{"bandwidth:1000"},
+							LinkBandwidthPerEndpoint: ptr.To[uint32](100),
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "peer with dynamic asn",
+			crs: ClusterResources{
+
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:      42,
+							DynamicASN: v1beta2.InternalASNMode,
+							Address:    "1.2.3.4",
+						},
+					},
+				},
+			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:          "peer1",
+						MyASN:         42,
+						DynamicASN:    "internal",
+						Addr:          net.ParseIP("1.2.3.4"),
+						NodeSelectors: []labels.Selector{labels.Everything()},
+						EBGPMultiHop:  false,
+					},
+				},
+				Pools:       &Pools{ByName: map[string]*Pool{}},
+				BFDProfiles: map[string]*BFDProfile{},
+			},
+		},
+		{
+			desc: "peer with prefixes to receive",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.4",
+							ToReceive: &v1beta2.Receive{
+								Prefixes: []v1beta2.PrefixSelector{
+									{Prefix: "0.0.0.0/0"},
+									{Prefix: "192.168.0.0/16", GE: 24, LE: 28},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:          "peer1",
+						MyASN:         42,
+						ASN:           43,
+						Addr:          net.ParseIP("1.2.3.4"),
+						NodeSelectors: []labels.Selector{labels.Everything()},
+						ToReceive: &Receive{
+							Prefixes: []PrefixSelector{
+								{Prefix: ipnet("0.0.0.0/0")},
+								{Prefix: ipnet("192.168.0.0/16"), GE: 24, LE: 28},
+							},
+						},
+					},
+				},
+				Pools:       &Pools{ByName: map[string]*Pool{}},
+				BFDProfiles: map[string]*BFDProfile{},
+			},
+		},
+		{
+			desc: "peer receiving all the prefixes",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.4",
+							ToReceive: &v1beta2.Receive{
+								Mode: v1beta2.ReceiveAll,
+							},
+						},
+					},
+				},
+			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:          "peer1",
+						MyASN:         42,
+						ASN:           43,
+						Addr:          net.ParseIP("1.2.3.4"),
+						NodeSelectors: []labels.Selector{labels.Everything()},
+						ToReceive:     &Receive{All: true},
+					},
+				},
+				Pools:       &Pools{ByName: map[string]*Pool{}},
+				BFDProfiles: map[string]*BFDProfile{},
+			},
+		},
+		{
+			desc: "peer receiving all the prefixes with a list",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.4",
+							ToReceive: &v1beta2.Receive{
+								Mode: v1beta2.ReceiveAll,
+								Prefixes: []v1beta2.PrefixSelector{
+									{Prefix: "0.0.0.0/0"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "peer with invalid prefix to receive",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.4",
+							ToReceive: &v1beta2.Receive{
+								Prefixes: []v1beta2.PrefixSelector{
+									{Prefix: "192.168.1.1/16"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "peer with ge greater than le",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.4",
+							ToReceive: &v1beta2.Receive{
+								Prefixes: []v1beta2.PrefixSelector{
+									{Prefix: "192.168.0.0/16", GE: 28, LE: 24},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "peer with ge too small",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     43,
+							Address: "1.2.3.4",
+							ToReceive: &v1beta2.Receive{
+								Prefixes: []v1beta2.PrefixSelector{
+									{Prefix: "192.168.0.0/16", GE: 16},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "peer without asn or dynamic asn",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:      42,
+							ASN:        0,
+							DynamicASN: "",
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "peer with both asn and dynamic asn",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:      42,
+							ASN:        42,
+							DynamicASN: v1beta2.InternalASNMode,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "unnumbered peer without address with interface ok",
+			crs: ClusterResources{
+
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:      42,
+							DynamicASN: v1beta2.InternalASNMode,
+							Interface:  "net0",
+						},
+					},
+				},
+			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:          "peer1",
+						Iface:         "net0",
+						MyASN:         42,
+						DynamicASN:    "internal",
+						NodeSelectors: []labels.Selector{labels.Everything()},
+						EBGPMultiHop:  false,
+					},
+				},
+				Pools:       &Pools{ByName: map[string]*Pool{}},
+				BFDProfiles: map[string]*BFDProfile{},
+			},
+		},
+		{
+			desc: "unnumbered peer without address without interface nok",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "invalid",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:      42,
+							DynamicASN: "internal",
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "unnumbered peer with address with interface nok",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "invalid",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							Address:    "1.2.3.4",
+							Interface:  "net0",
+							MyASN:      42,
+							DynamicASN: "internal",
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "dynamic peer with listen range ok",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:       42,
+							ASN:         142,
+							ListenRange: "10.0.0.0/24",
+						},
+					},
+				},
+			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:          "peer1",
+						MyASN:         42,
+						ASN:           142,
+						ListenRange:   ipnet("10.0.0.0/24"),
+						NodeSelectors: []labels.Selector{labels.Everything()},
+					},
+				},
+				Pools:       &Pools{ByName: map[string]*Pool{}},
+				BFDProfiles: map[string]*BFDProfile{},
+			},
+		},
+		{
+			desc: "dynamic peer with listen range and address nok",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "invalid",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:       42,
+							ASN:         142,
+							Address:     "10.0.0.1",
+							ListenRange: "10.0.0.0/24",
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "dynamic peer with invalid listen range nok",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "invalid",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:       42,
+							ASN:         142,
+							ListenRange: "10.0.0.0",
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "peers with address from the node",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:       42,
+							ASN:         142,
+							AddressFrom: &v1beta2.PeerAddressSource{NodeAnnotation: "metallb.io/tor"},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer2",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:       42,
+							ASN:         142,
+							AddressFrom: &v1beta2.PeerAddressSource{DefaultGateway: "ipv6"},
+						},
+					},
+				},
+			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:          "peer1",
+						MyASN:         42,
+						ASN:           142,
+						AddrFrom:      &PeerAddressSource{NodeAnnotation: "metallb.io/tor"},
+						NodeSelectors: []labels.Selector{labels.Everything()},
+					},
+					"peer2": {
+						Name:          "peer2",
+						MyASN:         42,
+						ASN:           142,
+						AddrFrom:      &PeerAddressSource{DefaultGateway: ipfamily.IPv6},
+						NodeSelectors: []labels.Selector{labels.Everything()},
+					},
+				},
+				Pools:       &Pools{ByName: map[string]*Pool{}},
+				BFDProfiles: map[string]*BFDProfile{},
+			},
+		},
+		{
+			desc: "peer with address from the node and address nok",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "invalid",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:       42,
+							ASN:         142,
+							Address:     "10.0.0.1",
+							AddressFrom: &v1beta2.PeerAddressSource{NodeLabel: "tor"},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "peer with two address sources nok",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "invalid",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN: 42,
+							ASN:   142,
+							AddressFrom: &v1beta2.PeerAddressSource{
+								NodeLabel:      "tor",
+								DefaultGateway: "ipv4",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "peer with invalid default gateway family nok",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "invalid",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:       42,
+							ASN:         142,
+							AddressFrom: &v1beta2.PeerAddressSource{DefaultGateway: "dual"},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "peer with tcpAO keys",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     142,
+							Address: "1.2.3.4",
+							TCPAO: &v1beta2.TCPAO{
+								Keys: []v1beta2.TCPAOKey{
+									{SendID: 1, RecvID: 2, KeySecret: corev1.SecretReference{Name: "key1"}},
+									{SendID: 3, RecvID: 4, Algorithm: "hmac-sha256", KeySecret: corev1.SecretReference{Name: "key2"}},
+								},
+								CurrentKeyID: ptr.To[uint8](3),
+							},
+						},
+					},
+				},
+				PasswordSecrets: map[string]corev1.Secret{
+					"key1": {ObjectMeta: metav1.ObjectMeta{Name: "key1", Namespace: "metallb-system"},
+						Data: map[string][]byte{"key": []byte("secret1")}},
+					"key2": {ObjectMeta: metav1.ObjectMeta{Name: "key2", Namespace: "metallb-system"},
+						Data: map[string][]byte{"key": []byte("secret2")}},
+				},
+			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:          "peer1",
+						MyASN:         42,
+						ASN:           142,
+						Addr:          net.ParseIP("1.2.3.4"),
+						NodeSelectors: []labels.Selector{labels.Everything()},
+						TCPAO: &TCPAO{
+							Keys: []TCPAOKey{
+								{SendID: 1, RecvID: 2, Algorithm: "hmac-sha1", Key: "secret1"},
+								{SendID: 3, RecvID: 4, Algorithm: "hmac-sha256", Key: "secret2"},
+							},
+							CurrentKeyID: 3,
+						},
+					},
+				},
+				Pools:       &Pools{ByName: map[string]*Pool{}},
+				BFDProfiles: map[string]*BFDProfile{},
+			},
+		},
+		{
+			desc: "peer with duplicate tcpAO key ids nok",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "invalid",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     142,
+							Address: "1.2.3.4",
+							TCPAO: &v1beta2.TCPAO{
+								Keys: []v1beta2.TCPAOKey{
+									{SendID: 1, RecvID: 2, KeySecret: corev1.SecretReference{Name: "key1"}},
+									{SendID: 1, RecvID: 3, KeySecret: corev1.SecretReference{Name: "key1"}},
+								},
+							},
+						},
+					},
+				},
+				PasswordSecrets: map[string]corev1.Secret{
+					"key1": {ObjectMeta: metav1.ObjectMeta{Name: "key1", Namespace: "metallb-system"},
+						Data: map[string][]byte{"key": []byte("secret1")}},
+				},
+			},
+		},
+		{
+			desc: "peer with tcpAO current key not matching nok",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "invalid",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     142,
+							Address: "1.2.3.4",
+							TCPAO: &v1beta2.TCPAO{
+								Keys: []v1beta2.TCPAOKey{
+									{SendID: 1, RecvID: 2, KeySecret: corev1.SecretReference{Name: "key1"}},
+								},
+								CurrentKeyID: ptr.To[uint8](5),
+							},
+						},
+					},
+				},
+				PasswordSecrets: map[string]corev1.Secret{
+					"key1": {ObjectMeta: metav1.ObjectMeta{Name: "key1", Namespace: "metallb-system"},
+						Data: map[string][]byte{"key": []byte("secret1")}},
+				},
+			},
+		},
+		{
+			desc: "passive peer with tcpAO nok",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "invalid",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     142,
+							Address: "1.2.3.4",
+							Passive: true,
+							TCPAO: &v1beta2.TCPAO{
+								Keys: []v1beta2.TCPAOKey{
+									{SendID: 1, RecvID: 2, KeySecret: corev1.SecretReference{Name: "key1"}},
+								},
+							},
+						},
+					},
+				},
+				PasswordSecrets: map[string]corev1.Secret{
+					"key1": {ObjectMeta: metav1.ObjectMeta{Name: "key1", Namespace: "metallb-system"},
+						Data: map[string][]byte{"key": []byte("secret1")}},
+				},
+			},
+		},
+		{
+			desc: "peer with DisableMP field",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:     42,
+							ASN:       142,
+							Address:   "1.2.3.4",
+							DisableMP: true,
+						},
+					},
+				},
+			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:                   "peer1",
+						MyASN:                  42,
+						ASN:                    142,
+						Addr:                   net.ParseIP("1.2.3.4"),
+						NodeSelectors:          []labels.Selector{labels.Everything()},
+						DisableMP:              true,
+						DualStackAddressFamily: false,
+					},
+				},
+				Pools:       &Pools{ByName: map[string]*Pool{}},
+				BFDProfiles: map[string]*BFDProfile{},
+			},
+		},
+		{
+			desc: "peer with localASN field",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:    100,
+							ASN:      200,
+							Address:  "1.2.3.4",
+							LocalASN: 65410,
+						},
+					},
+				},
+			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:          "peer1",
+						MyASN:         100,
+						ASN:           200,
+						Addr:          net.ParseIP("1.2.3.4"),
+						NodeSelectors: []labels.Selector{labels.Everything()},
+						LocalASN:      65410,
+					},
+				},
+				Pools:       &Pools{ByName: map[string]*Pool{}},
+				BFDProfiles: map[string]*BFDProfile{},
+			},
+		},
+		{
+			desc: "peers with template",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							Address:      "1.2.3.4",
+							PeerTemplate: "tor",
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer2",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:        100,
+							DynamicASN:   v1beta2.ExternalASNMode,
+							Address:      "1.2.3.5",
+							HoldTime:     ptr.To(metav1.Duration{Duration: 30 * time.Second}),
+							Password:     "peerpass",
+							PeerTemplate: "tor",
+						},
+					},
+				},
+				PeerTemplates: []v1beta2.BGPPeerTemplate{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "tor",
+						},
+						Spec: v1beta2.BGPPeerTemplateSpec{
+							MyASN:    42,
+							ASN:      142,
+							HoldTime: ptr.To(metav1.Duration{Duration: 90 * time.Second}),
+							PasswordSecret: corev1.SecretReference{Name: "bgpsecret",
+								Namespace: "metallb-system"},
+							BFDProfile:            "default",
+							EnableGracefulRestart: ptr.To(true),
+							EBGPMultiHop:          ptr.To(true),
+						},
+					},
+				},
+				BFDProfiles: []v1beta1.BFDProfile{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "default",
+						},
+					},
+				},
+				PasswordSecrets: map[string]corev1.Secret{
+					"bgpsecret": {Type: corev1.SecretTypeBasicAuth, ObjectMeta: metav1.ObjectMeta{Name: "bgpsecret", Namespace: "metallb-system"},
+						Data: map[string][]byte{"password": []byte("nopass")}},
+				},
+			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:           "peer1",
+						PeerTemplate:   "tor",
+						MyASN:          42,
+						ASN:            142,
+						Addr:           net.ParseIP("1.2.3.4"),
+						HoldTime:       ptr.To(90 * time.Second),
+						KeepaliveTime:  ptr.To(30 * time.Second),
+						NodeSelectors:  []labels.Selector{labels.Everything()},
+						SecretPassword: "nopass",
+						PasswordRef: corev1.SecretReference{
+							Name:      "bgpsecret",
+							Namespace: "metallb-system",
+						},
+						BFDProfile:            "default",
+						EnableGracefulRestart: true,
+						EBGPMultiHop:          true,
+					},
+					"peer2": {
+						Name:                  "peer2",
+						PeerTemplate:          "tor",
+						MyASN:                 100,
+						DynamicASN:            "external",
+						Addr:                  net.ParseIP("1.2.3.5"),
+						HoldTime:              ptr.To(30 * time.Second),
+						KeepaliveTime:         ptr.To(10 * time.Second),
+						NodeSelectors:         []labels.Selector{labels.Everything()},
+						Password:              "peerpass",
+						BFDProfile:            "default",
+						EnableGracefulRestart: true,
+						EBGPMultiHop:          true,
+					},
+				},
+				Pools: &Pools{ByName: map[string]*Pool{}},
+				BFDProfiles: map[string]*BFDProfile{
+					"default": {
+						Name: "default",
+					},
+				},
+			},
+		},
+		{
+			desc: "peer overriding the template booleans",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							Address:      "1.2.3.4",
+							PeerTemplate: "tor",
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer2",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							Address:               "1.2.3.5",
+							EnableGracefulRestart: ptr.To(false),
+							EBGPMultiHop:          ptr.To(false),
+							PeerTemplate:          "tor",
+						},
+					},
+				},
+				PeerTemplates: []v1beta2.BGPPeerTemplate{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "tor",
+						},
+						Spec: v1beta2.BGPPeerTemplateSpec{
+							MyASN:                 42,
+							ASN:                   142,
+							EnableGracefulRestart: ptr.To(true),
+							EBGPMultiHop:          ptr.To(true),
+						},
+					},
+				},
+			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:                  "peer1",
+						PeerTemplate:          "tor",
+						MyASN:                 42,
+						ASN:                   142,
+						Addr:                  net.ParseIP("1.2.3.4"),
+						NodeSelectors:         []labels.Selector{labels.Everything()},
+						EnableGracefulRestart: true,
+						EBGPMultiHop:          true,
+					},
+					"peer2": {
+						Name:          "peer2",
+						PeerTemplate:  "tor",
+						MyASN:         42,
+						ASN:           142,
+						Addr:          net.ParseIP("1.2.3.5"),
+						NodeSelectors: []labels.Selector{labels.Everything()},
+					},
+				},
+				Pools:       &Pools{ByName: map[string]*Pool{}},
+				BFDProfiles: map[string]*BFDProfile{},
+			},
+		},
+		{
+			desc: "peer with missing template",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							Address:      "1.2.3.4",
+							PeerTemplate: "tor",
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "duplicate peer templates",
+			crs: ClusterResources{
+				PeerTemplates: []v1beta2.BGPPeerTemplate{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "tor",
+						},
+						Spec: v1beta2.BGPPeerTemplateSpec{
+							MyASN: 42,
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "tor",
+						},
+						Spec: v1beta2.BGPPeerTemplateSpec{
+							MyASN: 43,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "peer with template missing the peer asn",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							Address:      "1.2.3.4",
+							PeerTemplate: "tor",
+						},
+					},
+				},
+				PeerTemplates: []v1beta2.BGPPeerTemplate{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "tor",
+						},
+						Spec: v1beta2.BGPPeerTemplateSpec{
+							MyASN: 42,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := For(test.crs, DontValidate, test.opts)
+			if err != nil && test.want != nil {
+				t.Errorf("%q: parse failed: %s", test.desc, err)
+				return
+			}
+			if test.want == nil && err == nil {
+				t.Errorf("%q: parse unexpectedly succeeded", test.desc)
+				return
+			}
+			selectorComparer := cmp.Comparer(func(x, y labels.Selector) bool {
+				if x == nil {
+					return y == nil
+				}
+				if y == nil {
+					return x == nil
+				}
+				// Nothing() and Everything() have the same string
+				// representation, stupidly. So, compare explicitly for
+				// Nothing.
+				if x == labels.Nothing() {
+					return y == labels.Nothing()
+				}
+				if y == labels.Nothing() {
+					return x == labels.Nothing()
+				}
+				return x.String() == y.String()
+			})
+			// We don't care about comparing cidrPerAddress as it's calculated
+			cidrPerAddressComparer := cmp.Comparer(func(x, y map[string][]*net.IPNet) bool {
+				return true
+			})
+
+			if diff := cmp.Diff(test.want, got, selectorComparer, cidrPerAddressComparer, cmp.AllowUnexported(Pool{})); diff != "" {
+				t.Errorf("%q: parse returned wrong result (-want, +got)\n%s", test.desc, diff)
+			}
+		})
+	}
+}
+
+func TestContainsAdvertisement(t *testing.T) {
+	tests := []struct {
+		desc    string
+		advs    []*L2Advertisement
+		toCheck *L2Advertisement
+		expect  bool
+	}{
+		{
+			desc: "Contain",
+			advs: []*L2Advertisement{
+				{
+					Nodes: map[string]bool{
+						"nodeA": true,
+						"nodeB": true,
+					},
+					Interfaces:    []string{"eth0", "eth1"},
+					AllInterfaces: false,
+				},
+				{
+					Nodes: map[string]bool{
+						"nodeB": true,
+					},
+					Interfaces:    []string{},
+					AllInterfaces: true,
+				},
+			},
+			toCheck: &L2Advertisement{
+				Nodes: map[string]bool{
+					"nodeB": true,
+					"nodeA": true,
+				},
+				Interfaces:    []string{"eth1", "eth0"},
+				AllInterfaces: false,
+			},
+			expect: true,
+		},
+		{
+			desc: "Not contain: Nodes don't equal",
+			advs: []*L2Advertisement{
+				{
+					Nodes: map[string]bool{
+						"nodeA": true,
+						"nodeB": true,
+					},
+					Interfaces:    []string{"eth0", "eth1"},
+					AllInterfaces: false,
+				},
+			},
+			toCheck: &L2Advertisement{
+				Nodes: map[string]bool{
+					"nodeB": true,
+					"nodeC": true,
+				},
+				Interfaces:    []string{"eth1", "eth0"},
+				AllInterfaces: false,
+			},
+			expect: false,
+		},
+		{
+			desc: "Not contain: Interfaces don't equal",
+			advs: []*L2Advertisement{
+				{
+					Nodes: map[string]bool{
+						"nodeA": true,
+						"nodeB": true,
+					},
+					Interfaces:    []string{"eth0", "eth1"},
+					AllInterfaces: false,
 				},
 			},
 			toCheck: &L2Advertisement{
@@ -4052,48 +5826,240 @@ func TestContainsAdvertisement(t *testing.T) {
 				AllInterfaces:  true,
 				PreferredNodes: map[string]int64{"edge-a": 50},
 			},
-			expect: false,
+			expect: false,
+		},
+		{
+			desc: "Identical ads without preferences still dedupe",
+			advs: []*L2Advertisement{
+				{
+					Nodes:         map[string]bool{"a": true},
+					AllInterfaces: true,
+				},
+			},
+			toCheck: &L2Advertisement{
+				Nodes:         map[string]bool{"a": true},
+				AllInterfaces: true,
+			},
+			expect: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			result := containsAdvertisement(test.advs, test.toCheck)
+			if result != test.expect {
+				t.Errorf("expect is %v, but result is %v", test.expect, result)
+			}
+		})
+	}
+}
+
+func FuzzParseCIDR(f *testing.F) {
+	f.Fuzz(func(t *testing.T, input string) {
+		_, _ = ParseCIDR(input)
+	})
+}
+
+func TestServiceSelectors(t *testing.T) {
+	tests := []struct {
+		desc    string
+		crs     ClusterResources
+		want    *Config
+		wantErr string
+	}{
+		{
+			desc: "BGP advertisement with service selectors",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{"1.2.3.0/24"},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "adv1"},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							ServiceSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{"app": "nginx"},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						AutoAssign: true,
+						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								Name:                "adv1",
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{},
+								ServiceSelectors:    []labels.Selector{selector("app=nginx")},
+							},
+						},
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
+			},
+		},
+		{
+			desc: "L2 advertisement with service selectors",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{"1.2.3.0/24"},
+						},
+					},
+				},
+				L2Advs: []v1beta1.L2Advertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "adv1"},
+						Spec: v1beta1.L2AdvertisementSpec{
+							ServiceSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{"app": "web"},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						AutoAssign: true,
+						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
+						L2Advertisements: []*L2Advertisement{
+							{
+								Nodes:            map[string]bool{},
+								AllInterfaces:    true,
+								ServiceSelectors: []labels.Selector{selector("app=web")},
+							},
+						},
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
+			},
+		},
+		{
+			desc: "BGP advertisement with multiple service selectors (OR logic)",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{"1.2.3.0/24"},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "adv1"},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							ServiceSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{"app": "nginx"},
+								},
+								{
+									MatchLabels: map[string]string{"app": "apache"},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						AutoAssign: true,
+						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								Name:                "adv1",
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{},
+								ServiceSelectors:    []labels.Selector{selector("app=apache"), selector("app=nginx")}, // sorted
+							},
+						},
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
+			},
+		},
+		{
+			desc: "BGP advertisement - serviceSelectors with non-default aggregationLength is mutually exclusive",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{"1.2.3.0/24"},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "adv1"},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLength: ptr.To[int32](24),
+							ServiceSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{"app": "nginx"},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: "serviceSelectors and aggregationLength are mutually exclusive",
 		},
 		{
-			desc: "Identical ads without preferences still dedupe",
-			advs: []*L2Advertisement{
-				{
-					Nodes:         map[string]bool{"a": true},
-					AllInterfaces: true,
+			desc: "BGP advertisement - serviceSelectors with non-default aggregationLengthV6 is mutually exclusive",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{"2001:db8::/64"},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "adv1"},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLengthV6: ptr.To[int32](64),
+							ServiceSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{"app": "nginx"},
+								},
+							},
+						},
+					},
 				},
 			},
-			toCheck: &L2Advertisement{
-				Nodes:         map[string]bool{"a": true},
-				AllInterfaces: true,
-			},
-			expect: true,
+			wantErr: "serviceSelectors and aggregationLengthV6 are mutually exclusive",
 		},
-	}
-	for _, test := range tests {
-		t.Run(test.desc, func(t *testing.T) {
-			result := containsAdvertisement(test.advs, test.toCheck)
-			if result != test.expect {
-				t.Errorf("expect is %v, but result is %v", test.expect, result)
-			}
-		})
-	}
-}
-
-func FuzzParseCIDR(f *testing.F) {
-	f.Fuzz(func(t *testing.T, input string) {
-		_, _ = ParseCIDR(input)
-	})
-}
-
-func TestServiceSelectors(t *testing.T) {
-	tests := []struct {
-		desc    string
-		crs     ClusterResources
-		want    *Config
-		wantErr string
-	}{
 		{
-			desc: "BGP advertisement with service selectors",
+			desc: "BGP advertisement - serviceSelectors with default aggregationLengthV4 is allowed",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
@@ -4107,6 +6073,7 @@ func TestServiceSelectors(t *testing.T) {
 					{
 						ObjectMeta: metav1.ObjectMeta{Name: "adv1"},
 						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLength: ptr.To[int32](32),
 							ServiceSelectors: []metav1.LabelSelector{
 								{
 									MatchLabels: map[string]string{"app": "nginx"},
@@ -4139,23 +6106,24 @@ func TestServiceSelectors(t *testing.T) {
 			},
 		},
 		{
-			desc: "L2 advertisement with service selectors",
+			desc: "BGP advertisement - serviceSelectors with default aggregationLengthV6 is allowed",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
 						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{"1.2.3.0/24"},
+							Addresses: []string{"1000::/64"},
 						},
 					},
 				},
-				L2Advs: []v1beta1.L2Advertisement{
+				BGPAdvs: []v1beta1.BGPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{Name: "adv1"},
-						Spec: v1beta1.L2AdvertisementSpec{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLengthV6: ptr.To[int32](128),
 							ServiceSelectors: []metav1.LabelSelector{
 								{
-									MatchLabels: map[string]string{"app": "web"},
+									MatchLabels: map[string]string{"app": "nginx"},
 								},
 							},
 						},
@@ -4167,12 +6135,15 @@ func TestServiceSelectors(t *testing.T) {
 					"pool1": {
 						Name:       "pool1",
 						AutoAssign: true,
-						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
-						L2Advertisements: []*L2Advertisement{
+						CIDR:       []*net.IPNet{ipnet("1000::/64")},
+						BGPAdvertisements: []*BGPAdvertisement{
 							{
-								Nodes:            map[string]bool{},
-								AllInterfaces:    true,
-								ServiceSelectors: []labels.Selector{selector("app=web")},
+								Name:                "adv1",
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{},
+								ServiceSelectors:    []labels.Selector{selector("app=nginx")},
 							},
 						},
 					},
@@ -4182,13 +6153,13 @@ func TestServiceSelectors(t *testing.T) {
 			},
 		},
 		{
-			desc: "BGP advertisement with multiple service selectors (OR logic)",
+			desc: "BGP advertisement - serviceSelectors with both default aggregationLength and aggregationLengthV6 is allowed",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
 						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{"1.2.3.0/24"},
+							Addresses: []string{"1.2.3.0/24", "1000::/64"},
 						},
 					},
 				},
@@ -4196,13 +6167,12 @@ func TestServiceSelectors(t *testing.T) {
 					{
 						ObjectMeta: metav1.ObjectMeta{Name: "adv1"},
 						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLength:   ptr.To[int32](32),
+							AggregationLengthV6: ptr.To[int32](128),
 							ServiceSelectors: []metav1.LabelSelector{
 								{
 									MatchLabels: map[string]string{"app": "nginx"},
 								},
-								{
-									MatchLabels: map[string]string{"app": "apache"},
-								},
 							},
 						},
 					},
@@ -4213,7 +6183,7 @@ func TestServiceSelectors(t *testing.T) {
 					"pool1": {
 						Name:       "pool1",
 						AutoAssign: true,
-						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
+						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24"), ipnet("1000::/64")},
 						BGPAdvertisements: []*BGPAdvertisement{
 							{
 								Name:                "adv1",
@@ -4221,7 +6191,7 @@ func TestServiceSelectors(t *testing.T) {
 								AggregationLengthV6: 128,
 								Communities:         map[community.BGPCommunity]bool{},
 								Nodes:               map[string]bool{},
-								ServiceSelectors:    []labels.Selector{selector("app=apache"), selector("app=nginx")}, // sorted
+								ServiceSelectors:    []labels.Selector{selector("app=nginx")},
 							},
 						},
 					},
@@ -4231,509 +6201,1035 @@ func TestServiceSelectors(t *testing.T) {
 			},
 		},
 		{
-			desc: "BGP advertisement - serviceSelectors with non-default aggregationLength is mutually exclusive",
-			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{
-					{
-						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
-						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{"1.2.3.0/24"},
-						},
-					},
+			desc: "duplicate service selectors",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{"1.2.3.0/24"},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "adv1"},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							ServiceSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{"app": "nginx"},
+								},
+								{
+									MatchLabels: map[string]string{"app": "nginx"},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: "duplicate definition of serviceSelectors",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := For(tt.crs, DontValidate, ForOptions{})
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Errorf("expected error containing %q, got nil", tt.wantErr)
+					return
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			selectorComparer := cmp.Comparer(func(x, y labels.Selector) bool {
+				if x == nil && y == nil {
+					return true
+				}
+				if x == nil || y == nil {
+					return false
+				}
+				return x.String() == y.String()
+			})
+			cidrPerAddressComparer := cmp.Comparer(func(x, y map[string][]*net.IPNet) bool {
+				return true
+			})
+
+			if diff := cmp.Diff(tt.want, got, selectorComparer, cidrPerAddressComparer, cmp.AllowUnexported(Pool{})); diff != "" {
+				t.Errorf("parse returned wrong result (-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestL2AdvertisementFromCRPreferred(t *testing.T) {
+	edgeNodes := []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "edge-a", Labels: map[string]string{"role": "edge", "zone": "primary"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "edge-b", Labels: map[string]string{"role": "edge"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "worker-a", Labels: map[string]string{"role": "worker"}}},
+	}
+
+	tests := []struct {
+		desc             string
+		nodes            []corev1.Node
+		crd              v1beta1.L2Advertisement
+		wantNodes        map[string]bool
+		wantPreferred    map[string]int64
+		wantSvcSelectors []string
+	}{
+		{
+			desc:  "preferred selector scores matching nodes",
+			nodes: edgeNodes,
+			crd: v1beta1.L2Advertisement{
+				ObjectMeta: metav1.ObjectMeta{Name: "prefer-edge"},
+				Spec: v1beta1.L2AdvertisementSpec{
+					PreferredNodeSelectors: []v1beta1.PreferredNodeSelector{
+						{Weight: 100, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"role": "edge"}}},
+					},
+				},
+			},
+			wantNodes:     map[string]bool{"edge-a": true, "edge-b": true, "worker-a": true},
+			wantPreferred: map[string]int64{"edge-a": 100, "edge-b": 100},
+		},
+		{
+			desc:  "service selectors do not restrict preferred scoring",
+			nodes: edgeNodes,
+			crd: v1beta1.L2Advertisement{
+				ObjectMeta: metav1.ObjectMeta{Name: "prefer-edge-with-svc-selectors"},
+				Spec: v1beta1.L2AdvertisementSpec{
+					ServiceSelectors: []metav1.LabelSelector{
+						{MatchLabels: map[string]string{"app": "web"}},
+					},
+					PreferredNodeSelectors: []v1beta1.PreferredNodeSelector{
+						{Weight: 100, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"role": "edge"}}},
+					},
+				},
+			},
+			wantNodes:        map[string]bool{"edge-a": true, "edge-b": true, "worker-a": true},
+			wantPreferred:    map[string]int64{"edge-a": 100, "edge-b": 100},
+			wantSvcSelectors: []string{"app=web"},
+		},
+		{
+			desc:  "no preferred selectors leaves PreferredNodes nil",
+			nodes: edgeNodes,
+			crd: v1beta1.L2Advertisement{
+				ObjectMeta: metav1.ObjectMeta{Name: "basic"},
+				Spec:       v1beta1.L2AdvertisementSpec{},
+			},
+			wantNodes:     map[string]bool{"edge-a": true, "edge-b": true, "worker-a": true},
+			wantPreferred: nil,
+		},
+		{
+			desc:  "preferred selectors disjoint from node selectors yield no scores",
+			nodes: edgeNodes,
+			crd: v1beta1.L2Advertisement{
+				ObjectMeta: metav1.ObjectMeta{Name: "disjoint"},
+				Spec: v1beta1.L2AdvertisementSpec{
+					NodeSelectors: []metav1.LabelSelector{
+						{MatchLabels: map[string]string{"role": "worker"}},
+					},
+					PreferredNodeSelectors: []v1beta1.PreferredNodeSelector{
+						{Weight: 50, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"role": "edge"}}},
+					},
+				},
+			},
+			wantNodes:     map[string]bool{"worker-a": true},
+			wantPreferred: nil,
+		},
+		{
+			desc:  "multiple preferred selectors sum on matching nodes",
+			nodes: edgeNodes,
+			crd: v1beta1.L2Advertisement{
+				ObjectMeta: metav1.ObjectMeta{Name: "sum-edge-zone"},
+				Spec: v1beta1.L2AdvertisementSpec{
+					PreferredNodeSelectors: []v1beta1.PreferredNodeSelector{
+						{Weight: 40, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"role": "edge"}}},
+						{Weight: 30, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"zone": "primary"}}},
+					},
+				},
+			},
+			wantNodes:     map[string]bool{"edge-a": true, "edge-b": true, "worker-a": true},
+			wantPreferred: map[string]int64{"edge-a": 70, "edge-b": 40},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := l2AdvertisementFromCR(tc.crd, tc.nodes)
+			if err != nil {
+				t.Fatalf("l2AdvertisementFromCR returned error: %v", err)
+			}
+			if tc.wantNodes != nil {
+				if diff := cmp.Diff(tc.wantNodes, got.Nodes); diff != "" {
+					t.Fatalf("Nodes mismatch (-want +got):\n%s", diff)
+				}
+			}
+			if diff := cmp.Diff(tc.wantPreferred, got.PreferredNodes); diff != "" {
+				t.Fatalf("PreferredNodes mismatch (-want +got):\n%s", diff)
+			}
+			gotSvcSelectors := make([]string, 0, len(got.ServiceSelectors))
+			for _, s := range got.ServiceSelectors {
+				gotSvcSelectors = append(gotSvcSelectors, s.String())
+			}
+			if diff := cmp.Diff(tc.wantSvcSelectors, gotSvcSelectors, cmp.Transformer("nilEmpty", func(in []string) []string {
+				if len(in) == 0 {
+					return nil
+				}
+				return in
+			})); diff != "" {
+				t.Fatalf("ServiceSelectors mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetL2AdvertisementsToPoolsPreferred(t *testing.T) {
+	tests := []struct {
+		desc          string
+		pools         []v1beta1.IPAddressPool
+		advs          []v1beta1.L2Advertisement
+		wantPreferred []map[string]int64
+	}{
+		{
+			desc: "pool selected by name and selector attaches once",
+			pools: []v1beta1.IPAddressPool{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "pool-a", Labels: map[string]string{"shared": "true"}},
+					Spec:       v1beta1.IPAddressPoolSpec{Addresses: []string{"10.20.0.0/24"}},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
-					{
-						ObjectMeta: metav1.ObjectMeta{Name: "adv1"},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLength: ptr.To[int32](24),
-							ServiceSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{"app": "nginx"},
-								},
-							},
+			},
+			advs: []v1beta1.L2Advertisement{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "prefer-edge"},
+					Spec: v1beta1.L2AdvertisementSpec{
+						IPAddressPools: []string{"pool-a"},
+						IPAddressPoolSelectors: []metav1.LabelSelector{
+							{MatchLabels: map[string]string{"shared": "true"}},
+						},
+						PreferredNodeSelectors: []v1beta1.PreferredNodeSelector{
+							{Weight: 50, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"role": "edge"}}},
 						},
 					},
 				},
 			},
-			wantErr: "serviceSelectors and aggregationLength are mutually exclusive",
+			wantPreferred: []map[string]int64{{"edge-a": 50}},
 		},
 		{
-			desc: "BGP advertisement - serviceSelectors with non-default aggregationLengthV6 is mutually exclusive",
-			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{
-					{
-						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
-						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{"2001:db8::/64"},
+			desc: "two CRs targeting the same pool attach independently",
+			pools: []v1beta1.IPAddressPool{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "pool-a"},
+					Spec:       v1beta1.IPAddressPoolSpec{Addresses: []string{"10.20.0.0/24"}},
+				},
+			},
+			advs: []v1beta1.L2Advertisement{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ad1-edge-70"},
+					Spec: v1beta1.L2AdvertisementSpec{
+						IPAddressPools: []string{"pool-a"},
+						PreferredNodeSelectors: []v1beta1.PreferredNodeSelector{
+							{Weight: 70, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"role": "edge"}}},
 						},
 					},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
-					{
-						ObjectMeta: metav1.ObjectMeta{Name: "adv1"},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLengthV6: ptr.To[int32](64),
-							ServiceSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{"app": "nginx"},
-								},
-							},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ad2-edge-30"},
+					Spec: v1beta1.L2AdvertisementSpec{
+						IPAddressPools: []string{"pool-a"},
+						PreferredNodeSelectors: []v1beta1.PreferredNodeSelector{
+							{Weight: 30, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"role": "edge"}}},
 						},
 					},
 				},
 			},
-			wantErr: "serviceSelectors and aggregationLengthV6 are mutually exclusive",
+			wantPreferred: []map[string]int64{{"edge-a": 30}, {"edge-a": 70}},
 		},
 		{
-			desc: "BGP advertisement - serviceSelectors with default aggregationLengthV4 is allowed",
-			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{
-					{
-						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
-						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{"1.2.3.0/24"},
-						},
-					},
-				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
-					{
-						ObjectMeta: metav1.ObjectMeta{Name: "adv1"},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLength: ptr.To[int32](32),
-							ServiceSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{"app": "nginx"},
-								},
-							},
-						},
-					},
+			desc: "one CR with two selectors sums on the same node",
+			pools: []v1beta1.IPAddressPool{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "pool-a"},
+					Spec:       v1beta1.IPAddressPoolSpec{Addresses: []string{"10.20.0.0/24"}},
 				},
 			},
-			want: &Config{
-				Pools: &Pools{ByName: map[string]*Pool{
-					"pool1": {
-						Name:       "pool1",
-						AutoAssign: true,
-						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
-						BGPAdvertisements: []*BGPAdvertisement{
-							{
-								Name:                "adv1",
-								AggregationLength:   32,
-								AggregationLengthV6: 128,
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{},
-								ServiceSelectors:    []labels.Selector{selector("app=nginx")},
-							},
+			advs: []v1beta1.L2Advertisement{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "sum-edge-zone"},
+					Spec: v1beta1.L2AdvertisementSpec{
+						IPAddressPools: []string{"pool-a"},
+						PreferredNodeSelectors: []v1beta1.PreferredNodeSelector{
+							{Weight: 40, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"role": "edge"}}},
+							{Weight: 30, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"zone": "primary"}}},
 						},
 					},
-				}},
-				BFDProfiles: map[string]*BFDProfile{},
-				Peers:       map[string]*Peer{},
+				},
 			},
+			wantPreferred: []map[string]int64{{"edge-a": 70}},
+		},
+	}
+
+	nodes := []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "edge-a", Labels: map[string]string{"role": "edge", "zone": "primary"}}},
+	}
+
+	sortPreferred := func(s []map[string]int64) {
+		sort.Slice(s, func(i, j int) bool {
+			var ti, tj int64
+			for _, v := range s[i] {
+				ti += v
+			}
+			for _, v := range s[j] {
+				tj += v
+			}
+			return ti < tj
+		})
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			poolMap := map[string]*Pool{"pool-a": {Name: "pool-a"}}
+			if err := setL2AdvertisementsToPools(tc.pools, tc.advs, nodes, poolMap); err != nil {
+				t.Fatalf("setL2AdvertisementsToPools: %v", err)
+			}
+			gotAds := poolMap["pool-a"].L2Advertisements
+			gotPreferred := make([]map[string]int64, 0, len(gotAds))
+			for _, a := range gotAds {
+				gotPreferred = append(gotPreferred, a.PreferredNodes)
+			}
+			sortPreferred(gotPreferred)
+			sortPreferred(tc.wantPreferred)
+			if diff := cmp.Diff(tc.wantPreferred, gotPreferred); diff != "" {
+				t.Fatalf("pool-a L2Advertisements mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestImportVRFs(t *testing.T) {
+	tests := []struct {
+		desc       string
+		importVRFs []string
+		want       []string
+		wantErr    bool
+	}{
+		{
+			desc:       "default and named vrfs",
+			importVRFs: []string{"default", "red"},
+			want:       []string{"", "red"},
 		},
 		{
-			desc: "BGP advertisement - serviceSelectors with default aggregationLengthV6 is allowed",
-			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{
-					{
-						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
-						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{"1000::/64"},
-						},
-					},
-				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
-					{
-						ObjectMeta: metav1.ObjectMeta{Name: "adv1"},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLengthV6: ptr.To[int32](128),
-							ServiceSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{"app": "nginx"},
-								},
-							},
-						},
+			desc:       "empty vrf",
+			importVRFs: []string{""},
+			wantErr:    true,
+		},
+		{
+			desc:       "duplicate vrf",
+			importVRFs: []string{"red", "red"},
+			wantErr:    true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			adv := v1beta1.BGPAdvertisement{
+				ObjectMeta: metav1.ObjectMeta{Name: "adv"},
+				Spec:       v1beta1.BGPAdvertisementSpec{ImportVRFs: test.importVRFs},
+			}
+			got, err := bgpAdvertisementFromCR(adv, map[string]community.BGPCommunity{}, nil)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %s", err)
+			}
+			if diff := cmp.Diff(test.want, got.ImportVRFs); diff != "" {
+				t.Fatalf("unexpected import vrfs (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEVPN(t *testing.T) {
+	red := func() *v1beta1.EVPN {
+		return &v1beta1.EVPN{
+			VRF:                "red",
+			VNI:                100,
+			RouteDistinguisher: "10.1.1.1:100",
+			ImportRouteTargets: []string{"64512:100"},
+			ExportRouteTargets: []string{"64512:100", "192.168.1.1:200"},
+		}
+	}
+	tests := []struct {
+		desc    string
+		spec    v1beta1.BGPAdvertisementSpec
+		want    *EVPN
+		wantErr bool
+	}{
+		{
+			desc: "valid",
+			spec: v1beta1.BGPAdvertisementSpec{EVPN: red()},
+			want: &EVPN{
+				VRF:                "red",
+				VNI:                100,
+				RouteDistinguisher: "10.1.1.1:100",
+				ImportRouteTargets: []string{"64512:100"},
+				ExportRouteTargets: []string{"64512:100", "192.168.1.1:200"},
+			},
+		},
+		{
+			desc: "derived route distinguisher and targets",
+			spec: v1beta1.BGPAdvertisementSpec{EVPN: &v1beta1.EVPN{VRF: "red", VNI: 100}},
+			want: &EVPN{VRF: "red", VNI: 100},
+		},
+		{
+			desc:    "default vrf",
+			spec:    v1beta1.BGPAdvertisementSpec{EVPN: &v1beta1.EVPN{VRF: "default", VNI: 100}},
+			wantErr: true,
+		},
+		{
+			desc:    "vni out of range",
+			spec:    v1beta1.BGPAdvertisementSpec{EVPN: &v1beta1.EVPN{VRF: "red", VNI: 1 << 24}},
+			wantErr: true,
+		},
+		{
+			desc:    "invalid route distinguisher",
+			spec:    v1beta1.BGPAdvertisementSpec{EVPN: &v1beta1.EVPN{VRF: "red", VNI: 100, RouteDistinguisher: "100"}},
+			wantErr: true,
+		},
+		{
+			desc:    "invalid route target",
+			spec:    v1beta1.BGPAdvertisementSpec{EVPN: &v1beta1.EVPN{VRF: "red", VNI: 100, ExportRouteTargets: []string{"70000:70000"}}},
+			wantErr: true,
+		},
+		{
+			desc:    "duplicate route target",
+			spec:    v1beta1.BGPAdvertisementSpec{EVPN: &v1beta1.EVPN{VRF: "red", VNI: 100, ImportRouteTargets: []string{"64512:100", "64512:100"}}},
+			wantErr: true,
+		},
+		{
+			desc:    "with communities",
+			spec:    v1beta1.BGPAdvertisementSpec{EVPN: red(), Communities: []string{"64512:1"}},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			adv := v1beta1.BGPAdvertisement{
+				ObjectMeta: metav1.ObjectMeta{Name: "adv"},
+				Spec:       test.spec,
+			}
+			got, err := bgpAdvertisementFromCR(adv, map[string]community.BGPCommunity{}, nil)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %s", err)
+			}
+			if diff := cmp.Diff(test.want, got.EVPN); diff != "" {
+				t.Fatalf("unexpected evpn (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEVPNAcrossAdvertisements(t *testing.T) {
+	resources := func(vrfName string, evpns ...*v1beta1.EVPN) ClusterResources {
+		res := ClusterResources{
+			Peers: []v1beta2.BGPPeer{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "peer1"},
+					Spec: v1beta2.BGPPeerSpec{
+						MyASN:   42,
+						ASN:     142,
+						Address: "1.2.3.4",
+						VRFName: vrfName,
 					},
 				},
 			},
-			want: &Config{
-				Pools: &Pools{ByName: map[string]*Pool{
-					"pool1": {
-						Name:       "pool1",
-						AutoAssign: true,
-						CIDR:       []*net.IPNet{ipnet("1000::/64")},
-						BGPAdvertisements: []*BGPAdvertisement{
-							{
-								Name:                "adv1",
-								AggregationLength:   32,
-								AggregationLengthV6: 128,
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{},
-								ServiceSelectors:    []labels.Selector{selector("app=nginx")},
-							},
-						},
-					},
-				}},
-				BFDProfiles: map[string]*BFDProfile{},
-				Peers:       map[string]*Peer{},
+			Pools: []v1beta1.IPAddressPool{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+					Spec:       v1beta1.IPAddressPoolSpec{Addresses: []string{"10.20.0.0/24"}},
+				},
 			},
+		}
+		for i, e := range evpns {
+			res.BGPAdvs = append(res.BGPAdvs, v1beta1.BGPAdvertisement{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("adv%d", i+1)},
+				Spec: v1beta1.BGPAdvertisementSpec{
+					IPAddressPools: []string{"pool1"},
+					Peers:          []string{"peer1"},
+					EVPN:           e,
+				},
+			})
+		}
+		return res
+	}
+	tests := []struct {
+		desc    string
+		crs     ClusterResources
+		wantErr string
+	}{
+		{
+			desc: "same vrf, same settings",
+			crs:  resources("", &v1beta1.EVPN{VRF: "red", VNI: 100}, &v1beta1.EVPN{VRF: "red", VNI: 100}),
 		},
 		{
-			desc: "BGP advertisement - serviceSelectors with both default aggregationLength and aggregationLengthV6 is allowed",
-			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{
-					{
-						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
-						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{"1.2.3.0/24", "1000::/64"},
-						},
+			desc: "different vrfs",
+			crs:  resources("", &v1beta1.EVPN{VRF: "red", VNI: 100}, &v1beta1.EVPN{VRF: "blue", VNI: 200}),
+		},
+		{
+			desc:    "same vrf, different settings",
+			crs:     resources("", &v1beta1.EVPN{VRF: "red", VNI: 100}, &v1beta1.EVPN{VRF: "red", VNI: 100, RouteDistinguisher: "1.1.1.1:100"}),
+			wantErr: "configure the evpn of vrf red differently",
+		},
+		{
+			desc:    "same vni, different vrfs",
+			crs:     resources("", &v1beta1.EVPN{VRF: "red", VNI: 100}, &v1beta1.EVPN{VRF: "blue", VNI: 100}),
+			wantErr: "bind the vni 100 to different vrfs",
+		},
+		{
+			desc:    "peer in a vrf",
+			crs:     resources("red", &v1beta1.EVPN{VRF: "red", VNI: 100}),
+			wantErr: "only the peers in the default VRF are supported",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := For(test.crs, DontValidate, ForOptions{})
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLabeledUnicast(t *testing.T) {
+	resources := func(vrfName string, specs ...v1beta1.BGPAdvertisementSpec) ClusterResources {
+		res := ClusterResources{
+			Peers: []v1beta2.BGPPeer{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "peer1"},
+					Spec: v1beta2.BGPPeerSpec{
+						MyASN:   42,
+						ASN:     142,
+						Address: "1.2.3.4",
+						VRFName: vrfName,
 					},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
-					{
-						ObjectMeta: metav1.ObjectMeta{Name: "adv1"},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLength:   ptr.To[int32](32),
-							AggregationLengthV6: ptr.To[int32](128),
-							ServiceSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{"app": "nginx"},
-								},
-							},
-						},
-					},
+			},
+			Pools: []v1beta1.IPAddressPool{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+					Spec:       v1beta1.IPAddressPoolSpec{Addresses: []string{"10.20.0.0/24", "10.30.0.10-10.30.0.11"}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "pool2"},
+					Spec:       v1beta1.IPAddressPoolSpec{Addresses: []string{"2001:db8::/120"}},
 				},
 			},
-			want: &Config{
-				Pools: &Pools{ByName: map[string]*Pool{
-					"pool1": {
-						Name:       "pool1",
-						AutoAssign: true,
-						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24"), ipnet("1000::/64")},
-						BGPAdvertisements: []*BGPAdvertisement{
-							{
-								Name:                "adv1",
-								AggregationLength:   32,
-								AggregationLengthV6: 128,
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{},
-								ServiceSelectors:    []labels.Selector{selector("app=nginx")},
-							},
-						},
-					},
-				}},
-				BFDProfiles: map[string]*BFDProfile{},
-				Peers:       map[string]*Peer{},
+		}
+		for i, spec := range specs {
+			if spec.IPAddressPools == nil {
+				spec.IPAddressPools = []string{"pool1", "pool2"}
+			}
+			spec.Peers = []string{"peer1"}
+			res.BGPAdvs = append(res.BGPAdvs, v1beta1.BGPAdvertisement{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("adv%d", i+1)},
+				Spec:       spec,
+			})
+		}
+		return res
+	}
+	labeled := func(indexes ...v1beta1.PoolLabelIndex) v1beta1.BGPAdvertisementSpec {
+		return v1beta1.BGPAdvertisementSpec{LabeledUnicast: &v1beta1.LabeledUnicast{LabelIndexes: indexes}}
+	}
+	tests := []struct {
+		desc        string
+		crs         ClusterResources
+		wantIndexes map[string]uint32
+		wantErr     string
+	}{
+		{
+			desc: "label indexes per pool",
+			crs:  resources("", labeled(v1beta1.PoolLabelIndex{IPAddressPool: "pool1", Base: 1000}, v1beta1.PoolLabelIndex{IPAddressPool: "pool2", Base: 2000})),
+			wantIndexes: map[string]uint32{
+				"10.20.0.0":     1000,
+				"10.20.0.255":   1255,
+				"10.30.0.10":    1256,
+				"10.30.0.11":    1257,
+				"2001:db8::":    2000,
+				"2001:db8::ff":  2255,
+				"2001:db8::100": 0,
 			},
 		},
 		{
-			desc: "duplicate service selectors",
-			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{
-					{
-						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
-						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{"1.2.3.0/24"},
-						},
-					},
-				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
-					{
-						ObjectMeta: metav1.ObjectMeta{Name: "adv1"},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							ServiceSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{"app": "nginx"},
-								},
-								{
-									MatchLabels: map[string]string{"app": "nginx"},
-								},
-							},
-						},
-					},
-				},
+			desc: "label allocated by frr",
+			crs:  resources("", labeled()),
+			wantIndexes: map[string]uint32{
+				"10.20.0.1": 0,
 			},
-			wantErr: "duplicate definition of serviceSelectors",
+		},
+		{
+			desc: "same pool, same base",
+			crs:  resources("", labeled(v1beta1.PoolLabelIndex{IPAddressPool: "pool1", Base: 1000}), labeled(v1beta1.PoolLabelIndex{IPAddressPool: "pool1", Base: 1000})),
+		},
+		{
+			desc:    "same pool, different bases",
+			crs:     resources("", labeled(v1beta1.PoolLabelIndex{IPAddressPool: "pool1", Base: 1000}), labeled(v1beta1.PoolLabelIndex{IPAddressPool: "pool1", Base: 2000})),
+			wantErr: "set different label index bases for pool pool1",
+		},
+		{
+			desc:    "overlapping pools",
+			crs:     resources("", labeled(v1beta1.PoolLabelIndex{IPAddressPool: "pool1", Base: 1000}, v1beta1.PoolLabelIndex{IPAddressPool: "pool2", Base: 1257})),
+			wantErr: "overlap with the ones of pool pool1",
+		},
+		{
+			desc:    "pool exceeding the highest index",
+			crs:     resources("", labeled(v1beta1.PoolLabelIndex{IPAddressPool: "pool1", Base: 1048500})),
+			wantErr: "exceed the highest label index",
+		},
+		{
+			desc: "pool not selected",
+			crs: resources("", v1beta1.BGPAdvertisementSpec{
+				IPAddressPools: []string{"pool1"},
+				LabeledUnicast: &v1beta1.LabeledUnicast{LabelIndexes: []v1beta1.PoolLabelIndex{{IPAddressPool: "pool2", Base: 1000}}},
+			}),
+			wantErr: "has a label index for pool pool2 which it does not select",
+		},
+		{
+			desc:    "duplicate pool",
+			crs:     resources("", labeled(v1beta1.PoolLabelIndex{IPAddressPool: "pool1", Base: 1000}, v1beta1.PoolLabelIndex{IPAddressPool: "pool1", Base: 2000})),
+			wantErr: "duplicate label index for pool pool1",
+		},
+		{
+			desc: "label indexes with aggregation",
+			crs: resources("", v1beta1.BGPAdvertisementSpec{
+				AggregationLength: ptr.To(int32(24)),
+				LabeledUnicast:    &v1beta1.LabeledUnicast{LabelIndexes: []v1beta1.PoolLabelIndex{{IPAddressPool: "pool1", Base: 1000}}},
+			}),
+			wantErr: "labeledUnicast labelIndexes and aggregation are mutually exclusive",
+		},
+		{
+			desc: "with evpn",
+			crs: resources("", v1beta1.BGPAdvertisementSpec{
+				EVPN:           &v1beta1.EVPN{VRF: "red", VNI: 100},
+				LabeledUnicast: &v1beta1.LabeledUnicast{},
+			}),
+			wantErr: "labeledUnicast and evpn are mutually exclusive",
+		},
+		{
+			desc:    "peer in a vrf",
+			crs:     resources("red", labeled()),
+			wantErr: "only the peers in the default VRF are supported",
 		},
 	}
-
-	for _, tt := range tests {
-		t.Run(tt.desc, func(t *testing.T) {
-			got, err := For(tt.crs, DontValidate, ForOptions{})
-			if tt.wantErr != "" {
-				if err == nil {
-					t.Errorf("expected error containing %q, got nil", tt.wantErr)
-					return
-				}
-				if !strings.Contains(err.Error(), tt.wantErr) {
-					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg, err := For(test.crs, DontValidate, ForOptions{})
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", test.wantErr, err)
 				}
 				return
 			}
 			if err != nil {
-				t.Errorf("unexpected error: %v", err)
-				return
+				t.Fatalf("unexpected error %s", err)
 			}
-
-			selectorComparer := cmp.Comparer(func(x, y labels.Selector) bool {
-				if x == nil && y == nil {
-					return true
+			for ip, want := range test.wantIndexes {
+				pool := cfg.Pools.ByName["pool1"]
+				if strings.Contains(ip, ":") {
+					pool = cfg.Pools.ByName["pool2"]
 				}
-				if x == nil || y == nil {
-					return false
+				got, ok := pool.BGPAdvertisements[0].LabeledUnicast.LabelIndex(pool, net.ParseIP(ip))
+				if ok != (want != 0) || got != want {
+					t.Fatalf("unexpected label index for %s, want %d got %d (%t)", ip, want, got, ok)
 				}
-				return x.String() == y.String()
-			})
-			cidrPerAddressComparer := cmp.Comparer(func(x, y map[string][]*net.IPNet) bool {
-				return true
-			})
-
-			if diff := cmp.Diff(tt.want, got, selectorComparer, cidrPerAddressComparer, cmp.AllowUnexported(Pool{})); diff != "" {
-				t.Errorf("parse returned wrong result (-want, +got)\n%s", diff)
 			}
 		})
 	}
 }
 
-func TestL2AdvertisementFromCRPreferred(t *testing.T) {
-	edgeNodes := []corev1.Node{
-		{ObjectMeta: metav1.ObjectMeta{Name: "edge-a", Labels: map[string]string{"role": "edge", "zone": "primary"}}},
-		{ObjectMeta: metav1.ObjectMeta{Name: "edge-b", Labels: map[string]string{"role": "edge"}}},
-		{ObjectMeta: metav1.ObjectMeta{Name: "worker-a", Labels: map[string]string{"role": "worker"}}},
+func TestPrefixLimits(t *testing.T) {
+	tests := []struct {
+		desc    string
+		limits  *v1beta2.PrefixLimits
+		want    *PrefixLimits
+		wantErr bool
+	}{
+		{
+			desc: "no limits",
+		},
+		{
+			desc:   "both limits",
+			limits: &v1beta2.PrefixLimits{MaxAdvertised: ptr.To(uint32(10)), MaxAccepted: ptr.To(uint32(100))},
+			want:   &PrefixLimits{MaxAdvertised: 10, MaxAccepted: 100},
+		},
+		{
+			desc:   "advertised only",
+			limits: &v1beta2.PrefixLimits{MaxAdvertised: ptr.To(uint32(10))},
+			want:   &PrefixLimits{MaxAdvertised: 10},
+		},
+		{
+			desc:    "zero advertised",
+			limits:  &v1beta2.PrefixLimits{MaxAdvertised: ptr.To(uint32(0))},
+			wantErr: true,
+		},
+		{
+			desc:    "zero accepted",
+			limits:  &v1beta2.PrefixLimits{MaxAccepted: ptr.To(uint32(0))},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			p := v1beta2.BGPPeer{
+				ObjectMeta: metav1.ObjectMeta{Name: "peer1"},
+				Spec: v1beta2.BGPPeerSpec{
+					MyASN:        42,
+					ASN:          142,
+					Address:      "1.2.3.4",
+					PrefixLimits: test.limits,
+				},
+			}
+			got, err := peerFromCR(p, nil, false)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %s", err)
+			}
+			if diff := cmp.Diff(test.want, got.PrefixLimits); diff != "" {
+				t.Fatalf("unexpected prefix limits (-want +got):\n%s", diff)
+			}
+		})
 	}
+}
 
-	tests := []struct {
-		desc             string
-		nodes            []corev1.Node
-		crd              v1beta1.L2Advertisement
-		wantNodes        map[string]bool
-		wantPreferred    map[string]int64
-		wantSvcSelectors []string
+func TestBGPExtras(t *testing.T) {
+	peer := func(name string, connectTime *metav1.Duration) v1beta2.BGPPeer {
+		return v1beta2.BGPPeer{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1beta2.BGPPeerSpec{
+				MyASN:       42,
+				ASN:         142,
+				Address:     "1.2.3.4",
+				ConnectTime: connectTime,
+				ToReceive:   &v1beta2.Receive{Mode: v1beta2.ReceiveAll},
+			},
+		}
+	}
+	tenants := v1beta1.PrefixList{
+		Name: "tenants",
+		Entries: []v1beta1.PrefixListEntry{
+			{Action: v1beta1.PolicyDeny, Prefix: "10.1.0.0/16", GE: 24},
+			{Action: v1beta1.PolicyPermit, Prefix: "10.0.0.0/8", LE: 32},
+		},
+	}
+	parsedTenants := &PrefixList{
+		Name: "tenants",
+		Entries: []PrefixListEntry{
+			{PrefixSelector: PrefixSelector{Prefix: ipnet("10.1.0.0/16"), GE: 24}, Deny: true},
+			{PrefixSelector: PrefixSelector{Prefix: ipnet("10.0.0.0/8"), LE: 32}},
+		},
+	}
+
+	tests := []struct {
+		desc       string
+		crs        ClusterResources
+		wantExtras BGPExtras
+		wantPeer   *NeighborExtras
+		wantErr    bool
 	}{
 		{
-			desc:  "preferred selector scores matching nodes",
-			nodes: edgeNodes,
-			crd: v1beta1.L2Advertisement{
-				ObjectMeta: metav1.ObjectMeta{Name: "prefer-edge"},
-				Spec: v1beta1.L2AdvertisementSpec{
-					PreferredNodeSelectors: []v1beta1.PreferredNodeSelector{
-						{Weight: 100, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"role": "edge"}}},
+			desc: "raw from the configmap and the bgpextras",
+			crs: ClusterResources{
+				BGPExtras: corev1.ConfigMap{Data: map[string]string{bgpExtrasField: "# from the configmap"}},
+				Extras: []v1beta1.BGPExtras{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "extras1"},
+						Spec:       v1beta1.BGPExtrasSpec{Raw: "# from the cr"},
 					},
 				},
 			},
-			wantNodes:     map[string]bool{"edge-a": true, "edge-b": true, "worker-a": true},
-			wantPreferred: map[string]int64{"edge-a": 100, "edge-b": 100},
+			wantExtras: BGPExtras{Raw: "# from the configmap\n# from the cr"},
 		},
 		{
-			desc:  "service selectors do not restrict preferred scoring",
-			nodes: edgeNodes,
-			crd: v1beta1.L2Advertisement{
-				ObjectMeta: metav1.ObjectMeta{Name: "prefer-edge-with-svc-selectors"},
-				Spec: v1beta1.L2AdvertisementSpec{
-					ServiceSelectors: []metav1.LabelSelector{
-						{MatchLabels: map[string]string{"app": "web"}},
+			desc: "neighbor extras with policies",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{peer("peer1", nil)},
+				Communities: []v1beta1.Community{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "communities"},
+						Spec: v1beta1.CommunitySpec{
+							Communities: []v1beta1.CommunityAlias{{Name: "tenant", Value: "large:1:2:3"}},
+						},
 					},
-					PreferredNodeSelectors: []v1beta1.PreferredNodeSelector{
-						{Weight: 100, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"role": "edge"}}},
+				},
+				Extras: []v1beta1.BGPExtras{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "extras1"},
+						Spec: v1beta1.BGPExtrasSpec{
+							BestPath:    &v1beta1.BestPathExtras{ASPathMultipathRelax: true},
+							PrefixLists: []v1beta1.PrefixList{tenants},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "extras2"},
+						Spec: v1beta1.BGPExtrasSpec{
+							Neighbors: []v1beta1.NeighborExtras{
+								{
+									Peer:          "peer1",
+									MaximumPrefix: ptr.To(uint32(100)),
+									AllowASIn:     ptr.To(uint32(2)),
+									ConnectTime:   &metav1.Duration{Duration: 5 * time.Second},
+									Import: []v1beta1.PolicyEntry{
+										{
+											Action:      v1beta1.PolicyPermit,
+											PrefixList:  "tenants",
+											LocalPref:   ptr.To(uint32(200)),
+											Communities: []string{"tenant", "1111:2222"},
+										},
+									},
+									Export: []v1beta1.PolicyEntry{
+										{Action: v1beta1.PolicyDeny, PrefixList: "tenants"},
+									},
+								},
+							},
+						},
 					},
 				},
 			},
-			wantNodes:        map[string]bool{"edge-a": true, "edge-b": true, "worker-a": true},
-			wantPreferred:    map[string]int64{"edge-a": 100, "edge-b": 100},
-			wantSvcSelectors: []string{"app=web"},
+			wantExtras: BGPExtras{
+				BestPath:    &BestPath{ASPathMultipathRelax: true},
+				PrefixLists: []*PrefixList{parsedTenants},
+			},
+			wantPeer: &NeighborExtras{
+				MaximumPrefix: ptr.To(uint32(100)),
+				AllowASIn:     ptr.To(uint32(2)),
+				Import: []PolicyEntry{
+					{
+						PrefixList:  parsedTenants,
+						LocalPref:   ptr.To(uint32(200)),
+						Communities: []community.BGPCommunity{mustCommunity("large:1:2:3"), mustCommunity("1111:2222")},
+					},
+				},
+				Export: []PolicyEntry{
+					{Deny: true, PrefixList: parsedTenants},
+				},
+			},
 		},
 		{
-			desc:  "no preferred selectors leaves PreferredNodes nil",
-			nodes: edgeNodes,
-			crd: v1beta1.L2Advertisement{
-				ObjectMeta: metav1.ObjectMeta{Name: "basic"},
-				Spec:       v1beta1.L2AdvertisementSpec{},
+			desc: "duplicate prefix-list",
+			crs: ClusterResources{
+				Extras: []v1beta1.BGPExtras{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "extras1"},
+						Spec:       v1beta1.BGPExtrasSpec{PrefixLists: []v1beta1.PrefixList{tenants}},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "extras2"},
+						Spec:       v1beta1.BGPExtrasSpec{PrefixLists: []v1beta1.PrefixList{tenants}},
+					},
+				},
 			},
-			wantNodes:     map[string]bool{"edge-a": true, "edge-b": true, "worker-a": true},
-			wantPreferred: nil,
+			wantErr: true,
 		},
 		{
-			desc:  "preferred selectors disjoint from node selectors yield no scores",
-			nodes: edgeNodes,
-			crd: v1beta1.L2Advertisement{
-				ObjectMeta: metav1.ObjectMeta{Name: "disjoint"},
-				Spec: v1beta1.L2AdvertisementSpec{
-					NodeSelectors: []metav1.LabelSelector{
-						{MatchLabels: map[string]string{"role": "worker"}},
+			desc: "bestpath set twice",
+			crs: ClusterResources{
+				Extras: []v1beta1.BGPExtras{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "extras1"},
+						Spec:       v1beta1.BGPExtrasSpec{BestPath: &v1beta1.BestPathExtras{ASPathIgnore: true}},
 					},
-					PreferredNodeSelectors: []v1beta1.PreferredNodeSelector{
-						{Weight: 50, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"role": "edge"}}},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "extras2"},
+						Spec:       v1beta1.BGPExtrasSpec{BestPath: &v1beta1.BestPathExtras{CompareRouterID: true}},
 					},
 				},
 			},
-			wantNodes:     map[string]bool{"worker-a": true},
-			wantPreferred: nil,
+			wantErr: true,
 		},
 		{
-			desc:  "multiple preferred selectors sum on matching nodes",
-			nodes: edgeNodes,
-			crd: v1beta1.L2Advertisement{
-				ObjectMeta: metav1.ObjectMeta{Name: "sum-edge-zone"},
-				Spec: v1beta1.L2AdvertisementSpec{
-					PreferredNodeSelectors: []v1beta1.PreferredNodeSelector{
-						{Weight: 40, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"role": "edge"}}},
-						{Weight: 30, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"zone": "primary"}}},
+			desc: "peer set in two bgpextras",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{peer("peer1", nil)},
+				Extras: []v1beta1.BGPExtras{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "extras1"},
+						Spec:       v1beta1.BGPExtrasSpec{Neighbors: []v1beta1.NeighborExtras{{Peer: "peer1"}}},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "extras2"},
+						Spec:       v1beta1.BGPExtrasSpec{Neighbors: []v1beta1.NeighborExtras{{Peer: "peer1"}}},
 					},
 				},
 			},
-			wantNodes:     map[string]bool{"edge-a": true, "edge-b": true, "worker-a": true},
-			wantPreferred: map[string]int64{"edge-a": 70, "edge-b": 40},
+			wantErr: true,
 		},
-	}
-
-	for _, tc := range tests {
-		t.Run(tc.desc, func(t *testing.T) {
-			got, err := l2AdvertisementFromCR(tc.crd, tc.nodes)
-			if err != nil {
-				t.Fatalf("l2AdvertisementFromCR returned error: %v", err)
-			}
-			if tc.wantNodes != nil {
-				if diff := cmp.Diff(tc.wantNodes, got.Nodes); diff != "" {
-					t.Fatalf("Nodes mismatch (-want +got):\n%s", diff)
-				}
-			}
-			if diff := cmp.Diff(tc.wantPreferred, got.PreferredNodes); diff != "" {
-				t.Fatalf("PreferredNodes mismatch (-want +got):\n%s", diff)
-			}
-			gotSvcSelectors := make([]string, 0, len(got.ServiceSelectors))
-			for _, s := range got.ServiceSelectors {
-				gotSvcSelectors = append(gotSvcSelectors, s.String())
-			}
-			if diff := cmp.Diff(tc.wantSvcSelectors, gotSvcSelectors, cmp.Transformer("nilEmpty", func(in []string) []string {
-				if len(in) == 0 {
-					return nil
-				}
-				return in
-			})); diff != "" {
-				t.Fatalf("ServiceSelectors mismatch (-want +got):\n%s", diff)
-			}
-		})
-	}
-}
-
-func TestSetL2AdvertisementsToPoolsPreferred(t *testing.T) {
-	tests := []struct {
-		desc          string
-		pools         []v1beta1.IPAddressPool
-		advs          []v1beta1.L2Advertisement
-		wantPreferred []map[string]int64
-	}{
 		{
-			desc: "pool selected by name and selector attaches once",
-			pools: []v1beta1.IPAddressPool{
-				{
-					ObjectMeta: metav1.ObjectMeta{Name: "pool-a", Labels: map[string]string{"shared": "true"}},
-					Spec:       v1beta1.IPAddressPoolSpec{Addresses: []string{"10.20.0.0/24"}},
+			desc: "connect time set by both the peer and the bgpextras",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{peer("peer1", &metav1.Duration{Duration: time.Second})},
+				Extras: []v1beta1.BGPExtras{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "extras1"},
+						Spec: v1beta1.BGPExtrasSpec{
+							Neighbors: []v1beta1.NeighborExtras{
+								{Peer: "peer1", ConnectTime: &metav1.Duration{Duration: 5 * time.Second}},
+							},
+						},
+					},
 				},
 			},
-			advs: []v1beta1.L2Advertisement{
-				{
-					ObjectMeta: metav1.ObjectMeta{Name: "prefer-edge"},
-					Spec: v1beta1.L2AdvertisementSpec{
-						IPAddressPools: []string{"pool-a"},
-						IPAddressPoolSelectors: []metav1.LabelSelector{
-							{MatchLabels: map[string]string{"shared": "true"}},
-						},
-						PreferredNodeSelectors: []v1beta1.PreferredNodeSelector{
-							{Weight: 50, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"role": "edge"}}},
+			wantErr: true,
+		},
+		{
+			desc: "maximum prefixes accepted set by both the peer and the bgpextras",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{func() v1beta2.BGPPeer {
+					p := peer("peer1", nil)
+					p.Spec.PrefixLimits = &v1beta2.PrefixLimits{MaxAccepted: ptr.To(uint32(100))}
+					return p
+				}()},
+				Extras: []v1beta1.BGPExtras{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "extras1"},
+						Spec: v1beta1.BGPExtrasSpec{
+							Neighbors: []v1beta1.NeighborExtras{
+								{Peer: "peer1", MaximumPrefix: ptr.To(uint32(100))},
+							},
 						},
 					},
 				},
 			},
-			wantPreferred: []map[string]int64{{"edge-a": 50}},
+			wantErr: true,
 		},
 		{
-			desc: "two CRs targeting the same pool attach independently",
-			pools: []v1beta1.IPAddressPool{
-				{
-					ObjectMeta: metav1.ObjectMeta{Name: "pool-a"},
-					Spec:       v1beta1.IPAddressPoolSpec{Addresses: []string{"10.20.0.0/24"}},
+			desc: "undefined prefix-list",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{peer("peer1", nil)},
+				Extras: []v1beta1.BGPExtras{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "extras1"},
+						Spec: v1beta1.BGPExtrasSpec{
+							Neighbors: []v1beta1.NeighborExtras{
+								{
+									Peer:   "peer1",
+									Export: []v1beta1.PolicyEntry{{Action: v1beta1.PolicyDeny, PrefixList: "missing"}},
+								},
+							},
+						},
+					},
 				},
 			},
-			advs: []v1beta1.L2Advertisement{
-				{
-					ObjectMeta: metav1.ObjectMeta{Name: "ad1-edge-70"},
-					Spec: v1beta1.L2AdvertisementSpec{
-						IPAddressPools: []string{"pool-a"},
-						PreferredNodeSelectors: []v1beta1.PreferredNodeSelector{
-							{Weight: 70, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"role": "edge"}}},
+			wantErr: true,
+		},
+		{
+			desc: "import policy for a peer not receiving any prefix",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "peer1"},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     142,
+							Address: "1.2.3.4",
 						},
 					},
 				},
-				{
-					ObjectMeta: metav1.ObjectMeta{Name: "ad2-edge-30"},
-					Spec: v1beta1.L2AdvertisementSpec{
-						IPAddressPools: []string{"pool-a"},
-						PreferredNodeSelectors: []v1beta1.PreferredNodeSelector{
-							{Weight: 30, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"role": "edge"}}},
+				Extras: []v1beta1.BGPExtras{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "extras1"},
+						Spec: v1beta1.BGPExtrasSpec{
+							Neighbors: []v1beta1.NeighborExtras{
+								{
+									Peer:   "peer1",
+									Import: []v1beta1.PolicyEntry{{Action: v1beta1.PolicyPermit, LocalPref: ptr.To(uint32(200))}},
+								},
+							},
 						},
 					},
 				},
 			},
-			wantPreferred: []map[string]int64{{"edge-a": 30}, {"edge-a": 70}},
+			wantErr: true,
 		},
 		{
-			desc: "one CR with two selectors sums on the same node",
-			pools: []v1beta1.IPAddressPool{
-				{
-					ObjectMeta: metav1.ObjectMeta{Name: "pool-a"},
-					Spec:       v1beta1.IPAddressPoolSpec{Addresses: []string{"10.20.0.0/24"}},
-				},
-			},
-			advs: []v1beta1.L2Advertisement{
-				{
-					ObjectMeta: metav1.ObjectMeta{Name: "sum-edge-zone"},
-					Spec: v1beta1.L2AdvertisementSpec{
-						IPAddressPools: []string{"pool-a"},
-						PreferredNodeSelectors: []v1beta1.PreferredNodeSelector{
-							{Weight: 40, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"role": "edge"}}},
-							{Weight: 30, Preference: metav1.LabelSelector{MatchLabels: map[string]string{"zone": "primary"}}},
+			desc: "deny entry setting attributes",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{peer("peer1", nil)},
+				Extras: []v1beta1.BGPExtras{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "extras1"},
+						Spec: v1beta1.BGPExtrasSpec{
+							Neighbors: []v1beta1.NeighborExtras{
+								{
+									Peer:   "peer1",
+									Import: []v1beta1.PolicyEntry{{Action: v1beta1.PolicyDeny, Metric: ptr.To(uint32(10))}},
+								},
+							},
 						},
 					},
 				},
 			},
-			wantPreferred: []map[string]int64{{"edge-a": 70}},
+			wantErr: true,
 		},
 	}
 
-	nodes := []corev1.Node{
-		{ObjectMeta: metav1.ObjectMeta{Name: "edge-a", Labels: map[string]string{"role": "edge", "zone": "primary"}}},
-	}
-
-	sortPreferred := func(s []map[string]int64) {
-		sort.Slice(s, func(i, j int) bool {
-			var ti, tj int64
-			for _, v := range s[i] {
-				ti += v
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg, err := For(test.crs, DontValidate, ForOptions{})
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
 			}
-			for _, v := range s[j] {
-				tj += v
+			if err != nil {
+				t.Fatalf("unexpected error %s", err)
 			}
-			return ti < tj
-		})
-	}
-
-	for _, tc := range tests {
-		t.Run(tc.desc, func(t *testing.T) {
-			poolMap := map[string]*Pool{"pool-a": {Name: "pool-a"}}
-			if err := setL2AdvertisementsToPools(tc.pools, tc.advs, nodes, poolMap); err != nil {
-				t.Fatalf("setL2AdvertisementsToPools: %v", err)
+			if diff := cmp.Diff(test.wantExtras, cfg.BGPExtras); diff != "" {
+				t.Fatalf("unexpected extras (-want +got):\n%s", diff)
 			}
-			gotAds := poolMap["pool-a"].L2Advertisements
-			gotPreferred := make([]map[string]int64, 0, len(gotAds))
-			for _, a := range gotAds {
-				gotPreferred = append(gotPreferred, a.PreferredNodes)
+			if len(test.crs.Peers) == 0 {
+				return
 			}
-			sortPreferred(gotPreferred)
-			sortPreferred(tc.wantPreferred)
-			if diff := cmp.Diff(tc.wantPreferred, gotPreferred); diff != "" {
-				t.Fatalf("pool-a L2Advertisements mismatch (-want +got):\n%s", diff)
+			communityComparer := cmp.Comparer(func(x, y community.BGPCommunity) bool {
+				return x.String() == y.String()
+			})
+			if diff := cmp.Diff(test.wantPeer, cfg.Peers["peer1"].Extras, communityComparer); diff != "" {
+				t.Fatalf("unexpected peer extras (-want +got):\n%s", diff)
 			}
 		})
 	}
 }
+
+func mustCommunity(c string) community.BGPCommunity {
+	res, err := community.New(c)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
@@ -5,14 +5,18 @@ package config
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"math/big"
+	"reflect"
+	"slices"
 
 	metallbv1beta1 "go.universe.tf/metallb/api/v1beta1"
 	metallbv1beta2 "go.universe.tf/metallb/api/v1beta2"
-	"go.universe.tf/metallb/internal/bgp/community"
 	"go.universe.tf/metallb/internal/ipfamily"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
 )
 
 type Validate func(ClusterResources) error
@@ -22,7 +26,7 @@ func ValidationFor(bgpImpl string) Validate {
 	case "frr":
 		return DiscardNativeOnly
 	case "frr-k8s":
-		return DiscardNativeOnly
+		return DiscardFRRK8sUnsupported
 	case "native":
 		return DiscardFRROnly
 	}
@@ -45,7 +49,7 @@ func DiscardFRROnly(c ClusterResources) error {
 		if p.Spec.ConnectTime != nil {
 			return fmt.Errorf("peer %s has connect time set on native bgp mode", p.Spec.Address)
 		}
-		if p.Spec.EnableGracefulRestart {
+		if ptr.Deref(p.Spec.EnableGracefulRestart, false) {
 			return fmt.Errorf("peer %s has EnableGracefulRestart flag set on native bgp mode", p.Spec.Address)
 		}
 		if p.Spec.DisableMP {
@@ -57,22 +61,38 @@ func DiscardFRROnly(c ClusterResources) error {
 		if p.Spec.DynamicASN != "" {
 			return fmt.Errorf("peer %s has dynamicASN set on native bgp mode", p.Spec.Address)
 		}
-		if p.Spec.Interface != "" {
-			return fmt.Errorf("peer %s has interface set on native bgp mode", p.Spec.Address)
-		}
 		if p.Spec.LocalASN != 0 {
 			return fmt.Errorf("peer %s has localASN set on native bgp mode", p.Spec.Address)
 		}
+		if p.Spec.ToReceive != nil {
+			return fmt.Errorf("peer %s has toReceive set on native bgp mode", p.Spec.Address)
+		}
+		if l := p.Spec.PrefixLimits; l != nil && l.MaxAccepted != nil {
+			return fmt.Errorf("peer %s has prefixLimits maxAccepted set on native bgp mode", p.Spec.Address)
+		}
 	}
 	if len(c.BFDProfiles) > 0 {
 		return errors.New("bfd profiles section set")
 	}
-	// Only IPv4 BGP advertisements are supported in native mode.
-	if err := findIPv6BGPAdvertisement(c); err != nil {
-		return err
+	if len(c.Extras) > 0 {
+		return fmt.Errorf("bgpextras %s set on native bgp mode", c.Extras[0].Name)
+	}
+	for _, adv := range c.BGPAdvs {
+		if adv.Spec.Condition != nil {
+			return fmt.Errorf("bgpadvertisement %s has condition set on native bgp mode", adv.Name)
+		}
+		if len(adv.Spec.ImportVRFs) > 0 {
+			return fmt.Errorf("bgpadvertisement %s has importVRFs set on native bgp mode", adv.Name)
+		}
+		if adv.Spec.EVPN != nil {
+			return fmt.Errorf("bgpadvertisement %s has evpn set on native bgp mode", adv.Name)
+		}
+		if adv.Spec.LabeledUnicast != nil {
+			return fmt.Errorf("bgpadvertisement %s has labeledUnicast set on native bgp mode", adv.Name)
+		}
 	}
-	// Only legacy type communities are supported in native mode.
-	return findNonLegacyCommunity(c)
+	// Only IPv4 BGP advertisements are supported in native mode.
+	return findIPv6BGPAdvertisement(c)
 }
 
 // findIPv6BGPAdvertisement checks for IPv6 addresses. If it finds at least one IPv6 BGP advertisement, it will throw
@@ -107,39 +127,6 @@ func findIPv6BGPAdvertisement(c ClusterResources) error {
 	return nil
 }
 
-// findNonLegacyCommunity returns an error if it can find a non legacy community. If a community string can not be
-// parsed, the string will be ignored.
-func findNonLegacyCommunity(c ClusterResources) error {
-	for _, adv := range c.BGPAdvs {
-		for _, cs := range adv.Spec.Communities {
-			c, err := community.New(cs)
-			if err != nil {
-				// Skip aliases.
-				continue
-			}
-			if !community.IsLegacy(c) {
-				return fmt.Errorf("native BGP mode only supports legacy communities, BGP advertisement %q "+
-					"has non legacy community %q", adv.Name, cs)
-			}
-		}
-	}
-	for _, co := range c.Communities {
-		for _, cs := range co.Spec.Communities {
-			c, err := community.New(cs.Value)
-			if err != nil {
-				// Skip it if we cannot parse it - the purpose of this very verification is not to make sure that
-				// a string can be parsed or not.
-				continue
-			}
-			if !community.IsLegacy(c) {
-				return fmt.Errorf("native BGP mode only supports legacy communities, BGP community CR %q "+
-					"has non legacy community %q", co.Name, cs)
-			}
-		}
-	}
-	return nil
-}
-
 // DontValidate is a Validate function that always returns
 // success.
 func DontValidate(c ClusterResources) error {
@@ -149,12 +136,46 @@ func DontValidate(c ClusterResources) error {
 // DiscardNativeOnly returns an error if the current configFile contains
 // any options that are available only in the native implementation.
 func DiscardNativeOnly(c ClusterResources) error {
+	for _, p := range c.Peers {
+		if p.Spec.ListenRange != "" {
+			return fmt.Errorf("peer %s has listenRange set on frr bgp mode", PeerIdentifier(p.Spec))
+		}
+		if p.Spec.Passive {
+			return fmt.Errorf("peer %s has passive flag set on frr bgp mode", PeerIdentifier(p.Spec))
+		}
+		if p.Spec.TCPAO != nil {
+			return fmt.Errorf("peer %s has tcpAO set on frr bgp mode", PeerIdentifier(p.Spec))
+		}
+	}
 	if err := checkFRRPeersCompatible(c); err != nil {
 		return err
 	}
 	return nil
 }
 
+// DiscardFRRK8sUnsupported returns an error if the current configFile contains
+// any options that can't be translated to the FRR-K8s API, on top of the
+// ones available only in the native implementation.
+func DiscardFRRK8sUnsupported(c ClusterResources) error {
+	if err := DiscardNativeOnly(c); err != nil {
+		return err
+	}
+	for _, p := range c.Peers {
+		if l := p.Spec.PrefixLimits; l != nil && l.MaxAccepted != nil {
+			return fmt.Errorf("peer %s has prefixLimits maxAccepted set on frr-k8s bgp mode", p.Name)
+		}
+	}
+	for _, adv := range c.BGPAdvs {
+		if len(adv.Spec.ImportVRFs) > 0 {
+			return fmt.Errorf("bgpadvertisement %s has importVRFs set on frr-k8s bgp mode", adv.Name)
+		}
+		if adv.Spec.LabeledUnicast != nil {
+			return fmt.Errorf("bgpadvertisement %s has labeledUnicast set on frr-k8s bgp mode", adv.Name)
+		}
+	}
+	return nil
+}
+
 // checkFRRPeersCompatible validates that all BGPPeer combinations are valid for
 // FRR mode. Peers with disjoint nodeSelectors are exempt from constraints that
 // only apply when two peers share the same FRR instance (i.e. the same node).
@@ -194,8 +215,18 @@ func peersCanRunOnSameNode(p, q metallbv1beta2.BGPPeer) error {
 }
 
 // validateConfig is meant to validate all the inter-dependencies of a parsed configuration.
-// In this case, we ensure that bfd echo is not enabled on a v6 pool.
+// In this case, we ensure that bfd echo is not enabled on a v6 pool and that the
+// conditional advertisements can be applied.
 func validateConfig(cfg *Config) error {
+	if err := validateConditions(cfg); err != nil {
+		return err
+	}
+	if err := validateEVPNs(cfg); err != nil {
+		return err
+	}
+	if err := validateLabeledUnicast(cfg); err != nil {
+		return err
+	}
 	for _, p := range cfg.Pools.ByName {
 		containsV6 := false
 		for _, cidr := range p.CIDR {
@@ -228,6 +259,137 @@ func validateConfig(cfg *Config) error {
 	return nil
 }
 
+// validateConditions ensures that the peers tracked by the conditional advertisements
+// exist, are not dynamic and send the tracked routes, and that a peer does not get the announcements of
+// advertisements with different conditions, as only one can be applied to a peer.
+func validateConditions(cfg *Config) error {
+	advs := map[string]*BGPAdvertisement{}
+	for _, p := range cfg.Pools.ByName {
+		for _, a := range p.BGPAdvertisements {
+			if a.Condition != nil {
+				advs[a.Name] = a
+			}
+		}
+	}
+	names := slices.Sorted(maps.Keys(advs))
+	for i, name := range names {
+		a := advs[name]
+		for _, peerName := range a.Condition.Peers {
+			peer, ok := cfg.Peers[peerName]
+			if !ok {
+				return fmt.Errorf("bgpadvertisement %s has a condition on peer %s which does not exist", a.Name, peerName)
+			}
+			if peer.ToReceive == nil {
+				return fmt.Errorf("bgpadvertisement %s has a condition on peer %s which does not receive any route, toReceive must be set", a.Name, peerName)
+			}
+			// The dynamic peers have no address the routes can be
+			// matched against.
+			if peer.ListenRange != nil {
+				return fmt.Errorf("bgpadvertisement %s has a condition on peer %s which is a dynamic peer, only the peers with an address or an interface are supported", a.Name, peerName)
+			}
+			for _, target := range a.Peers {
+				if t, ok := cfg.Peers[target]; ok && t.VRF != peer.VRF {
+					return fmt.Errorf("bgpadvertisement %s has a condition on peer %s which is in a different VRF than peer %s", a.Name, peerName, target)
+				}
+			}
+		}
+		for _, other := range names[i+1:] {
+			o := advs[other]
+			if reflect.DeepEqual(a.Condition, o.Condition) {
+				continue
+			}
+			if len(a.Peers) == 0 || len(o.Peers) == 0 || slices.ContainsFunc(a.Peers, func(p string) bool { return slices.Contains(o.Peers, p) }) {
+				return fmt.Errorf("bgpadvertisements %s and %s have different conditions and may be announced to the same peer, only one condition per peer is supported", a.Name, o.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// validateEVPNs ensures that the advertisements announcing in the same VRF
+// configure its L3VNI the same way, that an L3VNI is bound to one VRF only,
+// and that the EVPN routes are announced to peers in the default VRF, as the
+// l2vpn evpn address family is configured there.
+func validateEVPNs(cfg *Config) error {
+	advs := map[string]*BGPAdvertisement{}
+	for _, p := range cfg.Pools.ByName {
+		for _, a := range p.BGPAdvertisements {
+			if a.EVPN != nil {
+				advs[a.Name] = a
+			}
+		}
+	}
+	names := slices.Sorted(maps.Keys(advs))
+	for i, name := range names {
+		a := advs[name]
+		for _, peerName := range a.Peers {
+			if peer, ok := cfg.Peers[peerName]; ok && peer.VRF != "" {
+				return fmt.Errorf("bgpadvertisement %s has evpn set and selects peer %s which is in vrf %s, only the peers in the default VRF are supported", a.Name, peerName, peer.VRF)
+			}
+		}
+		for _, other := range names[i+1:] {
+			o := advs[other]
+			if a.EVPN.VRF == o.EVPN.VRF && !reflect.DeepEqual(a.EVPN, o.EVPN) {
+				return fmt.Errorf("bgpadvertisements %s and %s configure the evpn of vrf %s differently", a.Name, o.Name, a.EVPN.VRF)
+			}
+			if a.EVPN.VNI == o.EVPN.VNI && a.EVPN.VRF != o.EVPN.VRF {
+				return fmt.Errorf("bgpadvertisements %s and %s bind the vni %d to different vrfs %s and %s", a.Name, o.Name, a.EVPN.VNI, a.EVPN.VRF, o.EVPN.VRF)
+			}
+		}
+	}
+	return nil
+}
+
+// validateLabeledUnicast ensures that the labeled-unicast announcements are
+// sent to peers in the default VRF, and that each IP gets a single label
+// index, unique across the pools.
+func validateLabeledUnicast(cfg *Config) error {
+	type indexRange struct {
+		pool      string
+		first     uint32
+		last      uint32
+		definedBy string
+	}
+	ranges := map[string]indexRange{}
+	for _, poolName := range slices.Sorted(maps.Keys(cfg.Pools.ByName)) {
+		for _, a := range cfg.Pools.ByName[poolName].BGPAdvertisements {
+			if a.LabeledUnicast == nil {
+				continue
+			}
+			for _, peerName := range a.Peers {
+				if peer, ok := cfg.Peers[peerName]; ok && peer.VRF != "" {
+					return fmt.Errorf("bgpadvertisement %s has labeledUnicast set and selects peer %s which is in vrf %s, only the peers in the default VRF are supported", a.Name, peerName, peer.VRF)
+				}
+			}
+			for _, name := range slices.Sorted(maps.Keys(a.LabeledUnicast.LabelIndexBases)) {
+				base := a.LabeledUnicast.LabelIndexBases[name]
+				pool, ok := cfg.Pools.ByName[name]
+				if !ok || !slices.ContainsFunc(pool.BGPAdvertisements, func(o *BGPAdvertisement) bool { return o.Name == a.Name }) {
+					return fmt.Errorf("bgpadvertisement %s has a label index for pool %s which it does not select", a.Name, name)
+				}
+				size := poolSize(pool)
+				if size.Cmp(big.NewInt(int64(maxLabelIndex-base)+1)) > 0 {
+					return fmt.Errorf("bgpadvertisement %s has label index base %d for pool %s, the %s IPs of the pool exceed the highest label index %d", a.Name, base, name, size, maxLabelIndex)
+				}
+				r := indexRange{pool: name, first: base, last: base + uint32(size.Uint64()) - 1, definedBy: a.Name}
+				if o, ok := ranges[name]; ok {
+					if o.first != r.first {
+						return fmt.Errorf("bgpadvertisements %s and %s set different label index bases for pool %s", o.definedBy, a.Name, name)
+					}
+					continue
+				}
+				for _, o := range ranges {
+					if r.first <= o.last && o.first <= r.last {
+						return fmt.Errorf("the label indexes of pool %s set in %s overlap with the ones of pool %s set in %s", name, a.Name, o.pool, o.definedBy)
+					}
+				}
+				ranges[name] = r
+			}
+		}
+	}
+	return nil
+}
+
 func hasBFDEcho(peer *Peer, bfdProfiles map[string]*BFDProfile) bool {
 	profile, ok := bfdProfiles[peer.BFDProfile]
 	if !ok {
@@ -240,19 +402,39 @@ func hasBFDEcho(peer *Peer, bfdProfiles map[string]*BFDProfile) bool {
 }
 
 // PeerIdentifier returns a stable string key for a peer. For peers in the
-// default VRF (VRFName == ""), the key is just the address or interface name.
+// default VRF (VRFName == ""), the key is just the address, interface name,
+// listen range or the source of the address.
 // For peers in a named VRF the key is "address-vrf" or "interface-vrf".
 func PeerIdentifier(peer metallbv1beta2.BGPPeerSpec) string {
 	id := peer.Address
 	if peer.Address == "" {
 		id = peer.Interface
 	}
+	if id == "" {
+		id = peer.ListenRange
+	}
+	if id == "" && peer.AddressFrom != nil {
+		id = addressSourceIdentifier(peer.AddressFrom)
+	}
 	if peer.VRFName == "" {
 		return id
 	}
 	return fmt.Sprintf("%s-%s", id, peer.VRFName)
 }
 
+// addressSourceIdentifier returns a key for the source of a peer address,
+// such as "nodeAnnotation=<key>". Two peers reading their address from the
+// same source dial the same address on any node.
+func addressSourceIdentifier(from *metallbv1beta2.PeerAddressSource) string {
+	switch {
+	case from.NodeAnnotation != "":
+		return "nodeAnnotation=" + from.NodeAnnotation
+	case from.NodeLabel != "":
+		return "nodeLabel=" + from.NodeLabel
+	}
+	return "defaultGateway=" + from.DefaultGateway
+}
+
 // peersShareNode returns true if any cluster node matches both p1 and p2's
 // nodeSelectors, meaning the two peers would be configured on the same FRR
 // instance. Conservatively returns true when no nodes are known.
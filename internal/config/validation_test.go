@@ -175,7 +175,7 @@ func TestValidate(t *testing.T) {
 				Peers: []v1beta2.BGPPeer{
 					{
 						Spec: v1beta2.BGPPeerSpec{
-							EnableGracefulRestart: true,
+							EnableGracefulRestart: ptr.To(true),
 						},
 					},
 				},
@@ -219,7 +219,6 @@ func TestValidate(t *testing.T) {
 					},
 				},
 			},
-			mustFail: true,
 		},
 		{
 			desc: "localASN",
@@ -271,7 +270,20 @@ func TestValidate(t *testing.T) {
 					},
 				},
 			},
-			mustFail: true,
+			mustFail: false,
+		},
+		{
+			desc: "extended BGP communities inside BGP Advertisement",
+			config: ClusterResources{
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							Communities: []string{"target:64512:100", "bandwidth:1000"},
+						},
+					},
+				},
+			},
+			mustFail: false,
 		},
 		{
 			desc: "large BGP community inside Community CR",
@@ -288,7 +300,7 @@ func TestValidate(t *testing.T) {
 					},
 				},
 			},
-			mustFail: true,
+			mustFail: false,
 		},
 		{
 			desc: "should pass",
@@ -302,6 +314,117 @@ func TestValidate(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc: "peer with toReceive",
+			config: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							Address: "1.2.3.4",
+							ToReceive: &v1beta2.Receive{
+								Mode: v1beta2.ReceiveAll,
+							},
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
+		{
+			desc: "peer with prefix limits",
+			config: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							Address:      "1.2.3.4",
+							PrefixLimits: &v1beta2.PrefixLimits{MaxAdvertised: ptr.To(uint32(10))},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "peer with maximum prefixes accepted",
+			config: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							Address:      "1.2.3.4",
+							PrefixLimits: &v1beta2.PrefixLimits{MaxAccepted: ptr.To(uint32(10))},
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
+		{
+			desc: "bgpadvertisement with condition",
+			config: ClusterResources{
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							Condition: &v1beta1.AdvertisementCondition{
+								Mode:     v1beta1.AdvertisementConditionExist,
+								Prefixes: []string{"0.0.0.0/0"},
+							},
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
+		{
+			desc: "bgpadvertisement with import vrfs",
+			config: ClusterResources{
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							ImportVRFs: []string{"red"},
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
+		{
+			desc: "bgpadvertisement with evpn",
+			config: ClusterResources{
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							EVPN: &v1beta1.EVPN{VRF: "red", VNI: 100},
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
+		{
+			desc: "bgpadvertisement with labeled unicast",
+			config: ClusterResources{
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							LabeledUnicast: &v1beta1.LabeledUnicast{},
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
+		{
+			desc: "bgpextras",
+			config: ClusterResources{
+				Extras: []v1beta1.BGPExtras{
+					{
+						Spec: v1beta1.BGPExtrasSpec{
+							BestPath: &v1beta1.BestPathExtras{ASPathMultipathRelax: true},
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
 	}
 
 	for _, test := range tests {
@@ -575,6 +698,54 @@ func TestValidateFRR(t *testing.T) {
 			},
 			mustFail: true,
 		},
+		{
+			desc: "peer with listen range",
+			config: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							ListenRange: "10.0.0.0/24",
+							MyASN:       123,
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
+		{
+			desc: "passive peer",
+			config: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							Address: "1.2.3.4",
+							Passive: true,
+							MyASN:   123,
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
+		{
+			desc: "peer with tcpAO",
+			config: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							Address: "1.2.3.4",
+							MyASN:   123,
+							TCPAO: &v1beta2.TCPAO{
+								Keys: []v1beta2.TCPAOKey{
+									{SendID: 1, RecvID: 1, KeySecret: corev1.SecretReference{Name: "key"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
 		{
 			desc: "two peers with interface set different",
 			config: ClusterResources{
@@ -712,6 +883,164 @@ func TestValidateFRR(t *testing.T) {
 	}
 }
 
+func TestValidateFRRK8s(t *testing.T) {
+	tests := []struct {
+		desc     string
+		config   ClusterResources
+		mustFail bool
+	}{
+		{
+			desc: "advertisement with local pref",
+			config: ClusterResources{
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							LocalPref: 100,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "peer with prefix limits",
+			config: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							Address:      "1.2.3.4",
+							PrefixLimits: &v1beta2.PrefixLimits{MaxAdvertised: ptr.To(uint32(10))},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "peer with maximum prefixes accepted",
+			config: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							Address:      "1.2.3.4",
+							PrefixLimits: &v1beta2.PrefixLimits{MaxAccepted: ptr.To(uint32(10))},
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
+		{
+			desc: "advertisement with AS path prepend",
+			config: ClusterResources{
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							ASPathPrepend: &v1beta1.ASPathPrepend{Count: 2},
+						},
+					},
+				},
+			},
+			mustFail: false,
+		},
+		{
+			desc: "advertisement with MED",
+			config: ClusterResources{
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							MED: &v1beta1.MED{Value: 10},
+						},
+					},
+				},
+			},
+			mustFail: false,
+		},
+		{
+			desc: "peer with passive set",
+			config: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							Address: "1.2.3.4",
+							Passive: true,
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
+		{
+			desc: "advertisement with import vrfs",
+			config: ClusterResources{
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							ImportVRFs: []string{"red"},
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
+		{
+			desc: "advertisement with labeled unicast",
+			config: ClusterResources{
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							LabeledUnicast: &v1beta1.LabeledUnicast{},
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
+		{
+			desc: "bgpextras with maximum prefix",
+			config: ClusterResources{
+				Extras: []v1beta1.BGPExtras{
+					{
+						Spec: v1beta1.BGPExtrasSpec{
+							Neighbors: []v1beta1.NeighborExtras{
+								{Peer: "peer1", MaximumPrefix: ptr.To(uint32(100))},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "bgpextras with import and export policies",
+			config: ClusterResources{
+				Extras: []v1beta1.BGPExtras{
+					{
+						Spec: v1beta1.BGPExtrasSpec{
+							Neighbors: []v1beta1.NeighborExtras{
+								{
+									Peer:   "peer1",
+									Import: []v1beta1.PolicyEntry{{Action: v1beta1.PolicyDeny}},
+									Export: []v1beta1.PolicyEntry{{Action: v1beta1.PolicyDeny}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			err := DiscardFRRK8sUnsupported(test.config)
+			if test.mustFail && err == nil {
+				t.Fatalf("Expected error for %s", test.desc)
+			}
+			if !test.mustFail && err != nil {
+				t.Fatalf("Not expected error %s for %s", err, test.desc)
+			}
+		})
+	}
+}
+
 // peerOnNodes returns a BGPPeer for address "1.2.3.4" selecting nodes by hostname (OR semantics).
 func peerOnNodes(hostnames ...string) v1beta2.BGPPeer {
 	sels := make([]v1.LabelSelector, len(hostnames))
@@ -30,12 +30,14 @@ type validator struct {
 
 func (v *validator) Validate(resources ...client.ObjectList) error {
 	clusterResources := ClusterResources{
-		Pools:       make([]metallbv1beta1.IPAddressPool, 0),
-		Peers:       make([]metallbv1beta2.BGPPeer, 0),
-		BFDProfiles: make([]metallbv1beta1.BFDProfile, 0),
-		BGPAdvs:     make([]metallbv1beta1.BGPAdvertisement, 0),
-		L2Advs:      make([]metallbv1beta1.L2Advertisement, 0),
-		Communities: make([]metallbv1beta1.Community, 0),
+		Pools:         make([]metallbv1beta1.IPAddressPool, 0),
+		Peers:         make([]metallbv1beta2.BGPPeer, 0),
+		PeerTemplates: make([]metallbv1beta2.BGPPeerTemplate, 0),
+		BFDProfiles:   make([]metallbv1beta1.BFDProfile, 0),
+		BGPAdvs:       make([]metallbv1beta1.BGPAdvertisement, 0),
+		L2Advs:        make([]metallbv1beta1.L2Advertisement, 0),
+		Communities:   make([]metallbv1beta1.Community, 0),
+		Extras:        make([]metallbv1beta1.BGPExtras, 0),
 	}
 	for _, list := range resources {
 		switch list := list.(type) {
@@ -43,6 +45,8 @@ func (v *validator) Validate(resources ...client.ObjectList) error {
 			clusterResources.Pools = append(clusterResources.Pools, list.Items...)
 		case *metallbv1beta2.BGPPeerList:
 			clusterResources.Peers = append(clusterResources.Peers, list.Items...)
+		case *metallbv1beta2.BGPPeerTemplateList:
+			clusterResources.PeerTemplates = append(clusterResources.PeerTemplates, list.Items...)
 		case *metallbv1beta1.BFDProfileList:
 			clusterResources.BFDProfiles = append(clusterResources.BFDProfiles, list.Items...)
 		case *metallbv1beta1.BGPAdvertisementList:
@@ -51,6 +55,8 @@ func (v *validator) Validate(resources ...client.ObjectList) error {
 			clusterResources.L2Advs = append(clusterResources.L2Advs, list.Items...)
 		case *metallbv1beta1.CommunityList:
 			clusterResources.Communities = append(clusterResources.Communities, list.Items...)
+		case *metallbv1beta1.BGPExtrasList:
+			clusterResources.Extras = append(clusterResources.Extras, list.Items...)
 		case *v1.NodeList:
 			clusterResources.Nodes = append(clusterResources.Nodes, list.Items...)
 		}
@@ -71,6 +77,10 @@ func resetTransientErrorsFields(clusterResources ClusterResources) ClusterResour
 		clusterResources.Peers[i].Spec.BFDProfile = ""
 		clusterResources.Peers[i].Spec.PasswordSecret = v1.SecretReference{}
 	}
+	for i := range clusterResources.PeerTemplates {
+		clusterResources.PeerTemplates[i].Spec.BFDProfile = ""
+		clusterResources.PeerTemplates[i].Spec.PasswordSecret = v1.SecretReference{}
+	}
 	for i, bgpAdv := range clusterResources.BGPAdvs {
 		var communities []string
 		for _, community := range bgpAdv.Spec.Communities {
@@ -81,5 +91,32 @@ func resetTransientErrorsFields(clusterResources ClusterResources) ClusterResour
 		}
 		clusterResources.BGPAdvs[i].Spec.Communities = communities
 	}
+	for i, e := range clusterResources.Extras {
+		neighbors := make([]metallbv1beta1.NeighborExtras, 0, len(e.Spec.Neighbors))
+		for _, n := range e.Spec.Neighbors {
+			n.Import = policyWithoutReferences(n.Import)
+			n.Export = policyWithoutReferences(n.Export)
+			neighbors = append(neighbors, n)
+		}
+		clusterResources.Extras[i].Spec.Neighbors = neighbors
+	}
 	return clusterResources
 }
+
+// policyWithoutReferences returns a copy of the policy without the references
+// to the prefix-lists and to the community aliases, that may be defined later.
+func policyWithoutReferences(policy []metallbv1beta1.PolicyEntry) []metallbv1beta1.PolicyEntry {
+	res := make([]metallbv1beta1.PolicyEntry, 0, len(policy))
+	for _, e := range policy {
+		e.PrefixList = ""
+		var communities []string
+		for _, c := range e.Communities {
+			if strings.Contains(c, ":") {
+				communities = append(communities, c)
+			}
+		}
+		e.Communities = communities
+		res = append(res, e)
+	}
+	return res
+}
@@ -30,6 +30,38 @@ func TestValidator(t *testing.T) {
 	if err != nil {
 		t.Error("The validator should not fail for non existing bfd profile")
 	}
+
+	withTemplate := metallbv1beta2.BGPPeerList{
+		Items: []metallbv1beta2.BGPPeer{
+			{
+				Spec: metallbv1beta2.BGPPeerSpec{
+					Address:      "1.2.3.4",
+					PeerTemplate: "tor",
+				},
+			},
+		},
+	}
+	err = v.Validate(&withTemplate)
+	if err == nil {
+		t.Error("The validator should fail for non existing peer template")
+	}
+
+	templateList := metallbv1beta2.BGPPeerTemplateList{
+		Items: []metallbv1beta2.BGPPeerTemplate{
+			{
+				Spec: metallbv1beta2.BGPPeerTemplateSpec{
+					MyASN:      42,
+					ASN:        42,
+					BFDProfile: "default",
+				},
+			},
+		},
+	}
+	templateList.Items[0].Name = "tor"
+	err = v.Validate(&withTemplate, &templateList)
+	if err != nil {
+		t.Errorf("The validator should not fail for a peer merged with its template: %s", err)
+	}
 }
 
 func TestResetTransientErrorsFields(t *testing.T) {
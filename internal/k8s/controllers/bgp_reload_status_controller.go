@@ -0,0 +1,151 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"go.universe.tf/metallb/api/v1beta1"
+	"go.universe.tf/metallb/internal/bgp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+type BGPReloadStatusFetcher func() bgp.ReloadStatus
+
+type bgpReloadStatusEvent struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+}
+
+func (evt *bgpReloadStatusEvent) DeepCopyObject() runtime.Object {
+	res := new(bgpReloadStatusEvent)
+	res.Name = evt.Name
+	res.Namespace = evt.Namespace
+	return res
+}
+
+func NewBGPReloadStatusEvent(namespace, name string) event.GenericEvent {
+	evt := bgpReloadStatusEvent{}
+	evt.Name = name
+	evt.Namespace = namespace
+	return event.GenericEvent{Object: &evt}
+}
+
+// BGPReloadStatusReconciler reports the outcome of the last reload of the
+// BGP configuration as a condition of the ConfigurationState of the node.
+type BGPReloadStatusReconciler struct {
+	client.Client
+	Logger          log.Logger
+	Namespace       string
+	ConfigStateName string
+	ReconcileChan   <-chan event.GenericEvent
+	StatusFetcher   BGPReloadStatusFetcher
+}
+
+func (r *BGPReloadStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	level.Info(r.Logger).Log("controller", "BGPReloadStatus", "start reconcile", req.String())
+	defer level.Info(r.Logger).Log("controller", "BGPReloadStatus", "end reconcile", req.String())
+
+	status := r.StatusFetcher()
+	if status.Time.IsZero() {
+		// No reload completed yet.
+		return ctrl.Result{}, nil
+	}
+
+	var current v1beta1.ConfigurationState
+	err := r.Get(ctx, types.NamespacedName{Namespace: r.Namespace, Name: r.ConfigStateName}, &current)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, fmt.Errorf("get %s/%s: %w", r.Namespace, r.ConfigStateName, err)
+	}
+	// The transition time is kept as long as the reloads keep succeeding,
+	// or failing.
+	var conditions []metav1.Condition
+	if c := meta.FindStatusCondition(current.Status.Conditions, ConditionTypeBGPReloadSucceeded); c != nil {
+		conditions = append(conditions, *c)
+	}
+	meta.SetStatusCondition(&conditions, reloadCondition(status))
+
+	configStatus := &v1beta1.ConfigurationState{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "metallb.io/v1beta1",
+			Kind:       "ConfigurationState",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.ConfigStateName,
+			Namespace: r.Namespace,
+		},
+		Status: v1beta1.ConfigurationStateStatus{
+			Conditions: conditions,
+		},
+	}
+
+	if err := r.Status().Patch(ctx, configStatus, client.Apply, client.FieldOwner("bgpReloadStatusReconciler"), client.ForceOwnership); err != nil {
+		return ctrl.Result{}, fmt.Errorf("patch %s/%s: %w", r.Namespace, r.ConfigStateName, err)
+	}
+	return ctrl.Result{}, nil
+}
+
+func reloadCondition(status bgp.ReloadStatus) metav1.Condition {
+	res := metav1.Condition{
+		Type:               ConditionTypeBGPReloadSucceeded,
+		Status:             metav1.ConditionTrue,
+		Reason:             ErrorTypeNone,
+		LastTransitionTime: metav1.NewTime(status.Time),
+	}
+	if status.ConfigHash != "" {
+		res.Message = "last successful configuration hash " + status.ConfigHash
+	}
+	if !status.Failed {
+		return res
+	}
+
+	res.Status = metav1.ConditionFalse
+	res.Reason = ErrorTypeReload
+	head := fmt.Sprintf("%d consecutive reload failures: ", status.ConsecutiveFailures)
+	tail := ""
+	if status.ConfigHash != "" {
+		tail = "\nlast successful configuration hash " + status.ConfigHash
+	}
+	// The error holds the output of the reload, cut so that the message
+	// fits in the condition.
+	res.Message = head + truncate(status.Error, maxConditionMessageLength-len(head)-len(tail)) + tail
+	return res
+}
+
+// maxConditionMessageLength keeps the message of the condition under the
+// 32768 bytes accepted by the API server.
+const maxConditionMessageLength = 32767
+
+const truncatedSuffix = " (truncated)"
+
+// truncate cuts s to at most maxLen bytes, without splitting a rune.
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return strings.ToValidUTF8(s[:maxLen-len(truncatedSuffix)], "") + truncatedSuffix
+}
+
+func (r *BGPReloadStatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("bgpreloadstatus").
+		// The condition is reported again when the ConfigurationState is recreated.
+		Watches(&v1beta1.ConfigurationState{}, &handler.EnqueueRequestForObject{},
+			builder.WithPredicates(NewConfigStateConditionReporterPredicate(r.Namespace, r.ConfigStateName))).
+		WatchesRawSource(source.Channel(r.ReconcileChan, &handler.EnqueueRequestForObject{})).
+		Complete(r)
+}
@@ -0,0 +1,187 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/go-cmp/cmp"
+	v1beta1 "go.universe.tf/metallb/api/v1beta1"
+	"go.universe.tf/metallb/internal/bgp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestBGPReloadStatusController(t *testing.T) {
+	reloadTime := time.Unix(1700000000, 0)
+	longError := strings.Repeat("é", maxConditionMessageLength)
+	tests := []struct {
+		desc           string
+		existing       []metav1.Condition
+		status         bgp.ReloadStatus
+		wantConditions []metav1.Condition
+	}{
+		{
+			desc:           "no reload yet",
+			status:         bgp.ReloadStatus{},
+			wantConditions: nil,
+		},
+		{
+			desc: "successful reload",
+			status: bgp.ReloadStatus{
+				Time:       reloadTime,
+				ConfigHash: "abc",
+			},
+			wantConditions: []metav1.Condition{
+				{
+					Type:               ConditionTypeBGPReloadSucceeded,
+					Status:             metav1.ConditionTrue,
+					Reason:             ErrorTypeNone,
+					Message:            "last successful configuration hash abc",
+					LastTransitionTime: metav1.NewTime(reloadTime),
+				},
+			},
+		},
+		{
+			desc: "failed reload",
+			status: bgp.ReloadStatus{
+				Time:                reloadTime,
+				Failed:              true,
+				Error:               "line 3: % Unknown command",
+				ConfigHash:          "abc",
+				ConsecutiveFailures: 2,
+			},
+			wantConditions: []metav1.Condition{
+				{
+					Type:               ConditionTypeBGPReloadSucceeded,
+					Status:             metav1.ConditionFalse,
+					Reason:             ErrorTypeReload,
+					Message:            "2 consecutive reload failures: line 3: % Unknown command\nlast successful configuration hash abc",
+					LastTransitionTime: metav1.NewTime(reloadTime),
+				},
+			},
+		}, {
+			desc: "failed again, the transition time is kept",
+			existing: []metav1.Condition{
+				{
+					Type:               ConditionTypeBGPReloadSucceeded,
+					Status:             metav1.ConditionFalse,
+					Reason:             ErrorTypeReload,
+					Message:            "1 consecutive reload failures: line 3: % Unknown command\nlast successful configuration hash abc",
+					LastTransitionTime: metav1.NewTime(reloadTime.Add(-time.Minute)),
+				},
+			},
+			status: bgp.ReloadStatus{
+				Time:                reloadTime,
+				Failed:              true,
+				Error:               "line 3: % Unknown command",
+				ConfigHash:          "abc",
+				ConsecutiveFailures: 2,
+			},
+			wantConditions: []metav1.Condition{
+				{
+					Type:               ConditionTypeBGPReloadSucceeded,
+					Status:             metav1.ConditionFalse,
+					Reason:             ErrorTypeReload,
+					Message:            "2 consecutive reload failures: line 3: % Unknown command\nlast successful configuration hash abc",
+					LastTransitionTime: metav1.NewTime(reloadTime.Add(-time.Minute)),
+				},
+			},
+		},
+		{
+			desc: "succeeded after failing, the transition time is updated",
+			existing: []metav1.Condition{
+				{
+					Type:               ConditionTypeBGPReloadSucceeded,
+					Status:             metav1.ConditionFalse,
+					Reason:             ErrorTypeReload,
+					Message:            "1 consecutive reload failures: line 3: % Unknown command",
+					LastTransitionTime: metav1.NewTime(reloadTime.Add(-time.Minute)),
+				},
+			},
+			status: bgp.ReloadStatus{
+				Time:       reloadTime,
+				ConfigHash: "abc",
+			},
+			wantConditions: []metav1.Condition{
+				{
+					Type:               ConditionTypeBGPReloadSucceeded,
+					Status:             metav1.ConditionTrue,
+					Reason:             ErrorTypeNone,
+					Message:            "last successful configuration hash abc",
+					LastTransitionTime: metav1.NewTime(reloadTime),
+				},
+			},
+		},
+		{
+			desc: "failed reload with a long output, the message is truncated",
+			status: bgp.ReloadStatus{
+				Time:                reloadTime,
+				Failed:              true,
+				Error:               longError,
+				ConfigHash:          "abc",
+				ConsecutiveFailures: 1,
+			},
+			wantConditions: []metav1.Condition{
+				{
+					Type:   ConditionTypeBGPReloadSucceeded,
+					Status: metav1.ConditionFalse,
+					Reason: ErrorTypeReload,
+					// The error is cut on a rune boundary, before the budget left by
+					// the rest of the message.
+					Message:            "1 consecutive reload failures: " + longError[:32684] + " (truncated)\nlast successful configuration hash abc",
+					LastTransitionTime: metav1.NewTime(reloadTime),
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			configStateRef := &v1beta1.ConfigurationState{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "speaker-node1",
+					Namespace: testNamespace,
+				},
+				Status: v1beta1.ConfigurationStateStatus{
+					Conditions: test.existing,
+				},
+			}
+			fakeClient, err := newFakeClient([]client.Object{configStateRef})
+			if err != nil {
+				t.Fatalf("failed to create fake client: %v", err)
+			}
+
+			r := &BGPReloadStatusReconciler{
+				Client:          fakeClient,
+				Logger:          log.NewNopLogger(),
+				Namespace:       testNamespace,
+				ConfigStateName: configStateRef.Name,
+				StatusFetcher:   func() bgp.ReloadStatus { return test.status },
+			}
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: "node1"}}
+			if _, err := r.Reconcile(context.TODO(), req); err != nil {
+				t.Fatalf("unexpected reconcile error: %v", err)
+			}
+
+			var got v1beta1.ConfigurationState
+			if err := fakeClient.Get(context.Background(), client.ObjectKeyFromObject(configStateRef), &got); err != nil {
+				t.Fatalf("failed to get ConfigurationState: %v", err)
+			}
+			if diff := cmp.Diff(test.wantConditions, got.Status.Conditions); diff != "" {
+				t.Errorf("conditions mismatch (-want +got):\n%s", diff)
+			}
+			for _, c := range got.Status.Conditions {
+				if len(c.Message) >= 32768 {
+					t.Errorf("condition message too long: %d bytes", len(c.Message))
+				}
+			}
+		})
+	}
+}
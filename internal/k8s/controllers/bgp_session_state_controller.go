@@ -0,0 +1,235 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"go.universe.tf/metallb/api/v1beta1"
+	"go.universe.tf/metallb/internal/bgp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+type BGPSessionStatesFetcher func() []bgp.SessionState
+
+type bgpSessionStateEvent struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+}
+
+func (evt *bgpSessionStateEvent) DeepCopyObject() runtime.Object {
+	res := new(bgpSessionStateEvent)
+	res.Name = evt.Name
+	res.Namespace = evt.Namespace
+	return res
+}
+
+func NewBGPSessionStateEvent(namespace, name string) event.GenericEvent {
+	evt := bgpSessionStateEvent{}
+	evt.Name = name
+	evt.Namespace = namespace
+	return event.GenericEvent{Object: &evt}
+}
+
+// BGPPeerSessionStateReconciler keeps a BGPPeerSessionState object for each of the
+// BGP sessions of the node.
+type BGPPeerSessionStateReconciler struct {
+	client.Client
+	Logger        log.Logger
+	NodeName      string
+	Namespace     string
+	SpeakerPod    *v1.Pod
+	ReconcileChan <-chan event.GenericEvent
+	StatesFetcher BGPSessionStatesFetcher
+	// Recorder emits an event when a peer exceeds its limit of prefixes,
+	// nil to skip them.
+	Recorder record.EventRecorder
+}
+
+func (r *BGPPeerSessionStateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	level.Info(r.Logger).Log("controller", "BGPPeerSessionState", "start reconcile", req.String())
+	defer level.Info(r.Logger).Log("controller", "BGPPeerSessionState", "end reconcile", req.String())
+
+	var existing v1beta1.BGPPeerSessionStateList
+	err := r.List(ctx, &existing, client.InNamespace(r.Namespace), client.MatchingLabels{LabelAnnounceNode: r.NodeName})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	byPeer := map[string]*v1beta1.BGPPeerSessionState{}
+	toDelete := []*v1beta1.BGPPeerSessionState{}
+	for i := range existing.Items {
+		s := &existing.Items[i]
+		key := sessionKey(s.Labels[LabelPeer], s.Labels[LabelVRF])
+		if _, ok := byPeer[key]; ok { // shouldn't happen, just in case the controller created redundant resources
+			toDelete = append(toDelete, s)
+			continue
+		}
+		byPeer[key] = s
+	}
+
+	errs := []error{}
+	for _, sessionState := range r.StatesFetcher() {
+		peer := labelFormatForPeer(sessionState.Peer)
+		key := sessionKey(peer, sessionState.VRF)
+		state, ok := byPeer[key]
+		delete(byPeer, key)
+		if !ok {
+			state = &v1beta1.BGPPeerSessionState{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "bgpsession-",
+					Namespace:    r.Namespace,
+				},
+			}
+		}
+
+		desiredLabels := map[string]string{
+			LabelAnnounceNode: r.NodeName,
+			LabelPeer:         peer,
+			LabelVRF:          sessionState.VRF,
+		}
+		desiredStatus := desiredSessionStatus(r.NodeName, sessionState, state.Status.Conditions)
+		wasExceeded := meta.IsStatusConditionTrue(state.Status.Conditions, ConditionTypePrefixLimitExceeded)
+		if ok && equality.Semantic.DeepEqual(state.Labels, desiredLabels) && equality.Semantic.DeepEqual(state.Status, desiredStatus) {
+			continue
+		}
+
+		result, err := controllerutil.CreateOrPatch(ctx, r.Client, state, func() error {
+			state.Labels = desiredLabels
+			state.Status = desiredStatus
+			return controllerutil.SetOwnerReference(r.SpeakerPod, state, r.Scheme())
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if result == controllerutil.OperationResultCreated {
+			// The status is patched when reconciling the creation event.
+			level.Debug(r.Logger).Log("controller", "BGPPeerSessionState", "created state", dumpResource(state))
+			continue
+		}
+		level.Debug(r.Logger).Log("controller", "BGPPeerSessionState", "updated state", dumpResource(state))
+		if r.Recorder != nil && !wasExceeded && sessionState.PrefixLimitExceeded {
+			r.Recorder.Eventf(state, v1.EventTypeWarning, "prefixLimitExceeded",
+				"peer %s sent more than %d prefixes in an address family, the ones beyond the limit are still accepted", sessionState.Peer, sessionState.PrefixLimit)
+		}
+	}
+
+	// The remaining ones belong to sessions that do not exist anymore.
+	for _, s := range byPeer {
+		toDelete = append(toDelete, s)
+	}
+	for _, s := range toDelete {
+		if err := r.Delete(ctx, s); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+
+	return ctrl.Result{}, utilerrors.NewAggregate(errs)
+}
+
+func (r *BGPPeerSessionStateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	p := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		if _, ok := o.(*v1beta1.BGPPeerSessionState); !ok {
+			return true
+		}
+		return o.GetLabels()[LabelAnnounceNode] == r.NodeName
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("bgppeersessionstate").
+		Watches(&v1beta1.BGPPeerSessionState{}, handler.EnqueueRequestsFromMapFunc(
+			func(ctx context.Context, object client.Object) []reconcile.Request {
+				// All the states of the node are reconciled together.
+				return []reconcile.Request{{NamespacedName: types.NamespacedName{
+					Name:      r.NodeName,
+					Namespace: r.Namespace,
+				}}}
+			})).
+		WatchesRawSource(source.Channel(r.ReconcileChan, &handler.EnqueueRequestForObject{})).
+		WithEventFilter(p).
+		Complete(r)
+}
+
+// desiredSessionStatus returns the status of the given session, keeping the
+// transition time of the current conditions.
+func desiredSessionStatus(node string, s bgp.SessionState, current []metav1.Condition) v1beta1.MetalLBBGPPeerSessionStateStatus {
+	res := v1beta1.MetalLBBGPPeerSessionStateStatus{
+		Node:             node,
+		Peer:             s.Peer,
+		VRF:              s.VRF,
+		BGPStatus:        s.State,
+		BFDStatus:        s.BFDState,
+		PrefixesSent:     s.PrefixesSent,
+		PrefixesReceived: s.PrefixesReceived,
+		LastError:        s.LastError,
+	}
+	if !s.UpSince.IsZero() {
+		// The API server stores the time with a second precision.
+		upSince := metav1.NewTime(s.UpSince.Truncate(time.Second))
+		res.EstablishedTime = &upSince
+	}
+	if s.PrefixLimit == 0 {
+		return res
+	}
+	if c := meta.FindStatusCondition(current, ConditionTypePrefixLimitExceeded); c != nil {
+		res.Conditions = append(res.Conditions, *c)
+	}
+	meta.SetStatusCondition(&res.Conditions, prefixLimitCondition(s))
+	return res
+}
+
+func prefixLimitCondition(s bgp.SessionState) metav1.Condition {
+	if !s.PrefixLimitExceeded {
+		return metav1.Condition{
+			Type:    ConditionTypePrefixLimitExceeded,
+			Status:  metav1.ConditionFalse,
+			Reason:  ReasonWithinPrefixLimit,
+			Message: fmt.Sprintf("the peer sent at most %d prefixes in each address family", s.PrefixLimit),
+		}
+	}
+	// The limit is set as a warning only, FRR keeps the session up and the
+	// prefixes accepted.
+	return metav1.Condition{
+		Type:    ConditionTypePrefixLimitExceeded,
+		Status:  metav1.ConditionTrue,
+		Reason:  ReasonPrefixLimitExceeded,
+		Message: fmt.Sprintf("the peer sent more than %d prefixes in an address family, the ones beyond the limit are still accepted", s.PrefixLimit),
+	}
+}
+
+func sessionKey(peer, vrf string) string {
+	return peer + "/" + vrf
+}
+
+// labelFormatForPeer returns the peer in a format suitable for a label value,
+// which can't contain ':'.
+func labelFormatForPeer(peer string) string {
+	addr, err := netip.ParseAddr(peer)
+	if err != nil || addr.Is4() { // the peer is an interface in the unnumbered case
+		return peer
+	}
+	return strings.ReplaceAll(addr.StringExpanded(), ":", "-")
+}
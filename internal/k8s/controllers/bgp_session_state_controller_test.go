@@ -0,0 +1,84 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/log"
+	v1beta1 "go.universe.tf/metallb/api/v1beta1"
+	"go.universe.tf/metallb/internal/bgp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestBGPPeerSessionStatePrefixLimit(t *testing.T) {
+	existing := &v1beta1.BGPPeerSessionState{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "bgpsession-1",
+			Namespace: testNamespace,
+			Labels: map[string]string{
+				LabelAnnounceNode: "node1",
+				LabelPeer:         "192.168.1.1",
+				LabelVRF:          "",
+			},
+		},
+	}
+	fakeClient, err := newFakeClient([]client.Object{existing})
+	if err != nil {
+		t.Fatalf("failed to create fake client: %v", err)
+	}
+
+	state := bgp.SessionState{
+		Peer:             "192.168.1.1",
+		State:            "Established",
+		PrefixesReceived: 3,
+		PrefixLimit:      10,
+	}
+	recorder := record.NewFakeRecorder(10)
+	r := &BGPPeerSessionStateReconciler{
+		Client:    fakeClient,
+		Logger:    log.NewNopLogger(),
+		NodeName:  "node1",
+		Namespace: testNamespace,
+		SpeakerPod: &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "speaker", Namespace: testNamespace, UID: "1234"},
+		},
+		StatesFetcher: func() []bgp.SessionState { return []bgp.SessionState{state} },
+		Recorder:      recorder,
+	}
+	reconcileAndCheck := func(wantStatus metav1.ConditionStatus, wantEvents int) {
+		t.Helper()
+		req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: "node1"}}
+		if _, err := r.Reconcile(context.TODO(), req); err != nil {
+			t.Fatalf("unexpected reconcile error: %v", err)
+		}
+		var got v1beta1.BGPPeerSessionState
+		if err := fakeClient.Get(context.Background(), client.ObjectKeyFromObject(existing), &got); err != nil {
+			t.Fatalf("failed to get BGPPeerSessionState: %v", err)
+		}
+		c := meta.FindStatusCondition(got.Status.Conditions, ConditionTypePrefixLimitExceeded)
+		if c == nil || c.Status != wantStatus {
+			t.Fatalf("expected the %s condition with status %s, got %v", ConditionTypePrefixLimitExceeded, wantStatus, c)
+		}
+		if len(recorder.Events) != wantEvents {
+			t.Fatalf("expected %d events, got %d", wantEvents, len(recorder.Events))
+		}
+	}
+
+	reconcileAndCheck(metav1.ConditionFalse, 0)
+
+	state.PrefixesReceived = 12
+	state.PrefixLimitExceeded = true
+	reconcileAndCheck(metav1.ConditionTrue, 1)
+
+	// The event is emitted once, when the limit is exceeded.
+	state.PrefixesReceived = 13
+	reconcileAndCheck(metav1.ConditionTrue, 1)
+}
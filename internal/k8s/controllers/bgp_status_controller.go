@@ -27,7 +27,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
-type PeersForService func(key string) sets.Set[string]
+// PeersForService returns whether the given service is advertised to each of
+// the BGP peers running on the node.
+type PeersForService func(key string) []v1beta1.ServiceBGPPeerCondition
+
+// PrefixesForService returns the VRFs each of the prefixes of the given
+// service is announced in by the node.
+type PrefixesForService func(key string) []v1beta1.ServiceBGPPrefix
 
 type bgpStatusEvent struct {
 	metav1.TypeMeta
@@ -56,6 +62,8 @@ type ServiceBGPStatusReconciler struct {
 	SpeakerPod    *v1.Pod
 	ReconcileChan <-chan event.GenericEvent
 	PeersFetcher  PeersForService
+	// PrefixesFetcher is nil when the VRFs of the prefixes are not reported.
+	PrefixesFetcher PrefixesForService
 }
 
 func (r *ServiceBGPStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -72,8 +80,8 @@ func (r *ServiceBGPStatusReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, err
 	}
 
-	peers := r.PeersFetcher(req.String())
-	if peers.Len() == 0 {
+	conditions := r.PeersFetcher(req.String())
+	if len(conditions) == 0 {
 		errs := []error{}
 		for i := range serviceBGPStatuses.Items {
 			if serviceBGPStatuses.Items[i].Labels[LabelAnnounceNode] != r.NodeName { // shouldn't happen because of the indexing, just in case
@@ -115,11 +123,26 @@ func (r *ServiceBGPStatusReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		state = &serviceBGPStatuses.Items[0]
 	}
 
+	peers := sets.New[string]()
+	for _, c := range conditions {
+		// The peers whose session state is unknown are listed as the service
+		// is configured to be advertised to them.
+		if c.Advertised || c.Reason == v1beta1.ServiceBGPPeerUnknown {
+			peers.Insert(c.Peer)
+		}
+	}
 	desiredStatus := v1beta1.MetalLBServiceBGPStatus{
 		Node:             r.NodeName,
 		ServiceName:      serviceName,
 		ServiceNamespace: serviceNamespace,
 		Peers:            sets.List(peers),
+		PeerConditions:   conditions,
+	}
+	if peers.Len() == 0 {
+		desiredStatus.Peers = nil
+	}
+	if r.PrefixesFetcher != nil {
+		desiredStatus.Prefixes = r.PrefixesFetcher(req.String())
 	}
 
 	if reflect.DeepEqual(state.Status, desiredStatus) {
@@ -157,6 +180,9 @@ func (r *ServiceBGPStatusReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 func (r *ServiceBGPStatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	p := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		if _, ok := o.(*v1beta1.BGPPeerSessionState); ok {
+			return o.GetLabels()[LabelAnnounceNode] == r.NodeName
+		}
 		_, ok := o.(*v1beta1.ServiceBGPStatus)
 		if !ok {
 			return true
@@ -229,11 +255,32 @@ func (r *ServiceBGPStatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
 					Namespace: labels[LabelServiceNamespace],
 				}}}
 			})).
+		// The sessions going up or down change whether the services are advertised.
+		Watches(&v1beta1.BGPPeerSessionState{}, handler.EnqueueRequestsFromMapFunc(r.servicesForNode)).
 		WatchesRawSource(source.Channel(r.ReconcileChan, &handler.EnqueueRequestForObject{})).
 		WithEventFilter(p).
 		Complete(r)
 }
 
+// servicesForNode returns a request for each of the services having a status
+// for the node.
+func (r *ServiceBGPStatusReconciler) servicesForNode(ctx context.Context, _ client.Object) []reconcile.Request {
+	var statuses v1beta1.ServiceBGPStatusList
+	err := r.List(ctx, &statuses, client.InNamespace(r.Namespace), client.MatchingLabels{LabelAnnounceNode: r.NodeName})
+	if err != nil {
+		level.Error(r.Logger).Log("controller", "ServiceBGPStatus", "error", err, "msg", "failed to list the statuses of the node")
+		return nil
+	}
+	res := make([]reconcile.Request, 0, len(statuses.Items))
+	for _, s := range statuses.Items {
+		res = append(res, reconcile.Request{NamespacedName: types.NamespacedName{
+			Name:      s.Labels[LabelServiceName],
+			Namespace: s.Labels[LabelServiceNamespace],
+		}})
+	}
+	return res
+}
+
 func indexFor(svcNs, svcName, node string) string {
 	return fmt.Sprintf("%s/%s-%s", svcNs, svcName, node)
 }
@@ -90,6 +90,12 @@ var requestHandler = func(r *ConfigReconciler, ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, err
 	}
 
+	var peerTemplates metallbv1beta2.BGPPeerTemplateList
+	if err := r.List(ctx, &peerTemplates, client.InNamespace(r.Namespace)); err != nil {
+		level.Error(r.Logger).Log("controller", "ConfigReconciler", "message", "failed to get bgppeertemplates", "error", err)
+		return ctrl.Result{}, err
+	}
+
 	var bfdProfiles metallbv1beta1.BFDProfileList
 	if err := r.List(ctx, &bfdProfiles, client.InNamespace(r.Namespace)); err != nil {
 		level.Error(r.Logger).Log("controller", "ConfigReconciler", "message", "failed to get bfdprofiles", "error", err)
@@ -114,6 +120,12 @@ var requestHandler = func(r *ConfigReconciler, ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, err
 	}
 
+	var extras metallbv1beta1.BGPExtrasList
+	if err := r.List(ctx, &extras, client.InNamespace(r.Namespace)); err != nil {
+		level.Error(r.Logger).Log("controller", "ConfigReconciler", "message", "failed to get bgpextras", "error", err)
+		return ctrl.Result{}, err
+	}
+
 	secrets, err := r.getSecrets(ctx)
 	if err != nil {
 		return ctrl.Result{}, err
@@ -166,6 +178,7 @@ var requestHandler = func(r *ConfigReconciler, ctx context.Context, req ctrl.Req
 	resources := config.ClusterResources{
 		Pools:           ipAddressPools.Items,
 		Peers:           peers,
+		PeerTemplates:   peerTemplates.Items,
 		BFDProfiles:     bfdProfiles.Items,
 		L2Advs:          l2Advertisements.Items,
 		BGPAdvs:         bgpAdvs,
@@ -174,6 +187,7 @@ var requestHandler = func(r *ConfigReconciler, ctx context.Context, req ctrl.Req
 		Nodes:           nodes.Items,
 		Namespaces:      namespaces.Items,
 		BGPExtras:       extrasMap,
+		Extras:          extras.Items,
 	}
 
 	level.Debug(r.Logger).Log("controller", "ConfigReconciler", "metallb CRs and Secrets", dumpClusterResources(&resources))
@@ -188,7 +202,7 @@ var requestHandler = func(r *ConfigReconciler, ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, nil
 	}
 
-	if cfg.BGPExtras != "" {
+	if cfg.BGPExtras.Raw != "" {
 		level.Info(r.Logger).Log("controller", "ConfigReconciler", "warning message", "BGP Extras provided, please note that this configuration is not supported and used at your own risk")
 	}
 	level.Debug(r.Logger).Log("controller", "ConfigReconciler", "rendered config", dumpConfig(cfg))
@@ -236,12 +250,14 @@ func (r *ConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&metallbv1beta2.BGPPeer{}).
+		Watches(&metallbv1beta2.BGPPeerTemplate{}, &handler.EnqueueRequestForObject{}).
 		Watches(&metallbv1beta1.IPAddressPool{}, &handler.EnqueueRequestForObject{}).
 		Watches(&corev1.Node{}, &handler.EnqueueRequestForObject{}).
 		Watches(&metallbv1beta1.BGPAdvertisement{}, &handler.EnqueueRequestForObject{}).
 		Watches(&metallbv1beta1.L2Advertisement{}, &handler.EnqueueRequestForObject{}).
 		Watches(&metallbv1beta1.BFDProfile{}, &handler.EnqueueRequestForObject{}).
 		Watches(&metallbv1beta1.Community{}, &handler.EnqueueRequestForObject{}).
+		Watches(&metallbv1beta1.BGPExtras{}, &handler.EnqueueRequestForObject{}).
 		Watches(&corev1.Secret{}, &handler.EnqueueRequestForObject{}).
 		Watches(&corev1.Namespace{}, &handler.EnqueueRequestForObject{}).
 		Watches(&corev1.ConfigMap{}, &handler.EnqueueRequestForObject{}).
@@ -12,6 +12,7 @@ func toConfig(fromK8s config.ClusterResources, validate config.Validate, opts co
 	resources := config.ClusterResources{
 		Pools:           sortedCopy(fromK8s.Pools),
 		Peers:           sortedCopy(fromK8s.Peers),
+		PeerTemplates:   sortedCopy(fromK8s.PeerTemplates),
 		BFDProfiles:     sortedCopy(fromK8s.BFDProfiles),
 		L2Advs:          sortedCopy(fromK8s.L2Advs),
 		BGPAdvs:         sortedCopy(fromK8s.BGPAdvs),
@@ -20,6 +21,7 @@ func toConfig(fromK8s config.ClusterResources, validate config.Validate, opts co
 		Nodes:           sortedCopy(fromK8s.Nodes),
 		Namespaces:      sortedCopy(fromK8s.Namespaces),
 		BGPExtras:       fromK8s.BGPExtras,
+		Extras:          sortedCopy(fromK8s.Extras),
 	}
 
 	cfg, err := config.For(resources, validate, opts)
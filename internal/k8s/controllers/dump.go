@@ -13,13 +13,15 @@ import (
 
 func dumpClusterResources(c *config.ClusterResources) string {
 	withNoSecret := config.ClusterResources{
-		Pools:       c.Pools,
-		Peers:       sanitizeBGPPeer(c.Peers...),
-		BFDProfiles: c.BFDProfiles,
-		L2Advs:      c.L2Advs,
-		BGPAdvs:     c.BGPAdvs,
-		Communities: c.Communities,
-		BGPExtras:   c.BGPExtras,
+		Pools:         c.Pools,
+		Peers:         sanitizeBGPPeer(c.Peers...),
+		PeerTemplates: c.PeerTemplates,
+		BFDProfiles:   c.BFDProfiles,
+		L2Advs:        c.L2Advs,
+		BGPAdvs:       c.BGPAdvs,
+		Communities:   c.Communities,
+		BGPExtras:     c.BGPExtras,
+		Extras:        c.Extras,
 	}
 	withNoSecret.PasswordSecrets = make(map[string]corev1.Secret)
 	for k, s := range c.PasswordSecrets {
@@ -36,6 +38,15 @@ func dumpConfig(cfg *config.Config) string {
 	for _, p := range cfg.Peers {
 		p1 := *p
 		p1.Password = "<retracted>"
+		if p.TCPAO != nil {
+			tcpAO := *p.TCPAO
+			tcpAO.Keys = make([]config.TCPAOKey, 0, len(p.TCPAO.Keys))
+			for _, k := range p.TCPAO.Keys {
+				k.Key = "<retracted>"
+				tcpAO.Keys = append(tcpAO.Keys, k)
+			}
+			p1.TCPAO = &tcpAO
+		}
 		toDump.Peers[p.Name] = &p1
 	}
 	return spew.Sdump(toDump)
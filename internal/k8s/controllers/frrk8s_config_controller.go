@@ -18,7 +18,9 @@ package controllers
 
 import (
 	"context"
+	"maps"
 	"reflect"
+	"slices"
 	"sync"
 	"time"
 
@@ -26,6 +28,7 @@ import (
 	"github.com/go-kit/log/level"
 
 	frrv1beta1 "github.com/metallb/frr-k8s/api/v1beta1"
+	metallbv1beta2 "go.universe.tf/metallb/api/v1beta2"
 	frrk8s "go.universe.tf/metallb/internal/bgp/frrk8s"
 	"go.universe.tf/metallb/internal/logging"
 
@@ -63,14 +66,17 @@ func NewFRRK8sConfigEvent() event.GenericEvent {
 
 type FRRK8sReconciler struct {
 	client.Client
-	Logger               log.Logger
-	LogLevel             logging.Level
-	Scheme               *runtime.Scheme
-	NodeName             string
-	FRRK8sNamespace      string
-	reconcileChan        chan event.GenericEvent
-	configChangedChan    chan struct{}
-	desiredConfiguration *frrv1beta1.FRRConfiguration
+	Logger          log.Logger
+	LogLevel        logging.Level
+	Scheme          *runtime.Scheme
+	NodeName        string
+	FRRK8sNamespace string
+	// Namespace is the namespace of the MetalLB resources, the BGPPeers
+	// owning the configuration scoped to them.
+	Namespace             string
+	reconcileChan         chan event.GenericEvent
+	configChangedChan     chan struct{}
+	desiredConfigurations []frrv1beta1.FRRConfiguration
 	sync.Mutex
 }
 
@@ -81,46 +87,137 @@ func (r *FRRK8sReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 
 	r.Lock()
 	defer r.Unlock()
-	if r.desiredConfiguration == nil {
+	if r.desiredConfigurations == nil {
 		config := &frrv1beta1.FRRConfiguration{ObjectMeta: metav1.ObjectMeta{Name: frrk8s.ConfigName(r.NodeName), Namespace: r.FRRK8sNamespace}}
 		err := r.Delete(ctx, config)
-		return ctrl.Result{}, client.IgnoreNotFound(err)
+		if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.deleteStale(ctx, nil)
+	}
+
+	desired := map[string]bool{}
+	for i := range r.desiredConfigurations {
+		config := &r.desiredConfigurations[i]
+		desired[config.Name] = true
+		if err := r.apply(ctx, config); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if err := r.deleteStale(ctx, desired); err != nil {
+		return ctrl.Result{}, err
 	}
 
+	return ctrl.Result{}, nil
+}
+
+// apply creates or updates the given configuration, unless it is already in
+// place.
+func (r *FRRK8sReconciler) apply(ctx context.Context, desired *frrv1beta1.FRRConfiguration) error {
 	current := frrv1beta1.FRRConfiguration{}
-	err := r.Get(ctx, client.ObjectKey{Name: r.desiredConfiguration.Name, Namespace: r.desiredConfiguration.Namespace}, &current)
+	err := r.Get(ctx, client.ObjectKey{Name: desired.Name, Namespace: desired.Namespace}, &current)
 	if err != nil && !apierrors.IsNotFound(err) {
-		return ctrl.Result{}, err
+		return err
 	}
 
-	if reflect.DeepEqual(current.Spec, r.desiredConfiguration.Spec) {
-		level.Debug(r.Logger).Log("controller", "FRRK8sReconciler", "event", "not reconciling because of no change")
-		return ctrl.Result{}, nil
+	owner, err := r.ownerFor(ctx, desired)
+	if err != nil {
+		return err
+	}
+
+	if reflect.DeepEqual(current.Spec, desired.Spec) && contains(current.Labels, desired.Labels) && contains(current.Annotations, desired.Annotations) && (owner == nil || isOwnedBy(&current, owner)) {
+		level.Debug(r.Logger).Log("controller", "FRRK8sReconciler", "event", "not reconciling because of no change", "config", desired.Name)
+		return nil
 	}
 
 	toApply := &frrv1beta1.FRRConfiguration{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      r.desiredConfiguration.Name,
-			Namespace: r.desiredConfiguration.Namespace},
+			Name:      desired.Name,
+			Namespace: desired.Namespace},
 	}
 	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, toApply, func() error {
-		r.desiredConfiguration.Spec.DeepCopyInto(&toApply.Spec)
+		desired.Spec.DeepCopyInto(&toApply.Spec)
+		if len(desired.Labels) > 0 && toApply.Labels == nil {
+			toApply.Labels = map[string]string{}
+		}
+		maps.Copy(toApply.Labels, desired.Labels)
+		if len(desired.Annotations) > 0 && toApply.Annotations == nil {
+			toApply.Annotations = map[string]string{}
+		}
+		maps.Copy(toApply.Annotations, desired.Annotations)
+		if owner != nil {
+			return controllerutil.SetOwnerReference(owner, toApply, r.Scheme)
+		}
 		return nil
 	})
 	if err != nil {
-		level.Info(r.Logger).Log("controller", "FRRConfiguration", "event", "failed to create frr8s configuration")
-		return ctrl.Result{}, err
+		level.Info(r.Logger).Log("controller", "FRRConfiguration", "event", "failed to create frr8s configuration", "config", desired.Name)
+		return err
 	}
 
 	if r.LogLevel == logging.LevelDebug {
-		toDump, err := frrk8s.ConfigToDump(*r.desiredConfiguration)
+		toDump, err := frrk8s.ConfigToDump(*desired)
 		if err != nil {
 			level.Error(r.Logger).Log("controller", "FRRConfiguration", "event", "failed to dump frr8s configuration", "error", err)
 		}
 		level.Debug(r.Logger).Log("controller", "FRRK8sReconciler", "event", "applied new configuration", "config", toDump)
 	}
+	return nil
+}
 
-	return ctrl.Result{}, nil
+// ownerFor returns the BGPPeer owning the configuration scoped to it, nil
+// if the configuration is not scoped to a peer or if the peer lives in
+// another namespace, as the owner references can't cross namespaces.
+func (r *FRRK8sReconciler) ownerFor(ctx context.Context, config *frrv1beta1.FRRConfiguration) (*metallbv1beta2.BGPPeer, error) {
+	peer := config.Annotations[frrk8s.PeerAnnotation]
+	if peer == "" || r.Namespace != config.Namespace {
+		return nil, nil
+	}
+	owner := &metallbv1beta2.BGPPeer{}
+	err := r.Get(ctx, client.ObjectKey{Name: peer, Namespace: r.Namespace}, owner)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return owner, nil
+}
+
+// deleteStale deletes the configurations scoped to the peers of the node
+// that are not desired anymore.
+func (r *FRRK8sReconciler) deleteStale(ctx context.Context, desired map[string]bool) error {
+	configs := frrv1beta1.FRRConfigurationList{}
+	err := r.List(ctx, &configs, client.InNamespace(r.FRRK8sNamespace), client.MatchingLabels{frrk8s.NodeLabel: frrk8s.LabelValue(r.NodeName)}, client.HasLabels{frrk8s.PeerLabel})
+	if err != nil {
+		return err
+	}
+	for i := range configs.Items {
+		if desired[configs.Items[i].Name] {
+			continue
+		}
+		level.Debug(r.Logger).Log("controller", "FRRK8sReconciler", "event", "deleting stale configuration", "config", configs.Items[i].Name)
+		if err := r.Delete(ctx, &configs.Items[i]); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contains tells if the current labels or annotations hold the desired ones.
+func contains(current, desired map[string]string) bool {
+	for k, v := range desired {
+		if current[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func isOwnedBy(obj client.Object, owner client.Object) bool {
+	return slices.ContainsFunc(obj.GetOwnerReferences(), func(ref metav1.OwnerReference) bool {
+		return ref.UID == owner.GetUID()
+	})
 }
 
 func (r *FRRK8sReconciler) SetupWithManager(mgr ctrl.Manager) error {
@@ -134,13 +231,10 @@ func (r *FRRK8sReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		if !ok {
 			return true
 		}
-		if config.Name != configName {
-			return false
-		}
 		if config.Namespace != r.FRRK8sNamespace {
 			return false
 		}
-		return true
+		return config.Name == configName || config.Labels[frrk8s.NodeLabel] == frrk8s.LabelValue(r.NodeName)
 	})
 
 	return ctrl.NewControllerManagedBy(mgr).
@@ -150,14 +244,22 @@ func (r *FRRK8sReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Complete(r)
 }
 
+// UpdateConfig records the desired configuration of the node, either a
+// single FRRConfiguration or the list of the ones scoped to its peers.
 func (r *FRRK8sReconciler) UpdateConfig(config interface{}) {
 	r.Lock()
 	defer r.Unlock()
-	desired, ok := config.(frrv1beta1.FRRConfiguration)
-	if !ok {
+	switch desired := config.(type) {
+	case frrv1beta1.FRRConfiguration:
+		r.desiredConfigurations = []frrv1beta1.FRRConfiguration{*desired.DeepCopy()}
+	case []frrv1beta1.FRRConfiguration:
+		r.desiredConfigurations = make([]frrv1beta1.FRRConfiguration, 0, len(desired))
+		for i := range desired {
+			r.desiredConfigurations = append(r.desiredConfigurations, *desired[i].DeepCopy())
+		}
+	default:
 		panic("received an event that is not frr configuration")
 	}
-	r.desiredConfiguration = desired.DeepCopy()
 	r.configChangedChan <- struct{}{}
 }
 
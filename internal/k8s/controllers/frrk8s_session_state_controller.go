@@ -0,0 +1,73 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	frrv1beta1 "github.com/metallb/frr-k8s/api/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// frrk8sNodeLabel is the label FRR-K8s sets on its BGPSessionState resources
+// with the name of the node the session runs on.
+const frrk8sNodeLabel = "frrk8s.metallb.io/node"
+
+// FRRK8sSessionStatesHandler receives the state of all the BGP sessions of
+// the node, as reported by FRR-K8s.
+type FRRK8sSessionStatesHandler func([]frrv1beta1.BGPSessionStateStatus)
+
+// FRRK8sSessionStateReconciler feeds the state of the BGP sessions of the
+// node, as reported by the FRR-K8s BGPSessionState resources, to the handler.
+type FRRK8sSessionStateReconciler struct {
+	client.Client
+	Logger          log.Logger
+	NodeName        string
+	FRRK8sNamespace string
+	Handler         FRRK8sSessionStatesHandler
+}
+
+func (r *FRRK8sSessionStateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	level.Info(r.Logger).Log("controller", "FRRK8sSessionState", "start reconcile", req.String())
+	defer level.Info(r.Logger).Log("controller", "FRRK8sSessionState", "end reconcile", req.String())
+
+	var existing frrv1beta1.BGPSessionStateList
+	err := r.List(ctx, &existing, client.InNamespace(r.FRRK8sNamespace), client.MatchingLabels{frrk8sNodeLabel: r.NodeName})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	states := make([]frrv1beta1.BGPSessionStateStatus, 0, len(existing.Items))
+	for _, s := range existing.Items {
+		states = append(states, s.Status)
+	}
+	r.Handler(states)
+
+	return ctrl.Result{}, nil
+}
+
+func (r *FRRK8sSessionStateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	p := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		return o.GetNamespace() == r.FRRK8sNamespace && o.GetLabels()[frrk8sNodeLabel] == r.NodeName
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("frrk8ssessionstate").
+		Watches(&frrv1beta1.BGPSessionState{}, handler.EnqueueRequestsFromMapFunc(
+			func(ctx context.Context, object client.Object) []reconcile.Request {
+				// All the states of the node are handled together.
+				return []reconcile.Request{{NamespacedName: types.NamespacedName{
+					Name:      r.NodeName,
+					Namespace: r.FRRK8sNamespace,
+				}}}
+			})).
+		WithEventFilter(p).
+		Complete(r)
+}
@@ -24,13 +24,22 @@ import (
 
 	k8snodes "go.universe.tf/metallb/internal/k8s/nodes"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
+// NewNodeEvent returns an event reconciling the given node, for the changes
+// on the node that are not reflected on its object.
+func NewNodeEvent(name string) event.GenericEvent {
+	return event.GenericEvent{Object: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}}
+}
+
 type NodeReconciler struct {
 	client.Client
 	Logger      log.Logger
@@ -39,6 +48,9 @@ type NodeReconciler struct {
 	Namespace   string
 	Handler     func(log.Logger, *corev1.Node) SyncState
 	ForceReload func()
+	// ReconcileChan, if set, triggers the reconciliation of the node
+	// outside of the updates of its object.
+	ReconcileChan <-chan event.GenericEvent
 }
 
 func (r *NodeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -103,13 +115,18 @@ func NodeReconcilerPredicate() predicate.Predicate {
 		predicate.Or(
 			nodeConditionNetworkAvailabilityStatusChanged,
 			predicate.LabelChangedPredicate{},
+			// The annotations may hold the address of the BGP peers.
+			predicate.AnnotationChangedPredicate{},
 		),
 	)
 }
 
 func (r *NodeReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.Node{}).
-		WithEventFilter(NodeReconcilerPredicate()).
-		Complete(r)
+		WithEventFilter(NodeReconcilerPredicate())
+	if r.ReconcileChan != nil {
+		b = b.WatchesRawSource(source.Channel(r.ReconcileChan, &handler.EnqueueRequestForObject{}))
+	}
+	return b.Complete(r)
 }
@@ -174,6 +174,12 @@ func TestNodeReconcilerPredicate(t *testing.T) {
 				ObjectNew: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"x": "y"}}}},
 			expected: true,
 		},
+		"annotation change": {
+			event: event.UpdateEvent{
+				ObjectOld: &corev1.Node{},
+				ObjectNew: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"x": "y"}}}},
+			expected: true,
+		},
 		"spec schedulable change": {
 			event: event.UpdateEvent{
 				ObjectOld: &corev1.Node{
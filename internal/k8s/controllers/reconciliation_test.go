@@ -73,7 +73,7 @@ var (
 	speakerPod *corev1.Pod
 
 	bgpStatusReconcileChan = make(chan event.GenericEvent)
-	bgpAdvs                = map[string]sets.Set[string]{}
+	bgpAdvs                = map[string][]v1beta1.ServiceBGPPeerCondition{}
 	bgpAdvsMutex           = sync.Mutex{}
 
 	poolStatusReconcileChan = make(chan event.GenericEvent)
@@ -232,7 +232,7 @@ var _ = BeforeSuite(func() {
 		Namespace:     speakerNamespace,
 		SpeakerPod:    speakerPod,
 		ReconcileChan: bgpStatusReconcileChan,
-		PeersFetcher: func(key string) sets.Set[string] {
+		PeersFetcher: func(key string) []v1beta1.ServiceBGPPeerCondition {
 			bgpAdvsMutex.Lock()
 			defer bgpAdvsMutex.Unlock()
 			return bgpAdvs[key]
@@ -520,6 +520,55 @@ var _ = Describe("FRRK8S Controller", func() {
 				return toCheck.Generation
 			}, 5*time.Second, 200*time.Millisecond).Should(Equal(storedConfig.Generation))
 		})
+
+		It("Should reconcile the configurations of the peers", func() {
+			nodeConfig := frrv1beta1.FRRConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      frrk8s.ConfigName(testNodeName),
+					Namespace: testNamespace,
+				},
+			}
+			peerConfig := frrv1beta1.FRRConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      frrk8s.PeerConfigName(testNodeName, "peer1"),
+					Namespace: testNamespace,
+					Labels: map[string]string{
+						frrk8s.NodeLabel: testNodeName,
+						frrk8s.PeerLabel: "peer1",
+					},
+					Annotations: map[string]string{
+						frrk8s.PeerAnnotation: "peer1",
+					},
+				},
+				Spec: frrv1beta1.FRRConfigurationSpec{
+					BGP: frrv1beta1.BGPConfig{
+						Routers: []frrv1beta1.Router{
+							{
+								ASN: 27,
+							},
+						},
+					},
+				},
+			}
+
+			frrk8sReconciler.UpdateConfig([]frrv1beta1.FRRConfiguration{nodeConfig, peerConfig})
+			Eventually(func() string {
+				toCheck := frrv1beta1.FRRConfiguration{}
+				err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: peerConfig.Name, Namespace: testNamespace}, &toCheck)
+				if err != nil {
+					return ""
+				}
+				return toCheck.Labels[frrk8s.PeerLabel]
+			}, 5*time.Second, 200*time.Millisecond).Should(Equal("peer1"))
+
+			// The configurations of the peers not desired anymore are deleted
+			frrk8sReconciler.UpdateConfig([]frrv1beta1.FRRConfiguration{nodeConfig})
+			Eventually(func() bool {
+				toCheck := frrv1beta1.FRRConfiguration{}
+				err := k8sClient.Get(context.TODO(), client.ObjectKey{Name: peerConfig.Name, Namespace: testNamespace}, &toCheck)
+				return apierrors.IsNotFound(err)
+			}, 5*time.Second, 200*time.Millisecond).Should(BeTrue())
+		})
 	})
 })
 
@@ -781,7 +830,9 @@ var _ = Describe("BGP Status Controller", func() {
 			}
 
 			bgpAdvsMutex.Lock()
-			bgpAdvs[serviceKey] = sets.New[string]("peer1")
+			bgpAdvs[serviceKey] = []v1beta1.ServiceBGPPeerCondition{
+				{Peer: "peer1", Advertised: true, Reason: v1beta1.ServiceBGPPeerAdvertised},
+			}
 			bgpAdvsMutex.Unlock()
 			bgpStatusReconcileChan <- NewBGPStatusEvent(testNamespace, testServiceName)
 			expectedPeers := []string{"peer1"}
@@ -799,7 +850,11 @@ var _ = Describe("BGP Status Controller", func() {
 			}, 5*time.Second, 200*time.Millisecond).ShouldNot(HaveOccurred())
 
 			bgpAdvsMutex.Lock()
-			bgpAdvs[serviceKey] = sets.New[string]("peer1", "peer2")
+			bgpAdvs[serviceKey] = []v1beta1.ServiceBGPPeerCondition{
+				{Peer: "peer1", Advertised: true, Reason: v1beta1.ServiceBGPPeerAdvertised},
+				{Peer: "peer2", Advertised: true, Reason: v1beta1.ServiceBGPPeerAdvertised},
+				{Peer: "peer3", Reason: v1beta1.ServiceBGPPeerSessionNotEstablished},
+			}
 			bgpAdvsMutex.Unlock()
 			bgpStatusReconcileChan <- NewBGPStatusEvent(testNamespace, testServiceName)
 			expectedPeers = []string{"peer1", "peer2"}
@@ -813,6 +868,10 @@ var _ = Describe("BGP Status Controller", func() {
 					return fmt.Errorf("expected peers to be %v, got %v", expectedPeers, s.Status.Peers)
 				}
 
+				if len(s.Status.PeerConditions) != 3 || s.Status.PeerConditions[2].Reason != v1beta1.ServiceBGPPeerSessionNotEstablished {
+					return fmt.Errorf("expected the peer3 session not to be established, got %v", s.Status.PeerConditions)
+				}
+
 				return nil
 			}, 5*time.Second, 200*time.Millisecond).ShouldNot(HaveOccurred())
 
@@ -33,12 +33,22 @@ const (
 	LabelAnnounceNode     = "metallb.io/node"
 	LabelServiceName      = "metallb.io/service-name"
 	LabelServiceNamespace = "metallb.io/service-namespace"
+	LabelPeer             = "metallb.io/peer"
+	LabelVRF              = "metallb.io/vrf"
 )
 
 // Condition types for ConfigurationState status reporting.
 const (
 	ConditionTypeConfigReconcilerValid = "configReconcilerValid"
 	ConditionTypePoolReconcilerValid   = "poolReconcilerValid"
+	ConditionTypeBGPReloadSucceeded    = "bgpReloadSucceeded"
+)
+
+// Condition types and reasons for BGPPeerSessionState status reporting.
+const (
+	ConditionTypePrefixLimitExceeded = "prefixLimitExceeded"
+	ReasonPrefixLimitExceeded        = "PrefixLimitExceeded"
+	ReasonWithinPrefixLimit          = "WithinLimit"
 )
 
 // Error types for condition reporting.
@@ -46,6 +56,7 @@ const (
 	ErrorTypeConfiguration = "ConfigurationError"
 	ErrorTypeInfra         = "InfraError"
 	ErrorTypeNone          = "Reconciled"
+	ErrorTypeReload        = "ReloadError"
 	ErrorTypeUnknown       = "UnknownError"
 )
 
@@ -125,13 +125,28 @@ type Config struct {
 	WithFRRK8s              bool
 	FRRK8sNamespace         string
 	FRRK8sSecretPassthrough bool
+	// FRRK8sSessionStatesHandler receives the state of the BGP sessions
+	// reported by FRR-K8s, when running with it.
+	FRRK8sSessionStatesHandler controllers.FRRK8sSessionStatesHandler
 	Listener
+	// NodeChan triggers the reconciliation of the node, for the changes
+	// on the node that are not reflected on its object.
+	NodeChan            <-chan event.GenericEvent
 	Layer2StatusChan    <-chan event.GenericEvent
 	Layer2StatusFetcher controllers.L2StatusFetcher
 	BGPStatusChan       <-chan event.GenericEvent
 	BGPPeersFetcher     controllers.PeersForService
-	PoolStatusChan      <-chan event.GenericEvent
-	PoolCountersFetcher controllers.PoolCountersFetcher
+	BGPPrefixesFetcher  controllers.PrefixesForService
+	// BGPSessionStateChan is nil when the BGP implementation does not
+	// report the state of its sessions.
+	BGPSessionStateChan     <-chan event.GenericEvent
+	BGPSessionStatesFetcher controllers.BGPSessionStatesFetcher
+	// BGPReloadStatusChan is nil when the BGP implementation does not
+	// report the outcome of its configuration reloads.
+	BGPReloadStatusChan    <-chan event.GenericEvent
+	BGPReloadStatusFetcher controllers.BGPReloadStatusFetcher
+	PoolStatusChan         <-chan event.GenericEvent
+	PoolCountersFetcher    controllers.PoolCountersFetcher
 }
 
 // New connects to masterAddr, using kubeconfig to authenticate.
@@ -144,16 +159,19 @@ func New(cfg *Config) (*Client, error) {
 	}
 
 	objectsPerNamespace := map[client.Object]cache.ByObject{
-		&metallbv1beta1.BFDProfile{}:       namespaceSelector,
-		&metallbv1beta1.BGPAdvertisement{}: namespaceSelector,
-		&metallbv1beta1.BGPPeer{}:          namespaceSelector,
-		&metallbv1beta1.IPAddressPool{}:    namespaceSelector,
-		&metallbv1beta1.L2Advertisement{}:  namespaceSelector,
-		&metallbv1beta2.BGPPeer{}:          namespaceSelector,
-		&metallbv1beta1.Community{}:        namespaceSelector,
-		&metallbv1beta1.ServiceBGPStatus{}: namespaceSelector,
-		&corev1.Secret{}:                   namespaceSelector,
-		&corev1.ConfigMap{}:                namespaceSelector,
+		&metallbv1beta1.BFDProfile{}:          namespaceSelector,
+		&metallbv1beta1.BGPAdvertisement{}:    namespaceSelector,
+		&metallbv1beta1.BGPPeer{}:             namespaceSelector,
+		&metallbv1beta1.IPAddressPool{}:       namespaceSelector,
+		&metallbv1beta1.L2Advertisement{}:     namespaceSelector,
+		&metallbv1beta2.BGPPeer{}:             namespaceSelector,
+		&metallbv1beta2.BGPPeerTemplate{}:     namespaceSelector,
+		&metallbv1beta1.Community{}:           namespaceSelector,
+		&metallbv1beta1.BGPExtras{}:           namespaceSelector,
+		&metallbv1beta1.ServiceBGPStatus{}:    namespaceSelector,
+		&metallbv1beta1.BGPPeerSessionState{}: namespaceSelector,
+		&corev1.Secret{}:                      namespaceSelector,
+		&corev1.ConfigMap{}:                   namespaceSelector,
 	}
 
 	metricsOpts := metricsserver.Options{
@@ -265,12 +283,13 @@ func New(cfg *Config) (*Client, error) {
 
 	if cfg.NodeChanged != nil {
 		if err = (&controllers.NodeReconciler{
-			Client:      mgr.GetClient(),
-			Logger:      cfg.Logger,
-			Scheme:      mgr.GetScheme(),
-			Handler:     cfg.NodeHandler,
-			NodeName:    cfg.NodeName,
-			ForceReload: reload,
+			Client:        mgr.GetClient(),
+			Logger:        cfg.Logger,
+			Scheme:        mgr.GetScheme(),
+			Handler:       cfg.NodeHandler,
+			NodeName:      cfg.NodeName,
+			ForceReload:   reload,
+			ReconcileChan: cfg.NodeChan,
 		}).SetupWithManager(mgr); err != nil {
 			level.Error(c.logger).Log("error", err, "unable to create controller", "node")
 			return nil, errors.Join(err, errors.New("failed to create node reconciler"))
@@ -283,6 +302,7 @@ func New(cfg *Config) (*Client, error) {
 			Logger:          cfg.Logger,
 			Scheme:          mgr.GetScheme(),
 			FRRK8sNamespace: cfg.FRRK8sNamespace,
+			Namespace:       cfg.Namespace,
 			NodeName:        cfg.NodeName,
 		}
 		if err := frrk8sController.SetupWithManager(mgr); err != nil {
@@ -290,6 +310,19 @@ func New(cfg *Config) (*Client, error) {
 			return nil, errors.Join(err, errors.New("failed to create frrk8s reconciler"))
 		}
 		c.BGPEventCallback = frrk8sController.UpdateConfig
+
+		if cfg.FRRK8sSessionStatesHandler != nil {
+			if err = (&controllers.FRRK8sSessionStateReconciler{
+				Client:          mgr.GetClient(),
+				Logger:          cfg.Logger,
+				NodeName:        cfg.NodeName,
+				FRRK8sNamespace: cfg.FRRK8sNamespace,
+				Handler:         cfg.FRRK8sSessionStatesHandler,
+			}).SetupWithManager(mgr); err != nil {
+				level.Error(c.logger).Log("error", err, "unable to create controller", "frrk8sSessionState")
+				return nil, errors.Join(err, errors.New("failed to create frrk8s session state reconciler"))
+			}
+		}
 	}
 
 	if cfg.ReadEndpoints {
@@ -361,15 +394,44 @@ func New(cfg *Config) (*Client, error) {
 
 	if cfg.BGPStatusChan != nil {
 		if err = (&controllers.ServiceBGPStatusReconciler{
+			Client:          mgr.GetClient(),
+			Logger:          cfg.Logger,
+			NodeName:        cfg.NodeName,
+			Namespace:       cfg.Namespace,
+			SpeakerPod:      selfPod.DeepCopy(),
+			ReconcileChan:   cfg.BGPStatusChan,
+			PeersFetcher:    cfg.BGPPeersFetcher,
+			PrefixesFetcher: cfg.BGPPrefixesFetcher,
+		}).SetupWithManager(mgr); err != nil {
+			level.Error(c.logger).Log("error", err, "unable to create controller", "layer2Status")
+		}
+	}
+
+	if cfg.BGPSessionStateChan != nil {
+		if err = (&controllers.BGPPeerSessionStateReconciler{
 			Client:        mgr.GetClient(),
 			Logger:        cfg.Logger,
 			NodeName:      cfg.NodeName,
 			Namespace:     cfg.Namespace,
 			SpeakerPod:    selfPod.DeepCopy(),
-			ReconcileChan: cfg.BGPStatusChan,
-			PeersFetcher:  cfg.BGPPeersFetcher,
+			ReconcileChan: cfg.BGPSessionStateChan,
+			StatesFetcher: cfg.BGPSessionStatesFetcher,
+			Recorder:      recorder,
 		}).SetupWithManager(mgr); err != nil {
-			level.Error(c.logger).Log("error", err, "unable to create controller", "layer2Status")
+			level.Error(c.logger).Log("error", err, "unable to create controller", "bgpPeerSessionState")
+		}
+	}
+
+	if cfg.BGPReloadStatusChan != nil {
+		if err = (&controllers.BGPReloadStatusReconciler{
+			Client:          mgr.GetClient(),
+			Logger:          cfg.Logger,
+			Namespace:       cfg.Namespace,
+			ConfigStateName: configStateName,
+			ReconcileChan:   cfg.BGPReloadStatusChan,
+			StatusFetcher:   cfg.BGPReloadStatusFetcher,
+		}).SetupWithManager(mgr); err != nil {
+			level.Error(c.logger).Log("error", err, "unable to create controller", "bgpReloadStatus")
 		}
 	}
 
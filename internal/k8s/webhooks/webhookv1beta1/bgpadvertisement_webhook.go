@@ -25,6 +25,7 @@ import (
 
 	"github.com/go-kit/log/level"
 	"go.universe.tf/metallb/api/v1beta1"
+	"go.universe.tf/metallb/internal/k8s/webhooks/webhookv1beta2"
 	admissionv1 "k8s.io/api/admission/v1"
 	v1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -115,8 +116,18 @@ func validateBGPAdvCreate(bgpAdv *v1beta1.BGPAdvertisement) error {
 		return err
 	}
 
+	// The peers are needed to validate the conditions of the advertisement.
+	peers, err := webhookv1beta2.GetExistingBGPPeers()
+	if err != nil {
+		return err
+	}
+	peerTemplates, err := webhookv1beta2.GetExistingBGPPeerTemplates()
+	if err != nil {
+		return err
+	}
+
 	toValidate := bgpAdvListWithUpdate(existingBGPAdvList, bgpAdv)
-	err = Validator.Validate(toValidate, ipAddressPools, nodes)
+	err = Validator.Validate(toValidate, ipAddressPools, nodes, peers, peerTemplates)
 	if err != nil {
 		level.Error(Logger).Log("webhook", "bgpadvertisement", "action", "create", "name", bgpAdv.Name, "namespace", bgpAdv.Namespace, "error", err)
 		return err
@@ -143,8 +154,17 @@ func validateBGPAdvUpdate(bgpAdv *v1beta1.BGPAdvertisement, _ *v1beta1.BGPAdvert
 		return err
 	}
 
+	peers, err := webhookv1beta2.GetExistingBGPPeers()
+	if err != nil {
+		return err
+	}
+	peerTemplates, err := webhookv1beta2.GetExistingBGPPeerTemplates()
+	if err != nil {
+		return err
+	}
+
 	toValidate := bgpAdvListWithUpdate(bgpAdvs, bgpAdv)
-	err = Validator.Validate(toValidate, ipAddressPools, nodes)
+	err = Validator.Validate(toValidate, ipAddressPools, nodes, peers, peerTemplates)
 	if err != nil {
 		level.Error(Logger).Log("webhook", "bgpadvertisement", "action", "create", "name", bgpAdv.Name, "namespace", bgpAdv.Namespace, "error", err)
 		return err
@@ -8,6 +8,8 @@ import (
 	"github.com/go-kit/log"
 	"github.com/google/go-cmp/cmp"
 	"go.universe.tf/metallb/api/v1beta1"
+	"go.universe.tf/metallb/api/v1beta2"
+	"go.universe.tf/metallb/internal/k8s/webhooks/webhookv1beta2"
 	v1core "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -39,11 +41,31 @@ func TestValidateBGPAdvertisement(t *testing.T) {
 	getExistingNodes = func() (*v1core.NodeList, error) {
 		return &v1core.NodeList{}, nil
 	}
+	peers := &v1beta2.BGPPeerList{
+		Items: []v1beta2.BGPPeer{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-peer",
+					Namespace: MetalLBTestNameSpace,
+				},
+			},
+		},
+	}
+	toRestoreBGPPeers := webhookv1beta2.GetExistingBGPPeers
+	webhookv1beta2.GetExistingBGPPeers = func() (*v1beta2.BGPPeerList, error) {
+		return peers, nil
+	}
+	toRestoreBGPPeerTemplates := webhookv1beta2.GetExistingBGPPeerTemplates
+	webhookv1beta2.GetExistingBGPPeerTemplates = func() (*v1beta2.BGPPeerTemplateList, error) {
+		return &v1beta2.BGPPeerTemplateList{}, nil
+	}
 
 	defer func() {
 		getExistingBGPAdvs = toRestore
 		getExistingIPAddressPools = toRestoreIPAddressPools
 		getExistingNodes = toRestoreNodes
+		webhookv1beta2.GetExistingBGPPeers = toRestoreBGPPeers
+		webhookv1beta2.GetExistingBGPPeerTemplates = toRestoreBGPPeerTemplates
 	}()
 
 	tests := []struct {
@@ -150,5 +172,8 @@ func TestValidateBGPAdvertisement(t *testing.T) {
 		if !cmp.Equal(test.expected, mock.bgpAdvs) {
 			t.Fatalf("test %s failed, %s", test.desc, cmp.Diff(test.expected, mock.bgpAdvs))
 		}
+		if test.expected != nil && !cmp.Equal(peers, mock.bgpPeers) {
+			t.Fatalf("test %s failed, the peers were not validated: %s", test.desc, cmp.Diff(peers, mock.bgpPeers))
+		}
 	}
 }
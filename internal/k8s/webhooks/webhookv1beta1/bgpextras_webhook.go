@@ -0,0 +1,159 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhookv1beta1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"errors"
+
+	"github.com/go-kit/log/level"
+	"go.universe.tf/metallb/api/v1beta1"
+	v1 "k8s.io/api/admission/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const bgpExtrasWebhookPath = "/validate-metallb-io-v1beta1-bgpextras"
+
+func (v *BGPExtrasValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	v.client = mgr.GetClient()
+	v.decoder = admission.NewDecoder(mgr.GetScheme())
+
+	mgr.GetWebhookServer().Register(
+		bgpExtrasWebhookPath,
+		&webhook.Admission{Handler: v})
+
+	return nil
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-metallb-io-v1beta1-bgpextras,mutating=false,failurePolicy=fail,groups=metallb.io,resources=bgpextras,versions=v1beta1,name=bgpextrasvalidationwebhook.metallb.io,sideEffects=None,admissionReviewVersions=v1
+type BGPExtrasValidator struct {
+	ClusterResourceNamespace string
+
+	client  client.Client
+	decoder admission.Decoder
+}
+
+// Handle handled incoming admission requests for BGPExtras objects.
+func (v *BGPExtrasValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var extras v1beta1.BGPExtras
+	var oldExtras v1beta1.BGPExtras
+	if req.Operation == v1.Delete {
+		if err := v.decoder.DecodeRaw(req.OldObject, &extras); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	} else {
+		if err := v.decoder.Decode(req, &extras); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if req.OldObject.Size() > 0 {
+			if err := v.decoder.DecodeRaw(req.OldObject, &oldExtras); err != nil {
+				return admission.Errored(http.StatusBadRequest, err)
+			}
+		}
+	}
+
+	switch req.Operation {
+	case v1.Create:
+		err := validateBGPExtrasCreate(&extras)
+		if err != nil {
+			return admission.Denied(err.Error())
+		}
+	case v1.Update:
+		err := validateBGPExtrasUpdate(&extras, &oldExtras)
+		if err != nil {
+			return admission.Denied(err.Error())
+		}
+	case v1.Delete:
+		err := validateBGPExtrasDelete(&extras)
+		if err != nil {
+			return admission.Denied(err.Error())
+		}
+	}
+	return admission.Allowed("")
+}
+
+// validateBGPExtrasCreate implements webhook.Validator so a webhook will be registered for BGPExtras.
+func validateBGPExtrasCreate(extras *v1beta1.BGPExtras) error {
+	level.Debug(Logger).Log("webhook", "bgpextras", "action", "create", "name", extras.Name, "namespace", extras.Namespace)
+
+	if extras.Namespace != MetalLBNamespace {
+		return fmt.Errorf("resource must be created in %s namespace", MetalLBNamespace)
+	}
+
+	existingExtrasList, err := getExistingBGPExtras()
+	if err != nil {
+		return err
+	}
+
+	extrasList := bgpExtrasListWithUpdate(existingExtrasList, extras)
+	err = Validator.Validate(extrasList)
+	if err != nil {
+		level.Error(Logger).Log("webhook", "bgpextras", "action", "create", "name", extras.Name, "namespace", extras.Namespace, "error", err)
+		return err
+	}
+	return nil
+}
+
+// validateBGPExtrasUpdate implements webhook.Validator so a webhook will be registered for BGPExtras.
+func validateBGPExtrasUpdate(extras *v1beta1.BGPExtras, _ *v1beta1.BGPExtras) error {
+	level.Debug(Logger).Log("webhook", "bgpextras", "action", "update", "name", extras.Name, "namespace", extras.Namespace)
+
+	existingExtrasList, err := getExistingBGPExtras()
+	if err != nil {
+		return err
+	}
+
+	extrasList := bgpExtrasListWithUpdate(existingExtrasList, extras)
+	err = Validator.Validate(extrasList)
+	if err != nil {
+		level.Error(Logger).Log("webhook", "bgpextras", "action", "update", "name", extras.Name, "namespace", extras.Namespace, "error", err)
+		return err
+	}
+	return nil
+}
+
+// validateBGPExtrasDelete implements webhook.Validator so a webhook will be registered for BGPExtras.
+func validateBGPExtrasDelete(extras *v1beta1.BGPExtras) error {
+	return nil
+}
+
+var getExistingBGPExtras = func() (*v1beta1.BGPExtrasList, error) {
+	existingExtrasList := &v1beta1.BGPExtrasList{}
+	err := WebhookClient.List(context.Background(), existingExtrasList, &client.ListOptions{Namespace: MetalLBNamespace})
+	if err != nil {
+		return nil, errors.Join(err, errors.New("failed to get existing BGPExtras objects"))
+	}
+	return existingExtrasList, nil
+}
+
+func bgpExtrasListWithUpdate(existing *v1beta1.BGPExtrasList, toAdd *v1beta1.BGPExtras) *v1beta1.BGPExtrasList {
+	res := existing.DeepCopy()
+	for i, item := range res.Items { // We override the element with the fresh copy
+		if item.Name == toAdd.Name {
+			res.Items[i] = *toAdd.DeepCopy()
+			return res
+		}
+	}
+	res.Items = append(res.Items, *toAdd.DeepCopy())
+	return res
+}
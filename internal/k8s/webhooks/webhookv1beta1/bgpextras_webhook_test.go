@@ -0,0 +1,124 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package webhookv1beta1
+
+import (
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/google/go-cmp/cmp"
+	"go.universe.tf/metallb/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateBGPExtras(t *testing.T) {
+	MetalLBNamespace = MetalLBTestNameSpace
+	Logger = log.NewNopLogger()
+
+	existing := v1beta1.BGPExtras{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-extras1",
+			Namespace: MetalLBTestNameSpace,
+		},
+		Spec: v1beta1.BGPExtrasSpec{
+			BestPath: &v1beta1.BestPathExtras{ASPathMultipathRelax: true},
+		},
+	}
+	toRestoreExtras := getExistingBGPExtras
+	getExistingBGPExtras = func() (*v1beta1.BGPExtrasList, error) {
+		return &v1beta1.BGPExtrasList{
+			Items: []v1beta1.BGPExtras{*existing.DeepCopy()},
+		}, nil
+	}
+	defer func() {
+		getExistingBGPExtras = toRestoreExtras
+	}()
+
+	second := v1beta1.BGPExtras{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-extras2",
+			Namespace: MetalLBTestNameSpace,
+		},
+		Spec: v1beta1.BGPExtrasSpec{
+			Neighbors: []v1beta1.NeighborExtras{
+				{Peer: "peer1"},
+			},
+		},
+	}
+	updated := v1beta1.BGPExtras{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-extras1",
+			Namespace: MetalLBTestNameSpace,
+		},
+		Spec: v1beta1.BGPExtrasSpec{
+			Raw: "ip prefix-list extra seq 1 permit 192.168.10.0/32",
+		},
+	}
+
+	tests := []struct {
+		desc         string
+		extras       *v1beta1.BGPExtras
+		isNew        bool
+		failValidate bool
+		expected     *v1beta1.BGPExtrasList
+	}{
+		{
+			desc:   "Second BGPExtras",
+			extras: &second,
+			isNew:  true,
+			expected: &v1beta1.BGPExtrasList{
+				Items: []v1beta1.BGPExtras{existing, second},
+			},
+		},
+		{
+			desc:   "Same BGPExtras, update",
+			extras: &updated,
+			isNew:  false,
+			expected: &v1beta1.BGPExtrasList{
+				Items: []v1beta1.BGPExtras{updated},
+			},
+		},
+		{
+			desc:   "Validation fails",
+			extras: &second,
+			isNew:  true,
+			expected: &v1beta1.BGPExtrasList{
+				Items: []v1beta1.BGPExtras{existing, second},
+			},
+			failValidate: true,
+		},
+		{
+			desc: "Validation must fail if created in different namespace",
+			extras: &v1beta1.BGPExtras{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-extras2",
+					Namespace: "default",
+				},
+			},
+			isNew:        true,
+			expected:     nil,
+			failValidate: true,
+		},
+	}
+	for _, test := range tests {
+		var err error
+		mock := &mockValidator{}
+		Validator = mock
+		mock.forceError = test.failValidate
+
+		if test.isNew {
+			err = validateBGPExtrasCreate(test.extras)
+		} else {
+			err = validateBGPExtrasUpdate(test.extras, nil)
+		}
+		if test.failValidate && err == nil {
+			t.Fatalf("test %s failed, expecting error", test.desc)
+		}
+		if !test.failValidate && err != nil {
+			t.Fatalf("test %s failed, unexpected error %s", test.desc, err)
+		}
+		if !cmp.Equal(test.expected, mock.bgpExtras) {
+			t.Fatalf("test %s failed, %s", test.desc, cmp.Diff(test.expected, mock.bgpExtras))
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 
 	"go.universe.tf/metallb/api/v1beta1"
+	"go.universe.tf/metallb/api/v1beta2"
 	v1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -15,6 +16,9 @@ type mockValidator struct {
 	bgpAdvs        *v1beta1.BGPAdvertisementList
 	l2Advs         *v1beta1.L2AdvertisementList
 	communities    *v1beta1.CommunityList
+	bgpExtras      *v1beta1.BGPExtrasList
+	bgpPeers       *v1beta2.BGPPeerList
+	peerTemplates  *v1beta2.BGPPeerTemplateList
 	nodes          *v1.NodeList
 	forceError     bool
 }
@@ -30,6 +34,12 @@ func (m *mockValidator) Validate(objects ...client.ObjectList) error {
 			m.ipAddressPools = list
 		case *v1beta1.CommunityList:
 			m.communities = list
+		case *v1beta1.BGPExtrasList:
+			m.bgpExtras = list
+		case *v1beta2.BGPPeerList:
+			m.bgpPeers = list
+		case *v1beta2.BGPPeerTemplateList:
+			m.peerTemplates = list
 		case *v1.NodeList:
 			m.nodes = list
 		default:
@@ -108,11 +108,15 @@ func validatePeerCreate(bgpPeer *v1beta2.BGPPeer) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	existingTemplates, err := GetExistingBGPPeerTemplates()
+	if err != nil {
+		return "", err
+	}
 	existingNodes, err := getExistingNodes()
 	if err != nil {
 		return "", err
 	}
-	validateArgs := peersAndNodesToObjects(bgpPeer, existingPeers, existingNodes)
+	validateArgs := peersAndNodesToObjects(bgpPeer, existingPeers, existingTemplates, existingNodes)
 	err = Validator.Validate(validateArgs...)
 	if err != nil {
 		level.Error(Logger).Log("webhook", "bgppeer", "action", "create", "name", bgpPeer.Name, "namespace", bgpPeer.Namespace, "error", err)
@@ -129,11 +133,15 @@ func validatePeerUpdate(bgpPeer *v1beta2.BGPPeer, _ *v1beta2.BGPPeer) error {
 	if err != nil {
 		return err
 	}
+	existingTemplates, err := GetExistingBGPPeerTemplates()
+	if err != nil {
+		return err
+	}
 	existingNodes, err := getExistingNodes()
 	if err != nil {
 		return err
 	}
-	validateArgs := peersAndNodesToObjects(bgpPeer, existingPeers, existingNodes)
+	validateArgs := peersAndNodesToObjects(bgpPeer, existingPeers, existingTemplates, existingNodes)
 	err = Validator.Validate(validateArgs...)
 	if err != nil {
 		level.Error(Logger).Log("webhook", "bgppeer", "action", "update", "name", bgpPeer.Name, "namespace", bgpPeer.Namespace, "error", err)
@@ -143,10 +151,11 @@ func validatePeerUpdate(bgpPeer *v1beta2.BGPPeer, _ *v1beta2.BGPPeer) error {
 }
 
 // peersAndNodesToObjects combines the given BGPPeer with existing peers and
-// converts them along with the node list into the ObjectList slice for validation.
-func peersAndNodesToObjects(bgpPeer *v1beta2.BGPPeer, existingBGPPeers *v1beta2.BGPPeerList, existingNodes *corev1.NodeList) []client.ObjectList {
+// converts them along with the peer templates and the node list into the
+// ObjectList slice for validation.
+func peersAndNodesToObjects(bgpPeer *v1beta2.BGPPeer, existingBGPPeers *v1beta2.BGPPeerList, existingTemplates *v1beta2.BGPPeerTemplateList, existingNodes *corev1.NodeList) []client.ObjectList {
 	toValidate := bgpPeerListWithUpdate(existingBGPPeers, bgpPeer)
-	return []client.ObjectList{toValidate, existingNodes}
+	return []client.ObjectList{toValidate, existingTemplates, existingNodes}
 }
 
 // validatePeerDelete implements webhook.Validator so a webhook will be registered for BGPPeer.
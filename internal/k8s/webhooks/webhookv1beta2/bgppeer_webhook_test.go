@@ -33,6 +33,10 @@ func TestValidateBGPPeer(t *testing.T) {
 			},
 		}, nil
 	}
+	toRestoreTemplates := GetExistingBGPPeerTemplates
+	GetExistingBGPPeerTemplates = func() (*v1beta2.BGPPeerTemplateList, error) {
+		return &v1beta2.BGPPeerTemplateList{}, nil
+	}
 	toRestoreNodes := getExistingNodes
 	getExistingNodes = func() (*corev1.NodeList, error) {
 		return &corev1.NodeList{}, nil
@@ -41,6 +45,7 @@ func TestValidateBGPPeer(t *testing.T) {
 
 	defer func() {
 		GetExistingBGPPeers = toRestorePeers
+		GetExistingBGPPeerTemplates = toRestoreTemplates
 		getExistingNodes = toRestoreNodes
 		Validator = toRestoreValidator
 	}()
@@ -180,10 +185,15 @@ func TestValidateBGPPeerPassesNodesToValidator(t *testing.T) {
 	GetExistingBGPPeers = func() (*v1beta2.BGPPeerList, error) {
 		return &v1beta2.BGPPeerList{Items: []v1beta2.BGPPeer{*existingPeer}}, nil
 	}
+	toRestoreTemplates := GetExistingBGPPeerTemplates
+	GetExistingBGPPeerTemplates = func() (*v1beta2.BGPPeerTemplateList, error) {
+		return &v1beta2.BGPPeerTemplateList{}, nil
+	}
 	toRestoreNodes := getExistingNodes
 	getExistingNodes = func() (*corev1.NodeList, error) { return nodes, nil }
 	defer func() {
 		GetExistingBGPPeers = toRestorePeers
+		GetExistingBGPPeerTemplates = toRestoreTemplates
 		getExistingNodes = toRestoreNodes
 	}()
 
@@ -0,0 +1,159 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package webhookv1beta2
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-kit/log/level"
+	"go.universe.tf/metallb/api/v1beta2"
+	v1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const bgpPeerTemplateWebhookPath = "/validate-metallb-io-v1beta2-bgppeertemplate"
+
+func (v *BGPPeerTemplateValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	v.client = mgr.GetClient()
+	v.decoder = admission.NewDecoder(mgr.GetScheme())
+
+	mgr.GetWebhookServer().Register(
+		bgpPeerTemplateWebhookPath,
+		&webhook.Admission{Handler: v})
+
+	return nil
+}
+
+// +kubebuilder:webhook:verbs=create;update;delete,path=/validate-metallb-io-v1beta2-bgppeertemplate,mutating=false,failurePolicy=fail,groups=metallb.io,resources=bgppeertemplates,versions=v1beta2,name=bgppeertemplatesvalidationwebhook.metallb.io,sideEffects=None,admissionReviewVersions=v1
+type BGPPeerTemplateValidator struct {
+	ClusterResourceNamespace string
+
+	client  client.Client
+	decoder admission.Decoder
+}
+
+// Handle handled incoming admission requests for BGPPeerTemplate objects.
+func (v *BGPPeerTemplateValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var template v1beta2.BGPPeerTemplate
+	if req.Operation == v1.Delete {
+		if err := v.decoder.DecodeRaw(req.OldObject, &template); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	} else {
+		if err := v.decoder.Decode(req, &template); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+
+	switch req.Operation {
+	case v1.Create:
+		err := validatePeerTemplateCreate(&template)
+		if err != nil {
+			return admission.Denied(err.Error())
+		}
+	case v1.Update:
+		err := validatePeerTemplateUpdate(&template)
+		if err != nil {
+			return admission.Denied(err.Error())
+		}
+	case v1.Delete:
+		err := validatePeerTemplateDelete(&template)
+		if err != nil {
+			return admission.Denied(err.Error())
+		}
+	}
+	return admission.Allowed("")
+}
+
+// validatePeerTemplateCreate implements webhook.Validator so a webhook will be registered for BGPPeerTemplate.
+func validatePeerTemplateCreate(template *v1beta2.BGPPeerTemplate) error {
+	level.Debug(Logger).Log("webhook", "bgppeertemplate", "action", "create", "name", template.Name, "namespace", template.Namespace)
+
+	if template.Namespace != MetalLBNamespace {
+		return fmt.Errorf("resource must be created in %s namespace", MetalLBNamespace)
+	}
+
+	return validatePeerTemplate(template, "create")
+}
+
+// validatePeerTemplateUpdate implements webhook.Validator so a webhook will be registered for BGPPeerTemplate.
+func validatePeerTemplateUpdate(template *v1beta2.BGPPeerTemplate) error {
+	level.Debug(Logger).Log("webhook", "bgppeertemplate", "action", "update", "name", template.Name, "namespace", template.Namespace)
+
+	return validatePeerTemplate(template, "update")
+}
+
+// validatePeerTemplate validates the existing peers merged with the given template.
+func validatePeerTemplate(template *v1beta2.BGPPeerTemplate, action string) error {
+	existingPeers, err := GetExistingBGPPeers()
+	if err != nil {
+		return err
+	}
+	existingTemplates, err := GetExistingBGPPeerTemplates()
+	if err != nil {
+		return err
+	}
+	existingNodes, err := getExistingNodes()
+	if err != nil {
+		return err
+	}
+	validateArgs := peerTemplatesAndNodesToObjects(template, existingPeers, existingTemplates, existingNodes)
+	err = Validator.Validate(validateArgs...)
+	if err != nil {
+		level.Error(Logger).Log("webhook", "bgppeertemplate", "action", action, "name", template.Name, "namespace", template.Namespace, "error", err)
+		return err
+	}
+	return nil
+}
+
+// validatePeerTemplateDelete implements webhook.Validator so a webhook will be registered for BGPPeerTemplate.
+func validatePeerTemplateDelete(template *v1beta2.BGPPeerTemplate) error {
+	level.Debug(Logger).Log("webhook", "bgppeertemplate", "action", "delete", "name", template.Name, "namespace", template.Namespace)
+
+	existingPeers, err := GetExistingBGPPeers()
+	if err != nil {
+		return err
+	}
+
+	for _, peer := range existingPeers.Items {
+		if template.Name == peer.Spec.PeerTemplate {
+			return fmt.Errorf("failed to delete BGPPeerTemplate %s, used by BGPPeer %s", template.Name, peer.Name)
+		}
+	}
+	return nil
+}
+
+// peerTemplatesAndNodesToObjects combines the given BGPPeerTemplate with existing
+// templates and converts them along with the peers and the node list into the
+// ObjectList slice for validation.
+func peerTemplatesAndNodesToObjects(template *v1beta2.BGPPeerTemplate, existingBGPPeers *v1beta2.BGPPeerList, existingTemplates *v1beta2.BGPPeerTemplateList, existingNodes *corev1.NodeList) []client.ObjectList {
+	toValidate := bgpPeerTemplateListWithUpdate(existingTemplates, template)
+	return []client.ObjectList{existingBGPPeers, toValidate, existingNodes}
+}
+
+var GetExistingBGPPeerTemplates = func() (*v1beta2.BGPPeerTemplateList, error) {
+	existingTemplates := &v1beta2.BGPPeerTemplateList{}
+	err := WebhookClient.List(context.Background(), existingTemplates, &client.ListOptions{Namespace: MetalLBNamespace})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing BGPPeerTemplate objects: %w", err)
+	}
+	return existingTemplates, nil
+}
+
+func bgpPeerTemplateListWithUpdate(existing *v1beta2.BGPPeerTemplateList, toAdd *v1beta2.BGPPeerTemplate) *v1beta2.BGPPeerTemplateList {
+	res := existing.DeepCopy()
+	for i, item := range res.Items { // We override the element with the fresh copy
+		if item.Name == toAdd.Name {
+			res.Items[i] = *toAdd.DeepCopy()
+			return res
+		}
+	}
+	res.Items = append(res.Items, *toAdd.DeepCopy())
+	return res
+}
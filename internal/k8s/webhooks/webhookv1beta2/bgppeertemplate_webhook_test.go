@@ -0,0 +1,193 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package webhookv1beta2
+
+import (
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/google/go-cmp/cmp"
+	"go.universe.tf/metallb/api/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateBGPPeerTemplate(t *testing.T) {
+	MetalLBNamespace = testNamespace
+	Logger = log.NewNopLogger()
+
+	template := v1beta2.BGPPeerTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-template",
+			Namespace: testNamespace,
+		},
+	}
+	peers := &v1beta2.BGPPeerList{
+		Items: []v1beta2.BGPPeer{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-peer",
+					Namespace: testNamespace,
+				},
+				Spec: v1beta2.BGPPeerSpec{
+					PeerTemplate: "test-template",
+				},
+			},
+		},
+	}
+
+	toRestorePeers := GetExistingBGPPeers
+	GetExistingBGPPeers = func() (*v1beta2.BGPPeerList, error) {
+		return peers, nil
+	}
+	toRestoreTemplates := GetExistingBGPPeerTemplates
+	GetExistingBGPPeerTemplates = func() (*v1beta2.BGPPeerTemplateList, error) {
+		return &v1beta2.BGPPeerTemplateList{
+			Items: []v1beta2.BGPPeerTemplate{
+				template,
+			},
+		}, nil
+	}
+	toRestoreNodes := getExistingNodes
+	getExistingNodes = func() (*corev1.NodeList, error) {
+		return &corev1.NodeList{}, nil
+	}
+	toRestoreValidator := Validator
+
+	defer func() {
+		GetExistingBGPPeers = toRestorePeers
+		GetExistingBGPPeerTemplates = toRestoreTemplates
+		getExistingNodes = toRestoreNodes
+		Validator = toRestoreValidator
+	}()
+
+	const (
+		isNew int = iota
+		isUpdate
+		isDel
+	)
+	tests := []struct {
+		desc         string
+		template     *v1beta2.BGPPeerTemplate
+		validateType int
+		failValidate bool
+		expected     *v1beta2.BGPPeerTemplateList
+	}{
+		{
+			desc: "Second template",
+			template: &v1beta2.BGPPeerTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: testNamespace,
+				},
+			},
+			validateType: isNew,
+			expected: &v1beta2.BGPPeerTemplateList{
+				Items: []v1beta2.BGPPeerTemplate{
+					template,
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "test",
+							Namespace: testNamespace,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Same, update",
+			template: &v1beta2.BGPPeerTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-template",
+					Namespace: testNamespace,
+				},
+				Spec: v1beta2.BGPPeerTemplateSpec{
+					MyASN: 64512,
+				},
+			},
+			validateType: isUpdate,
+			expected: &v1beta2.BGPPeerTemplateList{
+				Items: []v1beta2.BGPPeerTemplate{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "test-template",
+							Namespace: testNamespace,
+						},
+						Spec: v1beta2.BGPPeerTemplateSpec{
+							MyASN: 64512,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Validation failed",
+			template: &v1beta2.BGPPeerTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-template",
+					Namespace: testNamespace,
+				},
+			},
+			validateType: isUpdate,
+			expected: &v1beta2.BGPPeerTemplateList{
+				Items: []v1beta2.BGPPeerTemplate{
+					template,
+				},
+			},
+			failValidate: true,
+		},
+		{
+			desc: "Validation must fail if created in different namespace",
+			template: &v1beta2.BGPPeerTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-template1",
+					Namespace: "default",
+				},
+			},
+			validateType: isNew,
+			failValidate: true,
+		},
+		{
+			desc:         "Delete template used by bgppeer",
+			template:     &template,
+			validateType: isDel,
+			failValidate: true,
+		},
+		{
+			desc: "Delete unused template",
+			template: &v1beta2.BGPPeerTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "unused",
+					Namespace: testNamespace,
+				},
+			},
+			validateType: isDel,
+		},
+	}
+	for _, test := range tests {
+		var err error
+		mock := &mockValidator{}
+		Validator = mock
+		mock.forceError = test.failValidate
+		switch test.validateType {
+		case isNew:
+			err = validatePeerTemplateCreate(test.template)
+		case isUpdate:
+			err = validatePeerTemplateUpdate(test.template)
+		case isDel:
+			err = validatePeerTemplateDelete(test.template)
+		}
+		if test.failValidate && err == nil {
+			t.Fatalf("test %s failed, expecting error", test.desc)
+		}
+		if !test.failValidate && err != nil {
+			t.Fatalf("test %s failed, unexpected error %s", test.desc, err)
+		}
+		if !cmp.Equal(test.expected, mock.peerTemplates) {
+			t.Fatalf("test %s failed, %s", test.desc, cmp.Diff(test.expected, mock.peerTemplates))
+		}
+		if test.expected != nil && !cmp.Equal(peers, mock.bgpPeers) {
+			t.Fatalf("test %s failed, the peers were not validated: %s", test.desc, cmp.Diff(peers, mock.bgpPeers))
+		}
+	}
+}
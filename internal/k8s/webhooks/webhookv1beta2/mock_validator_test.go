@@ -11,9 +11,10 @@ import (
 )
 
 type mockValidator struct {
-	bgpPeers   *v1beta2.BGPPeerList
-	nodes      *corev1.NodeList
-	forceError bool
+	bgpPeers      *v1beta2.BGPPeerList
+	peerTemplates *v1beta2.BGPPeerTemplateList
+	nodes         *corev1.NodeList
+	forceError    bool
 }
 
 func (m *mockValidator) Validate(objects ...client.ObjectList) error {
@@ -21,6 +22,8 @@ func (m *mockValidator) Validate(objects ...client.ObjectList) error {
 		switch list := obj.(type) {
 		case *v1beta2.BGPPeerList:
 			m.bgpPeers = list
+		case *v1beta2.BGPPeerTemplateList:
+			m.peerTemplates = list
 		case *corev1.NodeList:
 			m.nodes = list
 		default:
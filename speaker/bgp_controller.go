@@ -17,14 +17,20 @@ package main
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"net"
 	"reflect"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	frrv1beta1 "github.com/metallb/frr-k8s/api/v1beta1"
+	"go.universe.tf/metallb/api/v1beta1"
 	"go.universe.tf/metallb/internal/bgp"
+	"go.universe.tf/metallb/internal/bgp/community"
 	bgpfrr "go.universe.tf/metallb/internal/bgp/frr"
 	bgpfrrk8s "go.universe.tf/metallb/internal/bgp/frrk8s"
 	bgpnative "go.universe.tf/metallb/internal/bgp/native"
@@ -58,24 +64,45 @@ type peer struct {
 	cfg     *config.Peer
 	session bgp.Session
 	id      string
+	// The address the session dials, resolved on the node for the
+	// peers deriving it from there, and the interface it is reached
+	// through when it is a link-local address.
+	addr net.IP
+	zone string
+	// The prefixes advertised to the peer, and the ones held back by its
+	// limit of advertised prefixes.
+	advertised sets.Set[string]
+	held       sets.Set[string]
 }
 
 type bgpController struct {
 	logger             log.Logger
 	myNode             string
 	nodeLabels         labels.Set
+	nodeAnnotations    map[string]string
 	peers              []*peer
 	svcAds             map[string][]*bgp.Advertisement
-	activeAds          map[string]sets.Set[string] // svc -> the peers it is advertised to
+	activeAds          map[string]map[string]sets.Set[string] // svc -> peer -> the prefixes of the svc advertised to it
+	activeSessions     map[string]bgp.Session                 // peer -> session, for the peers running on the node
+	activeVRFs         map[string]map[string]sets.Set[string] // svc -> prefix -> the VRFs it is announced in
+	heldAds            map[string]map[string]sets.Set[string] // svc -> peer -> the prefixes of the svc held back by the limit of the peer
+	excludedSvcs       map[string]string                      // svc -> the reason the node is excluded from announcing it
 	activeAdsMutex     sync.RWMutex
 	adsChangedCallback func(string)
 	bgpType            bgpImplementation
 	secretHandling     SecretHandling
 	sessionManager     bgp.SessionManager
 	ignoreExcludeLB    bool
+	dampening          *dampening // nil when the dampening is disabled
 }
 
 func (c *bgpController) SetConfig(l log.Logger, cfg *config.Config) error {
+	// The sessions are changed one by one before the extras referring to
+	// them are synced.
+	if tracker, ok := c.sessionManager.(bgp.SyncTracker); ok {
+		tracker.StartSync()
+		defer tracker.EndSync()
+	}
 	newPeers := make([]*peer, 0, len(cfg.Peers))
 newPeers:
 	for _, p := range cfg.Peers {
@@ -88,22 +115,36 @@ newPeers:
 				c.peers[i] = nil
 				continue newPeers
 			}
+			if updateTCPAO(l, ep, p) {
+				newPeers = append(newPeers, ep)
+				c.peers[i] = nil
+				continue newPeers
+			}
 		}
 		id := p.Iface
 		if p.Addr != nil {
 			id = p.Addr.String()
 		}
+		if p.ListenRange != nil {
+			id = p.ListenRange.String()
+		}
+		if p.AddrFrom != nil {
+			// Replaced by the address once resolved on the node.
+			id = p.Name
+		}
 
 		// No existing peers match, create a new one.
 		newPeers = append(newPeers, &peer{
-			cfg: p,
-			id:  id,
+			cfg:  p,
+			id:   id,
+			addr: p.Addr,
 		})
 	}
 
 	oldPeers := c.peers
 	c.peers = newPeers
 
+	sessionsRemoved := false
 	for _, p := range oldPeers {
 		if p == nil {
 			continue
@@ -114,6 +155,7 @@ newPeers:
 			if err := p.session.Close(); err != nil {
 				level.Error(l).Log("op", "setConfig", "error", err, "peer", p.id, "msg", "failed to shut down BGP session")
 			}
+			sessionsRemoved = true
 		}
 		level.Debug(l).Log("event", "peerRemoved", "peer", p.id, "reason", "removedFromConfig", "msg", "peer deconfigured, BGP session closed")
 	}
@@ -122,12 +164,44 @@ newPeers:
 	if err != nil {
 		return errors.Join(err, errors.New("failed to sync bfd profiles"))
 	}
+	if err := c.syncPeers(l); err != nil {
+		return err
+	}
+	// Synced after the peers, as the extra configuration may refer to the
+	// new ones.
 	err = c.sessionManager.SyncExtraInfo(cfg.BGPExtras)
 	if err != nil {
 		return errors.Join(err, errors.New("failed to sync extra info"))
 	}
+	if sessionsRemoved {
+		// The services are not advertised to the removed peers anymore.
+		return c.updateAds()
+	}
+	return nil
+}
 
-	return c.syncPeers(l)
+// updateTCPAO rolls over the TCP-AO keys of the existing peer's session
+// if they are the only difference with the new peer configuration, and
+// returns true if the session can be kept.
+func updateTCPAO(l log.Logger, ep *peer, p *config.Peer) bool {
+	if ep.session == nil || ep.cfg.TCPAO == nil || p.TCPAO == nil {
+		return false
+	}
+	updater, ok := ep.session.(bgp.TCPAOUpdater)
+	if !ok {
+		return false
+	}
+	oldCfg := *ep.cfg
+	oldCfg.TCPAO = p.TCPAO
+	if !reflect.DeepEqual(&oldCfg, p) {
+		return false
+	}
+	if err := updater.UpdateTCPAO(p.TCPAO); err != nil {
+		level.Info(l).Log("event", "tcpAOUpdateFailed", "peer", ep.id, "error", err, "msg", "failed to roll over TCP-AO keys, resetting BGP session")
+		return false
+	}
+	ep.cfg = p
+	return true
 }
 
 func (c *bgpController) SetEventCallback(callback func(interface{})) {
@@ -200,7 +274,7 @@ func (c *bgpController) ShouldAnnounce(l log.Logger, name string, _ []net.IP, po
 	return ""
 }
 
-// Called when either the peer list or node labels have changed,
+// Called when either the peer list or node labels or annotations have changed,
 // implying that the set of running BGP sessions may need tweaking.
 func (c *bgpController) syncPeers(l log.Logger) error {
 	var (
@@ -221,6 +295,29 @@ func (c *bgpController) syncPeers(l log.Logger) error {
 			}
 		}
 
+		addr, zone := p.addr, p.zone
+		if shouldRun && p.cfg.AddrFrom != nil && c.nodeLabels == nil {
+			// The node is not known yet, SetNode resyncs the peers.
+			shouldRun = false
+		}
+		if shouldRun && p.cfg.AddrFrom != nil {
+			var err error
+			addr, zone, err = c.peerAddress(p.cfg)
+			if err != nil {
+				level.Error(l).Log("op", "syncPeers", "error", err, "peer", p.cfg.Name, "msg", "failed to resolve the peer address on the node")
+				errs++
+				shouldRun = false
+			}
+		}
+		if p.session != nil && shouldRun && (!addr.Equal(p.addr) || zone != p.zone) {
+			level.Info(l).Log("event", "peerRemoved", "peer", p.id, "reason", "addressChanged", "msg", "peer address changed, closing BGP session")
+			if err := p.session.Close(); err != nil {
+				level.Error(l).Log("op", "syncPeers", "error", err, "peer", p.id, "msg", "failed to shut down BGP session")
+			}
+			p.session = nil
+			needUpdateAds = true
+		}
+
 		// Now, compare current state to intended state, and correct.
 		if p.session != nil && !shouldRun {
 			// Oops, session is running but shouldn't be. Shut it down.
@@ -229,9 +326,14 @@ func (c *bgpController) syncPeers(l log.Logger) error {
 				level.Error(l).Log("op", "syncPeers", "error", err, "peer", p.id, "msg", "failed to shut down BGP session")
 			}
 			p.session = nil
+			needUpdateAds = true
 		} else if p.session == nil && shouldRun {
 			// Session doesn't exist, but should be running. Create
 			// it.
+			p.addr, p.zone = addr, zone
+			if p.cfg.AddrFrom != nil {
+				p.id = addr.String()
+			}
 			level.Info(l).Log("event", "peerAdded", "peer", p.id, "msg", "peer configured, starting BGP session")
 			var routerID net.IP
 			if p.cfg.RouterID != nil {
@@ -239,13 +341,17 @@ func (c *bgpController) syncPeers(l log.Logger) error {
 			}
 
 			peerAddr := "" // we need because otherwise the value will "<nil>"
-			if p.cfg.Addr != nil {
-				peerAddr = p.cfg.Addr.String()
+			if p.addr != nil {
+				peerAddr = p.addr.String()
 			}
 			sessionParams := bgp.SessionParameters{
 				PeerAddress:            peerAddr,
+				PeerZone:               p.zone,
 				PeerPort:               p.cfg.Port,
 				PeerInterface:          p.cfg.Iface,
+				ListenRange:            p.cfg.ListenRange,
+				Passive:                p.cfg.Passive,
+				TCPAO:                  p.cfg.TCPAO,
 				SourceAddress:          p.cfg.SrcAddr,
 				MyASN:                  p.cfg.MyASN,
 				RouterID:               routerID,
@@ -263,6 +369,10 @@ func (c *bgpController) syncPeers(l log.Logger) error {
 				DualStackAddressFamily: p.cfg.DualStackAddressFamily,
 				DisableMP:              p.cfg.DisableMP, //nolint:staticcheck // SA1019: intentionally using deprecated field for translation
 				LocalASN:               p.cfg.LocalASN,
+				ToReceive:              p.cfg.ToReceive,
+				PeerGroup:              p.cfg.PeerTemplate,
+				Extras:                 p.cfg.Extras,
+				PrefixLimits:           p.cfg.PrefixLimits,
 			}
 			sessionParams.Password, sessionParams.PasswordRef = passwordForSession(p.cfg, c.bgpType, c.secretHandling)
 
@@ -322,10 +432,23 @@ func (c *bgpController) syncBFDProfiles(profiles map[string]*config.BFDProfile)
 	return c.sessionManager.SyncBFDProfiles(profiles)
 }
 
-func (c *bgpController) SetBalancer(l log.Logger, name string, lbIPs []net.IP, pool *config.Pool, _ service, svc *v1.Service) error {
+func (c *bgpController) SetBalancer(l log.Logger, name string, lbIPs []net.IP, pool *config.Pool, client service, svc *v1.Service, eps []discovery.EndpointSlice) error {
+	c.setExcluded(name, "")
+	if c.dampening != nil && c.dampening.advertise(l, name) {
+		client.Infof(svc, "bgpDampened", "flapping, not advertised until its penalty decays")
+		if _, ok := c.svcAds[name]; !ok {
+			return errHeld
+		}
+		delete(c.svcAds, name)
+		if err := c.updateAds(); err != nil {
+			return err
+		}
+		return errHeld
+	}
 	adsForService := bgpAdsForService(pool.BGPAdvertisements, c.myNode, svc)
 	c.svcAds[name] = nil
 	for _, lbIP := range lbIPs {
+		localEndpoints := c.readyLocalEndpoints(eps, lbIP)
 		for _, adCfg := range adsForService {
 			m := net.CIDRMask(adCfg.AggregationLength, 32)
 			if lbIP.To4() == nil {
@@ -336,15 +459,55 @@ func (c *bgpController) SetBalancer(l log.Logger, name string, lbIPs []net.IP, p
 					IP:   lbIP.Mask(m),
 					Mask: m,
 				},
-				LocalPref: adCfg.LocalPref,
+				LocalPref:     adCfg.LocalPref,
+				ASPathPrepend: adCfg.ASPathPrepend,
+				EVPN:          adCfg.EVPN,
+			}
+			if adCfg.LabeledUnicast != nil {
+				ad.LabeledUnicast = &bgp.LabeledUnicast{}
+				if index, ok := adCfg.LabeledUnicast.LabelIndex(pool, lbIP); ok {
+					ad.LabeledUnicast.LabelIndex = &index
+				}
+			}
+			if len(adCfg.PeerASPathPrepend) > 0 {
+				ad.PeerASPathPrepend = maps.Clone(adCfg.PeerASPathPrepend)
+			}
+			if adCfg.MED != nil {
+				med := *adCfg.MED
+				if adCfg.MEDFromReadyLocalEndpoints {
+					med -= min(med, localEndpoints)
+				}
+				ad.MED = &med
 			}
 			if len(adCfg.Peers) > 0 {
 				ad.Peers = make([]string, 0, len(adCfg.Peers))
 				ad.Peers = append(ad.Peers, adCfg.Peers...)
 			}
+			if adCfg.Condition != nil {
+				ad.Condition = conditionFor(adCfg.Condition)
+			}
+			if len(adCfg.ImportVRFs) > 0 {
+				ad.ImportVRFs = make([]string, 0, len(adCfg.ImportVRFs))
+				ad.ImportVRFs = append(ad.ImportVRFs, adCfg.ImportVRFs...)
+			}
+			if adCfg.Aggregation != nil {
+				ad.Aggregation = &bgp.Aggregation{SummaryOnly: adCfg.Aggregation.SummaryOnly}
+				if adCfg.Aggregation.WithinPools {
+					ad.Aggregation.Boundary = poolCIDRFor(pool, lbIP)
+				}
+			}
 			for comm := range adCfg.Communities {
 				ad.Communities = append(ad.Communities, comm)
 			}
+			if adCfg.LinkBandwidthPerEndpoint != 0 && localEndpoints > 0 {
+				// Multiplying in 64 bits, not to overflow before capping.
+				bandwidth := min(uint64(adCfg.LinkBandwidthPerEndpoint)*uint64(localEndpoints), community.MaxLinkBandwidth)
+				comm, err := community.NewLinkBandwidth(uint32(bandwidth))
+				if err != nil {
+					return err
+				}
+				ad.Communities = append(ad.Communities, comm)
+			}
 			sort.Slice(ad.Communities, func(i, j int) bool { return ad.Communities[i].LessThan(ad.Communities[j]) })
 			c.svcAds[name] = append(c.svcAds[name], ad)
 		}
@@ -353,11 +516,46 @@ func (c *bgpController) SetBalancer(l log.Logger, name string, lbIPs []net.IP, p
 	if err := c.updateAds(); err != nil {
 		return err
 	}
+	if peers := c.peersHoldingBack(name); len(peers) > 0 {
+		client.Errorf(svc, "prefixLimitExceeded", "not advertised to the peers %s, which reached their limit of advertised prefixes", strings.Join(peers, ", "))
+	}
 
 	level.Info(l).Log("event", "updatedAdvertisements", "numAds", len(c.svcAds[name]), "msg", "making advertisements using BGP")
 	return nil
 }
 
+// peersHoldingBack returns the peers the prefixes of the service are held
+// back from, as they reached their limit of advertised prefixes.
+func (c *bgpController) peersHoldingBack(key string) []string {
+	c.activeAdsMutex.RLock()
+	defer c.activeAdsMutex.RUnlock()
+	return slices.Sorted(maps.Keys(c.heldAds[key]))
+}
+
+// readyLocalEndpoints returns the number of ready endpoints running on the
+// node, among the ones of the same family as the given IP.
+func (c *bgpController) readyLocalEndpoints(eps []discovery.EndpointSlice, lbIP net.IP) uint32 {
+	addressType := discovery.AddressTypeIPv4
+	if lbIP.To4() == nil {
+		addressType = discovery.AddressTypeIPv6
+	}
+	var res uint32
+	for _, slice := range eps {
+		if slice.AddressType != addressType {
+			continue
+		}
+		for _, ep := range slice.Endpoints {
+			if ep.NodeName == nil || *ep.NodeName != c.myNode {
+				continue
+			}
+			if len(ep.Addresses) > 0 && epslices.EndpointCanServe(ep.Conditions) {
+				res++
+			}
+		}
+	}
+	return res
+}
+
 func (c *bgpController) updateAds() error {
 	newAds, err := c.publishAds()
 	if err != nil {
@@ -383,15 +581,56 @@ func (c *bgpController) publishAds() (map[string][]*bgp.Advertisement, error) {
 		if peer.session == nil {
 			continue
 		}
-		ads := adsForPeer(peer.cfg.Name, allAds)
+		// The conditions are resolved on each publication, as the
+		// sessions of the tracked peers come and go.
+		ads := bgp.Aggregate(c.resolveConditions(adsForPeer(peer.cfg.Name, allAds)))
+		var held sets.Set[string]
+		if l := peer.cfg.PrefixLimits; l != nil && l.MaxAdvertised > 0 {
+			ads, held = limitPrefixes(ads, peer.advertised, int(l.MaxAdvertised))
+			if held.Len() > 0 {
+				level.Warn(c.logger).Log("op", "publishAds", "peer", peer.cfg.Name, "limit", l.MaxAdvertised, "held", strings.Join(sets.List(held), ","), "msg", "limit of advertised prefixes reached, not advertising the new ones")
+			}
+		}
 		if err := peer.session.Set(ads...); err != nil {
 			return nil, err
 		}
+		peer.advertised = sets.New[string]()
+		for _, ad := range ads {
+			peer.advertised.Insert(ad.Prefix.String())
+		}
+		peer.held = held
 		adsSet[peer.cfg.Name] = ads
 	}
 	return adsSet, nil
 }
 
+// limitPrefixes keeps the advertisements of at most limit prefixes, the
+// already advertised ones first, then the others in lexical order. The
+// prefixes held back are returned too.
+func limitPrefixes(ads []*bgp.Advertisement, advertised sets.Set[string], limit int) ([]*bgp.Advertisement, sets.Set[string]) {
+	prefixes := sets.New[string]()
+	for _, ad := range ads {
+		prefixes.Insert(ad.Prefix.String())
+	}
+	if prefixes.Len() <= limit {
+		return ads, nil
+	}
+	kept := sets.New[string]()
+	for _, p := range slices.Concat(sets.List(prefixes.Intersection(advertised)), sets.List(prefixes.Difference(advertised))) {
+		if kept.Len() == limit {
+			break
+		}
+		kept.Insert(p)
+	}
+	res := []*bgp.Advertisement{}
+	for _, ad := range ads {
+		if kept.Has(ad.Prefix.String()) {
+			res = append(res, ad)
+		}
+	}
+	return res, prefixes.Difference(kept)
+}
+
 func (c *bgpController) notifyAdsChanged(newAds map[string][]*bgp.Advertisement) {
 	changedSvcs := []string{} // the services that their advs changed
 	defer func() {
@@ -403,6 +642,21 @@ func (c *bgpController) notifyAdsChanged(newAds map[string][]*bgp.Advertisement)
 	c.activeAdsMutex.Lock()
 	defer c.activeAdsMutex.Unlock()
 
+	newSessions := map[string]bgp.Session{}
+	peerVRFs := map[string]string{}
+	heldPrefixes := map[string]sets.Set[string]{}
+	for _, p := range c.peers {
+		if p.session != nil {
+			newSessions[p.cfg.Name] = p.session
+			peerVRFs[p.cfg.Name] = p.cfg.VRF
+			if p.held.Len() > 0 {
+				heldPrefixes[p.cfg.Name] = p.held
+			}
+		}
+	}
+	sessionsChanged := !maps.Equal(c.activeSessions, newSessions)
+	c.activeSessions = newSessions
+
 	pfxToSvc := map[string]sets.Set[string]{} // prefix -> the services that use it
 	for svcKey, ads := range c.svcAds {
 		for _, ad := range ads {
@@ -414,17 +668,57 @@ func (c *bgpController) notifyAdsChanged(newAds map[string][]*bgp.Advertisement)
 		}
 	}
 
-	oldActiveAds := c.activeAds
-	newActiveAds := map[string]sets.Set[string]{}
+	oldActiveAds, oldActiveVRFs, oldHeldAds := c.activeAds, c.activeVRFs, c.heldAds
+	newActiveAds := map[string]map[string]sets.Set[string]{}
+	newActiveVRFs := map[string]map[string]sets.Set[string]{}
+	newHeldAds := map[string]map[string]sets.Set[string]{}
+	for svc := range c.svcAds {
+		newActiveAds[svc] = map[string]sets.Set[string]{}
+		newActiveVRFs[svc] = map[string]sets.Set[string]{}
+	}
+	for peer, prefixes := range heldPrefixes {
+		for prefix := range prefixes {
+			svcs, ok := pfxToSvc[prefix]
+			if _, n, err := net.ParseCIDR(prefix); !ok && err == nil {
+				svcs = coveredServices(n, pfxToSvc)
+			}
+			for svc := range svcs {
+				if _, ok := newHeldAds[svc]; !ok {
+					newHeldAds[svc] = map[string]sets.Set[string]{}
+				}
+				if _, ok := newHeldAds[svc][peer]; !ok {
+					newHeldAds[svc][peer] = sets.New[string]()
+				}
+				newHeldAds[svc][peer].Insert(prefix)
+			}
+		}
+	}
 	for peer, ads := range newAds {
 		for _, ad := range ads {
-			adSvcs := pfxToSvc[ad.Prefix.String()]
+			adSvcs, ok := pfxToSvc[ad.Prefix.String()]
+			if !ok {
+				adSvcs = coveredServices(ad.Prefix, pfxToSvc)
+			}
 			for svc := range adSvcs {
-				if _, ok := newActiveAds[svc]; !ok {
-					newActiveAds[svc] = sets.New(peer)
-					continue
+				if _, ok := newActiveAds[svc][peer]; !ok {
+					newActiveAds[svc][peer] = sets.New[string]()
+				}
+				newActiveAds[svc][peer].Insert(ad.Prefix.String())
+
+				vrfs, ok := newActiveVRFs[svc][ad.Prefix.String()]
+				if !ok {
+					vrfs = sets.New[string]()
+					newActiveVRFs[svc][ad.Prefix.String()] = vrfs
+				}
+				// The EVPN routes are announced from the VRF of the L3VNI.
+				if ad.EVPN != nil {
+					vrfs.Insert(ad.EVPN.VRF)
+				} else {
+					vrfs.Insert(vrfName(peerVRFs[peer]))
+				}
+				for _, vrf := range ad.ImportVRFs {
+					vrfs.Insert(vrfName(vrf))
 				}
-				newActiveAds[svc].Insert(peer)
 			}
 		}
 	}
@@ -435,7 +729,10 @@ func (c *bgpController) notifyAdsChanged(newAds map[string][]*bgp.Advertisement)
 			changedSvcs = append(changedSvcs, svc)
 			continue
 		}
-		if !oldPeers.Equal(newPeers) {
+		// The peers the service is filtered out from are part of its
+		// status too, so any change of the sessions affects it.
+		if sessionsChanged || !reflect.DeepEqual(oldPeers, newPeers) || !reflect.DeepEqual(oldActiveVRFs[svc], newActiveVRFs[svc]) ||
+			!reflect.DeepEqual(oldHeldAds[svc], newHeldAds[svc]) {
 			changedSvcs = append(changedSvcs, svc)
 		}
 	}
@@ -447,13 +744,104 @@ func (c *bgpController) notifyAdsChanged(newAds map[string][]*bgp.Advertisement)
 		}
 	}
 	c.activeAds = newActiveAds
+	c.activeVRFs = newActiveVRFs
+	c.heldAds = newHeldAds
+}
+
+// vrfName returns the name of the VRF as reported in the status, where the
+// default VRF is named explicitly.
+func vrfName(vrf string) string {
+	if vrf == "" {
+		return config.DefaultVRF
+	}
+	return vrf
+}
+
+// coveredServices returns the services whose prefixes are covered by the
+// given one, as it happens when their IPs are aggregated.
+func coveredServices(prefix *net.IPNet, pfxToSvc map[string]sets.Set[string]) sets.Set[string] {
+	res := sets.New[string]()
+	ones, _ := prefix.Mask.Size()
+	for pfx, svcs := range pfxToSvc {
+		_, n, err := net.ParseCIDR(pfx)
+		if err != nil {
+			continue
+		}
+		if l, _ := n.Mask.Size(); l >= ones && prefix.Contains(n.IP) {
+			res = res.Union(svcs)
+		}
+	}
+	return res
+}
+
+// poolCIDRFor returns the CIDR of the pool the IP belongs to.
+func poolCIDRFor(pool *config.Pool, ip net.IP) *net.IPNet {
+	for _, cidr := range pool.CIDR {
+		if cidr.Contains(ip) {
+			return cidr
+		}
+	}
+	return nil
+}
+
+// conditionFor translates the condition of an advertisement. The tracked
+// peers are left as the names of the BGPPeers, resolveConditions replaces them
+// when the advertisements are handed to the sessions.
+func conditionFor(cond *config.AdvertisementCondition) *bgp.Condition {
+	res := &bgp.Condition{NonExist: cond.NonExist}
+	for _, p := range cond.Prefixes {
+		res.Prefixes = append(res.Prefixes, p.String())
+	}
+	if len(cond.Peers) > 0 {
+		res.Peers = append([]string{}, cond.Peers...)
+	}
+	return res
+}
+
+// resolveConditions replaces the names of the peers tracked by the conditions
+// of the advertisements with the addresses or interfaces of the sessions
+// running on the node. When none of the tracked peers has a session, no route
+// can be received from them: the advertisement is announced unconditionally
+// if it depends on the routes not existing, and dropped otherwise, as
+// tracking any peer instead would invert its intent.
+func (c *bgpController) resolveConditions(ads []*bgp.Advertisement) []*bgp.Advertisement {
+	ids := map[string]string{}
+	for _, p := range c.peers {
+		if p.session != nil {
+			ids[p.cfg.Name] = p.id
+		}
+	}
+	res := make([]*bgp.Advertisement, 0, len(ads))
+	for _, ad := range ads {
+		if ad.Condition == nil || len(ad.Condition.Peers) == 0 {
+			res = append(res, ad)
+			continue
+		}
+		cond := *ad.Condition
+		cond.Peers = nil
+		for _, name := range ad.Condition.Peers {
+			if id, ok := ids[name]; ok {
+				cond.Peers = append(cond.Peers, id)
+			}
+		}
+		resolved := *ad
+		resolved.Condition = &cond
+		if len(cond.Peers) == 0 {
+			if !cond.NonExist {
+				continue
+			}
+			resolved.Condition = nil
+		}
+		res = append(res, &resolved)
+	}
+	return res
 }
 
 func adsForPeer(peerName string, ads []*bgp.Advertisement) []*bgp.Advertisement {
 	res := []*bgp.Advertisement{}
 	for _, a := range ads {
 		if a.MatchesPeer(peerName) {
-			res = append(res, a)
+			res = append(res, a.ForPeer(peerName))
 		}
 	}
 	if len(res) == 0 {
@@ -463,6 +851,14 @@ func adsForPeer(peerName string, ads []*bgp.Advertisement) []*bgp.Advertisement
 }
 
 func (c *bgpController) DeleteBalancer(l log.Logger, name, reason string) error {
+	// The held services keep their advertisements, and their status.
+	if c.dampening != nil && c.dampening.withdraw(l, name, reason) {
+		level.Debug(l).Log("event", "bgpDampened", "reason", reason, "msg", "service flapping, keeping it advertised")
+		return errHeld
+	}
+	if c.setExcluded(name, reason) {
+		defer c.adsChangedCallback(name)
+	}
 	if _, ok := c.svcAds[name]; !ok {
 		return nil
 	}
@@ -470,6 +866,24 @@ func (c *bgpController) DeleteBalancer(l log.Logger, name, reason string) error
 	return c.updateAds()
 }
 
+// setExcluded records whether the service is not announced because the
+// node is excluded from announcing services, and returns true if that
+// changed.
+func (c *bgpController) setExcluded(name, reason string) bool {
+	c.activeAdsMutex.Lock()
+	defer c.activeAdsMutex.Unlock()
+
+	old, wasExcluded := c.excludedSvcs[name]
+	switch reason {
+	case "nodeNetworkUnavailable", "nodeLabeledExcludeBalancers":
+		c.excludedSvcs[name] = reason
+		return old != reason
+	default:
+		delete(c.excludedSvcs, name)
+		return wasExcluded
+	}
+}
+
 func (c *bgpController) SetNode(l log.Logger, node *v1.Node) error {
 	if c.myNode != node.Name {
 		return nil
@@ -479,24 +893,38 @@ func (c *bgpController) SetNode(l log.Logger, node *v1.Node) error {
 		nodeLabels = map[string]string{}
 	}
 	ns := labels.Set(nodeLabels)
-	if c.nodeLabels != nil && labels.Equals(c.nodeLabels, ns) {
-		// Node labels unchanged, no action required.
-		return nil
+	if c.nodeLabels != nil && labels.Equals(c.nodeLabels, ns) && maps.Equal(c.nodeAnnotations, node.Annotations) {
+		// Node labels and annotations unchanged, only the default gateway
+		// the peers may derive their address from can have moved.
+		if !c.hasDefaultGatewayPeers() {
+			return nil
+		}
+		return c.syncPeers(l)
 	}
 	c.nodeLabels = ns
-	level.Info(l).Log("event", "nodeLabelsChanged", "msg", "Node labels changed, resyncing BGP peers")
+	// The annotations may hold the address of the peers.
+	c.nodeAnnotations = node.Annotations
+	level.Info(l).Log("event", "nodeLabelsChanged", "msg", "Node labels or annotations changed, resyncing BGP peers")
 	return c.syncPeers(l)
 }
 
+// hasDefaultGatewayPeers tells if any of the peers derives its address from
+// the default gateway of the node.
+func (c *bgpController) hasDefaultGatewayPeers() bool {
+	return slices.ContainsFunc(c.peers, func(p *peer) bool {
+		return p.cfg.AddrFrom != nil && p.cfg.AddrFrom.DefaultGateway != ""
+	})
+}
+
 // Create a new 'bgp.SessionManager' of type 'bgpType'.
 var newBGP = func(cfg controllerConfig) bgp.SessionManager {
 	switch cfg.bgpType {
 	case bgpNative:
 		return bgpnative.NewSessionManager(cfg.Logger)
 	case bgpFrr:
-		return bgpfrr.NewSessionManager(cfg.Logger, cfg.LogLevel, cfg.BGPDebounceTimeout)
+		return bgpfrr.NewSessionManager(cfg.Logger, cfg.LogLevel, cfg.BGPDebounceTimeout, cfg.FRRIncrementalConfig)
 	case bgpFrrK8s:
-		return bgpfrrk8s.NewSessionManager(cfg.Logger, cfg.LogLevel, cfg.MyNode, cfg.FRRK8sNamespace)
+		return bgpfrrk8s.NewSessionManager(cfg.Logger, cfg.LogLevel, cfg.MyNode, cfg.FRRK8sNamespace, cfg.FRRK8sConfigPerPeer)
 	default:
 		panic(fmt.Sprintf("unsupported BGP implementation type: %s", cfg.bgpType))
 	}
@@ -544,13 +972,144 @@ func checkBGPAdvConflicts(ads []*config.BGPAdvertisement, pool *config.Pool) err
 				return fmt.Errorf("advertisements '%s' and '%s' have conflicting LOCAL_PREF (%d vs %d)",
 					ad1.Name, ad2.Name, ad1.LocalPref, ad2.LocalPref)
 			}
+			if !config.BGPAdvertisementsHaveCompatibleMED(ad1, ad2, pool) {
+				return fmt.Errorf("advertisements '%s' and '%s' have conflicting MED (%s vs %s)",
+					ad1.Name, ad2.Name, config.MEDString(ad1), config.MEDString(ad2))
+			}
 		}
 	}
 	return nil
 }
 
-func (c *bgpController) PeersForService(key string) sets.Set[string] {
+// PeersForService returns whether the service is advertised to each of the
+// BGP peers running on the node, or nil if the node is not meant to announce it.
+func (c *bgpController) PeersForService(key string) []v1beta1.ServiceBGPPeerCondition {
+	c.activeAdsMutex.RLock()
+	defer c.activeAdsMutex.RUnlock()
+
+	excludedReason, excluded := c.excludedSvcs[key]
+	peers, advertised := c.activeAds[key]
+	if !excluded && !advertised {
+		return nil
+	}
+
+	res := make([]v1beta1.ServiceBGPPeerCondition, 0, len(c.activeSessions))
+	for name, session := range c.activeSessions {
+		prefixes, ok := peers[name]
+		held := c.heldAds[key][name]
+		switch {
+		case excluded:
+			res = append(res, v1beta1.ServiceBGPPeerCondition{
+				Peer:    name,
+				Reason:  v1beta1.ServiceBGPPeerNodeExcluded,
+				Message: nodeExcludedMessage(excludedReason),
+			})
+		case held.Len() > 0:
+			res = append(res, v1beta1.ServiceBGPPeerCondition{
+				Peer:    name,
+				Reason:  v1beta1.ServiceBGPPeerPrefixLimitExceeded,
+				Message: fmt.Sprintf("the prefixes %s are held back, the peer reached its limit of advertised prefixes", strings.Join(sets.List(held), ", ")),
+			})
+		case !ok:
+			res = append(res, v1beta1.ServiceBGPPeerCondition{
+				Peer:    name,
+				Reason:  v1beta1.ServiceBGPPeerFiltered,
+				Message: "none of the BGPAdvertisements of the service select the peer",
+			})
+		default:
+			res = append(res, advertisementCondition(name, session, prefixes))
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Peer < res[j].Peer })
+	return res
+}
+
+// PrefixesForService returns the VRFs each of the prefixes of the service
+// is announced in, including the ones it is leaked into.
+func (c *bgpController) PrefixesForService(key string) []v1beta1.ServiceBGPPrefix {
 	c.activeAdsMutex.RLock()
 	defer c.activeAdsMutex.RUnlock()
-	return c.activeAds[key]
+
+	prefixes := c.activeVRFs[key]
+	if len(prefixes) == 0 {
+		return nil
+	}
+	res := make([]v1beta1.ServiceBGPPrefix, 0, len(prefixes))
+	for _, prefix := range slices.Sorted(maps.Keys(prefixes)) {
+		res = append(res, v1beta1.ServiceBGPPrefix{
+			Prefix: prefix,
+			VRFs:   sets.List(prefixes[prefix]),
+		})
+	}
+	return res
+}
+
+// frrk8sSessionStatesSyncer is implemented by the session managers fed
+// with the state of the sessions reported by FRR-K8s.
+type frrk8sSessionStatesSyncer interface {
+	SyncSessionStates(states []frrv1beta1.BGPSessionStateStatus) bool
+}
+
+// SyncFRRK8sSessionStates records the state of the sessions reported by
+// FRR-K8s, and refreshes the status of the advertised services when it
+// changes.
+func (c *bgpController) SyncFRRK8sSessionStates(states []frrv1beta1.BGPSessionStateStatus) {
+	syncer, ok := c.sessionManager.(frrk8sSessionStatesSyncer)
+	if !ok || !syncer.SyncSessionStates(states) {
+		return
+	}
+	c.activeAdsMutex.RLock()
+	svcs := slices.Sorted(maps.Keys(c.activeAds))
+	c.activeAdsMutex.RUnlock()
+	for _, svc := range svcs {
+		c.adsChangedCallback(svc)
+	}
+}
+
+// advertisementCondition tells whether the prefixes reached the peer. The
+// prefixes are never reported as advertised when the session is not able to
+// tell, as only the established sessions count.
+func advertisementCondition(peer string, session bgp.Session, prefixes sets.Set[string]) v1beta1.ServiceBGPPeerCondition {
+	reporter, ok := session.(bgp.AdvertisementStateReporter)
+	if !ok {
+		return v1beta1.ServiceBGPPeerCondition{
+			Peer:    peer,
+			Reason:  v1beta1.ServiceBGPPeerUnknown,
+			Message: "the BGP implementation does not report the state of the session with the peer",
+		}
+	}
+
+	pending := []string{}
+	for _, prefix := range sets.List(prefixes) {
+		established, advertised := reporter.AdvertisementState(prefix)
+		if !established {
+			return v1beta1.ServiceBGPPeerCondition{
+				Peer:    peer,
+				Reason:  v1beta1.ServiceBGPPeerSessionNotEstablished,
+				Message: "the BGP session with the peer is not established",
+			}
+		}
+		if !advertised {
+			pending = append(pending, prefix)
+		}
+	}
+	if len(pending) > 0 {
+		return v1beta1.ServiceBGPPeerCondition{
+			Peer:    peer,
+			Reason:  v1beta1.ServiceBGPPeerPending,
+			Message: fmt.Sprintf("the prefixes %s are not advertised to the peer yet", strings.Join(pending, ", ")),
+		}
+	}
+	return v1beta1.ServiceBGPPeerCondition{
+		Peer:       peer,
+		Advertised: true,
+		Reason:     v1beta1.ServiceBGPPeerAdvertised,
+	}
+}
+
+func nodeExcludedMessage(reason string) string {
+	if reason == "nodeNetworkUnavailable" {
+		return "the node has the NetworkUnavailable condition"
+	}
+	return "the node is labeled with node.kubernetes.io/exclude-from-external-load-balancers"
 }
@@ -5,6 +5,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"net"
 	"os"
 	"reflect"
@@ -16,10 +17,12 @@ import (
 	"go.universe.tf/metallb/internal/bgp"
 	"go.universe.tf/metallb/internal/bgp/community"
 	"go.universe.tf/metallb/internal/config"
+	"go.universe.tf/metallb/internal/ipfamily"
 	"go.universe.tf/metallb/internal/k8s/controllers"
 
 	"github.com/go-kit/log"
 	"github.com/google/go-cmp/cmp"
+	frrv1beta1 "github.com/metallb/frr-k8s/api/v1beta1"
 	v1 "k8s.io/api/core/v1"
 	discovery "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -106,22 +109,28 @@ type fakeBGPSessionManager struct {
 	sync.Mutex
 	// peer IP -> advertisements
 	gotAds map[string][]*bgp.Advertisement
+	// syncing is set between StartSync and EndSync.
+	syncing bool
 }
 
 func (f *fakeBGPSessionManager) NewSession(_ log.Logger, args bgp.SessionParameters) (bgp.Session, error) {
 	f.Lock()
 	defer f.Unlock()
 
-	if _, ok := f.gotAds[args.PeerAddress]; ok {
-		f.t.Errorf("Tried to create already existing BGP session to %q", args.PeerAddress)
+	addr := args.PeerAddress
+	if args.PeerZone != "" {
+		addr += "%" + args.PeerZone
+	}
+	if _, ok := f.gotAds[addr]; ok {
+		f.t.Errorf("Tried to create already existing BGP session to %q", addr)
 		return nil, errors.New("invariant violation")
 	}
 	// Nil because we haven't programmed any routes for it yet, but
 	// the key now exists in the map.
-	f.gotAds[args.PeerAddress] = nil
+	f.gotAds[addr] = nil
 	return &fakeSession{
 		f:    f,
-		addr: args.PeerAddress,
+		addr: addr,
 	}, nil
 }
 
@@ -129,10 +138,37 @@ func (f *fakeBGPSessionManager) SyncBFDProfiles(profiles map[string]*config.BFDP
 	return nil
 }
 
-func (f *fakeBGPSessionManager) SyncExtraInfo(extra string) error {
+func (f *fakeBGPSessionManager) SyncExtraInfo(extras config.BGPExtras) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if !f.syncing {
+		f.t.Errorf("Tried to sync the extras outside of a sync")
+		return errors.New("invariant violation")
+	}
 	return nil
 }
 
+func (f *fakeBGPSessionManager) StartSync() {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.syncing {
+		f.t.Errorf("Tried to start a sync while syncing")
+	}
+	f.syncing = true
+}
+
+func (f *fakeBGPSessionManager) EndSync() {
+	f.Lock()
+	defer f.Unlock()
+
+	if !f.syncing {
+		f.t.Errorf("Tried to end a sync while not syncing")
+	}
+	f.syncing = false
+}
+
 func (f *fakeBGPSessionManager) Ads() map[string][]*bgp.Advertisement {
 	ret := map[string][]*bgp.Advertisement{}
 
@@ -1388,6 +1424,183 @@ func TestNodeSelectors(t *testing.T) {
 	}
 }
 
+func TestPeerAddressFrom(t *testing.T) {
+	b := &fakeBGP{
+		t: t,
+	}
+	newBGP = b.NewSessionManager
+	c, err := newController(controllerConfig{
+		MyNode:                "pandora",
+		DisableLayer2:         true,
+		bgpType:               bgpNative,
+		BGPAdsChangedCallback: noopCallback,
+	})
+	if err != nil {
+		t.Fatalf("creating controller: %s", err)
+	}
+	c.client = &testK8S{t: t}
+
+	gateway, zone := net.ParseIP("192.168.0.1"), ""
+	oldDefaultGateway := defaultGateway
+	defaultGateway = func(family ipfamily.Family) (net.IP, string, error) {
+		if family != ipfamily.ForAddress(gateway) {
+			return nil, "", fmt.Errorf("no %s default gateway found", family)
+		}
+		return gateway, zone, nil
+	}
+	defer func() { defaultGateway = oldDefaultGateway }()
+
+	cfg := &config.Config{
+		Peers: map[string]*config.Peer{
+			"annotation": {
+				Name:          "annotation",
+				AddrFrom:      &config.PeerAddressSource{NodeAnnotation: "metallb.io/tor"},
+				NodeSelectors: []labels.Selector{labels.Everything()},
+			},
+			"label": {
+				Name:          "label",
+				AddrFrom:      &config.PeerAddressSource{NodeLabel: "tor"},
+				NodeSelectors: []labels.Selector{labels.Everything()},
+			},
+			"gateway": {
+				Name:          "gateway",
+				AddrFrom:      &config.PeerAddressSource{DefaultGateway: ipfamily.IPv4},
+				NodeSelectors: []labels.Selector{labels.Everything()},
+			},
+		},
+		Pools: &config.Pools{ByName: map[string]*config.Pool{}},
+	}
+	cfg6 := &config.Config{
+		Peers: maps.Clone(cfg.Peers),
+		Pools: cfg.Pools,
+	}
+	cfg6.Peers["gateway"] = &config.Peer{
+		Name:          "gateway",
+		AddrFrom:      &config.PeerAddressSource{DefaultGateway: ipfamily.IPv6},
+		NodeSelectors: []labels.Selector{labels.Everything()},
+	}
+
+	tests := []struct {
+		desc            string
+		config          *config.Config
+		node            *v1.Node
+		gateway         string
+		zone            string
+		wantAds         map[string][]*bgp.Advertisement
+		wantReturnState controllers.SyncState
+	}{
+		{
+			desc:    "Node not known yet, no sessions",
+			config:  cfg,
+			wantAds: map[string][]*bgp.Advertisement{},
+		},
+		{
+			desc: "Addresses resolved on the node",
+			node: &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "pandora",
+					Labels:      map[string]string{"tor": "fc00--1"},
+					Annotations: map[string]string{"metallb.io/tor": "10.0.0.1"},
+				},
+			},
+			wantAds: map[string][]*bgp.Advertisement{
+				"10.0.0.1":    nil,
+				"fc00::1":     nil,
+				"192.168.0.1": nil,
+			},
+		},
+		{
+			desc: "Annotation changed, the session moves to the new address",
+			node: &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "pandora",
+					Labels:      map[string]string{"tor": "fc00--1"},
+					Annotations: map[string]string{"metallb.io/tor": "10.0.0.2"},
+				},
+			},
+			gateway: "192.168.0.2",
+			wantAds: map[string][]*bgp.Advertisement{
+				"10.0.0.2":    nil,
+				"fc00::1":     nil,
+				"192.168.0.2": nil,
+			},
+		},
+		{
+			desc: "Default gateway changed, the session moves to the new gateway",
+			node: &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "pandora",
+					Labels:      map[string]string{"tor": "fc00--1"},
+					Annotations: map[string]string{"metallb.io/tor": "10.0.0.2"},
+				},
+			},
+			gateway: "192.168.0.3",
+			wantAds: map[string][]*bgp.Advertisement{
+				"10.0.0.2":    nil,
+				"fc00::1":     nil,
+				"192.168.0.3": nil,
+			},
+		},
+		{
+			desc:   "Link-local default gateway, the session is bound to its interface",
+			config: cfg6,
+			node: &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "pandora",
+					Labels:      map[string]string{"tor": "fc00--1"},
+					Annotations: map[string]string{"metallb.io/tor": "10.0.0.2"},
+				},
+			},
+			gateway: "fe80::1",
+			zone:    "eth0",
+			wantAds: map[string][]*bgp.Advertisement{
+				"10.0.0.2":     nil,
+				"fc00::1":      nil,
+				"fe80::1%eth0": nil,
+			},
+		},
+		{
+			desc: "Label removed, the session is closed",
+			node: &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "pandora",
+					Annotations: map[string]string{"metallb.io/tor": "10.0.0.2"},
+				},
+			},
+			wantAds: map[string][]*bgp.Advertisement{
+				"10.0.0.2":     nil,
+				"fe80::1%eth0": nil,
+			},
+			wantReturnState: controllers.SyncStateError,
+		},
+	}
+
+	l := log.NewNopLogger()
+	for _, test := range tests {
+		if test.gateway != "" {
+			gateway, zone = net.ParseIP(test.gateway), test.zone
+		}
+		if test.config != nil {
+			if c.SetConfig(l, test.config) == controllers.SyncStateError {
+				t.Errorf("%q: SetConfig failed", test.desc)
+			}
+		}
+
+		if test.node != nil {
+			if r := c.SetNode(l, test.node); r != test.wantReturnState {
+				t.Fatalf("%q: SetNode returns wrong value, got: %+v, want: %+v", test.desc, r, test.wantReturnState)
+			}
+		}
+
+		gotAds := b.sessionManager.Ads()
+		sortAds(test.wantAds)
+		sortAds(gotAds)
+		if diff := cmp.Diff(test.wantAds, gotAds); diff != "" {
+			t.Errorf("%q: unexpected advertisement state (-want +got)\n%s", test.desc, diff)
+		}
+	}
+}
+
 func TestShouldAnnounceExcludeLB(t *testing.T) {
 	epsOn := func(node string) map[string][]discovery.EndpointSlice {
 		return map[string][]discovery.EndpointSlice{
@@ -1661,6 +1874,69 @@ func TestPasswordForSession(t *testing.T) {
 	}
 }
 
+type tcpAOSession struct {
+	fakeSession
+	err     error
+	updated *config.TCPAO
+}
+
+func (s *tcpAOSession) UpdateTCPAO(tcpAO *config.TCPAO) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.updated = tcpAO
+	return nil
+}
+
+func TestUpdateTCPAO(t *testing.T) {
+	oldAO := &config.TCPAO{Keys: []config.TCPAOKey{{SendID: 1, RecvID: 1, Key: "key1"}}, CurrentKeyID: 1}
+	newAO := &config.TCPAO{Keys: []config.TCPAOKey{{SendID: 1, RecvID: 1, Key: "key1"}, {SendID: 2, RecvID: 2, Key: "key2"}}, CurrentKeyID: 2}
+	tests := []struct {
+		name      string
+		newCfg    *config.Peer
+		updateErr error
+		expected  bool
+	}{
+		{
+			name:     "only keys changed",
+			newCfg:   &config.Peer{Name: "peer", MyASN: 100, TCPAO: newAO},
+			expected: true,
+		},
+		{
+			name:   "other fields changed",
+			newCfg: &config.Peer{Name: "peer", MyASN: 200, TCPAO: newAO},
+		},
+		{
+			name:   "tcpAO disabled",
+			newCfg: &config.Peer{Name: "peer", MyASN: 100},
+		},
+		{
+			name:      "update failed",
+			newCfg:    &config.Peer{Name: "peer", MyASN: 100, TCPAO: newAO},
+			updateErr: errors.New("failed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &tcpAOSession{err: tt.updateErr}
+			ep := &peer{
+				cfg:     &config.Peer{Name: "peer", MyASN: 100, TCPAO: oldAO},
+				session: session,
+			}
+			if got := updateTCPAO(log.NewNopLogger(), ep, tt.newCfg); got != tt.expected {
+				t.Fatalf("unexpected result, got: %v, want: %v", got, tt.expected)
+			}
+			if !tt.expected {
+				return
+			}
+			if session.updated != newAO || ep.cfg != tt.newCfg {
+				t.Errorf("session not updated with the new keys")
+			}
+		})
+	}
+}
+
 func TestPeersForService(t *testing.T) {
 	callbackCounters := map[string]int{}
 	callback := func(key string) {
@@ -1943,7 +2219,15 @@ func TestPeersForService(t *testing.T) {
 		}
 
 		for svc, wantPeers := range test.expectedPeers {
-			if diff := cmp.Diff(wantPeers, sets.List(c.bgpPeersFetcher(svc))); diff != "" {
+			// The fake sessions don't report their state, the service is
+			// never advertised to them but only configured to be.
+			gotPeers := []string{}
+			for _, cond := range c.bgpPeersFetcher(svc) {
+				if cond.Reason == metallbv1beta1.ServiceBGPPeerUnknown {
+					gotPeers = append(gotPeers, cond.Peer)
+				}
+			}
+			if diff := cmp.Diff(wantPeers, gotPeers); diff != "" {
 				t.Errorf("%q: unexpected peers for service %s (-want +got)\n%s", test.desc, svc, diff)
 			}
 		}
@@ -2139,6 +2423,22 @@ func TestCheckBGPAdvConflicts(t *testing.T) {
 			ads:         []*config.BGPAdvertisement{},
 			expectError: false,
 		},
+		{
+			desc: "Conflict - different MED, same node",
+			ads: []*config.BGPAdvertisement{
+				{Name: "ad1", AggregationLength: 32, MED: ptr.To[uint32](10), Nodes: map[string]bool{"nodeA": true}},
+				{Name: "ad2", AggregationLength: 32, MED: ptr.To[uint32](10), MEDFromReadyLocalEndpoints: true, Nodes: map[string]bool{"nodeA": true}},
+			},
+			expectError: true,
+		},
+		{
+			desc: "No conflict - same MED",
+			ads: []*config.BGPAdvertisement{
+				{Name: "ad1", AggregationLength: 32, MED: ptr.To[uint32](10), Nodes: map[string]bool{"nodeA": true}},
+				{Name: "ad2", AggregationLength: 32, MED: ptr.To[uint32](10), Nodes: map[string]bool{"nodeA": true}},
+			},
+			expectError: false,
+		},
 		{
 			desc: "Conflict - different local pref, same node",
 			ads: []*config.BGPAdvertisement{
@@ -2595,3 +2895,818 @@ func TestHasHealthyEndpoint(t *testing.T) {
 		})
 	}
 }
+
+type fakeSessionStateReporter struct {
+	fakeBGPSessionManager
+	callback func()
+}
+
+func (f *fakeSessionStateReporter) SessionStates() []bgp.SessionState {
+	return []bgp.SessionState{{Peer: "1.2.3.4", State: "Established"}}
+}
+
+func (f *fakeSessionStateReporter) SetSessionStateCallback(callback func()) {
+	f.callback = callback
+}
+
+func TestBGPSessionStatesFetcher(t *testing.T) {
+	reporter := &fakeSessionStateReporter{}
+	newBGP = func(controllerConfig) bgp.SessionManager { return reporter }
+	changed := false
+	c, err := newController(controllerConfig{
+		MyNode:                "pandora",
+		DisableLayer2:         true,
+		bgpType:               bgpNative,
+		BGPAdsChangedCallback: noopCallback,
+		BGPSessionStateChange: func() { changed = true },
+	})
+	if err != nil {
+		t.Fatalf("creating controller: %s", err)
+	}
+	if c.bgpSessionStatesFetcher == nil {
+		t.Fatalf("no session states fetcher for a session manager reporting states")
+	}
+	if states := c.bgpSessionStatesFetcher(); len(states) != 1 || states[0].Peer != "1.2.3.4" {
+		t.Fatalf("unexpected session states %v", states)
+	}
+	if reporter.callback == nil {
+		t.Fatalf("session state callback not set")
+	}
+	reporter.callback()
+	if !changed {
+		t.Fatalf("session state change not propagated")
+	}
+
+	b := &fakeBGP{t: t}
+	newBGP = b.NewSessionManager
+	c, err = newController(controllerConfig{
+		MyNode:                "pandora",
+		DisableLayer2:         true,
+		bgpType:               bgpNative,
+		BGPAdsChangedCallback: noopCallback,
+		BGPSessionStateChange: func() {},
+	})
+	if err != nil {
+		t.Fatalf("creating controller: %s", err)
+	}
+	if c.bgpSessionStatesFetcher != nil {
+		t.Fatalf("session states fetcher set for a session manager not reporting states")
+	}
+}
+
+type fakeReloadStatusReporter struct {
+	fakeBGPSessionManager
+	callback func()
+}
+
+func (f *fakeReloadStatusReporter) ReloadStatus() bgp.ReloadStatus {
+	return bgp.ReloadStatus{Failed: true, ConsecutiveFailures: 1}
+}
+
+func (f *fakeReloadStatusReporter) SetReloadStatusCallback(callback func()) {
+	f.callback = callback
+}
+
+func TestBGPReloadStatusFetcher(t *testing.T) {
+	reporter := &fakeReloadStatusReporter{}
+	newBGP = func(controllerConfig) bgp.SessionManager { return reporter }
+	changed := false
+	c, err := newController(controllerConfig{
+		MyNode:                "pandora",
+		DisableLayer2:         true,
+		bgpType:               bgpFrr,
+		BGPAdsChangedCallback: noopCallback,
+		BGPReloadStatusChange: func() { changed = true },
+	})
+	if err != nil {
+		t.Fatalf("creating controller: %s", err)
+	}
+	if c.bgpReloadStatusFetcher == nil {
+		t.Fatalf("no reload status fetcher for a session manager reporting reloads")
+	}
+	if status := c.bgpReloadStatusFetcher(); !status.Failed || status.ConsecutiveFailures != 1 {
+		t.Fatalf("unexpected reload status %v", status)
+	}
+	if reporter.callback == nil {
+		t.Fatalf("reload status callback not set")
+	}
+	reporter.callback()
+	if !changed {
+		t.Fatalf("reload status change not propagated")
+	}
+
+	b := &fakeBGP{t: t}
+	newBGP = b.NewSessionManager
+	c, err = newController(controllerConfig{
+		MyNode:                "pandora",
+		DisableLayer2:         true,
+		bgpType:               bgpNative,
+		BGPAdsChangedCallback: noopCallback,
+		BGPReloadStatusChange: func() {},
+	})
+	if err != nil {
+		t.Fatalf("creating controller: %s", err)
+	}
+	if c.bgpReloadStatusFetcher != nil {
+		t.Fatalf("reload status fetcher set for a session manager not reporting reloads")
+	}
+}
+
+type fakeFRRK8sSessionStatesSyncer struct {
+	fakeBGPSessionManager
+	states []frrv1beta1.BGPSessionStateStatus
+}
+
+func (f *fakeFRRK8sSessionStatesSyncer) SyncSessionStates(states []frrv1beta1.BGPSessionStateStatus) bool {
+	changed := !reflect.DeepEqual(f.states, states)
+	f.states = states
+	return changed
+}
+
+func TestFRRK8sSessionStatesHandler(t *testing.T) {
+	syncer := &fakeFRRK8sSessionStatesSyncer{}
+	newBGP = func(controllerConfig) bgp.SessionManager { return syncer }
+	changedSvcs := []string{}
+	c, err := newController(controllerConfig{
+		MyNode:                "pandora",
+		DisableLayer2:         true,
+		bgpType:               bgpFrrK8s,
+		BGPAdsChangedCallback: func(svc string) { changedSvcs = append(changedSvcs, svc) },
+	})
+	if err != nil {
+		t.Fatalf("creating controller: %s", err)
+	}
+	if c.frrk8sSessionStatesHandler == nil {
+		t.Fatalf("no session states handler in frr-k8s mode")
+	}
+	bgpController := c.protocolHandlers[config.BGP].(*bgpController)
+	bgpController.activeAds = map[string]map[string]sets.Set[string]{
+		"default/b": {"peer1": sets.New("10.20.30.2/32")},
+		"default/a": {"peer1": sets.New("10.20.30.1/32")},
+	}
+
+	states := []frrv1beta1.BGPSessionStateStatus{{Peer: "1.2.3.4", BGPStatus: "Established"}}
+	c.frrk8sSessionStatesHandler(states)
+	if !reflect.DeepEqual(syncer.states, states) {
+		t.Fatalf("unexpected synced states %v", syncer.states)
+	}
+	if diff := cmp.Diff([]string{"default/a", "default/b"}, changedSvcs); diff != "" {
+		t.Fatalf("unexpected refreshed services (-want +got)\n%s", diff)
+	}
+
+	changedSvcs = []string{}
+	c.frrk8sSessionStatesHandler(states)
+	if len(changedSvcs) != 0 {
+		t.Fatalf("services refreshed with no change in the states: %v", changedSvcs)
+	}
+
+	b := &fakeBGP{t: t}
+	newBGP = b.NewSessionManager
+	c, err = newController(controllerConfig{
+		MyNode:                "pandora",
+		DisableLayer2:         true,
+		bgpType:               bgpFrr,
+		BGPAdsChangedCallback: noopCallback,
+	})
+	if err != nil {
+		t.Fatalf("creating controller: %s", err)
+	}
+	if c.frrk8sSessionStatesHandler != nil {
+		t.Fatalf("session states handler set in frr mode")
+	}
+}
+
+// fakeReportingSession is a session reporting the state of its advertisements.
+type fakeReportingSession struct {
+	fakeSession
+	established bool
+	advertised  sets.Set[string]
+}
+
+func (f *fakeReportingSession) AdvertisementState(prefix string) (bool, bool) {
+	return f.established, f.established && f.advertised.Has(prefix)
+}
+
+func TestPeerConditionsForService(t *testing.T) {
+	svc := "default/test"
+	c := &bgpController{
+		activeAds: map[string]map[string]sets.Set[string]{
+			svc: {
+				"established": sets.New("10.20.30.1/32", "2001:db8::1/128"),
+				"down":        sets.New("10.20.30.1/32"),
+				"pending":     sets.New("10.20.30.1/32", "2001:db8::1/128"),
+				"unreporting": sets.New("10.20.30.1/32"),
+			},
+		},
+		activeSessions: map[string]bgp.Session{
+			"established": &fakeReportingSession{established: true, advertised: sets.New("10.20.30.1/32", "2001:db8::1/128")},
+			"down":        &fakeReportingSession{},
+			"pending":     &fakeReportingSession{established: true, advertised: sets.New("10.20.30.1/32")},
+			"unreporting": &fakeSession{},
+			"filtered":    &fakeReportingSession{established: true},
+		},
+		excludedSvcs:       map[string]string{},
+		adsChangedCallback: func(string) {},
+	}
+
+	expected := []metallbv1beta1.ServiceBGPPeerCondition{
+		{
+			Peer:    "down",
+			Reason:  metallbv1beta1.ServiceBGPPeerSessionNotEstablished,
+			Message: "the BGP session with the peer is not established",
+		},
+		{
+			Peer:       "established",
+			Advertised: true,
+			Reason:     metallbv1beta1.ServiceBGPPeerAdvertised,
+		},
+		{
+			Peer:    "filtered",
+			Reason:  metallbv1beta1.ServiceBGPPeerFiltered,
+			Message: "none of the BGPAdvertisements of the service select the peer",
+		},
+		{
+			Peer:    "pending",
+			Reason:  metallbv1beta1.ServiceBGPPeerPending,
+			Message: "the prefixes 2001:db8::1/128 are not advertised to the peer yet",
+		},
+		{
+			Peer:    "unreporting",
+			Reason:  metallbv1beta1.ServiceBGPPeerUnknown,
+			Message: "the BGP implementation does not report the state of the session with the peer",
+		},
+	}
+	if diff := cmp.Diff(expected, c.PeersForService(svc)); diff != "" {
+		t.Fatalf("unexpected conditions (-want +got)\n%s", diff)
+	}
+
+	if res := c.PeersForService("default/other"); res != nil {
+		t.Fatalf("expected no conditions for a service not announced, got %v", res)
+	}
+
+	if !c.setExcluded(svc, "nodeLabeledExcludeBalancers") {
+		t.Fatalf("expected the exclusion to be reported as a change")
+	}
+	if c.setExcluded(svc, "nodeLabeledExcludeBalancers") {
+		t.Fatalf("expected the same exclusion not to be reported as a change")
+	}
+	for _, cond := range c.PeersForService(svc) {
+		if cond.Advertised || cond.Reason != metallbv1beta1.ServiceBGPPeerNodeExcluded {
+			t.Fatalf("expected the peer to be excluded, got %v", cond)
+		}
+	}
+
+	if !c.setExcluded(svc, "serviceDeleted") {
+		t.Fatalf("expected the exclusion removal to be reported as a change")
+	}
+	if diff := cmp.Diff(expected, c.PeersForService(svc)); diff != "" {
+		t.Fatalf("unexpected conditions after removing the exclusion (-want +got)\n%s", diff)
+	}
+}
+
+func TestPrefixesForService(t *testing.T) {
+	svc := "default/test"
+	ad1 := &bgp.Advertisement{Prefix: ipnet("10.20.30.1/32"), ImportVRFs: []string{"red"}}
+	ad2 := &bgp.Advertisement{Prefix: ipnet("2001:db8::1/128")}
+	// The EVPN routes are announced from the VRF of the L3VNI.
+	ad3 := &bgp.Advertisement{Prefix: ipnet("10.20.30.2/32"), EVPN: &config.EVPN{VRF: "green", VNI: 100}}
+	changed := []string{}
+	c := &bgpController{
+		peers: []*peer{
+			{cfg: &config.Peer{Name: "peer1"}, session: &fakeSession{}},
+			{cfg: &config.Peer{Name: "peer2", VRF: "blue"}, session: &fakeSession{}},
+		},
+		svcAds: map[string][]*bgp.Advertisement{
+			svc: {ad1, ad2, ad3},
+		},
+		excludedSvcs:       map[string]string{},
+		adsChangedCallback: func(key string) { changed = append(changed, key) },
+	}
+
+	c.notifyAdsChanged(map[string][]*bgp.Advertisement{
+		"peer1": {ad1, ad2, ad3},
+		"peer2": {ad2},
+	})
+	expected := []metallbv1beta1.ServiceBGPPrefix{
+		{Prefix: "10.20.30.1/32", VRFs: []string{"default", "red"}},
+		{Prefix: "10.20.30.2/32", VRFs: []string{"green"}},
+		{Prefix: "2001:db8::1/128", VRFs: []string{"blue", "default"}},
+	}
+	if diff := cmp.Diff(expected, c.PrefixesForService(svc)); diff != "" {
+		t.Fatalf("unexpected prefixes (-want +got)\n%s", diff)
+	}
+	if res := c.PrefixesForService("default/other"); res != nil {
+		t.Fatalf("expected no prefixes for a service not announced, got %v", res)
+	}
+
+	// Announcing the prefixes to the same peers in different VRFs changes
+	// the status.
+	changed = []string{}
+	c.notifyAdsChanged(map[string][]*bgp.Advertisement{
+		"peer1": {ad1, ad2, ad3},
+		"peer2": {ad1, ad2},
+	})
+	if diff := cmp.Diff([]string{svc}, changed); diff != "" {
+		t.Fatalf("unexpected changed services (-want +got)\n%s", diff)
+	}
+	expected[0].VRFs = []string{"blue", "default", "red"}
+	if diff := cmp.Diff(expected, c.PrefixesForService(svc)); diff != "" {
+		t.Fatalf("unexpected prefixes (-want +got)\n%s", diff)
+	}
+}
+
+func TestPrefixLimit(t *testing.T) {
+	sm := &fakeBGPSessionManager{t: t, gotAds: map[string][]*bgp.Advertisement{}}
+	limited, _ := sm.NewSession(log.NewNopLogger(), bgp.SessionParameters{PeerAddress: "1.2.3.4"})
+	unlimited, _ := sm.NewSession(log.NewNopLogger(), bgp.SessionParameters{PeerAddress: "1.2.3.5"})
+	c := &bgpController{
+		logger: log.NewNopLogger(),
+		peers: []*peer{
+			{cfg: &config.Peer{Name: "peer1", PrefixLimits: &config.PrefixLimits{MaxAdvertised: 2}}, session: limited},
+			{cfg: &config.Peer{Name: "peer2"}, session: unlimited},
+		},
+		svcAds: map[string][]*bgp.Advertisement{
+			"default/b": {{Prefix: ipnet("10.20.30.2/32")}},
+			"default/c": {{Prefix: ipnet("10.20.30.3/32")}},
+		},
+		excludedSvcs:       map[string]string{},
+		adsChangedCallback: func(string) {},
+	}
+	advertised := func(addr string) []string {
+		res := []string{}
+		for _, ad := range sm.Ads()[addr] {
+			res = append(res, ad.Prefix.String())
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	if err := c.updateAds(); err != nil {
+		t.Fatalf("failed to update the advertisements: %s", err)
+	}
+	if diff := cmp.Diff([]string{"10.20.30.2/32", "10.20.30.3/32"}, advertised("1.2.3.4")); diff != "" {
+		t.Fatalf("unexpected advertisements (-want +got)\n%s", diff)
+	}
+
+	// The prefixes already advertised are kept, even if the new one sorts
+	// before them.
+	c.svcAds["default/a"] = []*bgp.Advertisement{{Prefix: ipnet("10.20.30.1/32")}}
+	if err := c.updateAds(); err != nil {
+		t.Fatalf("failed to update the advertisements: %s", err)
+	}
+	if diff := cmp.Diff([]string{"10.20.30.2/32", "10.20.30.3/32"}, advertised("1.2.3.4")); diff != "" {
+		t.Fatalf("unexpected advertisements (-want +got)\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"10.20.30.1/32", "10.20.30.2/32", "10.20.30.3/32"}, advertised("1.2.3.5")); diff != "" {
+		t.Fatalf("unexpected advertisements to the unlimited peer (-want +got)\n%s", diff)
+	}
+	expected := []metallbv1beta1.ServiceBGPPeerCondition{
+		{
+			Peer:    "peer1",
+			Reason:  metallbv1beta1.ServiceBGPPeerPrefixLimitExceeded,
+			Message: "the prefixes 10.20.30.1/32 are held back, the peer reached its limit of advertised prefixes",
+		},
+		{
+			Peer:    "peer2",
+			Reason:  metallbv1beta1.ServiceBGPPeerUnknown,
+			Message: "the BGP implementation does not report the state of the session with the peer",
+		},
+	}
+	if diff := cmp.Diff(expected, c.PeersForService("default/a")); diff != "" {
+		t.Fatalf("unexpected conditions (-want +got)\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"peer1"}, c.peersHoldingBack("default/a")); diff != "" {
+		t.Fatalf("unexpected peers holding back the service (-want +got)\n%s", diff)
+	}
+
+	// Withdrawing a prefix makes room for the held back one.
+	delete(c.svcAds, "default/c")
+	if err := c.updateAds(); err != nil {
+		t.Fatalf("failed to update the advertisements: %s", err)
+	}
+	if diff := cmp.Diff([]string{"10.20.30.1/32", "10.20.30.2/32"}, advertised("1.2.3.4")); diff != "" {
+		t.Fatalf("unexpected advertisements (-want +got)\n%s", diff)
+	}
+	if peers := c.peersHoldingBack("default/a"); len(peers) > 0 {
+		t.Fatalf("expected no peer holding back the service, got %v", peers)
+	}
+}
+
+func TestAdsForPeerASPathPrepend(t *testing.T) {
+	ads := []*bgp.Advertisement{
+		{
+			Prefix:            ipnet("10.20.30.1/32"),
+			ASPathPrepend:     2,
+			PeerASPathPrepend: map[string]uint32{"peer1": 0, "peer2": 4},
+		},
+		{
+			Prefix:        ipnet("10.20.30.2/32"),
+			ASPathPrepend: 1,
+			Peers:         []string{"peer1"},
+		},
+	}
+
+	tests := []struct {
+		peer     string
+		expected map[string]uint32 // prefix -> count
+	}{
+		{peer: "peer1", expected: map[string]uint32{"10.20.30.1/32": 0, "10.20.30.2/32": 1}},
+		{peer: "peer2", expected: map[string]uint32{"10.20.30.1/32": 4}},
+		{peer: "peer3", expected: map[string]uint32{"10.20.30.1/32": 2}},
+	}
+	for _, tc := range tests {
+		got := map[string]uint32{}
+		for _, ad := range adsForPeer(tc.peer, ads) {
+			if ad.PeerASPathPrepend != nil {
+				t.Errorf("%s: the per peer prepend was not resolved for %s", tc.peer, ad.Prefix)
+			}
+			got[ad.Prefix.String()] = ad.ASPathPrepend
+		}
+		if diff := cmp.Diff(tc.expected, got); diff != "" {
+			t.Errorf("%s: unexpected AS path prepend (-want +got)\n%s", tc.peer, diff)
+		}
+	}
+	if ads[0].ASPathPrepend != 2 {
+		t.Errorf("the original advertisement was modified")
+	}
+}
+
+func TestSetBalancerMED(t *testing.T) {
+	c := &bgpController{
+		myNode:             "pandora",
+		svcAds:             map[string][]*bgp.Advertisement{},
+		activeAds:          map[string]map[string]sets.Set[string]{},
+		excludedSvcs:       map[string]string{},
+		adsChangedCallback: func(string) {},
+	}
+	pool := &config.Pool{
+		BGPAdvertisements: []*config.BGPAdvertisement{
+			{
+				AggregationLength:          32,
+				AggregationLengthV6:        128,
+				MED:                        ptr.To[uint32](100),
+				MEDFromReadyLocalEndpoints: true,
+				Nodes:                      map[string]bool{"pandora": true},
+			},
+			{
+				AggregationLength:   32,
+				AggregationLengthV6: 128,
+				MED:                 ptr.To[uint32](50),
+				Nodes:               map[string]bool{"pandora": true},
+			},
+		},
+	}
+	endpoint := func(node string, ready bool) discovery.Endpoint {
+		return discovery.Endpoint{
+			Addresses:  []string{"10.1.1.1"},
+			NodeName:   ptr.To(node),
+			Conditions: discovery.EndpointConditions{Ready: ptr.To(ready)},
+		}
+	}
+	eps := []discovery.EndpointSlice{
+		{
+			AddressType: discovery.AddressTypeIPv4,
+			Endpoints: []discovery.Endpoint{
+				endpoint("pandora", true),
+				endpoint("pandora", true),
+				endpoint("pandora", false),
+				endpoint("iris", true),
+			},
+		},
+		{
+			AddressType: discovery.AddressTypeIPv6,
+			Endpoints: []discovery.Endpoint{
+				endpoint("pandora", true),
+			},
+		},
+	}
+
+	lbIPs := []net.IP{net.ParseIP("10.20.30.1"), net.ParseIP("2001:db8::1")}
+	err := c.SetBalancer(log.NewNopLogger(), "test", lbIPs, pool, nil, &v1.Service{}, eps)
+	if err != nil {
+		t.Fatalf("set balancer: %s", err)
+	}
+
+	expected := map[string][]uint32{
+		"10.20.30.1/32":   {98, 50},
+		"2001:db8::1/128": {99, 50},
+	}
+	got := map[string][]uint32{}
+	for _, ad := range c.svcAds["test"] {
+		if ad.MED == nil {
+			t.Fatalf("no MED set for %s", ad.Prefix)
+		}
+		got[ad.Prefix.String()] = append(got[ad.Prefix.String()], *ad.MED)
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatalf("unexpected MEDs (-want +got)\n%s", diff)
+	}
+}
+
+func TestSetBalancerLinkBandwidth(t *testing.T) {
+	c := &bgpController{
+		myNode:             "pandora",
+		svcAds:             map[string][]*bgp.Advertisement{},
+		activeAds:          map[string]map[string]sets.Set[string]{},
+		excludedSvcs:       map[string]string{},
+		adsChangedCallback: func(string) {},
+	}
+	endpoints := func(n int) []discovery.Endpoint {
+		res := []discovery.Endpoint{}
+		for range n {
+			res = append(res, discovery.Endpoint{
+				Addresses:  []string{"10.1.1.1"},
+				NodeName:   ptr.To("pandora"),
+				Conditions: discovery.EndpointConditions{Ready: ptr.To(true)},
+			})
+		}
+		return res
+	}
+
+	tests := []struct {
+		desc        string
+		perEndpoint uint32
+		endpoints   int
+		expected    []string
+	}{
+		{desc: "no local endpoints", perEndpoint: 10000, endpoints: 0, expected: nil},
+		{desc: "one local endpoint", perEndpoint: 10000, endpoints: 1, expected: []string{"bandwidth:10000"}},
+		{desc: "capped bandwidth", perEndpoint: 10000, endpoints: 3, expected: []string{"bandwidth:25600"}},
+		{desc: "capped bandwidth overflowing 32 bits", perEndpoint: 1 << 31, endpoints: 2, expected: []string{"bandwidth:25600"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			pool := &config.Pool{
+				BGPAdvertisements: []*config.BGPAdvertisement{
+					{
+						AggregationLength:        32,
+						AggregationLengthV6:      128,
+						LinkBandwidthPerEndpoint: tc.perEndpoint,
+						Nodes:                    map[string]bool{"pandora": true},
+					},
+				},
+			}
+			eps := []discovery.EndpointSlice{
+				{
+					AddressType: discovery.AddressTypeIPv4,
+					Endpoints:   endpoints(tc.endpoints),
+				},
+			}
+			err := c.SetBalancer(log.NewNopLogger(), "test", []net.IP{net.ParseIP("10.20.30.1")}, pool, nil, &v1.Service{}, eps)
+			if err != nil {
+				t.Fatalf("set balancer: %s", err)
+			}
+			var got []string
+			for _, comm := range c.svcAds["test"][0].Communities {
+				got = append(got, comm.String())
+			}
+			if diff := cmp.Diff(tc.expected, got); diff != "" {
+				t.Fatalf("unexpected communities (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetBalancerAggregation(t *testing.T) {
+	f := &fakeBGPSessionManager{t: t, gotAds: map[string][]*bgp.Advertisement{"10.0.0.1": nil}}
+	c := &bgpController{
+		myNode: "pandora",
+		peers: []*peer{
+			{cfg: &config.Peer{Name: "peer1"}, id: "10.0.0.1", session: &fakeSession{f: f, addr: "10.0.0.1"}},
+		},
+		svcAds:             map[string][]*bgp.Advertisement{},
+		activeAds:          map[string]map[string]sets.Set[string]{},
+		excludedSvcs:       map[string]string{},
+		adsChangedCallback: func(string) {},
+	}
+	pool := &config.Pool{
+		CIDR: []*net.IPNet{ipnet("10.20.30.0/24")},
+		BGPAdvertisements: []*config.BGPAdvertisement{
+			{
+				AggregationLength:   32,
+				AggregationLengthV6: 128,
+				Aggregation:         &config.Aggregation{WithinPools: true, SummaryOnly: true},
+				Nodes:               map[string]bool{"pandora": true},
+			},
+		},
+	}
+
+	for svc, ip := range map[string]string{"svc1": "10.20.30.0", "svc2": "10.20.30.1", "svc3": "10.20.30.3"} {
+		err := c.SetBalancer(log.NewNopLogger(), svc, []net.IP{net.ParseIP(ip)}, pool, nil, &v1.Service{}, nil)
+		if err != nil {
+			t.Fatalf("set balancer: %s", err)
+		}
+	}
+
+	var got []string
+	for _, ad := range f.Ads()["10.0.0.1"] {
+		got = append(got, ad.Prefix.String())
+	}
+	sort.Strings(got)
+	if diff := cmp.Diff([]string{"10.20.30.0/31", "10.20.30.3/32"}, got); diff != "" {
+		t.Fatalf("unexpected advertised prefixes (-want +got)\n%s", diff)
+	}
+
+	expectedActive := map[string]map[string]sets.Set[string]{
+		"svc1": {"peer1": sets.New("10.20.30.0/31")},
+		"svc2": {"peer1": sets.New("10.20.30.0/31")},
+		"svc3": {"peer1": sets.New("10.20.30.3/32")},
+	}
+	if diff := cmp.Diff(expectedActive, c.activeAds); diff != "" {
+		t.Fatalf("unexpected active advertisements (-want +got)\n%s", diff)
+	}
+}
+
+func TestSetBalancerCondition(t *testing.T) {
+	f := &fakeBGPSessionManager{t: t, gotAds: map[string][]*bgp.Advertisement{"10.0.0.1": nil, "10.0.0.3": nil}}
+	c := &bgpController{
+		myNode: "pandora",
+		peers: []*peer{
+			{cfg: &config.Peer{Name: "primary", Addr: net.ParseIP("10.0.0.1")}, id: "10.0.0.1", session: &fakeSession{f: f, addr: "10.0.0.1"}},
+			{cfg: &config.Peer{Name: "unnumbered", Iface: "eth1"}, id: "eth1"},
+			{cfg: &config.Peer{Name: "target", Addr: net.ParseIP("10.0.0.3")}, id: "10.0.0.3", session: &fakeSession{f: f, addr: "10.0.0.3"}},
+		},
+		svcAds:             map[string][]*bgp.Advertisement{},
+		activeAds:          map[string]map[string]sets.Set[string]{},
+		excludedSvcs:       map[string]string{},
+		adsChangedCallback: func(string) {},
+	}
+	pool := &config.Pool{
+		BGPAdvertisements: []*config.BGPAdvertisement{
+			{
+				AggregationLength:   32,
+				AggregationLengthV6: 128,
+				Nodes:               map[string]bool{"pandora": true},
+				Peers:               []string{"target"},
+				Condition: &config.AdvertisementCondition{
+					NonExist: true,
+					Prefixes: []*net.IPNet{ipnet("0.0.0.0/0")},
+					Peers:    []string{"primary", "unnumbered"},
+				},
+			},
+		},
+	}
+
+	err := c.SetBalancer(log.NewNopLogger(), "test", []net.IP{net.ParseIP("10.20.30.1")}, pool, nil, &v1.Service{}, nil)
+	if err != nil {
+		t.Fatalf("set balancer: %s", err)
+	}
+	// The peers with no session on the node are not tracked.
+	expected := &bgp.Condition{
+		NonExist: true,
+		Prefixes: []string{"0.0.0.0/0"},
+		Peers:    []string{"10.0.0.1"},
+	}
+	if len(f.gotAds["10.0.0.3"]) != 1 {
+		t.Fatalf("expected one advertisement, got %d", len(f.gotAds["10.0.0.3"]))
+	}
+	if diff := cmp.Diff(expected, f.gotAds["10.0.0.3"][0].Condition); diff != "" {
+		t.Fatalf("unexpected condition (-want +got)\n%s", diff)
+	}
+
+	// When none of the tracked peers has a session, no route can be
+	// received from them and the advertisement is announced unconditionally.
+	c.peers[0].session = nil
+	delete(f.gotAds, "10.0.0.1")
+	if err := c.updateAds(); err != nil {
+		t.Fatalf("update ads: %s", err)
+	}
+	if len(f.gotAds["10.0.0.3"]) != 1 {
+		t.Fatalf("expected one advertisement, got %d", len(f.gotAds["10.0.0.3"]))
+	}
+	if f.gotAds["10.0.0.3"][0].Condition != nil {
+		t.Fatalf("expected no condition, got %v", f.gotAds["10.0.0.3"][0].Condition)
+	}
+
+	// The advertisements depending on the routes existing are not announced
+	// rather than tracking any peer.
+	c.svcAds["test"][0].Condition.NonExist = false
+	if err := c.updateAds(); err != nil {
+		t.Fatalf("update ads: %s", err)
+	}
+	if len(f.gotAds["10.0.0.3"]) != 0 {
+		t.Fatalf("expected no advertisement, got %v", f.gotAds["10.0.0.3"])
+	}
+	c.svcAds["test"][0].Condition.NonExist = true
+
+	// The conditions are resolved again when the session comes back.
+	c.peers[1].session = &fakeSession{f: f, addr: "eth1"}
+	f.gotAds["eth1"] = nil
+	if err := c.updateAds(); err != nil {
+		t.Fatalf("update ads: %s", err)
+	}
+	expected.Peers = []string{"eth1"}
+	if len(f.gotAds["10.0.0.3"]) != 1 {
+		t.Fatalf("expected one advertisement, got %d", len(f.gotAds["10.0.0.3"]))
+	}
+	if diff := cmp.Diff(expected, f.gotAds["10.0.0.3"][0].Condition); diff != "" {
+		t.Fatalf("unexpected condition (-want +got)\n%s", diff)
+	}
+}
+
+func TestConditionPeerNotOnNode(t *testing.T) {
+	b := &fakeBGP{
+		t: t,
+	}
+	newBGP = b.NewSessionManager
+	c, err := newController(controllerConfig{
+		MyNode:                "pandora",
+		DisableLayer2:         true,
+		bgpType:               bgpFrr,
+		BGPAdsChangedCallback: noopCallback,
+	})
+	if err != nil {
+		t.Fatalf("creating controller: %s", err)
+	}
+	c.client = &testK8S{t: t}
+
+	configFor := func(nonExist bool) *config.Config {
+		return &config.Config{
+			Peers: map[string]*config.Peer{
+				"primary": {
+					Name:          "primary",
+					Addr:          net.ParseIP("1.2.3.4"),
+					NodeSelectors: []labels.Selector{mustSelector("rack=a")},
+				},
+				"backup": {
+					Name:          "backup",
+					Addr:          net.ParseIP("2.3.4.5"),
+					NodeSelectors: []labels.Selector{labels.Everything()},
+				},
+			},
+			Pools: &config.Pools{ByName: map[string]*config.Pool{
+				"default": {
+					CIDR: []*net.IPNet{ipnet("10.20.30.0/24")},
+					BGPAdvertisements: []*config.BGPAdvertisement{
+						{
+							AggregationLength: 32,
+							Nodes:             map[string]bool{"pandora": true},
+							Peers:             []string{"backup"},
+							Condition: &config.AdvertisementCondition{
+								NonExist: nonExist,
+								Prefixes: []*net.IPNet{ipnet("0.0.0.0/0")},
+								Peers:    []string{"primary"},
+							},
+						},
+					},
+				},
+			}},
+		}
+	}
+	svc := &v1.Service{
+		Spec: v1.ServiceSpec{
+			Type:                  "LoadBalancer",
+			ExternalTrafficPolicy: "Cluster",
+		},
+		Status: statusAssigned("10.20.30.1"),
+	}
+	eps := []discovery.EndpointSlice{
+		{
+			Endpoints: []discovery.Endpoint{
+				{
+					Addresses: []string{"2.3.4.5"},
+					NodeName:  ptr.To("pandora"),
+					Conditions: discovery.EndpointConditions{
+						Ready: ptr.To(true),
+					},
+				},
+			},
+		},
+	}
+
+	l := log.NewNopLogger()
+	// The primary peer is selected out of the node: no route can be received
+	// from it, the advertisement to the backup peer is announced unconditionally.
+	if c.SetConfig(l, configFor(true)) == controllers.SyncStateError {
+		t.Fatalf("SetConfig failed")
+	}
+	if c.SetBalancer(l, "test1", svc, eps) == controllers.SyncStateError {
+		t.Fatalf("SetBalancer failed")
+	}
+	wantAds := map[string][]*bgp.Advertisement{
+		"2.3.4.5": {
+			{
+				Prefix: ipnet("10.20.30.1/32"),
+				Peers:  []string{"backup"},
+			},
+		},
+	}
+	if diff := cmp.Diff(wantAds, b.sessionManager.Ads()); diff != "" {
+		t.Errorf("unexpected advertisement state (-want +got)\n%s", diff)
+	}
+
+	// An advertisement depending on the routes of the primary peer existing is
+	// not announced.
+	if c.SetConfig(l, configFor(false)) == controllers.SyncStateError {
+		t.Fatalf("SetConfig failed")
+	}
+	if c.SetBalancer(l, "test1", svc, eps) == controllers.SyncStateError {
+		t.Fatalf("SetBalancer failed")
+	}
+	wantAds = map[string][]*bgp.Advertisement{
+		"2.3.4.5": nil,
+	}
+	if diff := cmp.Diff(wantAds, b.sessionManager.Ads()); diff != "" {
+		t.Errorf("unexpected advertisement state (-want +got)\n%s", diff)
+	}
+}
@@ -0,0 +1,182 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dampeningHold is the state the advertisements of a flapping service are
+// held in while it is suppressed.
+type dampeningHold string
+
+const (
+	holdAdvertised dampeningHold = "advertised"
+	holdWithdrawn  dampeningHold = "withdrawn"
+)
+
+// The penalties follow the defaults of RFC 2439: each flap adds flapPenalty,
+// a service is suppressed above suppressThreshold and reused once its
+// penalty decayed below reuseThreshold. The penalty is capped so that a
+// service is not suppressed for more than four half-lives.
+const (
+	flapPenalty       = 1000
+	suppressThreshold = 2000
+	reuseThreshold    = 750
+	maxPenalty        = reuseThreshold * 16
+)
+
+var dampeningStats = struct {
+	flaps      *prometheus.CounterVec
+	suppressed *prometheus.GaugeVec
+}{
+	flaps: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "metallb",
+		Subsystem: "speaker",
+		Name:      "bgp_flaps_total",
+		Help:      "Number of times the BGP advertisements of a service were withdrawn because its endpoints went away",
+	}, []string{
+		"service",
+	}),
+	suppressed: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "metallb",
+		Subsystem: "speaker",
+		Name:      "bgp_dampened",
+		Help:      "Services whose BGP advertisements are held by the dampening, as they are flapping",
+	}, []string{
+		"service",
+	}),
+}
+
+// dampening penalises the services whose BGP advertisements flap because
+// their endpoints come and go, and holds the advertisements of the ones
+// flapping too often until their penalty decays.
+type dampening struct {
+	halfLife time.Duration
+	hold     dampeningHold
+	// onReuse is called once the penalty of a suppressed service decayed
+	// enough for it to be reused, so that the services are resynced.
+	onReuse func()
+	now     func() time.Time
+	flaps   map[string]*flapState
+}
+
+type flapState struct {
+	penalty    float64
+	updated    time.Time
+	suppressed bool
+	// Whether the service was to be advertised as of the last update.
+	advertised bool
+	timer      *time.Timer
+}
+
+func newDampening(halfLife time.Duration, hold dampeningHold, onReuse func()) *dampening {
+	return &dampening{
+		halfLife: halfLife,
+		hold:     hold,
+		onReuse:  onReuse,
+		now:      time.Now,
+		flaps:    map[string]*flapState{},
+	}
+}
+
+// isFlap returns true if the service is withdrawn because of its endpoints,
+// which is what makes it flap.
+func isFlap(reason string) bool {
+	return reason == "noLocalEndpoints" || reason == "noEndpoints"
+}
+
+// advertise records that the service is to be advertised, and returns true
+// if its advertisements must be held withdrawn nonetheless.
+func (d *dampening) advertise(l log.Logger, name string) bool {
+	s, ok := d.flaps[name]
+	if !ok {
+		s = &flapState{updated: d.now()}
+		d.flaps[name] = s
+	}
+	d.decay(l, name, s)
+	s.advertised = true
+	return s.suppressed && d.hold == holdWithdrawn
+}
+
+// withdraw records that the service is not to be advertised anymore, and
+// returns true if its advertisements must be held advertised nonetheless.
+func (d *dampening) withdraw(l log.Logger, name, reason string) bool {
+	if !isFlap(reason) {
+		d.forget(name)
+		return false
+	}
+	s, ok := d.flaps[name]
+	if !ok {
+		return false
+	}
+	d.decay(l, name, s)
+	if s.advertised {
+		s.advertised = false
+		s.penalty = min(s.penalty+flapPenalty, maxPenalty)
+		dampeningStats.flaps.WithLabelValues(name).Inc()
+		if !s.suppressed && s.penalty >= suppressThreshold {
+			level.Info(l).Log("event", "bgpDampened", "service", name, "penalty", int(s.penalty), "hold", d.hold, "msg", "service flapping, holding its advertisements")
+			s.suppressed = true
+			dampeningStats.suppressed.WithLabelValues(name).Set(1)
+		}
+		if s.suppressed {
+			d.scheduleReuse(s)
+		}
+	}
+	return s.suppressed && d.hold == holdAdvertised
+}
+
+// decay lowers the penalty of the service according to the time elapsed
+// since its last update, releasing it if it fell below the reuse threshold.
+func (d *dampening) decay(l log.Logger, name string, s *flapState) {
+	now := d.now()
+	elapsed := now.Sub(s.updated)
+	s.penalty *= math.Exp2(-elapsed.Seconds() / d.halfLife.Seconds())
+	s.updated = now
+	if s.suppressed && s.penalty < reuseThreshold {
+		level.Info(l).Log("event", "bgpDampeningReleased", "service", name, "msg", "service not flapping anymore, releasing its advertisements")
+		s.suppressed = false
+		dampeningStats.suppressed.DeleteLabelValues(name)
+		if s.timer != nil {
+			s.timer.Stop()
+			s.timer = nil
+		}
+	}
+}
+
+// scheduleReuse arms the timer resyncing the services once the penalty of
+// the service decayed below the reuse threshold.
+func (d *dampening) scheduleReuse(s *flapState) {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	// A second of slack, so that the penalty is below the threshold by the
+	// time the service is resynced.
+	after := time.Duration(math.Log2(s.penalty/reuseThreshold)*float64(d.halfLife)) + time.Second
+	s.timer = time.AfterFunc(after, func() {
+		if d.onReuse != nil {
+			d.onReuse()
+		}
+	})
+}
+
+// forget drops the state of the service, which is not announced for reasons
+// other than its endpoints.
+func (d *dampening) forget(name string) {
+	s, ok := d.flaps[name]
+	if !ok {
+		return
+	}
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	delete(d.flaps, name)
+	dampeningStats.flaps.DeleteLabelValues(name)
+	dampeningStats.suppressed.DeleteLabelValues(name)
+}
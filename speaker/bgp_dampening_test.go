@@ -0,0 +1,158 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"go.universe.tf/metallb/internal/bgp"
+	"go.universe.tf/metallb/internal/config"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestDampening(t *testing.T) {
+	pool := &config.Pool{
+		BGPAdvertisements: []*config.BGPAdvertisement{
+			{
+				AggregationLength:   32,
+				AggregationLengthV6: 128,
+				Nodes:               map[string]bool{"pandora": true},
+			},
+		},
+	}
+	lbIPs := []net.IP{net.ParseIP("10.20.30.1")}
+
+	tests := []struct {
+		desc string
+		hold dampeningHold
+		// Whether the service is advertised while suppressed, when its
+		// endpoints are gone and when they are back.
+		withoutEndpoints bool
+		withEndpoints    bool
+	}{
+		{desc: "held advertised", hold: holdAdvertised, withoutEndpoints: true, withEndpoints: true},
+		{desc: "held withdrawn", hold: holdWithdrawn, withoutEndpoints: false, withEndpoints: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			now := time.Now()
+			d := newDampening(time.Minute, tc.hold, nil)
+			d.now = func() time.Time { return now }
+			c := &bgpController{
+				myNode:             "pandora",
+				svcAds:             map[string][]*bgp.Advertisement{},
+				activeAds:          map[string]map[string]sets.Set[string]{},
+				excludedSvcs:       map[string]string{},
+				adsChangedCallback: func(string) {},
+				dampening:          d,
+			}
+			k := &testK8S{t: t}
+			l := log.NewNopLogger()
+			name := "test/" + string(tc.hold)
+			// The suppressed services are held, withdrawn or advertised.
+			heldWithdrawn := false
+			heldAdvertised := false
+			set := func() {
+				t.Helper()
+				err := c.SetBalancer(l, name, lbIPs, pool, k, &v1.Service{}, nil)
+				if held := errors.Is(err, errHeld); held != heldWithdrawn || (err != nil && !held) {
+					t.Fatalf("set balancer: expected held %v, got %v", heldWithdrawn, err)
+				}
+			}
+			del := func(reason string) {
+				t.Helper()
+				err := c.DeleteBalancer(l, name, reason)
+				if held := errors.Is(err, errHeld); held != heldAdvertised || (err != nil && !held) {
+					t.Fatalf("delete balancer: expected held %v, got %v", heldAdvertised, err)
+				}
+			}
+			expectAdvertised := func(step string, expected bool) {
+				t.Helper()
+				if _, ok := c.svcAds[name]; ok != expected {
+					t.Fatalf("%s: expected advertised %v, got %v", step, expected, ok)
+				}
+			}
+
+			// A single flap is not enough to suppress the service.
+			set()
+			del("noEndpoints")
+			expectAdvertised("first flap", false)
+			set()
+			expectAdvertised("back after the first flap", true)
+
+			heldAdvertised = tc.hold == holdAdvertised
+			del("noLocalEndpoints")
+			expectAdvertised("suppressed without endpoints", tc.withoutEndpoints)
+			// Being told again the endpoints are gone is not a flap.
+			del("noLocalEndpoints")
+			if got := testutil.ToFloat64(dampeningStats.flaps.WithLabelValues(name)); got != 2 {
+				t.Fatalf("expected 2 flaps, got %v", got)
+			}
+			if got := testutil.ToFloat64(dampeningStats.suppressed.WithLabelValues(name)); got != 1 {
+				t.Fatalf("expected the service to be reported as dampened, got %v", got)
+			}
+			heldWithdrawn = tc.hold == holdWithdrawn
+			set()
+			expectAdvertised("suppressed with endpoints", tc.withEndpoints)
+
+			// Two half-lives later the penalty is below the reuse threshold.
+			now = now.Add(2 * time.Minute)
+			heldAdvertised, heldWithdrawn = false, false
+			if tc.hold == holdAdvertised {
+				del("noEndpoints")
+				expectAdvertised("released without endpoints", false)
+			} else {
+				set()
+				expectAdvertised("released with endpoints", true)
+			}
+			if d.flaps[name].suppressed {
+				t.Fatalf("expected the service to be released")
+			}
+
+			// The other reasons withdraw the service right away and drop
+			// its penalty, even when it is suppressed again.
+			set()
+			// With the penalty left from the release, this flap suppresses
+			// the service again.
+			heldAdvertised = tc.hold == holdAdvertised
+			del("noEndpoints")
+			heldAdvertised = false
+			set()
+			del("serviceDeleted")
+			expectAdvertised("deleted", false)
+			if _, ok := d.flaps[name]; ok {
+				t.Fatalf("expected the state of the deleted service to be dropped")
+			}
+		})
+	}
+}
+
+func TestDampeningDecay(t *testing.T) {
+	now := time.Now()
+	d := newDampening(10*time.Minute, holdAdvertised, nil)
+	d.now = func() time.Time { return now }
+	s := &flapState{penalty: 4000, updated: now, suppressed: true}
+
+	now = now.Add(10 * time.Minute)
+	d.decay(log.NewNopLogger(), "test", s)
+	if int(s.penalty) != 2000 || !s.suppressed {
+		t.Fatalf("expected a suppressed penalty of 2000 after a half-life, got %v (suppressed %v)", s.penalty, s.suppressed)
+	}
+	now = now.Add(10 * time.Minute)
+	d.decay(log.NewNopLogger(), "test", s)
+	if int(s.penalty) != 1000 || !s.suppressed {
+		t.Fatalf("expected a suppressed penalty of 1000 after two half-lives, got %v (suppressed %v)", s.penalty, s.suppressed)
+	}
+	now = now.Add(5 * time.Minute)
+	d.decay(log.NewNopLogger(), "test", s)
+	if s.suppressed {
+		t.Fatalf("expected the service to be released with a penalty of %v", s.penalty)
+	}
+}
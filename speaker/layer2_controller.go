@@ -132,7 +132,7 @@ func (c *layer2Controller) ShouldAnnounce(l log.Logger, name string, toAnnounce
 	return "notOwner"
 }
 
-func (c *layer2Controller) SetBalancer(l log.Logger, name string, lbIPs []net.IP, pool *config.Pool, client service, svc *v1.Service) error {
+func (c *layer2Controller) SetBalancer(l log.Logger, name string, lbIPs []net.IP, pool *config.Pool, client service, svc *v1.Service, _ []discovery.EndpointSlice) error {
 	ifs := c.announcer.GetInterfaces()
 	updateStatus := false
 	allAdsForService := l2AdsForService(pool.L2Advertisements, svc)
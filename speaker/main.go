@@ -15,6 +15,7 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"net"
@@ -77,6 +78,8 @@ type service interface {
 
 func main() {
 	crmetrics.Registry.MustRegister(announcing)
+	crmetrics.Registry.MustRegister(dampeningStats.flaps)
+	crmetrics.Registry.MustRegister(dampeningStats.suppressed)
 
 	var (
 		bgpDebounceTimeoutMs = flag.String("bgp-debounce-timeout", os.Getenv("METALLB_BGP_DEBOUNCE_TIMEOUT"),
@@ -99,6 +102,10 @@ func main() {
 		ignoreLBExclude         = flag.Bool("ignore-exclude-lb", false, "ignore the exclude-from-external-load-balancers label")
 		frrK8sNamespace         = flag.String("frrk8s-namespace", os.Getenv("FRRK8S_NAMESPACE"), "the namespace frr-k8s is being deployed on")
 		frrK8sSecretPassthrough = flag.Bool("frrk8s-secret-passthrough", false, "pass BGP secret references to frr-k8s without resolving them, the secret must exist in the frr-k8s namespace")
+		bgpDampeningHalfLife    = flag.Duration("bgp-dampening-half-life", 0, "half-life of the penalty of the services whose BGP advertisements flap because of their endpoints. Zero disables the dampening")
+		bgpDampeningHold        = flag.String("bgp-dampening-hold", string(holdAdvertised), "the state the advertisements of a flapping service are held in while dampened, must be one of: [advertised, withdrawn]")
+		frrK8sConfigPerPeer     = flag.Bool("frrk8s-config-per-peer", false, "configure each BGP peer with its own FRRConfiguration, next to the one of the node. Only applies when METALLB_BGP_TYPE=frr-k8s")
+		frrIncrementalConfig    = flag.Bool("frr-incremental-config", false, "apply the FRR configuration changes over vtysh instead of reloading the configuration file. Only applies when METALLB_BGP_TYPE=frr")
 		tlsMinVersion           = flag.String("tls-min-version", "", "Minimum TLS version (VersionTLS12 or VersionTLS13). If empty, defaults to VersionTLS13.")
 		tlsCipherSuites         = flag.String("tls-cipher-suites", "", "Comma-separated list of TLS cipher suites. Only applies to TLS 1.2. If empty, uses Go defaults.")
 		tlsCurvePreferences     = flag.String("tls-curve-preferences", "", "Comma-separated list of numeric CurveID values (see https://pkg.go.dev/crypto/tls#CurveID). If empty, uses Go defaults.")
@@ -204,12 +211,40 @@ func main() {
 		}
 	}
 
+	if *frrK8sConfigPerPeer && bgpType != string(bgpFrrK8s) {
+		level.Error(logger).Log("op", "startup", "error", "--frrk8s-config-per-peer requires METALLB_BGP_TYPE=frr-k8s")
+		os.Exit(1)
+	}
+
+	if *frrIncrementalConfig && bgpType != string(bgpFrr) {
+		level.Error(logger).Log("op", "startup", "error", "--frr-incremental-config requires METALLB_BGP_TYPE=frr")
+		os.Exit(1)
+	}
+
+	if *bgpDampeningHalfLife < 0 {
+		level.Error(logger).Log("op", "startup", "error", "--bgp-dampening-half-life must not be negative")
+		os.Exit(1)
+	}
+	switch dampeningHold(*bgpDampeningHold) {
+	case holdAdvertised, holdWithdrawn:
+	default:
+		level.Error(logger).Log("op", "startup", "error", fmt.Sprintf("invalid --bgp-dampening-hold %q, must be one of: [advertised, withdrawn]", *bgpDampeningHold))
+		os.Exit(1)
+	}
+
 	if *frrK8sNamespace == "" { // if not set, assuming it runs under metallb
 		frrK8sNamespace = namespace
 	}
 
 	l2StatusChan := make(chan event.GenericEvent)
 	bgpStatusChan := make(chan event.GenericEvent)
+	bgpSessionStateChan := make(chan event.GenericEvent, 1)
+	bgpReloadStatusChan := make(chan event.GenericEvent, 1)
+	nodeChan := make(chan event.GenericEvent, 1)
+
+	// The client is created after the controller, the dampening resyncs the
+	// services through it when a flapping service can be reused.
+	var forceSync func()
 
 	// Setup all clients and speakers, config decides what is being done runtime.
 	ctrl, err := newController(controllerConfig{
@@ -217,6 +252,7 @@ func main() {
 		Namespace:               *namespace,
 		FRRK8sNamespace:         *frrK8sNamespace,
 		FRRK8sSecretPassthrough: *frrK8sSecretPassthrough,
+		FRRK8sConfigPerPeer:     *frrK8sConfigPerPeer,
 		Logger:                  logger,
 		LogLevel:                logging.Level(*logLevel),
 		SList:                   sList,
@@ -224,6 +260,10 @@ func main() {
 		InterfaceExcludeRegexp:  interfacesToExclude,
 		IgnoreExcludeLB:         *ignoreLBExclude,
 		BGPDebounceTimeout:      bgpDebounceTimeout,
+		FRRIncrementalConfig:    *frrIncrementalConfig,
+		BGPDampeningHalfLife:    *bgpDampeningHalfLife,
+		BGPDampeningHold:        dampeningHold(*bgpDampeningHold),
+		BGPDampeningReuse:       func() { forceSync() },
 		Layer2StatusChange: func(namespacedName types.NamespacedName) {
 			l2StatusChan <- controllers.NewL2StatusEvent(namespacedName.Namespace, namespacedName.Name)
 		},
@@ -235,20 +275,50 @@ func main() {
 			}
 			bgpStatusChan <- controllers.NewBGPStatusEvent(ns, name)
 		},
+		BGPSessionStateChange: func() {
+			// All the sessions are reconciled together, no need to queue
+			// more than one event.
+			select {
+			case bgpSessionStateChan <- controllers.NewBGPSessionStateEvent(*namespace, *myNode):
+			default:
+			}
+		},
+		BGPReloadStatusChange: func() {
+			// Only the last reload is reported.
+			select {
+			case bgpReloadStatusChan <- controllers.NewBGPReloadStatusEvent(*namespace, *myNode):
+			default:
+			}
+		},
 	})
 	if err != nil {
 		level.Error(logger).Log("op", "startup", "error", err, "msg", "failed to create MetalLB controller")
 		os.Exit(1)
 	}
 
-	var validateConfig config.Validate
-	if bgpType == "native" {
-		validateConfig = config.DiscardFRROnly
-	} else {
-		validateConfig = config.DiscardNativeOnly
+	// The peers deriving their address from the default gateway follow it
+	// as the routes of the node change.
+	err = watchDefaultRoutes(logger, func() {
+		select {
+		case nodeChan <- controllers.NewNodeEvent(*myNode):
+		default:
+		}
+	})
+	if err != nil {
+		level.Error(logger).Log("op", "startup", "error", err, "msg", "failed to watch the default routes, the default gateway is resolved on the node and configuration changes only")
 	}
 
+	validateConfig := config.ValidationFor(bgpType)
+
 	listenFRRK8s := bgpType == string(bgpFrrK8s)
+	var sessionStateChan <-chan event.GenericEvent
+	if ctrl.bgpSessionStatesFetcher != nil {
+		sessionStateChan = bgpSessionStateChan
+	}
+	var reloadStatusChan <-chan event.GenericEvent
+	if ctrl.bgpReloadStatusFetcher != nil {
+		reloadStatusChan = bgpReloadStatusChan
+	}
 	client, err := k8s.New(&k8s.Config{
 		ProcessName: "metallb-speaker",
 		NodeName:    *myNode,
@@ -268,22 +338,32 @@ func main() {
 			ConfigChanged:  ctrl.SetConfig,
 			NodeChanged:    ctrl.SetNode,
 		},
+		NodeChan:                nodeChan,
 		ValidateConfig:          validateConfig,
 		LoadBalancerClass:       *loadBalancerClass,
 		WithFRRK8s:              listenFRRK8s,
 		FRRK8sNamespace:         *frrK8sNamespace,
 		FRRK8sSecretPassthrough: *frrK8sSecretPassthrough,
 
+		FRRK8sSessionStatesHandler: ctrl.frrk8sSessionStatesHandler,
+
 		Layer2StatusChan:    l2StatusChan,
 		Layer2StatusFetcher: ctrl.layer2StatusFetchFunc,
 		BGPStatusChan:       bgpStatusChan,
 		BGPPeersFetcher:     ctrl.bgpPeersFetcher,
+		BGPPrefixesFetcher:  ctrl.bgpPrefixesFetcher,
+
+		BGPSessionStateChan:     sessionStateChan,
+		BGPSessionStatesFetcher: ctrl.bgpSessionStatesFetcher,
+		BGPReloadStatusChan:     reloadStatusChan,
+		BGPReloadStatusFetcher:  ctrl.bgpReloadStatusFetcher,
 	})
 	if err != nil {
 		level.Error(logger).Log("op", "startup", "error", err, "msg", "failed to create k8s client")
 		os.Exit(1)
 	}
 	ctrl.client = client
+	forceSync = client.ForceSync
 	ctrl.protocolHandlers[config.BGP].SetEventCallback(client.BGPEventCallback)
 
 	sList.Start(client)
@@ -311,6 +391,15 @@ type controller struct {
 
 	layer2StatusFetchFunc controllers.L2StatusFetcher
 	bgpPeersFetcher       controllers.PeersForService
+	bgpPrefixesFetcher    controllers.PrefixesForService
+	// bgpSessionStatesFetcher is nil if the BGP implementation does not
+	// report the state of its sessions.
+	bgpSessionStatesFetcher controllers.BGPSessionStatesFetcher
+	// bgpReloadStatusFetcher is nil if the BGP implementation does not
+	// report the outcome of its configuration reloads.
+	bgpReloadStatusFetcher controllers.BGPReloadStatusFetcher
+	// frrk8sSessionStatesHandler is nil unless running with FRR-K8s.
+	frrk8sSessionStatesHandler controllers.FRRK8sSessionStatesHandler
 }
 
 type controllerConfig struct {
@@ -318,6 +407,7 @@ type controllerConfig struct {
 	Namespace               string
 	FRRK8sNamespace         string
 	FRRK8sSecretPassthrough bool
+	FRRK8sConfigPerPeer     bool
 	Logger                  log.Logger
 	LogLevel                logging.Level
 	SList                   SpeakerList
@@ -332,8 +422,14 @@ type controllerConfig struct {
 	InterfaceExcludeRegexp       *regexp.Regexp
 	IgnoreExcludeLB              bool
 	BGPDebounceTimeout           time.Duration
+	FRRIncrementalConfig         bool
+	BGPDampeningHalfLife         time.Duration
+	BGPDampeningHold             dampeningHold
+	BGPDampeningReuse            func()
 	Layer2StatusChange           func(types.NamespacedName)
 	BGPAdsChangedCallback        func(string)
+	BGPSessionStateChange        func()
+	BGPReloadStatusChange        func()
 }
 
 func newController(cfg controllerConfig) (*controller, error) {
@@ -348,14 +444,33 @@ func newController(cfg controllerConfig) (*controller, error) {
 		logger:             cfg.Logger,
 		myNode:             cfg.MyNode,
 		svcAds:             make(map[string][]*bgp.Advertisement),
-		activeAds:          make(map[string]sets.Set[string]),
+		activeAds:          make(map[string]map[string]sets.Set[string]),
+		excludedSvcs:       make(map[string]string),
 		adsChangedCallback: cfg.BGPAdsChangedCallback,
 		bgpType:            cfg.bgpType,
 		sessionManager:     newBGP(cfg),
 		ignoreExcludeLB:    cfg.IgnoreExcludeLB,
 		secretHandling:     secretHandling,
 	}
+	if cfg.BGPDampeningHalfLife > 0 {
+		bgpController.dampening = newDampening(cfg.BGPDampeningHalfLife, cfg.BGPDampeningHold, cfg.BGPDampeningReuse)
+	}
 	bgpPeersFetcher := bgpController.PeersForService
+	bgpPrefixesFetcher := bgpController.PrefixesForService
+	var bgpSessionStatesFetcher controllers.BGPSessionStatesFetcher
+	if reporter, ok := bgpController.sessionManager.(bgp.SessionStateReporter); ok {
+		if cfg.BGPSessionStateChange != nil {
+			reporter.SetSessionStateCallback(cfg.BGPSessionStateChange)
+		}
+		bgpSessionStatesFetcher = reporter.SessionStates
+	}
+	var bgpReloadStatusFetcher controllers.BGPReloadStatusFetcher
+	if reporter, ok := bgpController.sessionManager.(bgp.ReloadStatusReporter); ok {
+		if cfg.BGPReloadStatusChange != nil {
+			reporter.SetReloadStatusCallback(cfg.BGPReloadStatusChange)
+		}
+		bgpReloadStatusFetcher = reporter.ReloadStatus
+	}
 
 	handlers := map[config.Proto]Protocol{
 		config.BGP: bgpController,
@@ -388,6 +503,13 @@ func newController(cfg controllerConfig) (*controller, error) {
 		protocols:             protocols,
 		layer2StatusFetchFunc: layer2StatusFetcher,
 		bgpPeersFetcher:       bgpPeersFetcher,
+		bgpPrefixesFetcher:    bgpPrefixesFetcher,
+
+		bgpSessionStatesFetcher: bgpSessionStatesFetcher,
+		bgpReloadStatusFetcher:  bgpReloadStatusFetcher,
+	}
+	if cfg.bgpType == bgpFrrK8s {
+		ret.frrk8sSessionStatesHandler = bgpController.SyncFRRK8sSessionStates
 	}
 	ret.announced[config.BGP] = map[string]bool{}
 	ret.announced[config.Layer2] = map[string]bool{}
@@ -475,7 +597,12 @@ func (c *controller) handleService(l log.Logger,
 		return c.deleteBalancerProtocol(l, protocol, name, deleteReason)
 	}
 
-	if err := handler.SetBalancer(l, name, lbIPs, pool, c.client, svc); err != nil {
+	err := handler.SetBalancer(l, name, lbIPs, pool, c.client, svc, eps)
+	if errors.Is(err, errHeld) {
+		// The service is held withdrawn.
+		return c.withdrawn(l, protocol, name, "dampened")
+	}
+	if err != nil {
 		level.Error(l).Log("op", "setBalancer", "error", err, "msg", "failed to announce service")
 		return controllers.SyncStateError
 	}
@@ -508,15 +635,32 @@ func (c *controller) deleteBalancer(l log.Logger, name, reason string) controlle
 }
 
 func (c *controller) deleteBalancerProtocol(l log.Logger, protocol config.Proto, name, reason string) controllers.SyncState {
-	announced := c.announced[protocol][name]
-	if !announced {
+	handler := c.protocolHandlers[protocol]
+	if handler == nil {
 		return controllers.SyncStateSuccess
 	}
-
-	if err := c.protocolHandlers[protocol].DeleteBalancer(l, name, reason); err != nil {
+	// The handler is told about the services it does not announce too,
+	// as it may report the reason.
+	err := handler.DeleteBalancer(l, name, reason)
+	if errors.Is(err, errHeld) {
+		// The service is held announced.
+		level.Debug(l).Log("event", "serviceHeld", "reason", reason, "protocol", protocol, "msg", "service announcement held")
+		return controllers.SyncStateSuccess
+	}
+	if err != nil {
 		level.Error(l).Log("op", "deleteBalancer", "error", err, "msg", "failed to clear balancer state", "protocol", protocol)
 		return controllers.SyncStateError
 	}
+	return c.withdrawn(l, protocol, name, reason)
+}
+
+// withdrawn clears the state of the service announced with the given
+// protocol, which is not anymore.
+func (c *controller) withdrawn(l log.Logger, protocol config.Proto, name, reason string) controllers.SyncState {
+	announced := c.announced[protocol][name]
+	if !announced {
+		return controllers.SyncStateSuccess
+	}
 
 	for _, ip := range c.svcIPs[name] {
 		ok := announcing.Delete(prometheus.Labels{
@@ -668,11 +812,16 @@ func isNodeAvailableChanged(oldNodes map[string]*v1.Node, newNode *v1.Node) bool
 	return false
 }
 
+// errHeld is returned by the Protocols holding a service in the state it
+// was in: SetBalancer keeps it withdrawn, and DeleteBalancer keeps it
+// announced.
+var errHeld = errors.New("service announcement held")
+
 // A Protocol can advertise an IP address.
 type Protocol interface {
 	SetConfig(log.Logger, *config.Config) error
 	ShouldAnnounce(log.Logger, string, []net.IP, *config.Pool, *v1.Service, []discovery.EndpointSlice, map[string]*v1.Node) string
-	SetBalancer(log.Logger, string, []net.IP, *config.Pool, service, *v1.Service) error
+	SetBalancer(log.Logger, string, []net.IP, *config.Pool, service, *v1.Service, []discovery.EndpointSlice) error
 	DeleteBalancer(log.Logger, string, string) error
 	SetNode(log.Logger, *v1.Node) error
 	SetEventCallback(func(interface{}))
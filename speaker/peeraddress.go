@@ -0,0 +1,242 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"go.universe.tf/metallb/internal/config"
+	"go.universe.tf/metallb/internal/ipfamily"
+)
+
+// peerAddress returns the address to dial for the given peer on the node,
+// resolving it from the node when the peer derives it from there, along with
+// the interface it is reached through when it is a link-local address.
+func (c *bgpController) peerAddress(p *config.Peer) (net.IP, string, error) {
+	from := p.AddrFrom
+	if from == nil {
+		return p.Addr, "", nil
+	}
+
+	var value string
+	switch {
+	case from.NodeAnnotation != "":
+		value = c.nodeAnnotations[from.NodeAnnotation]
+		if value == "" {
+			return nil, "", fmt.Errorf("node %s has no annotation %s", c.myNode, from.NodeAnnotation)
+		}
+	case from.NodeLabel != "":
+		value = c.nodeLabels[from.NodeLabel]
+		if value == "" {
+			return nil, "", fmt.Errorf("node %s has no label %s", c.myNode, from.NodeLabel)
+		}
+		// Label values can't contain colons, IPv6 addresses use dashes instead.
+		value = strings.ReplaceAll(value, "-", ":")
+	default:
+		return defaultGateway(from.DefaultGateway)
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, "", fmt.Errorf("invalid peer address %q on node %s", value, c.myNode)
+	}
+	return ip, "", nil
+}
+
+// defaultGateway returns the gateway of the default route of the node
+// for the given family, from the main routing table, along with the
+// interface it is reached through when it is a link-local address. The
+// first nexthop is used for the multipath routes.
+var defaultGateway = func(family ipfamily.Family) (net.IP, string, error) {
+	af := syscall.AF_INET
+	if family == ipfamily.IPv6 {
+		af = syscall.AF_INET6
+	}
+	rib, err := syscall.NetlinkRIB(syscall.RTM_GETROUTE, af)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to dump the %s routes: %w", family, err)
+	}
+	msgs, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse the %s routes: %w", family, err)
+	}
+
+	var (
+		gateway  *nexthop
+		priority uint32
+	)
+	for _, m := range msgs {
+		if m.Header.Type != syscall.RTM_NEWROUTE || !isDefaultRoute(m.Data, af) {
+			continue
+		}
+		attrs, err := syscall.ParseNetlinkRouteAttr(&m)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse the %s default route: %w", family, err)
+		}
+		var prio uint32
+		for _, a := range attrs {
+			if a.Attr.Type == syscall.RTA_PRIORITY && len(a.Value) == 4 {
+				prio = binary.NativeEndian.Uint32(a.Value)
+			}
+		}
+		nexthops := routeNexthops(attrs)
+		if len(nexthops) == 0 {
+			continue
+		}
+		if gateway == nil || prio < priority {
+			gateway, priority = &nexthops[0], prio
+		}
+	}
+	if gateway == nil {
+		return nil, "", fmt.Errorf("no %s default gateway found", family)
+	}
+	if gateway.ip.To4() != nil || !gateway.ip.IsLinkLocalUnicast() {
+		return gateway.ip, "", nil
+	}
+	iface, err := net.InterfaceByIndex(gateway.ifindex)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find the interface of the %s default gateway %s: %w", family, gateway.ip, err)
+	}
+	return gateway.ip, iface.Name, nil
+}
+
+// isDefaultRoute tells if the given rtmsg is the one of a unicast default
+// route of the given address family, in the main routing table.
+func isDefaultRoute(rtmsg []byte, af int) bool {
+	if len(rtmsg) < syscall.SizeofRtMsg {
+		return false
+	}
+	// The rtmsg header: family, dst_len, src_len, tos, table, protocol, scope, type.
+	return int(rtmsg[0]) == af && rtmsg[1] == 0 && rtmsg[4] == syscall.RT_TABLE_MAIN && rtmsg[7] == syscall.RTN_UNICAST
+}
+
+type nexthop struct {
+	ip      net.IP
+	ifindex int
+}
+
+// routeNexthops returns the nexthops of a route, either its own gateway or
+// the ones of its RTA_MULTIPATH attribute for the multipath routes.
+func routeNexthops(attrs []syscall.NetlinkRouteAttr) []nexthop {
+	var (
+		res    []nexthop
+		single nexthop
+	)
+	for _, a := range attrs {
+		switch a.Attr.Type {
+		case syscall.RTA_GATEWAY:
+			single.ip = net.IP(a.Value)
+		case syscall.RTA_OIF:
+			if len(a.Value) == 4 {
+				single.ifindex = int(binary.NativeEndian.Uint32(a.Value))
+			}
+		case syscall.RTA_MULTIPATH:
+			res = append(res, parseMultipath(a.Value)...)
+		}
+	}
+	if single.ip != nil {
+		res = append([]nexthop{single}, res...)
+	}
+	return res
+}
+
+// parseMultipath parses the value of a RTA_MULTIPATH attribute, a list of
+// rtnexthop headers each followed by the attributes of the nexthop.
+func parseMultipath(b []byte) []nexthop {
+	var res []nexthop
+	for len(b) >= syscall.SizeofRtNexthop {
+		// The rtnexthop header: len, flags, hops, ifindex.
+		l := int(binary.NativeEndian.Uint16(b[0:2]))
+		if l < syscall.SizeofRtNexthop || l > len(b) {
+			break
+		}
+		nh := nexthop{ifindex: int(int32(binary.NativeEndian.Uint32(b[4:8])))}
+		attrs := b[syscall.SizeofRtNexthop:l]
+		for len(attrs) >= syscall.SizeofRtAttr {
+			// The rtattr header: len, type.
+			al := int(binary.NativeEndian.Uint16(attrs[0:2]))
+			if al < syscall.SizeofRtAttr || al > len(attrs) {
+				break
+			}
+			if binary.NativeEndian.Uint16(attrs[2:4]) == syscall.RTA_GATEWAY {
+				nh.ip = net.IP(attrs[syscall.SizeofRtAttr:al])
+			}
+			attrs = attrs[min(rtaAlign(al), len(attrs)):]
+		}
+		if nh.ip != nil {
+			res = append(res, nh)
+		}
+		b = b[min(rtaAlign(l), len(b)):]
+	}
+	return res
+}
+
+func rtaAlign(l int) int {
+	return (l + syscall.RTA_ALIGNTO - 1) &^ (syscall.RTA_ALIGNTO - 1)
+}
+
+// watchDefaultRoutes calls changed whenever a default route of the main
+// routing table is added or removed on the node, for the peers deriving
+// their address from the default gateway to follow it.
+func watchDefaultRoutes(l log.Logger, changed func()) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return fmt.Errorf("failed to open the netlink socket: %w", err)
+	}
+	sa := &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		// The groups are a bitmask of the RTNLGRP values.
+		Groups: 1<<(syscall.RTNLGRP_IPV4_ROUTE-1) | 1<<(syscall.RTNLGRP_IPV6_ROUTE-1),
+	}
+	if err := syscall.Bind(fd, sa); err != nil {
+		syscall.Close(fd)
+		return fmt.Errorf("failed to subscribe to the route updates: %w", err)
+	}
+
+	go func() {
+		defer syscall.Close(fd)
+		buf := make([]byte, os.Getpagesize()*8)
+		for {
+			n, _, err := syscall.Recvfrom(fd, buf, 0)
+			if errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			if err != nil {
+				// ENOBUFS means updates were dropped, one of them may be
+				// about a default route.
+				if errors.Is(err, syscall.ENOBUFS) {
+					changed()
+					continue
+				}
+				level.Error(l).Log("op", "watchDefaultRoutes", "error", err, "msg", "failed to receive the route updates, no longer following the default gateway")
+				return
+			}
+			msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+			if err != nil {
+				level.Error(l).Log("op", "watchDefaultRoutes", "error", err, "msg", "failed to parse the route updates")
+				continue
+			}
+			if slices.ContainsFunc(msgs, isDefaultRouteUpdate) {
+				changed()
+			}
+		}
+	}()
+	return nil
+}
+
+func isDefaultRouteUpdate(m syscall.NetlinkMessage) bool {
+	if m.Header.Type != syscall.RTM_NEWROUTE && m.Header.Type != syscall.RTM_DELROUTE {
+		return false
+	}
+	return isDefaultRoute(m.Data, syscall.AF_INET) || isDefaultRoute(m.Data, syscall.AF_INET6)
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRouteNexthops(t *testing.T) {
+	u32 := func(v uint32) []byte {
+		return binary.NativeEndian.AppendUint32(nil, v)
+	}
+	// rtnexthop returns a nexthop of a RTA_MULTIPATH attribute, holding
+	// its gateway attribute.
+	rtnexthop := func(ifindex uint32, gateway net.IP) []byte {
+		attr := binary.NativeEndian.AppendUint16(nil, uint16(syscall.SizeofRtAttr+len(gateway)))
+		attr = binary.NativeEndian.AppendUint16(attr, syscall.RTA_GATEWAY)
+		attr = append(attr, gateway...)
+		for len(attr)%syscall.RTA_ALIGNTO != 0 {
+			attr = append(attr, 0)
+		}
+		b := binary.NativeEndian.AppendUint16(nil, uint16(syscall.SizeofRtNexthop+len(attr)))
+		b = append(b, 0, 0)
+		b = binary.NativeEndian.AppendUint32(b, ifindex)
+		return append(b, attr...)
+	}
+	attr := func(typ uint16, value []byte) syscall.NetlinkRouteAttr {
+		return syscall.NetlinkRouteAttr{Attr: syscall.RtAttr{Type: typ}, Value: value}
+	}
+
+	tests := []struct {
+		desc  string
+		attrs []syscall.NetlinkRouteAttr
+		want  []nexthop
+	}{
+		{
+			desc: "single gateway",
+			attrs: []syscall.NetlinkRouteAttr{
+				attr(syscall.RTA_GATEWAY, net.ParseIP("192.168.0.1").To4()),
+				attr(syscall.RTA_OIF, u32(2)),
+			},
+			want: []nexthop{{ip: net.ParseIP("192.168.0.1").To4(), ifindex: 2}},
+		},
+		{
+			desc: "multipath",
+			attrs: []syscall.NetlinkRouteAttr{
+				attr(syscall.RTA_MULTIPATH, append(
+					rtnexthop(3, net.ParseIP("fe80::1")),
+					rtnexthop(4, net.ParseIP("fe80::2"))...)),
+			},
+			want: []nexthop{
+				{ip: net.ParseIP("fe80::1"), ifindex: 3},
+				{ip: net.ParseIP("fe80::2"), ifindex: 4},
+			},
+		},
+		{
+			desc: "truncated multipath",
+			attrs: []syscall.NetlinkRouteAttr{
+				attr(syscall.RTA_MULTIPATH, rtnexthop(3, net.ParseIP("10.0.0.1").To4())[:10]),
+			},
+		},
+		{
+			desc: "no gateway",
+			attrs: []syscall.NetlinkRouteAttr{
+				attr(syscall.RTA_OIF, u32(2)),
+			},
+		},
+	}
+	for _, test := range tests {
+		got := routeNexthops(test.attrs)
+		if diff := cmp.Diff(test.want, got, cmp.AllowUnexported(nexthop{})); diff != "" {
+			t.Errorf("%s: unexpected nexthops (-want +got)\n%s", test.desc, diff)
+		}
+	}
+}
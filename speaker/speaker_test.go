@@ -159,10 +159,76 @@ func TestLoadBalancerCreation(t *testing.T) {
 	}
 }
 
+func TestLoadBalancerHeld(t *testing.T) {
+	l2MockHandler := &MockProtocol{protocol: config.Layer2}
+	bgpMockHandler := &MockProtocol{protocol: config.BGP, shouldAnnounce: true}
+	c := mockNewController(l2MockHandler, bgpMockHandler, t)
+
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "testsvc",
+		},
+		Spec: v1.ServiceSpec{
+			Type:                  "LoadBalancer",
+			ExternalTrafficPolicy: "Cluster",
+		},
+		Status: statusAssigned("10.20.30.1"),
+	}
+	cfg := &config.Config{
+		Pools: &config.Pools{ByName: map[string]*config.Pool{
+			"default": {
+				CIDR: []*net.IPNet{ipnet("10.20.30.0/24")},
+			},
+		}},
+	}
+	if state := c.SetConfig(logger, cfg); state != controllers.SyncStateReprocessAll {
+		t.Fatalf("Set config failed")
+	}
+
+	if state := c.SetBalancer(logger, "testsvc", svc, nil); state != controllers.SyncStateSuccess {
+		t.Fatalf("Set balancer failed")
+	}
+	if !c.announced[config.BGP]["testsvc"] {
+		t.Fatal("ip is not announced in bgp")
+	}
+
+	// The handler keeps the service announced, it is not withdrawn.
+	bgpMockHandler.held = true
+	bgpMockHandler.shouldAnnounce = false
+	if state := c.SetBalancer(logger, "testsvc", svc, nil); state != controllers.SyncStateSuccess {
+		t.Fatalf("Set balancer failed")
+	}
+	if !bgpMockHandler.deleteBalancerCalled {
+		t.Fatal("bgp delete handler was not called")
+	}
+	if !c.announced[config.BGP]["testsvc"] {
+		t.Fatal("held announced, ip is not announced in bgp")
+	}
+	if _, ok := c.svcIPs["testsvc"]; !ok {
+		t.Fatal("held announced, svc ip is removed")
+	}
+
+	// The handler keeps the service withdrawn, it is not announced.
+	bgpMockHandler.shouldAnnounce = true
+	if state := c.SetBalancer(logger, "testsvc", svc, nil); state != controllers.SyncStateSuccess {
+		t.Fatalf("Set balancer failed")
+	}
+	if !bgpMockHandler.setBalancerCalled {
+		t.Fatal("bgp handler was not called")
+	}
+	if c.announced[config.BGP]["testsvc"] {
+		t.Fatal("held withdrawn, ip is announced in bgp")
+	}
+	if _, ok := c.svcIPs["testsvc"]; ok {
+		t.Fatal("held withdrawn, svc ip is not removed")
+	}
+}
+
 type MockProtocol struct {
 	config               *config.Config
 	protocol             config.Proto
 	shouldAnnounce       bool
+	held                 bool
 	setBalancerCalled    bool
 	deleteBalancerCalled bool
 }
@@ -179,13 +245,19 @@ func (m *MockProtocol) ShouldAnnounce(_ log.Logger, _ string, _ []net.IP, _ *con
 	return "no announce"
 }
 
-func (m *MockProtocol) SetBalancer(_ log.Logger, _ string, _ []net.IP, _ *config.Pool, _ service, _ *v1.Service) error {
+func (m *MockProtocol) SetBalancer(_ log.Logger, _ string, _ []net.IP, _ *config.Pool, _ service, _ *v1.Service, _ []discovery.EndpointSlice) error {
 	m.setBalancerCalled = true
+	if m.held {
+		return errHeld
+	}
 	return nil
 }
 
 func (m *MockProtocol) DeleteBalancer(_ log.Logger, _ string, _ string) error {
 	m.deleteBalancerCalled = true
+	if m.held {
+		return errHeld
+	}
 	return nil
 }
 
@@ -10,7 +10,7 @@ OUTPUT="./report"
 
 function get_metallb_crs() {
     declare -a METALLB_CRDS=("bgppeers" "bfdprofiles" "bgpAdvertisements" "ipaddresspools" 
-    "l2advertisements" "communities" "servicel2statuses" "servicebgpstatuses" "frrconfigurations" "frrnodestates")
+    "l2advertisements" "communities" "servicel2statuses" "servicebgpstatuses" "bgppeersessionstates" "frrconfigurations" "frrnodestates")
     mkdir -p ${OUTPUT}/crds/
 
     for CRD in "${METALLB_CRDS[@]}"; do